@@ -1,15 +1,15 @@
 package authservice
 
 import (
-	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/kelcheone/chemistke/cmd/utils"
 	user_proto "github.com/kelcheone/chemistke/pkg/grpc/user"
 	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type User struct {
@@ -35,7 +35,8 @@ type LoginResponse struct {
 
 // ErrResponse represents an error response
 type ErrResponse struct {
-	Message string `json:"message" example:"invalid credentials"` // Error message
+	Message string `json:"error" example:"invalid credentials"` // Error message
+	Code    string `json:"code,omitempty" example:"unauthenticated"`
 }
 
 // NewUserClient initializes a new user client with the gRPC service
@@ -54,14 +55,9 @@ func NewUserClient(client user_proto.UserServiceClient) {
 // @Failure 400 {object} ErrResponse "Bad request - invalid input"
 // @Failure 401 {object} ErrResponse "Unauthorized - invalid credentials"
 // @Failure 500 {object} ErrResponse "Internal server error"
+// @Failure 503 {object} ErrResponse "User service unavailable"
 // @Router /auth/login [post]
 func (u *User) Login(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-
-	_ = ctx
-
-	defer cancel()
-
 	var user User
 
 	if err := c.Bind(&user); err != nil {
@@ -76,36 +72,29 @@ func (u *User) Login(c echo.Context) error {
 			Message: fmt.Sprintf("could not validate request: %+v", err.Error()),
 		})
 	}
-	log.Printf("got use details: %+v", user)
-
-	getUserResp, err := u.Client.GetUserByEmail(ctx, &user_proto.GetUserByEmailRequest{Email: user.Email})
-	if err != nil {
-		log.Println("error getting user by email: ", err.Error())
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrResponse{Message: fmt.Sprintf("%v", err.Error())},
-		)
-	}
-
-	hashedPassword := getUserResp.User.Password
-
-	err = utils.ComparePassword(user.Password, hashedPassword)
+	verifyResp, err := u.Client.VerifyCredentials(c.Request().Context(), &user_proto.VerifyCredentialsRequest{
+		Email:    user.Email,
+		Password: user.Password,
+	})
 	if err != nil {
-		log.Println("error comparing passwords: ", err.Error())
+		// only bad credentials are a 401, anything else is left to the
+		// error handler, which maps it like any other service error.
+		if status.Code(err) != codes.Unauthenticated {
+			return err
+		}
+		slog.WarnContext(c.Request().Context(), "login failed", "error", err)
 		return c.JSON(
-			http.StatusInternalServerError,
-			ErrResponse{Message: fmt.Sprintf("%v", err.Error())},
+			http.StatusUnauthorized,
+			ErrResponse{Message: "invalid credentials", Code: "unauthenticated"},
 		)
 	}
 
-	fmt.Printf("%+v\n", getUserResp)
-
 	tokenString, err := utils.CreateToken(
-		getUserResp.User.Id.Value,
-		getUserResp.User.Email,
-		getUserResp.User.Name,
-		getUserResp.User.Phone,
-		getUserResp.User.Role.String(),
+		verifyResp.User.Id.Value,
+		verifyResp.User.Email,
+		verifyResp.User.Name,
+		verifyResp.User.Phone,
+		verifyResp.User.Role.String(),
 	)
 	if err != nil {
 		return c.JSON(
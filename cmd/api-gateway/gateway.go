@@ -1,18 +1,17 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"os"
 
-	"github.com/go-playground/validator"
-	"github.com/joho/godotenv"
-	authservice "github.com/kelcheone/chemistke/cmd/api-gateway/auth"
 	routes "github.com/kelcheone/chemistke/cmd/api-gateway/routes"
 	"github.com/kelcheone/chemistke/cmd/utils"
 	_ "github.com/kelcheone/chemistke/docs"
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/tracing"
 	user_proto "github.com/kelcheone/chemistke/pkg/grpc/user"
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
@@ -26,14 +25,6 @@ func NewServer(userClient user_proto.UserServiceClient) *Server {
 	}
 }
 
-type CustomValidator struct {
-	validator *validator.Validate
-}
-
-func (cv *CustomValidator) Validate(i interface{}) error {
-	return cv.validator.Struct(i)
-}
-
 /*
 @host chemistke-production.up.railway.app
 */
@@ -60,182 +51,76 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 // @description JWT Authorization header using the Bearer scheme. Example: "Authorization: Bearer {token}"
 
 func main() {
-	_ = godotenv.Load()
-	userServer, CloseUserConn, err := routes.ConnectUserServer(os.Getenv("USER_SERVICE_HOST"))
+	logging.Init("api-gateway")
+	if err := run(); err != nil {
+		logging.Fatal("api-gateway stopped", "error", err)
+	}
+}
+
+func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	logging.SetLevel(cfg.Log.Level)
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		fmt.Print(cfg)
+		return nil
+	}
+	if err := cfg.ValidateGateway(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	utils.SetSecretKey(cfg.Gateway.JWTSecret)
+
+	shutdownTracing, err := tracing.Init(context.Background(), "api-gateway", cfg.Tracing)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("could not start tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	userServer, CloseUserConn, err := routes.ConnectUserServer(cfg.Services.User.Host)
+	if err != nil {
+		return fmt.Errorf("could not connect to the user service: %w", err)
 	}
 
 	defer CloseUserConn()
 
-	productsServer, CloseProductConn, err := routes.ConnectProductServer(
-		os.Getenv("PRODUCT_SERVICE_HOST"),
-	)
+	productsServer, CloseProductConn, err := routes.ConnectProductServer(cfg.Services.Product.Host)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("could not connect to the product service: %w", err)
 	}
 
 	defer CloseProductConn()
 
-	ordersServer, CloseOrderConn, err := routes.ConnectOrdersServer(
-		os.Getenv("ORDERS_SERVICE_HOST"),
-	)
+	ordersServer, CloseOrderConn, err := routes.ConnectOrdersServer(cfg.Services.Order.Host)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("could not connect to the orders service: %w", err)
 	}
 
 	defer CloseOrderConn()
+	ordersServer.UserClient = userServer.UserClient
 
-	cmsServer, CloseCmsConn, err := routes.ConnectCmsServer(os.Getenv("CMS_SERVICE_HOST"))
+	cmsServer, CloseCmsConn, err := routes.ConnectCmsServer(cfg.Services.Cms.Host)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("could not connect to the cms service: %w", err)
 	}
 
 	defer CloseCmsConn()
 
-	e := echo.New()
-	e.Validator = &CustomValidator{validator: validator.New()}
-	e.IPExtractor = echo.ExtractIPFromXFFHeader()
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"http://localhost:3000"},
-		AllowHeaders: []string{
-			echo.HeaderOrigin,
-			echo.HeaderContentType,
-			echo.HeaderAccept,
-			echo.HeaderAuthorization,
-			"X-Requested-With",
-		},
-		AllowMethods: []string{
-			echo.GET,
-			echo.HEAD,
-			echo.PUT,
-			echo.PATCH,
-			echo.POST,
-			echo.DELETE,
-			echo.OPTIONS,
-		},
-		AllowCredentials: true,
-		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
-	}))
-	e.Use(middleware.Logger())
+	seoServer := &routes.SeoServer{
+		CmsClient:     cmsServer.CmsClient,
+		ProductClient: productsServer.ProductClient,
+		SiteURL:       cfg.Gateway.SiteURL,
+	}
 
+	e := routes.NewRouter(routes.Servers{
+		Users:    userServer,
+		Products: productsServer,
+		Orders:   ordersServer,
+		Cms:      cmsServer,
+		Seo:      seoServer,
+	}, cfg.Gateway)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	v1 := e.Group("/api/v1")
-
-	users := v1.Group("/users")
-	users.POST("", userServer.CreateUser)
-	users.GET("/get-user", userServer.GetUser)
-	users.GET("", userServer.GetUsers, utils.AuthMiddleware())
-	users.GET("/get-user-by-email", userServer.GetUserByEmail)
-	users.PATCH("", userServer.UpdateUser, utils.AuthMiddleware())
-	users.DELETE("", userServer.DeleteUser, utils.AuthMiddleware())
-
-	auth := v1.Group("/auth")
-	auth.POST("/login", func(c echo.Context) error {
-		user := authservice.User{
-			Client: userServer.UserClient,
-		}
-
-		return user.Login(c)
-	})
-
-	auth.GET("/me", func(c echo.Context) error {
-		user := authservice.User{
-			Client: userServer.UserClient,
-		}
-
-		return user.Me(c)
-	}, utils.AuthMiddleware())
-
-	auth.POST("/logout", func(c echo.Context) error {
-		user := authservice.User{
-			Client: userServer.UserClient,
-		}
-
-		return user.Logout(c)
-	}, utils.AuthMiddleware())
-
-	products := v1.Group("/products")
-	products.POST("", productsServer.CreateProduct, utils.AuthMiddleware())
-	products.GET("/:id", productsServer.GetProduct)
-	products.GET("", productsServer.GetProducts)
-	products.GET("/featured", productsServer.GetFeaturedProducts)
-	products.GET("/by-brand/:id", productsServer.GetProductsByBrand)
-	products.GET("/by-category/:id", productsServer.GetProductsByCategory)
-	products.GET("/by-category/slug/:slug", productsServer.GetProductsByCategorySlug)
-	products.GET("/by-subcategory/:id", productsServer.GetProductsBySCategory)
-	products.GET("/slug/:slug", productsServer.GetProductBySlug)
-
-	products.POST("/reviews", productsServer.CreateReview, utils.AuthMiddleware())
-	products.GET("/ratings/:id", productsServer.GetProductRating)
-	products.GET("/reviews/:id", productsServer.GetReview)
-	products.GET("/:id/reviews", productsServer.GetReviews)
-
-	// product-category
-	products.POST("/categories", productsServer.CreateCategory, utils.AuthMiddleware())
-	products.GET("/categories/:id", productsServer.GetCategory)
-	products.GET("/categories", productsServer.GetCategories)
-	products.GET("/categories/featured", productsServer.GetFeaturedCategories)
-	products.PATCH("/categories", productsServer.UpdateCategory, utils.AuthMiddleware())
-	products.DELETE("/categories/:id", productsServer.DeleteCategory, utils.AuthMiddleware())
-	products.GET("/categories/:id/subcategories", productsServer.GetSubCategories)
-	products.GET("/categories/slug/:slug", productsServer.GetCategoryBySlug)
-	// product-sub-category
-	products.POST("/subcategories", productsServer.CreateSubCategory, utils.AuthMiddleware())
-	products.GET("/subcategories/:id", productsServer.GetSubCategory)
-	products.GET("/subcategories", productsServer.GetSubCategories)
-	products.PATCH("/subcategories", productsServer.UpdateSubCategory, utils.AuthMiddleware())
-	products.DELETE("/subcategories/:id", productsServer.DeleteSubCategory, utils.AuthMiddleware())
-	products.GET("/subcategories/slug/:slug", productsServer.GetSubCategoryBySlug)
-	// product-brand
-	products.POST("/brands", productsServer.CreateBrand, utils.AuthMiddleware())
-	products.GET("/brands", productsServer.GetBrands)
-	products.GET("/brands/:id", productsServer.GetBrand)
-	products.PATCH("/brands", productsServer.UpdateBrand, utils.AuthMiddleware())
-	products.DELETE("/brands/:id", productsServer.DeleteBrand, utils.AuthMiddleware())
-
-	products.PATCH("", productsServer.UpdateProduct, utils.AuthMiddleware())
-	products.DELETE("/:id", productsServer.DeleteProduct, utils.AuthMiddleware())
-	products.POST("/images/upload", productsServer.UploadImage)
-	products.GET("/images/:id", productsServer.GetProductImages)
-
-	orders := v1.Group("/orders", utils.AuthMiddleware())
-	orders.POST("", ordersServer.CreateOrder)
-	orders.GET("/:id", ordersServer.GetOrder)
-	orders.DELETE("/:id", ordersServer.DeleteOrder)
-	orders.GET("/user", ordersServer.GetUserOders)
-	orders.GET("", ordersServer.GetOders)
-	orders.PATCH("", ordersServer.UpdateOrder)
-
-	cms := v1.Group("/cms")
-
-	authors := cms.Group("/authors")
-	authors.POST("", cmsServer.CreateAuthor, utils.AuthMiddleware())
-	authors.GET("/:id", cmsServer.GetAuthor)
-	authors.PATCH("", cmsServer.UpdateAuthor, utils.AuthMiddleware())
-	authors.DELETE("/:id", cmsServer.DeleteAuthor, utils.AuthMiddleware())
-	authors.GET("", cmsServer.ListAuthors)
-
-	categories := cms.Group("/categories")
-	categories.POST("", cmsServer.CreateCategory, utils.AuthMiddleware())
-	categories.GET("/:id", cmsServer.GetCategory)
-	categories.GET("", cmsServer.ListCategories)
-	categories.PATCH("", cmsServer.UpdateCategory, utils.AuthMiddleware())
-	categories.DELETE("/:id", cmsServer.DeleteCategory, utils.AuthMiddleware())
-
-	posts := cms.Group("/posts")
-	posts.POST("", cmsServer.CreatePost, utils.AuthMiddleware())
-	posts.GET("/:id", cmsServer.GetPost)
-	posts.GET("", cmsServer.ListPosts)
-	posts.PATCH("", cmsServer.UpdatePost, utils.AuthMiddleware())
-	posts.DELETE("/:id", cmsServer.DeletePost, utils.AuthMiddleware())
-	posts.GET("/category", cmsServer.GetCategoryPosts)
-	posts.GET("/author", cmsServer.GetAuthorPosts)
-	posts.GET("/get-by-author-category", cmsServer.GetAuthorCategoryPosts)
-
-	e.GET("/health", func(c echo.Context) error {
-		return c.String(200, "OK")
-	})
-	e.Logger.Fatal(e.Start(":9090"))
+	return e.Start(cfg.Gateway.Addr)
 }
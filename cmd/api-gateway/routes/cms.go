@@ -1,12 +1,18 @@
 package routes
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/tracing"
 	cms_proto "github.com/kelcheone/chemistke/pkg/grpc/cms"
 	"github.com/labstack/echo/v4"
 	"google.golang.org/grpc"
@@ -21,11 +27,163 @@ type Post struct {
 	CoverImage    string `json:"cover_image"    example:"https://example.com/images/crypto-future.jpg"`
 	Title         string `json:"title"          example:"Why ozempic is good for you."                 binding:"required"`
 	Description   string `json:"description"    example:"10 benefits of ozempic"                       binding:"required"`
-	Slug          string `json:"slug"           example:"why-ozempic-is-good-for-you"                  binding:"required"`
-	Content       string `json:"content"        example:"lorem ipsum"                                  binding:"required"`
-	Status        string `json:"status"         example:"draft"                                        binding:"required"`
+	Slug          string `json:"slug"           example:"why-ozempic-is-good-for-you"`
+	Content       string `json:"content"        example:"## Lorem ipsum"                               binding:"required"`
+	Status        string `json:"status"         example:"draft"`
 	AuthorId      string `json:"author_id"      example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"         binding:"required"`
 	CategroyId    string `json:"category_id"    example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"         binding:"required"`
+	PublishAt     string `json:"publish_at,omitempty"  example:"2024-11-16T10:30:00Z"`
+	ReviewNote    string `json:"review_note,omitempty" example:"Please cite your sources"`
+	// rendered by the cms service from the markdown in Content
+	ContentHtml        string     `json:"content_html"         example:"<p>lorem ipsum</p>"`
+	Toc                []TocEntry `json:"toc"`
+	WordCount          int32      `json:"word_count"           example:"1200"`
+	ReadingTimeMinutes int32      `json:"reading_time_minutes" example:"6"`
+	// replaces the post's tags on create and update
+	TagIds []string `json:"tag_ids"        example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	Tags   []Tag    `json:"tags,omitempty"`
+	// products linked by hand, replaced on create and update. Content can
+	// also embed products with {{< product id="..." >}}.
+	ProductIds []string         `json:"product_ids"        example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	Products   []ProductSummary `json:"products,omitempty"`
+}
+
+// ProductSummary is a product card for a product mentioned in a post
+type ProductSummary struct {
+	Id       string  `json:"id"        example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	Name     string  `json:"name"      example:"Coartem 80/480mg"`
+	Slug     string  `json:"slug"      example:"coartem-80-480mg-a0c485f70db3"`
+	Price    float32 `json:"price"     example:"650"`
+	ImageUrl string  `json:"image_url" example:"https://example.com/images/coartem.png"`
+	InStock  bool    `json:"in_stock"  example:"true"`
+}
+
+// Tag represents a topic posts can be tagged with
+type Tag struct {
+	Id          string `json:"id"          example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	Name        string `json:"name"        example:"Diabetes"                               binding:"required"`
+	Slug        string `json:"slug"        example:"diabetes"`
+	Description string `json:"description" example:"Living with and managing diabetes"`
+	PostCount   int32  `json:"post_count"  example:"12"`
+}
+
+func convertTag(tag *cms_proto.Tag) Tag {
+	return Tag{
+		Id:          tag.TagId.GetValue(),
+		Name:        tag.Name,
+		Slug:        tag.Slug,
+		Description: tag.Description,
+		PostCount:   tag.PostCount,
+	}
+}
+
+func uuids(ids []string) []*cms_proto.UUID {
+	var out []*cms_proto.UUID
+	for _, id := range ids {
+		out = append(out, &cms_proto.UUID{Value: id})
+	}
+	return out
+}
+
+// TocEntry is a heading in a post's table of contents
+type TocEntry struct {
+	Level  int32  `json:"level"  example:"2"`
+	Text   string `json:"text"   example:"Symptoms"`
+	Anchor string `json:"anchor" example:"symptoms"`
+}
+
+// PostResponse wraps a single post
+type PostResponse struct {
+	Post Post `json:"post"`
+}
+
+// PostsResponse wraps a list of posts
+type PostsResponse struct {
+	Posts []Post `json:"posts"`
+}
+
+func convertPost(post *cms_proto.Post) Post {
+	var tags []Tag
+	for _, tag := range post.Tags {
+		tags = append(tags, convertTag(tag))
+	}
+
+	var productIds []string
+	for _, id := range post.ProductIds {
+		productIds = append(productIds, id.GetValue())
+	}
+	var products []ProductSummary
+	for _, product := range post.Products {
+		products = append(products, ProductSummary{
+			Id:       product.ProductId.GetValue(),
+			Name:     product.Name,
+			Slug:     product.Slug,
+			Price:    product.Price,
+			ImageUrl: product.ImageUrl,
+			InStock:  product.InStock,
+		})
+	}
+
+	toc := []TocEntry{}
+	for _, entry := range post.Toc {
+		toc = append(toc, TocEntry{
+			Level:  entry.Level,
+			Text:   entry.Text,
+			Anchor: entry.Anchor,
+		})
+	}
+
+	return Post{
+		Id:            post.PostId.GetValue(),
+		PublishedDate: post.PublishedDate,
+		UpdatedDate:   post.UpdatedDate,
+		CoverImage:    post.CoverImage,
+		Title:         post.Title,
+		Description:   post.Description,
+		Slug:          post.Slug,
+		Content:       post.Content,
+		Status:        strings.ToLower(post.Status.String()),
+		AuthorId:      post.AuthorId.GetValue(),
+		CategroyId:    post.CategoryId.GetValue(),
+		PublishAt:     post.PublishAt,
+		ReviewNote:    post.ReviewNote,
+
+		ContentHtml:        post.ContentHtml,
+		Toc:                toc,
+		WordCount:          post.WordCount,
+		ReadingTimeMinutes: post.ReadingTimeMinutes,
+		Tags:               tags,
+		ProductIds:         productIds,
+		Products:           products,
+	}
+}
+
+func convertPosts(posts []*cms_proto.Post) PostsResponse {
+	resp := PostsResponse{Posts: []Post{}}
+	for _, post := range posts {
+		resp.Posts = append(resp.Posts, convertPost(post))
+	}
+	return resp
+}
+
+// parsePostStatus accepts the lower case names used in the API, e.g. "in_review".
+func parsePostStatus(name string) (cms_proto.PostStatus, error) {
+	if name == "" {
+		return cms_proto.PostStatus_DRAFT, nil
+	}
+	st, ok := cms_proto.PostStatus_value[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown post status %q", name)
+	}
+	return cms_proto.PostStatus(st), nil
+}
+
+// isStaff reports whether the request carries an admin or author token, in
+// the cookie or the Authorization header. The public routes it is used on
+// run utils.OptionalAuthMiddleware to read it.
+func isStaff(c echo.Context) bool {
+	claims := utils.ExtractClaimsFromRequest(c)
+	return claims != nil && (claims.Admin || claims.Author)
 }
 
 // Author represents a given author
@@ -35,13 +193,14 @@ type Author struct {
 	Avatar string `json:"avatar"  example:"https://example.com/images/avatar.png"`
 	Url    string `json:"url"     example:"https://mywebsite.com"`
 	UserId string `json:"user_id" example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"  binding:"required"`
+	Slug   string `json:"slug"    example:"jane-doe"`
 }
 
 // Category represents category to which the content refers to
 type Category struct {
 	Id          string `json:"id"          example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
 	Name        string `json:"name"        example:"Antibiotics"                            binding:"required"`
-	Slug        string `json:"slug"        example:"antibiotics"                            binding:"required"`
+	Slug        string `json:"slug"        example:"antibiotics"`
 	Description string `json:"description" example:"All you need to know about antibiotics" binding:"required"`
 }
 
@@ -53,6 +212,8 @@ func ConnectCmsServer(link string) (*CmsServer, func(), error) {
 	cmsConn, err := grpc.NewClient(
 		link,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logging.UnaryClientInterceptor()),
+		tracing.DialOption(),
 	)
 	if err != nil {
 		return nil, nil, fmt.Errorf(
@@ -113,9 +274,7 @@ func (s *CmsServer) CreateAuthor(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	resp, err := s.CmsClient.CreateAuthor(
@@ -126,13 +285,12 @@ func (s *CmsServer) CreateAuthor(c echo.Context) error {
 				Url:    author.Url,
 				Avatar: author.Avatar,
 				UserId: &cms_proto.UUID{Value: author.UserId},
+				Slug:   author.Slug,
 			},
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -164,9 +322,7 @@ func (s *CmsServer) GetAuthor(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -216,13 +372,12 @@ func (s *CmsServer) UpdateAuthor(c echo.Context) error {
 				Avatar:   author.Avatar,
 				UserId:   &cms_proto.UUID{Value: author.UserId},
 				Url:      author.Url,
+				Slug:     author.Slug,
 			},
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusNoContent, resp)
@@ -263,9 +418,7 @@ func (s *CmsServer) DeleteAuthor(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -284,7 +437,6 @@ func (s *CmsServer) DeleteAuthor(c echo.Context) error {
 // @Router /cms/authors [get]
 func (s *CmsServer) ListAuthors(c echo.Context) error {
 	page := c.QueryParam("page")
-	log.Printf("The current page is %v\n", page)
 
 	int_page, err := strconv.Atoi(page)
 	if err != nil {
@@ -294,7 +446,6 @@ func (s *CmsServer) ListAuthors(c echo.Context) error {
 	}
 
 	limit := c.QueryParam("limit")
-	log.Printf("The required limit is: %v\n", limit)
 
 	int_limit, err := strconv.Atoi(limit)
 	if err != nil {
@@ -310,9 +461,7 @@ func (s *CmsServer) ListAuthors(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -355,9 +504,7 @@ func (s *CmsServer) CreateCategory(c echo.Context) error {
 
 	resp, err := s.CmsClient.CreateCategory(c.Request().Context(), nReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -388,10 +535,7 @@ func (s *CmsServer) GetCategory(c echo.Context) error {
 		&cms_proto.GetCategoryRequest{CategoryId: &cms_proto.UUID{Value: id}},
 	)
 	if err != nil {
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrResponse{Message: err.Error()},
-		)
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -442,9 +586,7 @@ func (s *CmsServer) UpdateCategory(c echo.Context) error {
 
 	resp, err := s.CmsClient.UpdateCategory(c.Request().Context(), nReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusNoContent, resp)
@@ -465,7 +607,6 @@ func (s *CmsServer) UpdateCategory(c echo.Context) error {
 func (s *CmsServer) DeleteCategory(c echo.Context) error {
 	claims := utils.ExtractClaimsFromRequest(c)
 
-	log.Printf("Is admin: %v is Author: %v\n", claims.Admin, claims.Author)
 	if !claims.Admin && !claims.Author {
 		return c.JSON(http.StatusUnauthorized, ErrResponse{
 			Message: "not authorized for this operations",
@@ -487,9 +628,7 @@ func (s *CmsServer) DeleteCategory(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusNoContent, resp)
 }
@@ -532,9 +671,7 @@ func (s *CmsServer) ListCategories(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -567,26 +704,31 @@ func (s *CmsServer) CreatePost(c echo.Context) error {
 		})
 	}
 
+	postStatus, err := parsePostStatus(post.Status)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: err.Error(),
+		})
+	}
+
 	nPost := &cms_proto.CreatePostRequest{
 		Post: &cms_proto.Post{
-			CategoryId:    &cms_proto.UUID{Value: post.CategroyId},
-			AuthorId:      &cms_proto.UUID{Value: post.AuthorId},
-			Status:        post.Status,
-			Title:         post.Title,
-			Slug:          post.Slug,
-			CoverImage:    post.CoverImage,
-			UpdatedDate:   post.UpdatedDate,
-			PublishedDate: post.PublishedDate,
-			Description:   post.Description,
-			Content:       post.Content,
+			CategoryId:  &cms_proto.UUID{Value: post.CategroyId},
+			AuthorId:    &cms_proto.UUID{Value: post.AuthorId},
+			Status:      postStatus,
+			Title:       post.Title,
+			Slug:        post.Slug,
+			CoverImage:  post.CoverImage,
+			Description: post.Description,
+			Content:     post.Content,
+			TagIds:      uuids(post.TagIds),
+			ProductIds:  uuids(post.ProductIds),
 		},
 	}
 
 	resp, err := s.CmsClient.CreatePost(c.Request().Context(), nPost)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusCreated, resp)
@@ -599,7 +741,7 @@ func (s *CmsServer) CreatePost(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param id path string true "Post Id"
-// @Success 200 {object} Post "Post fetched Sucessfully"
+// @Success 200 {object} PostResponse "Post fetched Sucessfully"
 // @Failure 400 {object} HTTPError "invalid input data"
 // @Failure 500 {object} HTTPError "internal server error"
 // @Router /cms/posts/{id} [get]
@@ -615,16 +757,23 @@ func (s *CmsServer) GetPost(c echo.Context) error {
 	resp, err := s.CmsClient.GetPost(
 		c.Request().Context(),
 		&cms_proto.GetPostRequest{
-			PostId: &cms_proto.UUID{Value: id},
+			PostId:             &cms_proto.UUID{Value: id},
+			IncludeUnpublished: isStaff(c),
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
-	return c.JSON(http.StatusOK, resp)
+	return c.JSON(http.StatusOK, PostResponse{Post: convertPost(resp.Post)})
+}
+
+// UpdatePostRequest is a Post whose tags and hand linked products are only
+// replaced when tag_ids or product_ids is sent. An empty list clears them.
+type UpdatePostRequest struct {
+	Post
+	TagIds     *[]string `json:"tag_ids"     example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	ProductIds *[]string `json:"product_ids" example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
 }
 
 // CreatePost godoc
@@ -633,7 +782,7 @@ func (s *CmsServer) GetPost(c echo.Context) error {
 // @Tags Content
 // @Accept json
 // @Produce json
-// @Param post body Post true "Post to create"
+// @Param post body UpdatePostRequest true "Post to update"
 // @Success 201 {object} Post "Post updated Sucessfully"
 // @Failure 400 {object} HTTPError "invalid input data"
 // @Failure 500 {object} HTTPError "internal server error"
@@ -647,7 +796,7 @@ func (s *CmsServer) UpdatePost(c echo.Context) error {
 		})
 	}
 
-	var post Post
+	var post UpdatePostRequest
 
 	if err := c.Bind(&post); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrResponse{
@@ -662,26 +811,30 @@ func (s *CmsServer) UpdatePost(c echo.Context) error {
 	}
 
 	uPost := &cms_proto.UpdatePostRequest{
-		PostId: &cms_proto.UUID{Value: post.Id},
+		PostId:   &cms_proto.UUID{Value: post.Id},
+		EditorId: &cms_proto.UUID{Value: claims.Id},
 		Post: &cms_proto.Post{
-			PostId:        &cms_proto.UUID{Value: post.Id},
-			CategoryId:    &cms_proto.UUID{Value: post.CategroyId},
-			AuthorId:      &cms_proto.UUID{Value: post.AuthorId},
-			Status:        post.Status,
-			Title:         post.Title,
-			Slug:          post.Slug,
-			CoverImage:    post.CoverImage,
-			UpdatedDate:   post.UpdatedDate,
-			PublishedDate: post.PublishedDate,
-			Description:   post.Description,
-			Content:       post.Content,
+			PostId:      &cms_proto.UUID{Value: post.Id},
+			CategoryId:  &cms_proto.UUID{Value: post.CategroyId},
+			AuthorId:    &cms_proto.UUID{Value: post.AuthorId},
+			Title:       post.Title,
+			Slug:        post.Slug,
+			CoverImage:  post.CoverImage,
+			Description: post.Description,
+			Content:     post.Content,
 		},
 	}
+	if post.TagIds != nil {
+		uPost.ReplaceTags = true
+		uPost.Post.TagIds = uuids(*post.TagIds)
+	}
+	if post.ProductIds != nil {
+		uPost.ReplaceProducts = true
+		uPost.Post.ProductIds = uuids(*post.ProductIds)
+	}
 	resp, err := s.CmsClient.UpdatePost(c.Request().Context(), uPost)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusNoContent, resp)
@@ -720,9 +873,7 @@ func (s *CmsServer) DeletePost(c echo.Context) error {
 		&cms_proto.DeletePostRequest{PostId: &cms_proto.UUID{Value: id}},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusNoContent, resp)
@@ -736,7 +887,8 @@ func (s *CmsServer) DeletePost(c echo.Context) error {
 // @Produce json
 // @Param page query int true "Page Number"
 // @Param limit query int true "Limit of Items to fetch"
-// @Success 200 {object} Author "Fetched Posts Sucessfully"
+// @Param status query []string false "Statuses to include, staff only" collectionFormat(multi)
+// @Success 200 {object} PostsResponse "Fetched Posts Sucessfully"
 // @Failure 400 {object} HTTPError "invalid input data"
 // @Failure 500 {object} HTTPError "internal server error"
 // @Router /cms/posts [get]
@@ -759,20 +911,30 @@ func (s *CmsServer) ListPosts(c echo.Context) error {
 		})
 	}
 
-	resp, err := s.CmsClient.ListPosts(
-		c.Request().Context(),
-		&cms_proto.ListPostsRequest{
-			Page:    int32(int_page),
-			PerPage: int32(int_limit),
-		},
-	)
+	req := &cms_proto.ListPostsRequest{
+		Page:    int32(int_page),
+		PerPage: int32(int_limit),
+	}
+
+	// readers always get published posts, staff may ask for other statuses.
+	if statuses := c.QueryParams()["status"]; len(statuses) > 0 && isStaff(c) {
+		for _, name := range statuses {
+			st, err := parsePostStatus(name)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, ErrResponse{
+					Message: err.Error(),
+				})
+			}
+			req.Statuses = append(req.Statuses, st)
+		}
+	}
+
+	resp, err := s.CmsClient.ListPosts(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
-	return c.JSON(http.StatusOK, resp)
+	return c.JSON(http.StatusOK, convertPosts(resp.Posts))
 }
 
 type PaginatedPostCategories struct {
@@ -790,7 +952,7 @@ type PaginatedPostCategories struct {
 // @param category_id query string true "Category ID"
 // @Param page query int true "Page Number"
 // @Param limit query int true "Limit of Items to fetch"
-// @Success 200 {object} Author "Fetched Posts Sucessfully"
+// @Success 200 {object} PostsResponse "Fetched Posts Sucessfully"
 // @Failure 400 {object} HTTPError "invalid input data"
 // @Failure 500 {object} HTTPError "internal server error"
 // @Router /cms/posts/category [get]
@@ -829,12 +991,10 @@ func (s *CmsServer) GetCategoryPosts(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
-	return c.JSON(http.StatusOK, resp)
+	return c.JSON(http.StatusOK, convertPosts(resp.Posts))
 }
 
 type PaginatedAuthorPosts struct {
@@ -852,7 +1012,7 @@ type PaginatedAuthorPosts struct {
 // @param author_id query string true "Author ID"
 // @Param page query int true "Page Number"
 // @Param limit query int true "Limit of Items to fetch"
-// @Success 200 {object} Author "Fetched Posts Sucessfully"
+// @Success 200 {object} PostsResponse "Fetched Posts Sucessfully"
 // @Failure 400 {object} HTTPError "invalid input data"
 // @Failure 500 {object} HTTPError "internal server error"
 // @Router /cms/posts/author [get]
@@ -891,12 +1051,10 @@ func (s *CmsServer) GetAuthorPosts(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
-	return c.JSON(http.StatusOK, resp)
+	return c.JSON(http.StatusOK, convertPosts(resp.Posts))
 }
 
 type PaginatedAuthorCategoryPosts struct {
@@ -916,7 +1074,7 @@ type PaginatedAuthorCategoryPosts struct {
 // @param category_id query string true "Category ID"
 // @Param page query int true "Page Number"
 // @Param limit query int true "Limit of Items to fetch"
-// @Success 200 {object} Author "Fetched Posts Sucessfully"
+// @Success 200 {object} PostsResponse "Fetched Posts Sucessfully"
 // @Failure 400 {object} HTTPError "invalid input data"
 // @Failure 500 {object} HTTPError "internal server error"
 // @Router /cms/posts/get-by-author-category [get]
@@ -962,10 +1120,1362 @@ func (s *CmsServer) GetAuthorCategoryPosts(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, convertPosts(resp.Posts))
+}
+
+// ApprovePostRequest is sent by an editor approving a post
+type ApprovePostRequest struct {
+	PublishAt string `json:"publish_at" example:"2024-11-16T10:30:00Z"`
+}
+
+// RejectPostRequest is sent by an editor sending a post back to its author
+type RejectPostRequest struct {
+	Note string `json:"note" example:"Please cite your sources"`
+}
+
+// SubmitPostForReview godoc
+// @Summary Submits a post for review.
+// @Description Moves a draft into the editors' review queue.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Success 200 {object} cms_proto.SubmitPostForReviewResponse "Post submitted Sucessfully"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/posts/{id}/submit [post]
+func (s *CmsServer) SubmitPostForReview(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	resp, err := s.CmsClient.SubmitPostForReview(
+		c.Request().Context(),
+		&cms_proto.SubmitPostForReviewRequest{
+			PostId: &cms_proto.UUID{Value: c.Param("id")},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ApprovePost godoc
+// @Summary Approves a post.
+// @Description Publishes a post under review, or schedules it when publish_at is in the future. Editors only.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Param approval body ApprovePostRequest false "When to publish"
+// @Success 200 {object} cms_proto.ApprovePostResponse "Post approved Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/posts/{id}/approve [post]
+func (s *CmsServer) ApprovePost(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	var req ApprovePostRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.ApprovePost(
+		c.Request().Context(),
+		&cms_proto.ApprovePostRequest{
+			PostId:     &cms_proto.UUID{Value: c.Param("id")},
+			ReviewerId: &cms_proto.UUID{Value: claims.Id},
+			PublishAt:  req.PublishAt,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// RejectPost godoc
+// @Summary Rejects a post.
+// @Description Sends a post under review, or a scheduled post, back to draft with a note. Editors only.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Param rejection body RejectPostRequest true "Why the post was rejected"
+// @Success 200 {object} cms_proto.RejectPostResponse "Post rejected Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/posts/{id}/reject [post]
+func (s *CmsServer) RejectPost(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	var req RejectPostRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.RejectPost(
+		c.Request().Context(),
+		&cms_proto.RejectPostRequest{
+			PostId:     &cms_proto.UUID{Value: c.Param("id")},
+			ReviewerId: &cms_proto.UUID{Value: claims.Id},
+			Note:       req.Note,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ArchivePost godoc
+// @Summary Archives a post.
+// @Description Takes a post out of circulation without deleting it. Editors only.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Success 200 {object} cms_proto.ArchivePostResponse "Post archived Sucessfully"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/posts/{id}/archive [post]
+func (s *CmsServer) ArchivePost(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	resp, err := s.CmsClient.ArchivePost(
+		c.Request().Context(),
+		&cms_proto.ArchivePostRequest{
+			PostId: &cms_proto.UUID{Value: c.Param("id")},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ListPostRevisions godoc
+// @Summary Lists a post's revisions.
+// @Description Lists every saved revision of a post, newest first, without the content.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Success 200 {object} cms_proto.ListPostRevisionsResponse "Fetched revisions Sucessfully"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/posts/{id}/revisions [get]
+func (s *CmsServer) ListPostRevisions(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
 		})
 	}
 
+	resp, err := s.CmsClient.ListPostRevisions(
+		c.Request().Context(),
+		&cms_proto.ListPostRevisionsRequest{
+			PostId: &cms_proto.UUID{Value: c.Param("id")},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
 	return c.JSON(http.StatusOK, resp)
 }
+
+// DiffPostRevisions godoc
+// @Summary Diffs two revisions of a post.
+// @Description Returns a line by line diff of the content and the other fields that changed.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Param from query int true "Older revision number"
+// @Param to query int true "Newer revision number"
+// @Success 200 {object} cms_proto.DiffPostRevisionsResponse "Diffed revisions Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/posts/{id}/revisions/diff [get]
+func (s *CmsServer) DiffPostRevisions(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	from, err := strconv.Atoi(c.QueryParam("from"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	to, err := strconv.Atoi(c.QueryParam("to"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.DiffPostRevisions(
+		c.Request().Context(),
+		&cms_proto.DiffPostRevisionsRequest{
+			PostId:       &cms_proto.UUID{Value: c.Param("id")},
+			FromRevision: int32(from),
+			ToRevision:   int32(to),
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// RestorePostRevision godoc
+// @Summary Restores an old revision of a post.
+// @Description Copies an old revision back onto the post and records it as a new revision.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Param revision path int true "Revision number"
+// @Success 200 {object} cms_proto.RestorePostRevisionResponse "Restored revision Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/posts/{id}/revisions/{revision}/restore [post]
+func (s *CmsServer) RestorePostRevision(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.RestorePostRevision(
+		c.Request().Context(),
+		&cms_proto.RestorePostRevisionRequest{
+			PostId:   &cms_proto.UUID{Value: c.Param("id")},
+			Revision: int32(revision),
+			EditorId: &cms_proto.UUID{Value: claims.Id},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// CreateTag godoc
+// @Summary Creates a tag.
+// @Description Creates a new tag posts can be tagged with. The slug is generated from the name when left empty.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param tag body Tag true "Tag to create"
+// @Success 201 {object} cms_proto.CreateTagResponse "Tag created Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/tags [post]
+func (s *CmsServer) CreateTag(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	var tag Tag
+	if err := c.Bind(&tag); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.CreateTag(
+		c.Request().Context(),
+		&cms_proto.CreateTagRequest{
+			Tag: &cms_proto.Tag{
+				Name:        tag.Name,
+				Slug:        tag.Slug,
+				Description: tag.Description,
+			},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// GetTag godoc
+// @Summary Gets a tag.
+// @Description Gets a tag and its count of published posts by slug.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param slug path string true "Tag slug"
+// @Success 200 {object} Tag "Fetched Tag Sucessfully"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/tags/{slug} [get]
+func (s *CmsServer) GetTag(c echo.Context) error {
+	resp, err := s.CmsClient.GetTag(
+		c.Request().Context(),
+		&cms_proto.GetTagRequest{Slug: c.Param("slug")},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, convertTag(resp.Tag))
+}
+
+// UpdateTag godoc
+// @Summary Updates a tag.
+// @Description Updates a tag's name, slug and description.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Param tag body Tag true "Updated tag"
+// @Success 200 {object} cms_proto.UpdateTagResponse "Tag updated Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/tags/{id} [patch]
+func (s *CmsServer) UpdateTag(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	var tag Tag
+	if err := c.Bind(&tag); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.UpdateTag(
+		c.Request().Context(),
+		&cms_proto.UpdateTagRequest{
+			TagId: &cms_proto.UUID{Value: c.Param("id")},
+			Tag: &cms_proto.Tag{
+				Name:        tag.Name,
+				Slug:        tag.Slug,
+				Description: tag.Description,
+			},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeleteTag godoc
+// @Summary Deletes a tag.
+// @Description Deletes a tag and removes it from every post.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Success 200 {object} cms_proto.DeleteTagResponse "Tag deleted Sucessfully"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/tags/{id} [delete]
+func (s *CmsServer) DeleteTag(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	resp, err := s.CmsClient.DeleteTag(
+		c.Request().Context(),
+		&cms_proto.DeleteTagRequest{TagId: &cms_proto.UUID{Value: c.Param("id")}},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ListTags godoc
+// @Summary Lists tags.
+// @Description Lists tags alphabetically with their count of published posts.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param page query int false "Page Number"
+// @Param limit query int false "Limit of Items to fetch"
+// @Success 200 {array} Tag "Fetched Tags Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/tags [get]
+func (s *CmsServer) ListTags(c echo.Context) error {
+	page, limit, err := pageParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.ListTags(
+		c.Request().Context(),
+		&cms_proto.ListTagsRequest{Page: page, PerPage: limit},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	tags := []Tag{}
+	for _, tag := range resp.Tags {
+		tags = append(tags, convertTag(tag))
+	}
+	return c.JSON(http.StatusOK, tags)
+}
+
+// TaggedPostsResponse is a page of posts carrying some tags
+type TaggedPostsResponse struct {
+	PostsResponse
+	Total int32 `json:"total" example:"12"`
+}
+
+// GetTagPosts godoc
+// @Summary Lists the posts with a tag.
+// @Description Lists published posts carrying the tag, newest first.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param slug path string true "Tag slug"
+// @Param page query int false "Page Number"
+// @Param limit query int false "Limit of Items to fetch"
+// @Success 200 {object} TaggedPostsResponse "Fetched Posts Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/tags/{slug}/posts [get]
+func (s *CmsServer) GetTagPosts(c echo.Context) error {
+	return s.taggedPosts(c, []string{c.Param("slug")}, cms_proto.GetTaggedPostsRequest_ANY)
+}
+
+// GetTaggedPosts godoc
+// @Summary Lists the posts with any or all of some tags.
+// @Description Lists published posts carrying any (default) or all of the given tags, newest first.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param tag query []string true "Tag slugs" collectionFormat(multi)
+// @Param match query string false "any or all"
+// @Param page query int false "Page Number"
+// @Param limit query int false "Limit of Items to fetch"
+// @Success 200 {object} TaggedPostsResponse "Fetched Posts Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/tags/posts [get]
+func (s *CmsServer) GetTaggedPosts(c echo.Context) error {
+	var match cms_proto.GetTaggedPostsRequest_Match
+	switch strings.ToLower(c.QueryParam("match")) {
+	case "", "any":
+		match = cms_proto.GetTaggedPostsRequest_ANY
+	case "all":
+		match = cms_proto.GetTaggedPostsRequest_ALL
+	default:
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "match must be any or all",
+		})
+	}
+
+	return s.taggedPosts(c, c.QueryParams()["tag"], match)
+}
+
+func (s *CmsServer) taggedPosts(
+	c echo.Context,
+	slugs []string,
+	match cms_proto.GetTaggedPostsRequest_Match,
+) error {
+	page, limit, err := pageParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.GetTaggedPosts(
+		c.Request().Context(),
+		&cms_proto.GetTaggedPostsRequest{
+			Slugs:   slugs,
+			Match:   match,
+			Page:    page,
+			PerPage: limit,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, TaggedPostsResponse{
+		PostsResponse: convertPosts(resp.Posts),
+		Total:         resp.Total,
+	})
+}
+
+// ProductPostsResponse is a page of articles mentioning a product
+type ProductPostsResponse struct {
+	PostsResponse
+	Total int32 `json:"total" example:"3"`
+}
+
+// GetProductPosts godoc
+// @Summary Lists articles mentioning a product.
+// @Description Lists published posts that link to or embed the product, newest first.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Product Id"
+// @Param page query int false "Page Number"
+// @Param limit query int false "Limit of Items to fetch"
+// @Success 200 {object} ProductPostsResponse "Fetched Posts Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /products/{id}/articles [get]
+func (s *CmsServer) GetProductPosts(c echo.Context) error {
+	page, limit, err := pageParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.GetProductPosts(
+		c.Request().Context(),
+		&cms_proto.GetProductPostsRequest{
+			ProductId: &cms_proto.UUID{Value: c.Param("id")},
+			Page:      page,
+			PerPage:   limit,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, ProductPostsResponse{
+		PostsResponse: convertPosts(resp.Posts),
+		Total:         resp.Total,
+	})
+}
+
+// pageParams reads the optional page and limit query parameters.
+func pageParams(c echo.Context) (int32, int32, error) {
+	var page, limit int
+	var err error
+	if p := c.QueryParam("page"); p != "" {
+		if page, err = strconv.Atoi(p); err != nil {
+			return 0, 0, err
+		}
+	}
+	if l := c.QueryParam("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			return 0, 0, err
+		}
+	}
+	return int32(page), int32(limit), nil
+}
+
+// GetPostBySlug godoc
+// @Summary Gets a Post by slug.
+// @Description Gets a post by its slug. Slugs a post used to have redirect to the current one.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param slug path string true "Post slug"
+// @Success 200 {object} PostResponse "Post fetched Sucessfully"
+// @Success 301 "Moved to the post's current slug"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/posts/slug/{slug} [get]
+func (s *CmsServer) GetPostBySlug(c echo.Context) error {
+	resp, err := s.CmsClient.GetPostBySlug(
+		c.Request().Context(),
+		&cms_proto.GetPostBySlugRequest{
+			Slug:               c.Param("slug"),
+			IncludeUnpublished: isStaff(c),
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+	if resp.RedirectSlug != "" {
+		return redirectToSlug(c, resp.RedirectSlug)
+	}
+
+	return c.JSON(http.StatusOK, PostResponse{Post: convertPost(resp.Post)})
+}
+
+// GetCategoryBySlug godoc
+// @Summary Gets a category by slug.
+// @Description Gets a category by its slug. Old slugs redirect to the current one.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param slug path string true "Category slug"
+// @Success 200 {object} Category "Category fetched Sucessfully"
+// @Success 301 "Moved to the category's current slug"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/categories/slug/{slug} [get]
+func (s *CmsServer) GetCategoryBySlug(c echo.Context) error {
+	resp, err := s.CmsClient.GetCategoryBySlug(
+		c.Request().Context(),
+		&cms_proto.GetCategoryBySlugRequest{Slug: c.Param("slug")},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+	if resp.RedirectSlug != "" {
+		return redirectToSlug(c, resp.RedirectSlug)
+	}
+
+	return c.JSON(http.StatusOK, &cms_proto.GetCategoryResponse{Category: resp.Category})
+}
+
+// GetAuthorBySlug godoc
+// @Summary Gets an author by slug.
+// @Description Gets an author by their slug. Old slugs redirect to the current one.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param slug path string true "Author slug"
+// @Success 200 {object} Author "Fetched Author Sucessfully"
+// @Success 301 "Moved to the author's current slug"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/authors/slug/{slug} [get]
+func (s *CmsServer) GetAuthorBySlug(c echo.Context) error {
+	resp, err := s.CmsClient.GetAuthorBySlug(
+		c.Request().Context(),
+		&cms_proto.GetAuthorBySlugRequest{Slug: c.Param("slug")},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+	if resp.RedirectSlug != "" {
+		return redirectToSlug(c, resp.RedirectSlug)
+	}
+
+	return c.JSON(http.StatusOK, &cms_proto.GetAuthorResponse{Author: resp.Author})
+}
+
+// redirectToSlug answers with a permanent redirect to the same route with
+// the last path segment swapped for slug.
+func redirectToSlug(c echo.Context, slug string) error {
+	u := *c.Request().URL
+	u.Path = path.Join(path.Dir(u.Path), slug)
+	u.RawPath = ""
+	return c.Redirect(http.StatusMovedPermanently, u.RequestURI())
+}
+
+// Comment is a reader's comment on a post, with its replies
+type Comment struct {
+	Id        string    `json:"id"                  example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	PostId    string    `json:"post_id"             example:"1bf447b8-a129-42a2-b11e-684a801568ff"`
+	ParentId  string    `json:"parent_id,omitempty" example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	UserId    string    `json:"user_id"             example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	UserName  string    `json:"user_name"           example:"Jane Doe"`
+	Body      string    `json:"body"                example:"Thanks, this cleared things up."`
+	Status    string    `json:"status"              example:"approved"`
+	CreatedAt string    `json:"created_at"          example:"2024-11-16T10:30:00Z"`
+	Replies   []Comment `json:"replies,omitempty"`
+}
+
+// CreateCommentRequest is a new comment, parent_id makes it a reply
+type CreateCommentRequest struct {
+	ParentId string `json:"parent_id" example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	Body     string `json:"body"      example:"Thanks, this cleared things up." binding:"required"`
+}
+
+// ModerateCommentRequest sets a comment's moderation status
+type ModerateCommentRequest struct {
+	Status string `json:"status" example:"approved" binding:"required"`
+}
+
+// CommentsResponse is a page of comment threads on a post
+type CommentsResponse struct {
+	Comments      []Comment `json:"comments"`
+	TotalThreads  int32     `json:"total_threads"  example:"4"`
+	TotalComments int32     `json:"total_comments" example:"9"`
+}
+
+// ModerationQueueResponse is a page of comments awaiting moderation
+type ModerationQueueResponse struct {
+	Comments []Comment `json:"comments"`
+	Total    int32     `json:"total" example:"3"`
+}
+
+func convertComment(comment *cms_proto.Comment) Comment {
+	var replies []Comment
+	for _, reply := range comment.Replies {
+		replies = append(replies, convertComment(reply))
+	}
+	return Comment{
+		Id:        comment.CommentId.GetValue(),
+		PostId:    comment.PostId.GetValue(),
+		ParentId:  comment.ParentId.GetValue(),
+		UserId:    comment.UserId.GetValue(),
+		UserName:  comment.UserName,
+		Body:      comment.Body,
+		Status:    strings.ToLower(comment.Status.String()),
+		CreatedAt: comment.CreatedAt,
+		Replies:   replies,
+	}
+}
+
+func convertComments(comments []*cms_proto.Comment) []Comment {
+	out := []Comment{}
+	for _, comment := range comments {
+		out = append(out, convertComment(comment))
+	}
+	return out
+}
+
+func parseCommentStatus(name string) (cms_proto.CommentStatus, bool) {
+	st, ok := cms_proto.CommentStatus_value[strings.ToUpper(name)]
+	return cms_proto.CommentStatus(st), ok
+}
+
+// ListComments godoc
+// @Summary Lists the comments on a post.
+// @Description Lists approved comments as threads, oldest first. A page is a page of top level comments.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Param page query int false "Page Number"
+// @Param limit query int false "Limit of Items to fetch"
+// @Success 200 {object} CommentsResponse "Fetched Comments Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/posts/{id}/comments [get]
+func (s *CmsServer) ListComments(c echo.Context) error {
+	page, limit, err := pageParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.ListComments(
+		c.Request().Context(),
+		&cms_proto.ListCommentsRequest{
+			PostId:  &cms_proto.UUID{Value: c.Param("id")},
+			Page:    page,
+			PerPage: limit,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, CommentsResponse{
+		Comments:      convertComments(resp.Comments),
+		TotalThreads:  resp.TotalThreads,
+		TotalComments: resp.TotalComments,
+	})
+}
+
+// CreateComment godoc
+// @Summary Comments on a post.
+// @Description Adds a comment or reply to a published post. New comments wait for moderation unless the user has had comments approved before.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Post Id"
+// @Param comment body CreateCommentRequest true "Comment to add"
+// @Success 201 {object} Comment "Comment created Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/posts/{id}/comments [post]
+func (s *CmsServer) CreateComment(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+
+	var req CreateCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	comment := &cms_proto.CreateCommentRequest{
+		PostId: &cms_proto.UUID{Value: c.Param("id")},
+		UserId: &cms_proto.UUID{Value: claims.Id},
+		Body:   req.Body,
+	}
+	if req.ParentId != "" {
+		comment.ParentId = &cms_proto.UUID{Value: req.ParentId}
+	}
+
+	resp, err := s.CmsClient.CreateComment(c.Request().Context(), comment)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, convertComment(resp.Comment))
+}
+
+// ListCommentsForModeration godoc
+// @Summary Lists comments to moderate.
+// @Description Lists comments in a moderation state, newest first. Authors only see comments on their own posts.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param status query string false "pending (default), approved, rejected or spam"
+// @Param page query int false "Page Number"
+// @Param limit query int false "Limit of Items to fetch"
+// @Success 200 {object} ModerationQueueResponse "Fetched Comments Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/comments [get]
+func (s *CmsServer) ListCommentsForModeration(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	commentStatus := cms_proto.CommentStatus_PENDING
+	if name := c.QueryParam("status"); name != "" {
+		st, ok := parseCommentStatus(name)
+		if !ok {
+			return c.JSON(http.StatusBadRequest, ErrResponse{
+				Message: "unknown status " + name,
+			})
+		}
+		commentStatus = st
+	}
+
+	page, limit, err := pageParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.ListCommentsForModeration(
+		c.Request().Context(),
+		&cms_proto.ListCommentsForModerationRequest{
+			ModeratorId: &cms_proto.UUID{Value: claims.Id},
+			Status:      commentStatus,
+			Page:        page,
+			PerPage:     limit,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, ModerationQueueResponse{
+		Comments: convertComments(resp.Comments),
+		Total:    resp.Total,
+	})
+}
+
+// ModerateComment godoc
+// @Summary Moderates a comment.
+// @Description Approves, rejects or marks a comment as spam. Authors can only moderate comments on their own posts.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Comment Id"
+// @Param moderation body ModerateCommentRequest true "New status"
+// @Success 200 {object} cms_proto.ModerateCommentResponse "Comment moderated Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/comments/{id} [patch]
+func (s *CmsServer) ModerateComment(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	var req ModerateCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+	commentStatus, ok := parseCommentStatus(req.Status)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "unknown status " + req.Status,
+		})
+	}
+
+	resp, err := s.CmsClient.ModerateComment(
+		c.Request().Context(),
+		&cms_proto.ModerateCommentRequest{
+			CommentId:   &cms_proto.UUID{Value: c.Param("id")},
+			ModeratorId: &cms_proto.UUID{Value: claims.Id},
+			Status:      commentStatus,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// MediaUsage is a post or author using a media asset
+type MediaUsage struct {
+	Kind  string `json:"kind"  example:"post"`
+	Id    string `json:"id"    example:"1bf447b8-a129-42a2-b11e-684a801568ff"`
+	Title string `json:"title" example:"Managing Hypertension"`
+}
+
+// MediaAsset is an uploaded cover image or avatar
+type MediaAsset struct {
+	Id          string       `json:"id"                example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	Url         string       `json:"url"               example:"https://chemistke.s3.af-south-1.amazonaws.com/cms/media/9f86d081884c7d65-cover.jpg"`
+	FileName    string       `json:"file_name"         example:"cover.jpg"`
+	ContentType string       `json:"content_type"      example:"image/jpeg"`
+	SizeBytes   int64        `json:"size_bytes"        example:"284311"`
+	Width       int32        `json:"width"             example:"1600"`
+	Height      int32        `json:"height"            example:"900"`
+	AltText     string       `json:"alt_text"          example:"A pharmacist checking a prescription"`
+	UploadedBy  string       `json:"uploaded_by"       example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	CreatedAt   string       `json:"created_at"        example:"2024-11-16T10:30:00Z"`
+	UsageCount  int32        `json:"usage_count"       example:"2"`
+	Usages      []MediaUsage `json:"usages,omitempty"`
+}
+
+// UpdateMediaRequest changes an asset's alt text
+type UpdateMediaRequest struct {
+	AltText string `json:"alt_text" example:"A pharmacist checking a prescription"`
+}
+
+// MediaListResponse is a page of the media library
+type MediaListResponse struct {
+	Assets []MediaAsset `json:"assets"`
+	Total  int32        `json:"total" example:"12"`
+}
+
+func convertMedia(asset *cms_proto.MediaAsset) MediaAsset {
+	var usages []MediaUsage
+	for _, usage := range asset.Usages {
+		usages = append(usages, MediaUsage{
+			Kind:  usage.Kind,
+			Id:    usage.Id.GetValue(),
+			Title: usage.Title,
+		})
+	}
+	return MediaAsset{
+		Id:          asset.AssetId.GetValue(),
+		Url:         asset.Url,
+		FileName:    asset.FileName,
+		ContentType: asset.ContentType,
+		SizeBytes:   asset.SizeBytes,
+		Width:       asset.Width,
+		Height:      asset.Height,
+		AltText:     asset.AltText,
+		UploadedBy:  asset.UploadedBy.GetValue(),
+		CreatedAt:   asset.CreatedAt,
+		UsageCount:  asset.UsageCount,
+		Usages:      usages,
+	}
+}
+
+// UploadMedia godoc
+// @Summary Uploads an image to the media library.
+// @Description Uploads a jpeg, png, gif or webp image of up to 3MB for use as a cover image or avatar.
+// @Tags Content
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Image file to upload"
+// @Param alt_text formData string false "Alt text"
+// @Success 201 {object} MediaAsset "Uploaded Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/media [post]
+func (s *CmsServer) UploadMedia(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrResponse{
+			Message: "could not open file",
+		})
+	}
+	defer file.Close()
+
+	fileBytes, err := io.ReadAll(file)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrResponse{
+			Message: "could not read file",
+		})
+	}
+
+	resp, err := s.CmsClient.UploadMedia(
+		c.Request().Context(),
+		&cms_proto.UploadMediaRequest{
+			Data:       fileBytes,
+			FileName:   fileHeader.Filename,
+			AltText:    c.FormValue("alt_text"),
+			UploadedBy: &cms_proto.UUID{Value: claims.Id},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, convertMedia(resp.Asset))
+}
+
+// ListMedia godoc
+// @Summary Lists the media library.
+// @Description Lists uploaded media newest first, with how many posts and authors use each asset.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param q query string false "Matches file names and alt text"
+// @Param page query int false "Page Number"
+// @Param limit query int false "Limit of Items to fetch"
+// @Success 200 {object} MediaListResponse "Fetched Media Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/media [get]
+func (s *CmsServer) ListMedia(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	page, limit, err := pageParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.ListMedia(
+		c.Request().Context(),
+		&cms_proto.ListMediaRequest{
+			Query:   c.QueryParam("q"),
+			Page:    page,
+			PerPage: limit,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	assets := []MediaAsset{}
+	for _, asset := range resp.Assets {
+		assets = append(assets, convertMedia(asset))
+	}
+	return c.JSON(http.StatusOK, MediaListResponse{
+		Assets: assets,
+		Total:  resp.Total,
+	})
+}
+
+// GetMedia godoc
+// @Summary Gets a media asset.
+// @Description Gets a media asset along with the posts and authors using it.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Success 200 {object} MediaAsset "Fetched Media Sucessfully"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/media/{id} [get]
+func (s *CmsServer) GetMedia(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	resp, err := s.CmsClient.GetMedia(
+		c.Request().Context(),
+		&cms_proto.GetMediaRequest{
+			AssetId: &cms_proto.UUID{Value: c.Param("id")},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, convertMedia(resp.Asset))
+}
+
+// UpdateMedia godoc
+// @Summary Updates a media asset.
+// @Description Updates the alt text of a media asset.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Param media body UpdateMediaRequest true "New alt text"
+// @Success 200 {object} MediaAsset "Updated Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/media/{id} [patch]
+func (s *CmsServer) UpdateMedia(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	var req UpdateMediaRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	resp, err := s.CmsClient.UpdateMedia(
+		c.Request().Context(),
+		&cms_proto.UpdateMediaRequest{
+			AssetId: &cms_proto.UUID{Value: c.Param("id")},
+			AltText: req.AltText,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, convertMedia(resp.Asset))
+}
+
+// DeleteMedia godoc
+// @Summary Deletes a media asset.
+// @Description Deletes a media asset and its file. Assets still used by a post or author cannot be deleted.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Success 200 {object} cms_proto.DeleteMediaResponse "Deleted Sucessfully"
+// @Failure 401 {object} HTTPError "unauthorized"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Security BearerAuth
+// @Router /cms/media/{id} [delete]
+func (s *CmsServer) DeleteMedia(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if !claims.Admin && !claims.Author {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not authorized for this operations",
+		})
+	}
+
+	resp, err := s.CmsClient.DeleteMedia(
+		c.Request().Context(),
+		&cms_proto.DeleteMediaRequest{
+			AssetId: &cms_proto.UUID{Value: c.Param("id")},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// RecordViewResponse says whether the view was counted
+type RecordViewResponse struct {
+	Counted bool `json:"counted" example:"true"`
+}
+
+// PopularPost is a post with its views over the requested period
+type PopularPost struct {
+	Post
+	Views int64 `json:"views" example:"1250"`
+}
+
+// PopularPostsResponse lists posts by views, most viewed first
+type PopularPostsResponse struct {
+	Posts []PopularPost `json:"posts"`
+}
+
+// viewerKey identifies the reader for view de-duplication. Signed in
+// readers are keyed by user, others by ip alone, hashed so the cms never
+// stores raw ips. Nothing the client controls, like the body or the user
+// agent, is used, so a reader can't be counted again by changing it. RealIP
+// only trusts X-Forwarded-For from private proxies, see NewRouter.
+func viewerKey(c echo.Context) string {
+	var viewer string
+	if claims := utils.ExtractClaimsFromRequest(c); claims != nil {
+		viewer = "user:" + claims.Id
+	} else {
+		viewer = "ip:" + c.RealIP()
+	}
+	sum := sha256.Sum256([]byte(viewer))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordPostView godoc
+// @Summary Records a view of a post.
+// @Description Counts a view of a published post. Repeat views by the same reader within 30 minutes are not counted.
+// @Tags Content
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 202 {object} RecordViewResponse "View Recorded"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/posts/{id}/views [post]
+func (s *CmsServer) RecordPostView(c echo.Context) error {
+	resp, err := s.CmsClient.RecordPostView(
+		c.Request().Context(),
+		&cms_proto.RecordPostViewRequest{
+			PostId: &cms_proto.UUID{Value: c.Param("id")},
+			Viewer: viewerKey(c),
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, RecordViewResponse{Counted: resp.Counted})
+}
+
+// GetPopularPosts godoc
+// @Summary Lists the most viewed posts.
+// @Description Lists published posts ranked by views over the last given number of days.
+// @Tags Content
+// @Accept json
+// @Produce json
+// @Param days query int false "Days to rank over, 7 by default, at most 365"
+// @Param limit query int false "Number of posts, 10 by default, at most 50"
+// @Param category_id query string false "Only rank posts in this category"
+// @Success 200 {object} PopularPostsResponse "Fetched Posts Sucessfully"
+// @Failure 400 {object} HTTPError "invalid input data"
+// @Failure 500 {object} HTTPError "internal server error"
+// @Router /cms/posts/popular [get]
+func (s *CmsServer) GetPopularPosts(c echo.Context) error {
+	var days int
+	if d := c.QueryParam("days"); d != "" {
+		var err error
+		if days, err = strconv.Atoi(d); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrResponse{
+				Message: "invalid request",
+			})
+		}
+	}
+	_, limit, err := pageParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "invalid request",
+		})
+	}
+
+	req := &cms_proto.GetPopularPostsRequest{
+		Days:  int32(days),
+		Limit: limit,
+	}
+	if categoryId := c.QueryParam("category_id"); categoryId != "" {
+		req.CategoryId = &cms_proto.UUID{Value: categoryId}
+	}
+
+	resp, err := s.CmsClient.GetPopularPosts(c.Request().Context(), req)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	posts := []PopularPost{}
+	for _, popular := range resp.Posts {
+		posts = append(posts, PopularPost{
+			Post:  convertPost(popular.Post),
+			Views: popular.Views,
+		})
+	}
+	return c.JSON(http.StatusOK, PopularPostsResponse{Posts: posts})
+}
@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+func TestIsStaff(t *testing.T) {
+	utils.SetSecretKey("test-secret")
+
+	token := func(role string) string {
+		t.Helper()
+		s, err := utils.CreateToken("id", "jane@example.com", "Jane", "+254700000000", role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(req *http.Request)
+		want    bool
+	}{
+		{"anonymous", func(*http.Request) {}, false},
+		{"bearer admin", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token("ADMIN")) }, true},
+		{"bearer author", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token("AUTHOR")) }, true},
+		{"bearer user", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token("USER")) }, false},
+		{"cookie admin", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "token", Value: token("ADMIN")}) }, true},
+		{"bad token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			var got bool
+			e.GET("/", func(c echo.Context) error {
+				got = isStaff(c)
+				return c.NoContent(http.StatusOK)
+			}, utils.OptionalAuthMiddleware())
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.prepare(req)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got != tt.want {
+				t.Errorf("isStaff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewerKey(t *testing.T) {
+	utils.SetSecretKey("test-secret")
+
+	token := func(id string) string {
+		t.Helper()
+		s, err := utils.CreateToken(id, "jane@example.com", "Jane", "+254700000000", "USER")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	e := NewRouter(Servers{}, config.Default().Gateway)
+	key := func(prepare func(req *http.Request)) string {
+		t.Helper()
+		var got string
+		e.POST("/key", func(c echo.Context) error {
+			got = viewerKey(c)
+			return c.NoContent(http.StatusOK)
+		}, utils.OptionalAuthMiddleware())
+
+		req := httptest.NewRequest(http.MethodPost, "/key", nil)
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("User-Agent", "firefox")
+		prepare(req)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	anonymous := key(func(*http.Request) {})
+	tests := []struct {
+		name    string
+		prepare func(req *http.Request)
+		same    bool
+	}{
+		{"same reader", func(*http.Request) {}, true},
+		{
+			"body is ignored",
+			func(r *http.Request) {
+				r.Body = io.NopCloser(strings.NewReader(`{"session_id":"fresh"}`))
+				r.Header.Set("Content-Type", "application/json")
+			},
+			true,
+		},
+		{"forwarded for from a public address is ignored", func(r *http.Request) { r.Header.Set("X-Forwarded-For", "198.51.100.1") }, true},
+		{"other user agent", func(r *http.Request) { r.Header.Set("User-Agent", "curl") }, true},
+		{"other address", func(r *http.Request) { r.RemoteAddr = "203.0.113.8:51234" }, false},
+		{"signed in", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token("user-1")) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := key(tt.prepare)
+			if (got == anonymous) != tt.same {
+				t.Errorf("same key = %v, want %v", got == anonymous, tt.same)
+			}
+		})
+	}
+
+	// a signed in reader keeps their key across addresses.
+	first := key(func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token("user-1")) })
+	moved := key(func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token("user-1"))
+		r.RemoteAddr = "198.51.100.2:4000"
+	})
+	if first != moved {
+		t.Error("signed in reader got a new key from another address")
+	}
+}
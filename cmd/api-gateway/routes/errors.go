@@ -0,0 +1,114 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// httpStatuses maps gRPC codes onto the HTTP status a client should see,
+// following the mapping used by grpc-gateway.
+var httpStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// HTTPStatusFromCode returns the HTTP status for a gRPC code.
+func HTTPStatusFromCode(code codes.Code) int {
+	if s, ok := httpStatuses[code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// errorCode is the snake case name of a gRPC code, e.g. not_found.
+func errorCode(code codes.Code) string {
+	var b strings.Builder
+	var prev rune
+	for _, r := range code.String() {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return strings.ToLower(b.String())
+}
+
+// grpcError answers a failed service call with the HTTP status matching
+// its gRPC code. Messages of server side failures are logged rather than
+// sent, they can carry query details.
+func grpcError(c echo.Context, err error) error {
+	st := status.Convert(err)
+	httpStatus := HTTPStatusFromCode(st.Code())
+
+	message := st.Message()
+	if httpStatus >= http.StatusInternalServerError && st.Code() != codes.Unavailable &&
+		st.Code() != codes.DeadlineExceeded && st.Code() != codes.Unimplemented {
+		slog.ErrorContext(c.Request().Context(), "service call failed", "route", c.Path(), "error", err)
+		message = http.StatusText(httpStatus)
+	}
+
+	return c.JSON(httpStatus, ErrResponse{
+		Message: message,
+		Code:    errorCode(st.Code()),
+	})
+}
+
+// HTTPErrorHandler writes errors that reach echo, such as unknown routes
+// or a failed middleware, in the same envelope as handler errors.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	var he *echo.HTTPError
+	if !errors.As(err, &he) {
+		if _, ok := status.FromError(err); ok {
+			if err := grpcError(c, err); err != nil {
+				c.Logger().Error(err)
+			}
+			return
+		}
+		slog.ErrorContext(c.Request().Context(), "request failed", "route", c.Path(), "error", err)
+		he = echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	message, ok := he.Message.(string)
+	if !ok {
+		message = http.StatusText(he.Code)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(he.Code)
+	} else {
+		err = c.JSON(he.Code, ErrResponse{
+			Message: message,
+			Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(he.Code)), " ", "_"),
+		})
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
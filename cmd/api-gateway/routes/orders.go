@@ -2,12 +2,14 @@ package routes
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/tracing"
 	order_proto "github.com/kelcheone/chemistke/pkg/grpc/order"
+	user_proto "github.com/kelcheone/chemistke/pkg/grpc/user"
 	"github.com/labstack/echo/v4"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -15,6 +17,8 @@ import (
 
 type OrderServer struct {
 	OrderClient order_proto.OrderServiceClient
+	// used to look up the delivery address at checkout
+	UserClient user_proto.UserServiceClient
 }
 
 // Order represents the data required and returned by the Order Service endpoints
@@ -25,6 +29,16 @@ type Order struct {
 	Status    string  `json:"status"     example:"pending"`
 	Quantity  int32   `json:"quantity"   example:"10"                                   binding:"required"`
 	Total     float32 `json:"total"      example:"100"                                  binding:"required"`
+	AddressId string  `json:"address_id" example:"62e9e179-3aaa-4dd5-a098-21f20da10f90" binding:"required"`
+}
+
+// CreateOrderRequest is the body of a new order. The order is always placed
+// for the logged in user.
+type CreateOrderRequest struct {
+	ProductId string  `json:"product_id" example:"62e9e179-3aaa-4dd5-a098-21f20da10f90" binding:"required"`
+	Quantity  int32   `json:"quantity"   example:"10"                                   binding:"required"`
+	Total     float32 `json:"total"      example:"100"                                  binding:"required"`
+	AddressId string  `json:"address_id" example:"62e9e179-3aaa-4dd5-a098-21f20da10f90" binding:"required"`
 }
 
 type IdReq struct {
@@ -35,6 +49,8 @@ func ConnectOrdersServer(link string) (*OrderServer, func(), error) {
 	orderConn, err := grpc.NewClient(
 		link,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logging.UnaryClientInterceptor()),
+		tracing.DialOption(),
 	)
 	if err != nil {
 		return nil, nil, fmt.Errorf(
@@ -58,14 +74,22 @@ func ConnectOrdersServer(link string) (*OrderServer, func(), error) {
 // @Tags Orders
 // @Accept json
 // @Produce json
-// @Param order body Order true "Oder info to create"
+// @Param order body CreateOrderRequest true "Oder info to create"
 // @Success 201 {Object} Order "Successfly created a product"
 // @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 401 {object} HTTPError "Not logged in"
 // @Failure 500 {object} HTTPError "Internal server error"
 // @Security BearerAuth
 // @Router /orders [post]
 func (o *OrderServer) CreateOrder(c echo.Context) error {
-	var order Order
+	claims := utils.ExtractClaimsFromRequest(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{
+			Message: "not logged in",
+		})
+	}
+
+	var order CreateOrderRequest
 
 	if err := c.Bind(&order); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrResponse{
@@ -73,19 +97,46 @@ func (o *OrderServer) CreateOrder(c echo.Context) error {
 		})
 	}
 
+	if order.AddressId == "" {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "a delivery address is required",
+		})
+	}
+
+	addressResp, err := o.UserClient.GetAddress(
+		c.Request().Context(),
+		&user_proto.GetAddressRequest{
+			Id:     &user_proto.UUID{Value: order.AddressId},
+			UserId: &user_proto.UUID{Value: claims.Id},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+	address := addressResp.Address
+
 	// TODO: Calculate the total based on the price of a product
 
 	nOrder := &order_proto.OrderProductRequest{
 		ProductId: &order_proto.UUID{Value: order.ProductId},
-		UserId:    &order_proto.UUID{Value: order.UserId},
+		UserId:    &order_proto.UUID{Value: claims.Id},
 		Quantity:  order.Quantity,
 		Total:     float32(order.Total),
+		DeliveryAddress: &order_proto.DeliveryAddress{
+			AddressId: address.Id.Value,
+			Label:     address.Label,
+			County:    address.County,
+			Town:      address.Town,
+			Street:    address.Street,
+			Landmark:  address.Landmark,
+			Latitude:  address.Latitude,
+			Longitude: address.Longitude,
+			Phone:     address.Phone,
+		},
 	}
 	resp, err := o.OrderClient.OrderProduct(c.Request().Context(), nOrder)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusCreated, resp)
@@ -100,6 +151,7 @@ func (o *OrderServer) CreateOrder(c echo.Context) error {
 // @Param id path string true "Oder ID"
 // @Success 200 {Object} Order "Successfly fetched order"
 // @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 403 {object} HTTPError "Not the owner of the order"
 // @Failure 500 {object} HTTPError "Internal server error"
 // @Security BearerAuth
 // @Router /orders/{id} [get]
@@ -117,13 +169,24 @@ func (o *OrderServer) GetOrder(c echo.Context) error {
 		&order_proto.GetOrderRequest{OrderId: &order_proto.UUID{Value: id}},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
+		return grpcError(c, err)
+	}
+
+	if !canAccessOrder(c, resp.Order.GetUserId().GetValue()) {
+		return c.JSON(http.StatusForbidden, ErrResponse{
+			Message: "not authorized to perform this action",
 		})
 	}
 	return c.JSON(http.StatusOK, resp)
 }
 
+// canAccessOrder reports whether the caller may see or change an order
+// placed by userId: only its owner or an admin can.
+func canAccessOrder(c echo.Context, userId string) bool {
+	claims := utils.ExtractClaimsFromRequest(c)
+	return claims != nil && (claims.Id == userId || claims.Admin)
+}
+
 type PaginatedReq struct {
 	Id    string `json:"id"`
 	Page  int32  `json:"page"`
@@ -141,6 +204,7 @@ type PaginatedReq struct {
 // @Param limit query int true "PaginatedReq limit"
 // @Success 201 {Object} Order "Successfly fetched user orders"
 // @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 403 {object} HTTPError "Not the user or an admin"
 // @Failure 500 {object} HTTPError "Internal server error"
 // @Security BearerAuth
 // @Router /orders/user [get]
@@ -168,16 +232,12 @@ func (o *OrderServer) GetUserOders(c echo.Context) error {
 			Message: "invalid request",
 		})
 	}
-	claims := utils.ExtractClaimsFromRequest(c)
-	if claims.Id != id || !claims.Admin {
-		return c.JSON(http.StatusUnauthorized, ErrResponse{
+	if !canAccessOrder(c, id) {
+		return c.JSON(http.StatusForbidden, ErrResponse{
 			Message: "not authorized to perform this action",
 		})
 	}
 
-	log.Println("--------------------------------------------------------")
-	log.Println("id: ", id)
-
 	nReq := &order_proto.GetUserOrdersRequest{
 		UserId: &order_proto.UUID{Value: id},
 		Limit:  int32(n_limit),
@@ -186,9 +246,7 @@ func (o *OrderServer) GetUserOders(c echo.Context) error {
 
 	resp, err := o.OrderClient.GetUserOrders(c.Request().Context(), nReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -239,9 +297,7 @@ func (o *OrderServer) GetOders(c echo.Context) error {
 
 	resp, err := o.OrderClient.GetOrders(c.Request().Context(), nReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -253,9 +309,10 @@ func (o *OrderServer) GetOders(c echo.Context) error {
 // @Tags Orders
 // @Accept json
 // @Produce json
-// @Param order body Order true "Oder info to create"
+// @Param order body CreateOrderRequest true "Oder info to create"
 // @Success 201 {Object} Order  "Oder Successfly updated"
 // @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 403 {object} HTTPError "Not the owner of the order"
 // @Failure 500 {object} HTTPError "Internal server error"
 // @Security BearerAuth
 // @Router /orders [patch]
@@ -268,9 +325,16 @@ func (o *OrderServer) UpdateOrder(c echo.Context) error {
 		})
 	}
 
-	claims := utils.ExtractClaimsFromRequest(c)
-	if claims.Id != order.UserId || !claims.Admin {
-		return c.JSON(http.StatusUnauthorized, ErrResponse{
+	// the owner comes from the stored order, not the request body.
+	stored, err := o.OrderClient.GetOrder(
+		c.Request().Context(),
+		&order_proto.GetOrderRequest{OrderId: &order_proto.UUID{Value: order.Id}},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+	if !canAccessOrder(c, stored.Order.GetUserId().GetValue()) {
+		return c.JSON(http.StatusForbidden, ErrResponse{
 			Message: "not authorized to perform this action",
 		})
 	}
@@ -284,9 +348,7 @@ func (o *OrderServer) UpdateOrder(c echo.Context) error {
 
 	resp, err := o.OrderClient.UpdateOrder(c.Request().Context(), nORder)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusNoContent, resp)
@@ -301,6 +363,7 @@ func (o *OrderServer) UpdateOrder(c echo.Context) error {
 // @Param id path string true "Order Id"
 // @Success 201 {Object} Order "Successfly deleted order"
 // @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 403 {object} HTTPError "Not the owner of the order"
 // @Failure 500 {object} HTTPError "Internal server error"
 // @Security BearerAuth
 // @Router /orders/{id} [delete]
@@ -317,14 +380,11 @@ func (o *OrderServer) DeleteOrder(c echo.Context) error {
 		&order_proto.GetOrderRequest{OrderId: &order_proto.UUID{Value: id}},
 	)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
-	claims := utils.ExtractClaimsFromRequest(c)
-	if claims.Id != order.Order.UserId.Value || !claims.Admin {
-		return c.JSON(http.StatusUnauthorized, ErrResponse{
+	if !canAccessOrder(c, order.Order.GetUserId().GetValue()) {
+		return c.JSON(http.StatusForbidden, ErrResponse{
 			Message: "not authorized to perform this action",
 		})
 	}
@@ -336,9 +396,7 @@ func (o *OrderServer) DeleteOrder(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusNoContent, resp)
 }
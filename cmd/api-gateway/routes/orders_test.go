@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
+	order_proto "github.com/kelcheone/chemistke/pkg/grpc/order"
+	"google.golang.org/grpc"
+)
+
+const (
+	ownerId = "9f0b7a4e-2a43-4d8e-9a36-6f5b0e0f7c11"
+	orderId = "4c2e8d7a-6b1f-4a3e-8f5d-2b9c0e1a7d34"
+)
+
+// ordersClient is an OrderServiceClient holding one order, placed by
+// ownerId.
+type ordersClient struct {
+	order_proto.OrderServiceClient
+}
+
+func (ordersClient) GetOrder(
+	context.Context,
+	*order_proto.GetOrderRequest,
+	...grpc.CallOption,
+) (*order_proto.GetOrderResponse, error) {
+	return &order_proto.GetOrderResponse{Order: &order_proto.Order{
+		Id:     &order_proto.UUID{Value: orderId},
+		UserId: &order_proto.UUID{Value: ownerId},
+	}}, nil
+}
+
+func (ordersClient) GetUserOrders(
+	context.Context,
+	*order_proto.GetUserOrdersRequest,
+	...grpc.CallOption,
+) (*order_proto.GetUserOrdersResponse, error) {
+	return &order_proto.GetUserOrdersResponse{}, nil
+}
+
+func (ordersClient) UpdateOrder(
+	context.Context,
+	*order_proto.UpdateOrderRequest,
+	...grpc.CallOption,
+) (*order_proto.UpdateOrderResponse, error) {
+	return &order_proto.UpdateOrderResponse{}, nil
+}
+
+func (ordersClient) DeleteOrder(
+	context.Context,
+	*order_proto.DeleteOrderRequest,
+	...grpc.CallOption,
+) (*order_proto.DeleteOrderResponse, error) {
+	return &order_proto.DeleteOrderResponse{}, nil
+}
+
+func TestOrderOwnership(t *testing.T) {
+	utils.SetSecretKey("test-secret")
+	e := NewRouter(Servers{Orders: &OrderServer{OrderClient: ordersClient{}}}, config.Default().Gateway)
+
+	requests := []struct {
+		name, method, path, body string
+		ok                       int
+	}{
+		{"get", http.MethodGet, "/api/v1/orders/" + orderId, "", http.StatusOK},
+		{"list", http.MethodGet, "/api/v1/orders/user?id=" + ownerId + "&page=1&limit=10", "", http.StatusOK},
+		// the body names the caller as the owner, the stored order decides.
+		{"update", http.MethodPatch, "/api/v1/orders", `{"id":"` + orderId + `","user_id":"{caller}","status":"cancelled"}`, http.StatusNoContent},
+		{"delete", http.MethodDelete, "/api/v1/orders/" + orderId, "", http.StatusNoContent},
+	}
+	callers := []struct {
+		name, id, role string
+		allowed        bool
+	}{
+		{"owner", ownerId, "USER", true},
+		{"another user", "0d6f3b2a-7e4c-4b1d-9a8e-5c3f2e1d0b9a", "USER", false},
+		{"admin", "0d6f3b2a-7e4c-4b1d-9a8e-5c3f2e1d0b9a", "ADMIN", true},
+	}
+
+	for _, r := range requests {
+		for _, caller := range callers {
+			t.Run(r.name+" by "+caller.name, func(t *testing.T) {
+				token, err := utils.CreateToken(caller.id, "jane@example.com", "Jane Doe", "+254700000000", caller.role)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req := httptest.NewRequest(r.method, r.path, strings.NewReader(strings.ReplaceAll(r.body, "{caller}", caller.id)))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+token)
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+
+				want := http.StatusForbidden
+				if caller.allowed {
+					want = r.ok
+				}
+				if rec.Code != want {
+					t.Errorf("got status %d, want %d: %s", rec.Code, want, rec.Body)
+				}
+			})
+		}
+	}
+}
@@ -3,12 +3,13 @@ package routes
 import (
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/tracing"
 	product_proto "github.com/kelcheone/chemistke/pkg/grpc/product"
 	"github.com/labstack/echo/v4"
 	"google.golang.org/grpc"
@@ -55,6 +56,8 @@ func ConnectProductServer(link string) (*ProductServer, func(), error) {
 	productConn, err := grpc.NewClient(
 		link,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logging.UnaryClientInterceptor()),
+		tracing.DialOption(),
 	)
 	if err != nil {
 		return nil, nil, fmt.Errorf(
@@ -115,9 +118,7 @@ func (p *ProductServer) CreateProduct(c echo.Context) error {
 
 	resp, err := p.ProductClient.CreateProduct(c.Request().Context(), nProduct)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -171,9 +172,7 @@ func (p *ProductServer) GetProduct(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetProduct(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	product := convertProduct(resp.Product)
@@ -207,9 +206,7 @@ func (p *ProductServer) GetProductBySlug(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetProductBySlug(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	product := convertProduct(resp.Product)
@@ -261,9 +258,7 @@ func (p *ProductServer) GetProducts(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetProducts(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	var products []Product
@@ -330,9 +325,7 @@ func (p *ProductServer) GetFeaturedProducts(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetFeaturedProducts(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	var products []Product
@@ -396,9 +389,7 @@ func (p *ProductServer) GetProductsByCategory(c echo.Context) error {
 		req,
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	var products []Product
 	for _, product := range resp.Products {
@@ -461,9 +452,7 @@ func (p *ProductServer) GetProductsByCategorySlug(c echo.Context) error {
 		req,
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	var products []Product
@@ -527,9 +516,7 @@ func (p *ProductServer) GetProductsBySCategory(c echo.Context) error {
 		req,
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	var products []Product
@@ -593,9 +580,7 @@ func (p *ProductServer) GetProductsByBrand(c echo.Context) error {
 		req,
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	var products []Product
@@ -633,8 +618,6 @@ func (p *ProductServer) UpdateProduct(c echo.Context) error {
 
 	claims := utils.ExtractClaimsFromRequest(c)
 
-	log.Printf("User Role: %v", claims.Admin)
-
 	if !claims.Admin {
 		return c.JSON(http.StatusUnauthorized, ErrResponse{
 			Message: "can't perform this operation.",
@@ -657,9 +640,7 @@ func (p *ProductServer) UpdateProduct(c echo.Context) error {
 
 	resp, err := p.ProductClient.UpdateProduct(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusNoContent, resp)
@@ -694,7 +675,6 @@ func (p *ProductServer) DeleteProduct(c echo.Context) error {
 		})
 	}
 
-	fmt.Println("The id is: ", id)
 	req := &product_proto.DeleteProductRequest{
 		Id: &product_proto.UUID{
 			Value: id,
@@ -703,9 +683,7 @@ func (p *ProductServer) DeleteProduct(c echo.Context) error {
 
 	resp, err := p.ProductClient.DeleteProduct(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusNoContent, resp)
@@ -763,9 +741,7 @@ func (p *ProductServer) UploadImage(c echo.Context) error {
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: fmt.Sprintf("could not upload: %+v", err.Error()),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -797,10 +773,7 @@ func (p *ProductServer) GetProductImages(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetProductImages(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrResponse{Message: err.Error()},
-		)
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -853,10 +826,7 @@ func (p *ProductServer) CreateReview(c echo.Context) error {
 
 	resp, err := p.ProductClient.CreateReview(c.Request().Context(), reqProto)
 	if err != nil {
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrResponse{Message: err.Error()},
-		)
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -897,10 +867,7 @@ func (p *ProductServer) GetReview(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetReview(c.Request().Context(), reqProto)
 	if err != nil {
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrResponse{Message: err.Error()},
-		)
+		return grpcError(c, err)
 	}
 	// create a new ReviewResponse from the response
 	respProto := &ReviewResponse{
@@ -941,10 +908,7 @@ func (p *ProductServer) GetReviews(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetReviews(c.Request().Context(), reqProto)
 	if err != nil {
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrResponse{Message: err.Error()},
-		)
+		return grpcError(c, err)
 	}
 	var reviews []*ReviewResponse
 	for _, review := range resp.Reviews {
@@ -996,14 +960,9 @@ func (p *ProductServer) GetProductRating(c echo.Context) error {
 		ProductId: &product_proto.UUID{Value: id},
 	}
 
-	log.Printf("The productId is: %s ", reqProto.ProductId.Value)
-
 	resp, err := p.ProductClient.GetProductRating(c.Request().Context(), reqProto)
 	if err != nil {
-		return c.JSON(
-			http.StatusInternalServerError,
-			ErrResponse{Message: err.Error()},
-		)
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -1044,9 +1003,7 @@ func (p *ProductServer) CreateCategory(c echo.Context) error {
 
 	resp, err := p.ProductClient.CreateCategory(c.Request().Context(), createCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusCreated, resp)
 }
@@ -1075,9 +1032,7 @@ func (p *ProductServer) GetCategory(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetCategory(c.Request().Context(), getCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	categoryResp := &ProductCategory{
@@ -1110,9 +1065,7 @@ func (p *ProductServer) GetCategoryBySlug(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetCategoryBySlug(c.Request().Context(), getCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	categoryResp := &ProductCategory{
@@ -1155,9 +1108,7 @@ func (p *ProductServer) UpdateCategory(c echo.Context) error {
 
 	resp, err := p.ProductClient.UpdateCategory(c.Request().Context(), updateCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusCreated, resp)
@@ -1188,9 +1139,7 @@ func (p *ProductServer) DeleteCategory(c echo.Context) error {
 
 	_, err := p.ProductClient.DeleteCategory(c.Request().Context(), deleteCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.NoContent(http.StatusNoContent)
@@ -1233,9 +1182,7 @@ func (p *ProductServer) GetCategories(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetCategories(c.Request().Context(), getCategoriesReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	var categories []ProductCategory
@@ -1290,9 +1237,7 @@ func (p *ProductServer) GetFeaturedCategories(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetFeaturedCategories(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: "failed to get featured categories",
-		})
+		return grpcError(c, err)
 	}
 
 	var categories []ProductCategory
@@ -1348,9 +1293,7 @@ func (p *ProductServer) CreateSubCategory(c echo.Context) error {
 
 	resp, err := p.ProductClient.CreateSubCategory(c.Request().Context(), createSubCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusCreated, resp)
@@ -1382,9 +1325,7 @@ func (p *ProductServer) GetSubCategory(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetSubCategory(c.Request().Context(), getSubCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, ProductSubCategory{
@@ -1421,9 +1362,7 @@ func (p *ProductServer) GetSubCategoryBySlug(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetSubCategoryBySlug(c.Request().Context(), getSubCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, ProductSubCategory{
@@ -1465,9 +1404,7 @@ func (p *ProductServer) UpdateSubCategory(c echo.Context) error {
 
 	resp, err := p.ProductClient.UpdateSubCategory(c.Request().Context(), updateSubCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -1495,9 +1432,7 @@ func (p *ProductServer) DeleteSubCategory(c echo.Context) error {
 
 	_, err := p.ProductClient.DeleteSubCategory(c.Request().Context(), deleteSubCategoryReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.NoContent(http.StatusNoContent)
@@ -1549,9 +1484,7 @@ func (p *ProductServer) GetSubCategories(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetSubCategories(c.Request().Context(), getSubCategoriesReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	var subCategories []ProductSubCategory
@@ -1605,9 +1538,7 @@ func (p *ProductServer) CreateBrand(c echo.Context) error {
 
 	resp, err := p.ProductClient.CreateBrand(c.Request().Context(), createBrandReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -1633,9 +1564,7 @@ func (p *ProductServer) GetBrand(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetBrand(c.Request().Context(), getBrandReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	brandResp := &Brand{
@@ -1675,9 +1604,7 @@ func (p *ProductServer) UpdateBrand(c echo.Context) error {
 
 	resp, err := p.ProductClient.UpdateBrand(c.Request().Context(), updateBrandReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -1704,9 +1631,7 @@ func (p *ProductServer) DeleteBrand(c echo.Context) error {
 
 	_, err := p.ProductClient.DeleteBrand(c.Request().Context(), deleteBrandReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	return c.NoContent(http.StatusNoContent)
@@ -1748,9 +1673,7 @@ func (p *ProductServer) GetBrands(c echo.Context) error {
 
 	resp, err := p.ProductClient.GetBrands(c.Request().Context(), getBrandsReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	var brands []Brand
@@ -0,0 +1,248 @@
+package routes
+
+import (
+	"github.com/go-playground/validator"
+	authservice "github.com/kelcheone/chemistke/cmd/api-gateway/auth"
+	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/metrics"
+	"github.com/kelcheone/chemistke/internal/tracing"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+type CustomValidator struct {
+	validator *validator.Validate
+}
+
+func (cv *CustomValidator) Validate(i interface{}) error {
+	return cv.validator.Struct(i)
+}
+
+// Servers holds a handler for each backing service.
+type Servers struct {
+	Users    *UserServer
+	Products *ProductServer
+	Orders   *OrderServer
+	Cms      *CmsServer
+	Seo      *SeoServer
+}
+
+// NewRouter returns the gateway with its middleware and every route
+// registered. It is shared by the gateway binary and the integration harness.
+func NewRouter(s Servers, cfg config.GatewayConfig) *echo.Echo {
+	e := echo.New()
+	e.Validator = &CustomValidator{validator: validator.New()}
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(tracing.EchoMiddleware("api-gateway"))
+	e.Use(logging.EchoMiddleware())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: cfg.AllowOrigins,
+		AllowHeaders: []string{
+			echo.HeaderOrigin,
+			echo.HeaderContentType,
+			echo.HeaderAccept,
+			echo.HeaderAuthorization,
+			"X-Requested-With",
+		},
+		AllowMethods: []string{
+			echo.GET,
+			echo.HEAD,
+			echo.PUT,
+			echo.PATCH,
+			echo.POST,
+			echo.DELETE,
+			echo.OPTIONS,
+		},
+		AllowCredentials: true,
+		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+	}))
+	e.Use(metrics.EchoMiddleware())
+
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
+	v1 := e.Group("/api/v1", middleware.ContextTimeout(cfg.RequestTimeout))
+
+	users := v1.Group("/users")
+	users.POST("", s.Users.CreateUser)
+	users.GET("/get-user", s.Users.GetUser)
+	users.GET("", s.Users.GetUsers, utils.AuthMiddleware())
+	users.GET("/search", s.Users.SearchUsers, utils.AuthMiddleware())
+	users.GET("/get-user-by-email", s.Users.GetUserByEmail)
+	users.PATCH("", s.Users.UpdateUser, utils.AuthMiddleware())
+	users.PATCH("/password", s.Users.ChangePassword, utils.AuthMiddleware())
+	users.PATCH("/:id/role", s.Users.UpdateUserRole, utils.AuthMiddleware())
+
+	users.GET("/me", s.Users.GetMe, utils.AuthMiddleware())
+	users.PATCH("/me", s.Users.UpdateMe, utils.AuthMiddleware())
+	users.DELETE("/me", s.Users.DeleteMe, utils.AuthMiddleware())
+	users.DELETE("", s.Users.DeleteUser, utils.AuthMiddleware())
+
+	addresses := users.Group("/me/addresses", utils.AuthMiddleware())
+	addresses.GET("", s.Users.ListAddresses)
+	addresses.POST("", s.Users.CreateAddress)
+	addresses.GET("/:id", s.Users.GetAddress)
+	addresses.PATCH("/:id", s.Users.UpdateAddress)
+	addresses.DELETE("/:id", s.Users.DeleteAddress)
+
+	exports := users.Group("/me/exports", utils.AuthMiddleware())
+	exports.POST("", s.Users.RequestDataExport)
+	exports.GET("/:id", s.Users.GetDataExport)
+
+	auth := v1.Group("/auth")
+	auth.POST("/login", func(c echo.Context) error {
+		user := authservice.User{
+			Client: s.Users.UserClient,
+		}
+
+		return user.Login(c)
+	})
+
+	auth.GET("/me", func(c echo.Context) error {
+		user := authservice.User{
+			Client: s.Users.UserClient,
+		}
+
+		return user.Me(c)
+	}, utils.AuthMiddleware())
+
+	auth.POST("/logout", func(c echo.Context) error {
+		user := authservice.User{
+			Client: s.Users.UserClient,
+		}
+
+		return user.Logout(c)
+	}, utils.AuthMiddleware())
+
+	products := v1.Group("/products")
+	products.POST("", s.Products.CreateProduct, utils.AuthMiddleware())
+	products.GET("/:id", s.Products.GetProduct)
+	products.GET("", s.Products.GetProducts)
+	products.GET("/featured", s.Products.GetFeaturedProducts)
+	products.GET("/by-brand/:id", s.Products.GetProductsByBrand)
+	products.GET("/by-category/:id", s.Products.GetProductsByCategory)
+	products.GET("/by-category/slug/:slug", s.Products.GetProductsByCategorySlug)
+	products.GET("/by-subcategory/:id", s.Products.GetProductsBySCategory)
+	products.GET("/slug/:slug", s.Products.GetProductBySlug)
+
+	products.POST("/reviews", s.Products.CreateReview, utils.AuthMiddleware())
+	products.GET("/ratings/:id", s.Products.GetProductRating)
+	products.GET("/reviews/:id", s.Products.GetReview)
+	products.GET("/:id/reviews", s.Products.GetReviews)
+	products.GET("/:id/articles", s.Cms.GetProductPosts)
+
+	// product-category
+	products.POST("/categories", s.Products.CreateCategory, utils.AuthMiddleware())
+	products.GET("/categories/:id", s.Products.GetCategory)
+	products.GET("/categories", s.Products.GetCategories)
+	products.GET("/categories/featured", s.Products.GetFeaturedCategories)
+	products.PATCH("/categories", s.Products.UpdateCategory, utils.AuthMiddleware())
+	products.DELETE("/categories/:id", s.Products.DeleteCategory, utils.AuthMiddleware())
+	products.GET("/categories/:id/subcategories", s.Products.GetSubCategories)
+	products.GET("/categories/slug/:slug", s.Products.GetCategoryBySlug)
+	// product-sub-category
+	products.POST("/subcategories", s.Products.CreateSubCategory, utils.AuthMiddleware())
+	products.GET("/subcategories/:id", s.Products.GetSubCategory)
+	products.GET("/subcategories", s.Products.GetSubCategories)
+	products.PATCH("/subcategories", s.Products.UpdateSubCategory, utils.AuthMiddleware())
+	products.DELETE("/subcategories/:id", s.Products.DeleteSubCategory, utils.AuthMiddleware())
+	products.GET("/subcategories/slug/:slug", s.Products.GetSubCategoryBySlug)
+	// product-brand
+	products.POST("/brands", s.Products.CreateBrand, utils.AuthMiddleware())
+	products.GET("/brands", s.Products.GetBrands)
+	products.GET("/brands/:id", s.Products.GetBrand)
+	products.PATCH("/brands", s.Products.UpdateBrand, utils.AuthMiddleware())
+	products.DELETE("/brands/:id", s.Products.DeleteBrand, utils.AuthMiddleware())
+
+	products.PATCH("", s.Products.UpdateProduct, utils.AuthMiddleware())
+	products.DELETE("/:id", s.Products.DeleteProduct, utils.AuthMiddleware())
+	products.POST("/images/upload", s.Products.UploadImage)
+	products.GET("/images/:id", s.Products.GetProductImages)
+
+	orders := v1.Group("/orders", utils.AuthMiddleware())
+	orders.POST("", s.Orders.CreateOrder)
+	orders.GET("/:id", s.Orders.GetOrder)
+	orders.DELETE("/:id", s.Orders.DeleteOrder)
+	orders.GET("/user", s.Orders.GetUserOders)
+	orders.GET("", s.Orders.GetOders)
+	orders.PATCH("", s.Orders.UpdateOrder)
+
+	cms := v1.Group("/cms")
+
+	authors := cms.Group("/authors")
+	authors.POST("", s.Cms.CreateAuthor, utils.AuthMiddleware())
+	authors.GET("/:id", s.Cms.GetAuthor)
+	authors.GET("/slug/:slug", s.Cms.GetAuthorBySlug)
+	authors.PATCH("", s.Cms.UpdateAuthor, utils.AuthMiddleware())
+	authors.DELETE("/:id", s.Cms.DeleteAuthor, utils.AuthMiddleware())
+	authors.GET("", s.Cms.ListAuthors)
+
+	categories := cms.Group("/categories")
+	categories.POST("", s.Cms.CreateCategory, utils.AuthMiddleware())
+	categories.GET("/:id", s.Cms.GetCategory)
+	categories.GET("/slug/:slug", s.Cms.GetCategoryBySlug)
+	categories.GET("", s.Cms.ListCategories)
+	categories.PATCH("", s.Cms.UpdateCategory, utils.AuthMiddleware())
+	categories.DELETE("/:id", s.Cms.DeleteCategory, utils.AuthMiddleware())
+
+	posts := cms.Group("/posts")
+	posts.POST("", s.Cms.CreatePost, utils.AuthMiddleware())
+	posts.GET("/:id", s.Cms.GetPost, utils.OptionalAuthMiddleware())
+	posts.GET("/slug/:slug", s.Cms.GetPostBySlug, utils.OptionalAuthMiddleware())
+	posts.GET("/popular", s.Cms.GetPopularPosts)
+	posts.GET("", s.Cms.ListPosts, utils.OptionalAuthMiddleware())
+	posts.PATCH("", s.Cms.UpdatePost, utils.AuthMiddleware())
+	posts.DELETE("/:id", s.Cms.DeletePost, utils.AuthMiddleware())
+	posts.GET("/category", s.Cms.GetCategoryPosts)
+	posts.GET("/author", s.Cms.GetAuthorPosts)
+	posts.GET("/get-by-author-category", s.Cms.GetAuthorCategoryPosts)
+	posts.POST("/:id/submit", s.Cms.SubmitPostForReview, utils.AuthMiddleware())
+	posts.POST("/:id/approve", s.Cms.ApprovePost, utils.AuthMiddleware())
+	posts.POST("/:id/reject", s.Cms.RejectPost, utils.AuthMiddleware())
+	posts.POST("/:id/archive", s.Cms.ArchivePost, utils.AuthMiddleware())
+
+	posts.GET("/:id/comments", s.Cms.ListComments)
+	posts.POST("/:id/comments", s.Cms.CreateComment, utils.AuthMiddleware())
+	posts.POST("/:id/views", s.Cms.RecordPostView, utils.OptionalAuthMiddleware())
+
+	comments := cms.Group("/comments", utils.AuthMiddleware())
+	comments.GET("", s.Cms.ListCommentsForModeration)
+	comments.PATCH("/:id", s.Cms.ModerateComment)
+
+	media := cms.Group("/media", utils.AuthMiddleware())
+	media.POST("", s.Cms.UploadMedia)
+	media.GET("", s.Cms.ListMedia)
+	media.GET("/:id", s.Cms.GetMedia)
+	media.PATCH("/:id", s.Cms.UpdateMedia)
+	media.DELETE("/:id", s.Cms.DeleteMedia)
+
+	revisions := posts.Group("/:id/revisions", utils.AuthMiddleware())
+	revisions.GET("", s.Cms.ListPostRevisions)
+	revisions.GET("/diff", s.Cms.DiffPostRevisions)
+	revisions.POST("/:revision/restore", s.Cms.RestorePostRevision)
+
+	tags := cms.Group("/tags")
+	tags.POST("", s.Cms.CreateTag, utils.AuthMiddleware())
+	tags.GET("", s.Cms.ListTags)
+	tags.GET("/posts", s.Cms.GetTaggedPosts)
+	tags.GET("/:slug", s.Cms.GetTag)
+	tags.GET("/:slug/posts", s.Cms.GetTagPosts)
+	tags.PATCH("/:id", s.Cms.UpdateTag, utils.AuthMiddleware())
+	tags.DELETE("/:id", s.Cms.DeleteTag, utils.AuthMiddleware())
+
+	// feeds and sitemaps live at the root where crawlers look for them.
+	e.GET("/feed.xml", s.Seo.RSS)
+	e.GET("/atom.xml", s.Seo.Atom)
+	e.GET("/blog/categories/:slug/feed.xml", s.Seo.CategoryRSS)
+	e.GET("/blog/categories/:slug/atom.xml", s.Seo.CategoryAtom)
+	e.GET("/sitemap.xml", s.Seo.SitemapIndex)
+	e.GET("/sitemaps/:name", s.Seo.Sitemap)
+
+	e.GET("/health", func(c echo.Context) error {
+		return c.String(200, "OK")
+	})
+
+	return e
+}
@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/kelcheone/chemistke/internal/metrics"
+	user_proto "github.com/kelcheone/chemistke/pkg/grpc/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// credentialsClient is a UserServiceClient whose VerifyCredentials fails
+// with err, or succeeds when err is nil. The deadline it was called with is
+// sent on deadline if that is set.
+type credentialsClient struct {
+	user_proto.UserServiceClient
+	err      error
+	deadline chan time.Time
+}
+
+func (c credentialsClient) VerifyCredentials(
+	ctx context.Context,
+	_ *user_proto.VerifyCredentialsRequest,
+	_ ...grpc.CallOption,
+) (*user_proto.VerifyCredentialsResponse, error) {
+	if c.deadline != nil {
+		deadline, _ := ctx.Deadline()
+		c.deadline <- deadline
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &user_proto.VerifyCredentialsResponse{User: &user_proto.User{
+		Id:    &user_proto.UUID{Value: "9f0b7a4e-2a43-4d8e-9a36-6f5b0e0f7c11"},
+		Email: "jane@example.com",
+	}}, nil
+}
+
+func TestLogin(t *testing.T) {
+	utils.SetSecretKey("test-secret")
+
+	tests := []struct {
+		name     string
+		err      error
+		want     int
+		wantCode string
+	}{
+		{"signed in", nil, http.StatusAccepted, ""},
+		{"bad credentials", status.Error(codes.Unauthenticated, "invalid credentials"), http.StatusUnauthorized, "unauthenticated"},
+		{"service down", status.Error(codes.Unavailable, "connection refused"), http.StatusServiceUnavailable, "unavailable"},
+		{"service failed", status.Error(codes.Internal, "error verifying credentials"), http.StatusInternalServerError, "internal"},
+		{"timed out", status.Error(codes.DeadlineExceeded, "deadline exceeded"), http.StatusGatewayTimeout, "deadline_exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewRouter(Servers{
+				Users: &UserServer{UserClient: credentialsClient{err: tt.err}},
+			}, config.Default().Gateway)
+
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/api/v1/auth/login",
+				strings.NewReader(`{"email":"jane@example.com","password":"secret"}`),
+			)
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.wantCode != "" && !strings.Contains(rec.Body.String(), `"code":"`+tt.wantCode+`"`) {
+				t.Errorf("body %s does not carry code %q", rec.Body, tt.wantCode)
+			}
+		})
+	}
+}
+
+// the metrics middleware runs before the error handler has written the
+// response, it must still record the status the client got.
+func TestMetricsRecordTranslatedStatus(t *testing.T) {
+	utils.SetSecretKey("test-secret")
+	e := NewRouter(Servers{
+		Users: &UserServer{UserClient: credentialsClient{err: status.Error(codes.Unavailable, "down")}},
+	}, config.Default().Gateway)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email":"jane@example.com","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	want := `chemistke_http_requests_total{method="POST",route="/api/v1/auth/login",status="503"}`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("metrics do not contain %s", want)
+	}
+}
+
+func TestLoginUsesRequestTimeout(t *testing.T) {
+	utils.SetSecretKey("test-secret")
+	cfg := config.Default().Gateway
+	cfg.RequestTimeout = time.Minute
+
+	deadline := make(chan time.Time, 1)
+	e := NewRouter(Servers{
+		Users: &UserServer{UserClient: credentialsClient{deadline: deadline}},
+	}, cfg)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email":"jane@example.com","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	if left := time.Until(<-deadline); left < 30*time.Second {
+		t.Errorf("called with %s left, want the request timeout of %s", left, cfg.RequestTimeout)
+	}
+}
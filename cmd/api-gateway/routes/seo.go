@@ -0,0 +1,474 @@
+package routes
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	cms_proto "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	product_proto "github.com/kelcheone/chemistke/pkg/grpc/product"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// feedSize is how many of the latest posts go in a feed.
+	feedSize = 20
+	// sitemapMaxURLs is the most URLs the sitemap protocol allows per file.
+	sitemapMaxURLs = 50000
+	// sitemapBatch keeps each rpc well under the default grpc message size.
+	sitemapBatch = 5000
+)
+
+// SeoServer serves feeds and sitemaps for the storefront. SiteURL is the
+// storefront the links in them point at, e.g. https://chemist.co.ke.
+type SeoServer struct {
+	CmsClient     cms_proto.CmsServiceClient
+	ProductClient product_proto.ProductServiceClient
+	SiteURL       string
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Guid        rssGuid `xml:"guid"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Id        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Updated   string   `xml:"updated"`
+	Published string   `xml:"published,omitempty"`
+	Link      atomLink `xml:"link"`
+	Summary   string   `xml:"summary"`
+	Content   atomText `xml:"content"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapLoc `xml:"url"`
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// feedInfo describes one feed, the site wide one or a category's.
+type feedInfo struct {
+	title string
+	// storefront page the feed is about
+	link string
+	// path of the feed itself
+	self string
+}
+
+// RSS serves the latest published posts as RSS 2.0.
+func (s *SeoServer) RSS(c echo.Context) error {
+	posts, err := s.latestPosts(c.Request().Context())
+	if err != nil {
+		return grpcError(c, err)
+	}
+	return s.writeRSS(c, s.siteFeed(c, "/feed.xml"), posts)
+}
+
+// Atom serves the latest published posts as an Atom feed.
+func (s *SeoServer) Atom(c echo.Context) error {
+	posts, err := s.latestPosts(c.Request().Context())
+	if err != nil {
+		return grpcError(c, err)
+	}
+	return s.writeAtom(c, s.siteFeed(c, "/atom.xml"), posts)
+}
+
+// CategoryRSS serves the latest published posts in a CMS category as RSS 2.0.
+func (s *SeoServer) CategoryRSS(c echo.Context) error {
+	return s.categoryFeed(c, "feed.xml", s.writeRSS)
+}
+
+// CategoryAtom serves the latest published posts in a CMS category as Atom.
+func (s *SeoServer) CategoryAtom(c echo.Context) error {
+	return s.categoryFeed(c, "atom.xml", s.writeAtom)
+}
+
+func (s *SeoServer) categoryFeed(
+	c echo.Context,
+	file string,
+	write func(echo.Context, feedInfo, []*cms_proto.Post) error,
+) error {
+	ctx := c.Request().Context()
+	resp, err := s.CmsClient.GetCategoryBySlug(
+		ctx,
+		&cms_proto.GetCategoryBySlugRequest{Slug: c.Param("slug")},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+	if resp.RedirectSlug != "" {
+		return c.Redirect(
+			http.StatusMovedPermanently,
+			"/blog/categories/"+resp.RedirectSlug+"/"+file,
+		)
+	}
+
+	posts, err := s.CmsClient.GetCategoryPosts(ctx, &cms_proto.GetCategoryPostsRequest{
+		CategoryId: resp.Category.CategoryId,
+		PerPage:    feedSize,
+	})
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	category := resp.Category
+	return write(c, feedInfo{
+		title: "ChemistKe - " + category.Name,
+		link:  s.siteURL(c) + "/blog/categories/" + category.Slug,
+		self:  requestBase(c) + "/blog/categories/" + category.Slug + "/" + file,
+	}, posts.Posts)
+}
+
+func (s *SeoServer) latestPosts(ctx context.Context) ([]*cms_proto.Post, error) {
+	resp, err := s.CmsClient.ListPosts(ctx, &cms_proto.ListPostsRequest{PerPage: feedSize})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Posts, nil
+}
+
+func (s *SeoServer) siteFeed(c echo.Context, file string) feedInfo {
+	return feedInfo{
+		title: "ChemistKe",
+		link:  s.siteURL(c) + "/blog",
+		self:  requestBase(c) + file,
+	}
+}
+
+func (s *SeoServer) writeRSS(c echo.Context, info feedInfo, posts []*cms_proto.Post) error {
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       info.title,
+			Link:        info.link,
+			Description: "Health and medicine articles from " + info.title,
+			Self:        atomLink{Href: info.self, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+
+	var latest time.Time
+	for _, post := range posts {
+		link := s.siteURL(c) + "/blog/" + post.Slug
+		published := parseTime(post.PublishedDate)
+		if updated := parseTime(post.UpdatedDate); updated.After(latest) {
+			latest = updated
+		}
+
+		item := rssItem{
+			Title:       post.Title,
+			Link:        link,
+			Guid:        rssGuid{IsPermaLink: true, Value: link},
+			Description: post.Description,
+		}
+		if !published.IsZero() {
+			item.PubDate = published.Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	if !latest.IsZero() {
+		feed.Channel.LastBuildDate = latest.Format(time.RFC1123Z)
+	}
+
+	return writeXML(c, "application/rss+xml", feed)
+}
+
+func (s *SeoServer) writeAtom(c echo.Context, info feedInfo, posts []*cms_proto.Post) error {
+	feed := atomFeed{
+		Xmlns:  "http://www.w3.org/2005/Atom",
+		Id:     info.link,
+		Title:  info.title,
+		Author: atomAuthor{Name: "ChemistKe"},
+		Links: []atomLink{
+			{Href: info.self, Rel: "self", Type: "application/atom+xml"},
+			{Href: info.link, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	var latest time.Time
+	for _, post := range posts {
+		link := s.siteURL(c) + "/blog/" + post.Slug
+		updated := parseTime(post.UpdatedDate)
+		if updated.After(latest) {
+			latest = updated
+		}
+
+		entry := atomEntry{
+			Id:      link,
+			Title:   post.Title,
+			Updated: updated.Format(time.RFC3339),
+			Link:    atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Summary: post.Description,
+			Content: atomText{Type: "html", Value: post.ContentHtml},
+		}
+		if published := parseTime(post.PublishedDate); !published.IsZero() {
+			entry.Published = published.Format(time.RFC3339)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	// updated is required, an empty feed is as old as the request.
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	feed.Updated = latest.Format(time.RFC3339)
+
+	return writeXML(c, "application/atom+xml", feed)
+}
+
+// sitemapSource is one kind of page listed in the sitemap.
+type sitemapSource struct {
+	// sitemap files are named <name>-<n>.xml
+	name string
+	// storefront path the slugs are appended to
+	path    string
+	chunks  func(ctx context.Context) ([]sitemapLoc, error)
+	entries func(ctx context.Context, offset, limit int32) ([]sitemapLoc, error)
+}
+
+func (s *SeoServer) sitemapSources() []sitemapSource {
+	product := func(name, path string, kind product_proto.SitemapKind) sitemapSource {
+		return sitemapSource{
+			name: name,
+			path: path,
+			chunks: func(ctx context.Context) ([]sitemapLoc, error) {
+				resp, err := s.ProductClient.GetSitemapIndex(ctx, &product_proto.GetSitemapIndexRequest{
+					Kind:      kind,
+					ChunkSize: sitemapMaxURLs,
+				})
+				if err != nil {
+					return nil, err
+				}
+				var chunks []sitemapLoc
+				for _, chunk := range resp.Chunks {
+					chunks = append(chunks, sitemapLoc{
+						Loc:     strconv.Itoa(int(chunk.Index) + 1),
+						LastMod: chunk.LastModified.AsTime().Format(time.RFC3339),
+					})
+				}
+				return chunks, nil
+			},
+			entries: func(ctx context.Context, offset, limit int32) ([]sitemapLoc, error) {
+				resp, err := s.ProductClient.GetSitemapEntries(ctx, &product_proto.GetSitemapEntriesRequest{
+					Kind:   kind,
+					Offset: offset,
+					Limit:  limit,
+				})
+				if err != nil {
+					return nil, err
+				}
+				var locs []sitemapLoc
+				for _, entry := range resp.Entries {
+					locs = append(locs, sitemapLoc{
+						Loc:     entry.Slug,
+						LastMod: entry.UpdatedAt.AsTime().Format(time.RFC3339),
+					})
+				}
+				return locs, nil
+			},
+		}
+	}
+
+	posts := sitemapSource{
+		name: "posts",
+		path: "/blog/",
+		chunks: func(ctx context.Context) ([]sitemapLoc, error) {
+			resp, err := s.CmsClient.GetSitemapIndex(ctx, &cms_proto.GetSitemapIndexRequest{
+				ChunkSize: sitemapMaxURLs,
+			})
+			if err != nil {
+				return nil, err
+			}
+			var chunks []sitemapLoc
+			for _, chunk := range resp.Chunks {
+				chunks = append(chunks, sitemapLoc{
+					Loc:     strconv.Itoa(int(chunk.Index) + 1),
+					LastMod: chunk.LastModified,
+				})
+			}
+			return chunks, nil
+		},
+		entries: func(ctx context.Context, offset, limit int32) ([]sitemapLoc, error) {
+			resp, err := s.CmsClient.GetSitemapEntries(ctx, &cms_proto.GetSitemapEntriesRequest{
+				Offset: offset,
+				Limit:  limit,
+			})
+			if err != nil {
+				return nil, err
+			}
+			var locs []sitemapLoc
+			for _, entry := range resp.Entries {
+				locs = append(locs, sitemapLoc{Loc: entry.Slug, LastMod: entry.UpdatedAt})
+			}
+			return locs, nil
+		},
+	}
+
+	return []sitemapSource{
+		product("products", "/products/", product_proto.SitemapKind_PRODUCTS),
+		product("categories", "/categories/", product_proto.SitemapKind_CATEGORIES),
+		product("subcategories", "/subcategories/", product_proto.SitemapKind_SUB_CATEGORIES),
+		posts,
+	}
+}
+
+// SitemapIndex serves /sitemap.xml, which points at one sitemap file per
+// 50,000 products, categories, sub-categories and posts.
+func (s *SeoServer) SitemapIndex(c echo.Context) error {
+	index := sitemapIndex{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+
+	for _, source := range s.sitemapSources() {
+		chunks, err := source.chunks(c.Request().Context())
+		if err != nil {
+			return grpcError(c, err)
+		}
+		for _, chunk := range chunks {
+			index.Sitemaps = append(index.Sitemaps, sitemapLoc{
+				Loc:     requestBase(c) + "/sitemaps/" + source.name + "-" + chunk.Loc + ".xml",
+				LastMod: chunk.LastMod,
+			})
+		}
+	}
+
+	return writeXML(c, "application/xml", index)
+}
+
+// Sitemap serves one of the files listed in the sitemap index, e.g.
+// /sitemaps/products-2.xml.
+func (s *SeoServer) Sitemap(c echo.Context) error {
+	name, number, ok := strings.Cut(strings.TrimSuffix(c.Param("name"), ".xml"), "-")
+	n, err := strconv.Atoi(number)
+	if !ok || err != nil || n < 1 {
+		return c.JSON(http.StatusNotFound, ErrResponse{Message: "sitemap not found"})
+	}
+
+	var source *sitemapSource
+	for _, src := range s.sitemapSources() {
+		if src.name == name {
+			source = &src
+			break
+		}
+	}
+	if source == nil {
+		return c.JSON(http.StatusNotFound, ErrResponse{Message: "sitemap not found"})
+	}
+
+	set := urlSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	start := int32(n-1) * sitemapMaxURLs
+	for offset := start; offset < start+sitemapMaxURLs; offset += sitemapBatch {
+		locs, err := source.entries(c.Request().Context(), offset, sitemapBatch)
+		if err != nil {
+			return grpcError(c, err)
+		}
+		for _, loc := range locs {
+			loc.Loc = s.siteURL(c) + source.path + loc.Loc
+			set.URLs = append(set.URLs, loc)
+		}
+		if len(locs) < sitemapBatch {
+			break
+		}
+	}
+	if len(set.URLs) == 0 {
+		return c.JSON(http.StatusNotFound, ErrResponse{Message: "sitemap not found"})
+	}
+
+	return writeXML(c, "application/xml", set)
+}
+
+// siteURL falls back to the host the request came in on when SiteURL is
+// not configured.
+func (s *SeoServer) siteURL(c echo.Context) string {
+	if s.SiteURL != "" {
+		return strings.TrimSuffix(s.SiteURL, "/")
+	}
+	return requestBase(c)
+}
+
+func requestBase(c echo.Context) string {
+	return c.Scheme() + "://" + c.Request().Host
+}
+
+func parseTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func writeXML(c echo.Context, contentType string, v interface{}) error {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrResponse{Message: err.Error()})
+	}
+	return c.Blob(
+		http.StatusOK,
+		contentType+"; charset=utf-8",
+		append([]byte(xml.Header), body...),
+	)
+}
@@ -1,15 +1,14 @@
 package routes
 
 import (
-	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/tracing"
 	user_proto "github.com/kelcheone/chemistke/pkg/grpc/user"
 	"github.com/labstack/echo/v4"
 	"google.golang.org/grpc"
@@ -38,11 +37,14 @@ type GetUserResponse struct {
 // HTTPError represents an error response
 type HTTPError struct {
 	Message string `json:"error"`
+	Code    string `json:"code,omitempty" example:"not_found"`
 }
 
-// ErrResponse represents an error response
+// ErrResponse represents an error response, code is set when the error
+// came from a service
 type ErrResponse struct {
 	Message string `json:"error"`
+	Code    string `json:"code,omitempty" example:"not_found"`
 }
 
 // UserServer handles user-related API endpoints
@@ -55,6 +57,8 @@ func ConnectUserServer(link string) (*UserServer, func(), error) {
 	userConn, err := grpc.NewClient(
 		link,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logging.UnaryClientInterceptor()),
+		tracing.DialOption(),
 	)
 	if err != nil {
 		return nil, nil, fmt.Errorf(
@@ -79,13 +83,11 @@ func ConnectUserServer(link string) (*UserServer, func(), error) {
 // @Param user body User true "User information to create"
 // @Success 201 {object} GetUserResponse "Successfully created user"
 // @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 409 {object} HTTPError "Email already registered"
 // @Failure 500 {object} HTTPError "Internal server error"
 // @Router /users [post]
 func (s *UserServer) CreateUser(c echo.Context) error {
 	var user User
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-
-	defer cancel()
 	type response struct {
 		Message string `json:"message"`
 	}
@@ -96,26 +98,21 @@ func (s *UserServer) CreateUser(c echo.Context) error {
 		})
 	}
 
+	// roles are only granted through the admin role endpoint
 	pbUSer := &user_proto.User{
 		Name:     user.Name,
 		Email:    user.Email,
 		Phone:    user.Phone,
 		Password: user.Password,
-	}
-	if user.Role == "ADMIN" {
-		pbUSer.Role = user_proto.UserRoles_ADMIN
-	} else {
-		pbUSer.Role = user_proto.UserRoles_USER
+		Role:     user_proto.UserRoles_USER,
 	}
 
 	res, err := s.UserClient.AddUser(
-		ctx,
+		c.Request().Context(),
 		&user_proto.AddUserRequest{User: pbUSer},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: fmt.Sprintf("%+v", err.Error()),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusCreated, res)
 }
@@ -147,11 +144,9 @@ func (s *UserServer) GetUser(c echo.Context) error {
 		},
 	}
 
-	gUser, err := s.UserClient.GetUser(context.TODO(), &userReq)
+	gUser, err := s.UserClient.GetUser(c.Request().Context(), &userReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	response := GetUserResponse{
@@ -187,11 +182,9 @@ func (s *UserServer) GetUserByEmail(c echo.Context) error {
 		Email: email,
 	}
 
-	gUser, err := s.UserClient.GetUserByEmail(context.TODO(), &userReq)
+	gUser, err := s.UserClient.GetUserByEmail(c.Request().Context(), &userReq)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 
 	response := GetUserResponse{
@@ -206,12 +199,11 @@ func (s *UserServer) GetUserByEmail(c echo.Context) error {
 
 // UpdateUser godoc
 // @Summary Update user details
-// @Description Update existing user information
+// @Description Update another user's profile. Admin only, use /users/me for your own profile.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param user body User true "Updated user information"
-// @Security ApiKeyAuth
 // @Success 200 {object} GetUserResponse "Successfully updated user"
 // @Failure 400 {object} HTTPError "Invalid input data"
 // @Failure 401 {object} HTTPError "Unauthorized"
@@ -229,20 +221,17 @@ func (s *UserServer) UpdateUser(c echo.Context) error {
 	}
 
 	userClaims := utils.ExtractClaimsFromRequest(c)
-	log.Printf("UserId %v vs Claims UserId %v", user.Id, userClaims.Id)
-	if userClaims.Id != user.Id {
+	if userClaims == nil || !userClaims.Admin {
 		return c.JSON(
 			http.StatusUnauthorized,
 			ErrResponse{Message: "can't update this record"},
 		)
 	}
 
-	var role user_proto.UserRoles
-
-	if user.Role == "ADMIN" {
-		role = user_proto.UserRoles_ADMIN
-	} else {
-		role = user_proto.UserRoles_USER
+	if user.Id == "" {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "missing user ID",
+		})
 	}
 
 	req := &user_proto.UpdateUserRequest{
@@ -251,20 +240,273 @@ func (s *UserServer) UpdateUser(c echo.Context) error {
 			Name:  user.Name,
 			Email: user.Email,
 			Phone: user.Phone,
-			Role:  role,
 		},
 	}
 
-	resp, err := s.UserClient.UpdateUser(context.TODO(), req)
+	resp, err := s.UserClient.UpdateUser(c.Request().Context(), req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: strings.TrimSpace(
-				err.Error(),
-			), // strings.Split(strings.Split(err.Error(), ",")[1], ":")[1],
+		return grpcError(c, err)
+	}
+	return c.JSON(http.StatusNoContent, resp)
+}
+
+// UpdateUserRoleRequest represents the role to assign to a user
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" example:"AUTHOR" validate:"required"`
+}
+
+// UpdateUserRole godoc
+// @Summary Change a user's role
+// @Description Assign a new role to a user. Admin only.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role body UpdateUserRoleRequest true "New role (ADMIN, USER, GUEST, AUTHOR)"
+// @Success 200 {object} user_proto.UpdateUserRoleResponse "Successfully updated role"
+// @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/{id}/role [patch]
+func (s *UserServer) UpdateUserRole(c echo.Context) error {
+	userClaims := utils.ExtractClaimsFromRequest(c)
+	if userClaims == nil || !userClaims.Admin {
+		return c.JSON(
+			http.StatusUnauthorized,
+			ErrResponse{Message: "not authorized to perform this action"},
+		)
+	}
 
+	var req UpdateUserRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "Invalid input",
 		})
 	}
-	return c.JSON(http.StatusNoContent, resp)
+
+	role, ok := user_proto.UserRoles_value[strings.ToUpper(req.Role)]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: fmt.Sprintf("unknown role %q", req.Role),
+		})
+	}
+
+	resp, err := s.UserClient.UpdateUserRole(
+		c.Request().Context(),
+		&user_proto.UpdateUserRoleRequest{
+			Id:   &user_proto.UUID{Value: c.Param("id")},
+			Role: user_proto.UserRoles(role),
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// UpdateProfileRequest represents the profile fields a user may change themselves
+type UpdateProfileRequest struct {
+	Name  string `json:"name"  example:"Jane Doe"`
+	Email string `json:"email" example:"jane.doe@example.com" validate:"omitempty,email"`
+	Phone string `json:"phone" example:"+254722000000"`
+}
+
+// GetMe godoc
+// @Summary Get the logged in user's profile
+// @Description Get the profile of the user the token belongs to
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Success 200 {object} GetUserResponse "Successfully retrieved user"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/me [get]
+func (s *UserServer) GetMe(c echo.Context) error {
+	userClaims := utils.ExtractClaimsFromRequest(c)
+	if userClaims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	gUser, err := s.UserClient.GetUser(
+		c.Request().Context(),
+		&user_proto.GetUserRequest{Id: &user_proto.UUID{Value: userClaims.Id}},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	response := GetUserResponse{
+		Id:    gUser.User.Id.Value,
+		Name:  gUser.User.Name,
+		Email: gUser.User.Email,
+		Phone: gUser.User.Phone,
+		Role:  gUser.User.Role.String(),
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// UpdateMe godoc
+// @Summary Update the logged in user's profile
+// @Description Update name, email or phone of the user the token belongs to. Empty fields are left unchanged.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param user body UpdateProfileRequest true "Profile fields to update"
+// @Success 200 {object} GetUserResponse "Successfully updated user"
+// @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/me [patch]
+func (s *UserServer) UpdateMe(c echo.Context) error {
+	userClaims := utils.ExtractClaimsFromRequest(c)
+	if userClaims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	var req UpdateProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "Invalid input",
+		})
+	}
+
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: fmt.Sprintf("could not validate request: %+v", err.Error()),
+		})
+	}
+
+	current, err := s.UserClient.GetUser(
+		c.Request().Context(),
+		&user_proto.GetUserRequest{Id: &user_proto.UUID{Value: userClaims.Id}},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	user := current.User
+	if req.Name != "" {
+		user.Name = req.Name
+	}
+	if req.Email != "" {
+		user.Email = req.Email
+	}
+	if req.Phone != "" {
+		user.Phone = req.Phone
+	}
+
+	_, err = s.UserClient.UpdateUser(
+		c.Request().Context(),
+		&user_proto.UpdateUserRequest{User: user},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	response := GetUserResponse{
+		Id:    user.Id.Value,
+		Name:  user.Name,
+		Email: user.Email,
+		Phone: user.Phone,
+		Role:  user.Role.String(),
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// DeleteMe godoc
+// @Summary Delete the logged in user's account
+// @Description Erase the account of the user the token belongs to. Personal details are anonymized, orders are kept
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Success 202 {object} DeleteUserResponse "Successfully deleted user"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/me [delete]
+func (s *UserServer) DeleteMe(c echo.Context) error {
+	userClaims := utils.ExtractClaimsFromRequest(c)
+	if userClaims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	resp, err := s.UserClient.DeleteUser(
+		c.Request().Context(),
+		&user_proto.DeleteUserRequest{Id: &user_proto.UUID{Value: userClaims.Id}},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	// the token is no longer tied to an account
+	c.SetCookie(&http.Cookie{
+		Name:     "token",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+// ChangePasswordRequest represents the data needed to change a password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" example:"oldPassw0rd" validate:"required"`
+	NewPassword     string `json:"new_password"     example:"newPassw0rd" validate:"required"`
+}
+
+// ChangePassword godoc
+// @Summary Change the password of the logged in user
+// @Description Change the password after confirming the current one
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param passwords body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} user_proto.ChangePasswordResponse "Successfully changed password"
+// @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/password [patch]
+func (s *UserServer) ChangePassword(c echo.Context) error {
+	var req ChangePasswordRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "Invalid input",
+		})
+	}
+
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: fmt.Sprintf("could not validate request: %+v", err.Error()),
+		})
+	}
+
+	userClaims := utils.ExtractClaimsFromRequest(c)
+	if userClaims == nil {
+		return c.JSON(
+			http.StatusUnauthorized,
+			ErrResponse{Message: "Unauthorized"},
+		)
+	}
+
+	resp, err := s.UserClient.ChangePassword(
+		c.Request().Context(),
+		&user_proto.ChangePasswordRequest{
+			Id:              &user_proto.UUID{Value: userClaims.Id},
+			CurrentPassword: req.CurrentPassword,
+			NewPassword:     req.NewPassword,
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
 }
 
 // DeleteUserResponse represents the response from the delete user operation
@@ -275,12 +517,11 @@ type DeleteUserResponse struct {
 
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Delete an existing user
+// @Description Delete another user's account. Admin only, use /users/me to delete your own account.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param user body User true "User ID to delete"
-// @Security ApiKeyAuth
 // @Success 202 {object} DeleteUserResponse "Successfully deleted user"
 // @Failure 400 {object} ErrResponse "Invalid input data"
 // @Failure 401 {object} ErrResponse "Unauthorized"
@@ -298,20 +539,18 @@ func (s *UserServer) DeleteUser(c echo.Context) error {
 	}
 
 	userClaims := utils.ExtractClaimsFromRequest(c)
-	if userClaims.Id != user.Id {
+	if userClaims == nil || !userClaims.Admin {
 		return c.JSON(
 			http.StatusUnauthorized,
-			ErrResponse{Message: "can't update this record"},
+			ErrResponse{Message: "can't delete this record"},
 		)
 	}
 	resp, err := s.UserClient.DeleteUser(
-		context.TODO(),
+		c.Request().Context(),
 		&user_proto.DeleteUserRequest{Id: &user_proto.UUID{Value: user.Id}},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrResponse{
-			Message: err.Error(),
-		})
+		return grpcError(c, err)
 	}
 	return c.JSON(http.StatusAccepted, resp)
 }
@@ -360,14 +599,14 @@ func (s *UserServer) GetUsers(c echo.Context) error {
 	req.Limit = n_limit
 
 	resp, err := s.UserClient.GetUsers(
-		context.TODO(),
+		c.Request().Context(),
 		&user_proto.GetUsersRequest{
 			Page:  int32(req.Page),
 			Limit: int32(req.Limit),
 		},
 	)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return grpcError(c, err)
 	}
 
 	var responses []GetUserResponse
@@ -392,3 +631,430 @@ func (s *UserServer) GetUsers(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, fResp)
 }
+
+// SearchUserResponse is a user as returned to admins searching users
+type SearchUserResponse struct {
+	GetUserResponse
+	CreatedAt string `json:"created_at" example:"2026-10-19T10:00:00Z"`
+}
+
+// SearchUsersResponse is a page of search results
+type SearchUsersResponse struct {
+	Users    []SearchUserResponse `json:"users"`
+	Page     int32                `json:"page"      example:"1"`
+	Limit    int32                `json:"limit"     example:"20"`
+	Total    int32                `json:"total"     example:"134"`
+	MaxPages int32                `json:"max_pages" example:"7"`
+}
+
+// SearchUsers godoc
+// @Summary Search users
+// @Description Find users by partial name, email or phone and filter by role or registration date. Admin only
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param q query string false "Partial name, email or phone"
+// @Param role query []string false "Roles to include" collectionFormat(multi)
+// @Param registered_from query string false "RFC3339 lower bound on registration date"
+// @Param registered_to query string false "RFC3339 upper bound on registration date"
+// @Param sort query string false "created_at, name or email"
+// @Param order query string false "asc or desc"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit, at most 100"
+// @Success 200 {object} SearchUsersResponse "Matching users"
+// @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/search [get]
+func (s *UserServer) SearchUsers(c echo.Context) error {
+	userClaims := utils.ExtractClaimsFromRequest(c)
+	if userClaims == nil || !userClaims.Admin {
+		return c.JSON(
+			http.StatusUnauthorized,
+			ErrResponse{Message: "not authorized to perform this action"},
+		)
+	}
+
+	req := &user_proto.SearchUsersRequest{
+		Query:          c.QueryParam("q"),
+		RegisteredFrom: c.QueryParam("registered_from"),
+		RegisteredTo:   c.QueryParam("registered_to"),
+		SortBy:         c.QueryParam("sort"),
+		Descending:     strings.EqualFold(c.QueryParam("order"), "desc"),
+	}
+
+	for _, name := range c.QueryParams()["role"] {
+		role, ok := user_proto.UserRoles_value[strings.ToUpper(name)]
+		if !ok {
+			return c.JSON(http.StatusBadRequest, ErrResponse{
+				Message: fmt.Sprintf("unknown role %q", name),
+			})
+		}
+		req.Roles = append(req.Roles, user_proto.UserRoles(role))
+	}
+
+	if page := c.QueryParam("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrResponse{Message: "invalid page"})
+		}
+		req.Page = int32(n)
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrResponse{Message: "invalid limit"})
+		}
+		req.Limit = int32(n)
+	}
+
+	resp, err := s.UserClient.SearchUsers(c.Request().Context(), req)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	users := []SearchUserResponse{}
+	for _, gUser := range resp.Users {
+		users = append(users, SearchUserResponse{
+			GetUserResponse: GetUserResponse{
+				Id:    gUser.Id.Value,
+				Name:  gUser.Name,
+				Email: gUser.Email,
+				Phone: gUser.Phone,
+				Role:  gUser.Role.String(),
+			},
+			CreatedAt: gUser.CreatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, SearchUsersResponse{
+		Users:    users,
+		Page:     resp.Page,
+		Limit:    resp.Limit,
+		Total:    resp.Total,
+		MaxPages: resp.MaxPages,
+	})
+}
+
+// Address represents a delivery address in the user's address book
+type Address struct {
+	Id        string  `json:"id"         example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	Label     string  `json:"label"      example:"Home"                                 validate:"required"`
+	County    string  `json:"county"     example:"Nairobi"                              validate:"required"`
+	Town      string  `json:"town"       example:"Westlands"                            validate:"required"`
+	Street    string  `json:"street"     example:"Waiyaki Way"`
+	Landmark  string  `json:"landmark"   example:"Opposite Sarit Centre"`
+	Latitude  float64 `json:"latitude"   example:"-1.2615"`
+	Longitude float64 `json:"longitude"  example:"36.8026"`
+	Phone     string  `json:"phone"      example:"+254722000000"                        validate:"required"`
+	IsDefault bool    `json:"is_default" example:"true"`
+}
+
+func convertAddress(address *user_proto.Address) Address {
+	return Address{
+		Id:        address.Id.Value,
+		Label:     address.Label,
+		County:    address.County,
+		Town:      address.Town,
+		Street:    address.Street,
+		Landmark:  address.Landmark,
+		Latitude:  address.Latitude,
+		Longitude: address.Longitude,
+		Phone:     address.Phone,
+		IsDefault: address.IsDefault,
+	}
+}
+
+func (a Address) toProto(userId string) *user_proto.Address {
+	return &user_proto.Address{
+		Id:        &user_proto.UUID{Value: a.Id},
+		UserId:    &user_proto.UUID{Value: userId},
+		Label:     a.Label,
+		County:    a.County,
+		Town:      a.Town,
+		Street:    a.Street,
+		Landmark:  a.Landmark,
+		Latitude:  a.Latitude,
+		Longitude: a.Longitude,
+		Phone:     a.Phone,
+		IsDefault: a.IsDefault,
+	}
+}
+
+// ListAddresses godoc
+// @Summary List the logged in user's addresses
+// @Description Get all the delivery addresses saved by the logged in user
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Success 200 {array} Address "Successfully retrieved addresses"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/me/addresses [get]
+func (s *UserServer) ListAddresses(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	resp, err := s.UserClient.ListAddresses(
+		c.Request().Context(),
+		&user_proto.ListAddressesRequest{
+			UserId: &user_proto.UUID{Value: claims.Id},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	addresses := []Address{}
+	for _, address := range resp.Addresses {
+		addresses = append(addresses, convertAddress(address))
+	}
+
+	return c.JSON(http.StatusOK, addresses)
+}
+
+// CreateAddress godoc
+// @Summary Add an address
+// @Description Save a new delivery address for the logged in user
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param address body Address true "Address to add"
+// @Success 201 {object} user_proto.AddAddressResponse "Successfully added address"
+// @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/me/addresses [post]
+func (s *UserServer) CreateAddress(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	var address Address
+	if err := c.Bind(&address); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "Invalid input",
+		})
+	}
+
+	if err := c.Validate(address); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: fmt.Sprintf("could not validate request: %+v", err.Error()),
+		})
+	}
+
+	resp, err := s.UserClient.AddAddress(
+		c.Request().Context(),
+		&user_proto.AddAddressRequest{Address: address.toProto(claims.Id)},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// GetAddress godoc
+// @Summary Get an address
+// @Description Get one of the logged in user's addresses
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "Address ID"
+// @Success 200 {object} Address "Successfully retrieved address"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 404 {object} HTTPError "Address not found"
+// @Security BearerAuth
+// @Router /users/me/addresses/{id} [get]
+func (s *UserServer) GetAddress(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	resp, err := s.UserClient.GetAddress(
+		c.Request().Context(),
+		&user_proto.GetAddressRequest{
+			Id:     &user_proto.UUID{Value: c.Param("id")},
+			UserId: &user_proto.UUID{Value: claims.Id},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, convertAddress(resp.Address))
+}
+
+// UpdateAddress godoc
+// @Summary Update an address
+// @Description Update one of the logged in user's addresses
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "Address ID"
+// @Param address body Address true "Updated address"
+// @Success 200 {object} user_proto.UpdateAddressResponse "Successfully updated address"
+// @Failure 400 {object} HTTPError "Invalid input data"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/me/addresses/{id} [patch]
+func (s *UserServer) UpdateAddress(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	var address Address
+	if err := c.Bind(&address); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: "Invalid input",
+		})
+	}
+	address.Id = c.Param("id")
+
+	if err := c.Validate(address); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrResponse{
+			Message: fmt.Sprintf("could not validate request: %+v", err.Error()),
+		})
+	}
+
+	resp, err := s.UserClient.UpdateAddress(
+		c.Request().Context(),
+		&user_proto.UpdateAddressRequest{Address: address.toProto(claims.Id)},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeleteAddress godoc
+// @Summary Delete an address
+// @Description Remove one of the logged in user's addresses
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "Address ID"
+// @Success 202 {object} user_proto.DeleteAddressResponse "Successfully deleted address"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/me/addresses/{id} [delete]
+func (s *UserServer) DeleteAddress(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	resp, err := s.UserClient.DeleteAddress(
+		c.Request().Context(),
+		&user_proto.DeleteAddressRequest{
+			Id:     &user_proto.UUID{Value: c.Param("id")},
+			UserId: &user_proto.UUID{Value: claims.Id},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+// DataExport describes a "download my data" job
+type DataExport struct {
+	Id          string `json:"id"                     example:"42cef6ad-1b39-4708-aa3f-a0c485f70db3"`
+	Status      string `json:"status"                 example:"pending"`
+	CreatedAt   string `json:"created_at"             example:"2026-10-19T10:00:00Z"`
+	CompletedAt string `json:"completed_at,omitempty" example:"2026-10-19T10:00:05Z"`
+	ExpiresAt   string `json:"expires_at"             example:"2026-10-26T10:00:00Z"`
+	Error       string `json:"error,omitempty"`
+}
+
+func convertDataExport(export *user_proto.DataExport) DataExport {
+	return DataExport{
+		Id:          export.Id.Value,
+		Status:      export.Status,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+		Error:       export.Error,
+	}
+}
+
+// RequestDataExport godoc
+// @Summary Request a copy of the logged in user's data
+// @Description Start a job that collects the user's profile, addresses, orders and reviews into a zip archive
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Success 202 {object} DataExport "Export started"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 500 {object} HTTPError "Internal server error"
+// @Security BearerAuth
+// @Router /users/me/exports [post]
+func (s *UserServer) RequestDataExport(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	resp, err := s.UserClient.RequestDataExport(
+		c.Request().Context(),
+		&user_proto.RequestDataExportRequest{UserId: &user_proto.UUID{Value: claims.Id}},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	return c.JSON(http.StatusAccepted, convertDataExport(resp.Export))
+}
+
+// GetDataExport godoc
+// @Summary Get a data export
+// @Description Returns the job status, or the zip archive once the export has completed
+// @Tags Users
+// @Accept json
+// @Produce json,application/zip
+// @Param id path string true "Export ID"
+// @Success 200 {object} DataExport "Export status"
+// @Failure 401 {object} HTTPError "Unauthorized"
+// @Failure 404 {object} HTTPError "Export not found"
+// @Security BearerAuth
+// @Router /users/me/exports/{id} [get]
+func (s *UserServer) GetDataExport(c echo.Context) error {
+	claims := utils.ExtractClaimsFromRequest(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, ErrResponse{Message: "Unauthorized"})
+	}
+
+	resp, err := s.UserClient.GetDataExport(
+		c.Request().Context(),
+		&user_proto.GetDataExportRequest{
+			Id:     &user_proto.UUID{Value: c.Param("id")},
+			UserId: &user_proto.UUID{Value: claims.Id},
+		},
+	)
+	if err != nil {
+		return grpcError(c, err)
+	}
+
+	export := resp.Export
+	if export.Status != "completed" || len(export.Archive) == 0 {
+		return c.JSON(http.StatusOK, convertDataExport(export))
+	}
+
+	c.Response().Header().Set(
+		echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=chemistke-data-%s.zip", export.Id.Value),
+	)
+	return c.Blob(http.StatusOK, "application/zip", export.Archive)
+}
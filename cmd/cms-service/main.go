@@ -1,33 +1,104 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"net"
+	"os"
 
 	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/files"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/metrics"
 	cmsservice "github.com/kelcheone/chemistke/internal/services/cms"
+	"github.com/kelcheone/chemistke/internal/tracing"
 	cms_proto "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	product_proto "github.com/kelcheone/chemistke/pkg/grpc/product"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
-	db, err := utils.GetDB()
+	logging.Init("cms-service")
+	if err := run(); err != nil {
+		logging.Fatal("cms-service stopped", "error", err)
+	}
+}
+
+func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	logging.SetLevel(cfg.Log.Level)
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		fmt.Print(cfg)
+		return nil
+	}
+	files.Configure(cfg.AWS)
+
+	db, err := utils.GetDB(cfg.Database)
 	if err != nil {
-		log.Panicf("errors connecting to the database: %v", err.Error())
+		return fmt.Errorf("errors connecting to the database: %w", err)
 	}
 
 	defer db.Close()
-	newCmsService := cmsservice.NewCmsService(db)
-	grpcServer := grpc.NewServer()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.RunMigrate(context.Background(), db, os.Args[2:], os.Stdout); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		return nil
+	}
+	// refuse to serve against a schema this build was not written for.
+	if err := database.CheckSchema(context.Background(), db); err != nil {
+		return fmt.Errorf("database schema is not current: %w", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), "cms-service", cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	newCmsService := cmsservice.NewCmsService(cmsservice.NewPostgresRepository(db))
+	if host := cfg.Services.Product.Host; host != "" {
+		productConn, err := grpc.NewClient(
+			host,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUnaryInterceptor(logging.UnaryClientInterceptor()),
+			tracing.DialOption(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to connect to the product service: %w", err)
+		}
+		defer productConn.Close()
+		newCmsService.Products = product_proto.NewProductServiceClient(productConn)
+	}
+	// flips scheduled posts live once their publish time passes.
+	go newCmsService.RunScheduler(context.Background(), cfg.Cms.SchedulerInterval)
+	metrics.RegisterDB(db, "cms")
+	metrics.Serve(cfg.Services.Cms.MetricsAddr)
+
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			logging.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor(),
+		),
+		tracing.ServerOption(),
+	)
 
 	cms_proto.RegisterCmsServiceServer(grpcServer, newCmsService)
 
-	lis, err := net.Listen("tcp", ":50051")
+	lis, err := net.Listen("tcp", cfg.Services.Cms.Addr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return fmt.Errorf("failed to listen: %w", err)
 	}
 
 	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+		return fmt.Errorf("failed to serve: %w", err)
 	}
+	return nil
 }
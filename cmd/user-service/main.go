@@ -1,33 +1,85 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"net"
+	"os"
 
 	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/metrics"
 	userservice "github.com/kelcheone/chemistke/internal/services/users"
+	"github.com/kelcheone/chemistke/internal/tracing"
 	user_proto "github.com/kelcheone/chemistke/pkg/grpc/user"
 	"google.golang.org/grpc"
 )
 
 func main() {
-	db, err := utils.GetDB()
+	logging.Init("user-service")
+	if err := run(); err != nil {
+		logging.Fatal("user-service stopped", "error", err)
+	}
+}
+
+func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	logging.SetLevel(cfg.Log.Level)
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		fmt.Print(cfg)
+		return nil
+	}
+
+	db, err := utils.GetDB(cfg.Database)
 	if err != nil {
-		log.Panicf("errors connecting to the database: %v", err.Error())
+		return fmt.Errorf("errors connecting to the database: %w", err)
 	}
 
 	defer db.Close()
-	newUserService := userservice.NewService(db)
-	grpcServer := grpc.NewServer()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.RunMigrate(context.Background(), db, os.Args[2:], os.Stdout); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		return nil
+	}
+	// refuse to serve against a schema this build was not written for.
+	if err := database.CheckSchema(context.Background(), db); err != nil {
+		return fmt.Errorf("database schema is not current: %w", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), "user-service", cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	newUserService := userservice.NewService(userservice.NewPostgresRepository(db), cfg.Password)
+	metrics.RegisterDB(db, "user")
+	metrics.Serve(cfg.Services.User.MetricsAddr)
+
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			logging.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor(),
+		),
+		tracing.ServerOption(),
+	)
 
 	user_proto.RegisterUserServiceServer(grpcServer, newUserService)
 
-	lis, err := net.Listen("tcp", ":50052")
+	lis, err := net.Listen("tcp", cfg.Services.User.Addr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return fmt.Errorf("failed to listen: %w", err)
 	}
 
 	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+		return fmt.Errorf("failed to serve: %w", err)
 	}
+	return nil
 }
@@ -2,24 +2,19 @@ package utils
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 )
 
 var secretKey []byte
 
-func init() {
-	godotenv.Load()
-	secretKey = []byte(os.Getenv("JWT_SECRET_KEY"))
-
-	if len(secretKey) == 0 {
-		panic("JWT_SECRET_KEY environment variable is not set")
-	}
+// SetSecretKey sets the key tokens are signed and verified with. It has to
+// be called before AuthMiddleware.
+func SetSecretKey(key string) {
+	secretKey = []byte(key)
 }
 
 type jwtCustomClaims struct {
@@ -148,3 +143,23 @@ func AuthMiddleware() echo.MiddlewareFunc {
 
 	return echojwt.WithConfig(config)
 }
+
+// OptionalAuthMiddleware reads the token like AuthMiddleware when one is
+// sent, so ExtractClaimsFromRequest works, but lets anonymous requests and
+// requests with a bad token through without claims.
+func OptionalAuthMiddleware() echo.MiddlewareFunc {
+	config := echojwt.Config{
+		TokenLookup: "cookie:token,header:Authorization:Bearer ",
+		NewClaimsFunc: func(c echo.Context) jwt.Claims {
+			return new(jwtCustomClaims)
+		},
+		ErrorHandler: func(c echo.Context, err error) error {
+			return nil
+		},
+		ContinueOnIgnoredError: true,
+
+		SigningKey: secretKey,
+	}
+
+	return echojwt.WithConfig(config)
+}
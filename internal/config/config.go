@@ -0,0 +1,434 @@
+// Package config loads the settings every binary runs with. Defaults are
+// overridden by an optional YAML file named by CONFIG_FILE, which is in turn
+// overridden by environment variables, including any set in a .env file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Database DatabaseConfig `yaml:"database"`
+	Gateway  GatewayConfig  `yaml:"gateway"`
+	Services ServicesConfig `yaml:"services"`
+	Cms      CmsConfig      `yaml:"cms"`
+	AWS      AWSConfig      `yaml:"aws"`
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Password PasswordConfig `yaml:"password"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+type GatewayConfig struct {
+	Addr      string `yaml:"addr"`
+	JWTSecret string `yaml:"jwt_secret"`
+	// AllowOrigins are the origins CORS lets call the API with credentials.
+	AllowOrigins []string `yaml:"allow_origins"`
+	// RequestTimeout bounds every API request. Handlers pass the request
+	// context to the services, so the deadline reaches their SQL queries too.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// SiteURL is the storefront that feed and sitemap links point at.
+	SiteURL string `yaml:"site_url"`
+}
+
+// ServiceConfig is where a gRPC service listens, where clients reach it and
+// where it serves its metrics.
+type ServiceConfig struct {
+	Addr        string `yaml:"addr"`
+	Host        string `yaml:"host"`
+	MetricsAddr string `yaml:"metrics_addr"`
+}
+
+type ServicesConfig struct {
+	// Monolith runs every service on one listener.
+	Monolith ServiceConfig `yaml:"monolith"`
+	Cms      ServiceConfig `yaml:"cms"`
+	User     ServiceConfig `yaml:"user"`
+	Product  ServiceConfig `yaml:"product"`
+	Order    ServiceConfig `yaml:"order"`
+}
+
+type CmsConfig struct {
+	// SchedulerInterval is how often scheduled posts are checked and
+	// published.
+	SchedulerInterval time.Duration `yaml:"scheduler_interval"`
+}
+
+type AWSConfig struct {
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level"`
+}
+
+type TracingConfig struct {
+	// Exporter is otlp, stdout, file or none. otlp reads its endpoint from
+	// the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter string `yaml:"exporter"`
+	// File is where the file exporter appends spans.
+	File string `yaml:"file"`
+}
+
+// PasswordConfig is the policy new passwords are checked against.
+type PasswordConfig struct {
+	MinLength     int  `yaml:"min_length"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+}
+
+// Default returns the settings used for anything that is not configured.
+// Service hosts are left empty, the binaries that dial them require them.
+func Default() Config {
+	return Config{
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    "5432",
+			SSLMode: "disable",
+		},
+		Gateway: GatewayConfig{
+			Addr:           ":9090",
+			AllowOrigins:   []string{"http://localhost:3000"},
+			RequestTimeout: 10 * time.Second,
+		},
+		Services: ServicesConfig{
+			Monolith: ServiceConfig{Addr: ":8090", MetricsAddr: ":9100"},
+			Cms:      ServiceConfig{Addr: ":50051", MetricsAddr: ":9101"},
+			User:     ServiceConfig{Addr: ":50052", MetricsAddr: ":9102"},
+			Product:  ServiceConfig{Addr: ":50053", MetricsAddr: ":9103"},
+			Order:    ServiceConfig{Addr: ":50054", MetricsAddr: ":9104"},
+		},
+		Cms: CmsConfig{
+			SchedulerInterval: time.Minute,
+		},
+		AWS: AWSConfig{
+			Region: "af-south-1",
+			Bucket: "chemistke",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Tracing: TracingConfig{
+			Exporter: "none",
+		},
+		Password: PasswordConfig{
+			MinLength:    8,
+			RequireLower: true,
+			RequireDigit: true,
+		},
+	}
+}
+
+// Load reads the configuration and validates it. A missing .env file is not
+// an error, a missing CONFIG_FILE is.
+func Load() (Config, error) {
+	_ = godotenv.Load()
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	// catch typos rather than silently running on defaults.
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("could not read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	setString(&c.Database.Host, "DB_HOST")
+	setString(&c.Database.Port, "DB_PORT")
+	setString(&c.Database.User, "DB_USER")
+	setString(&c.Database.Password, "DB_PASSWORD")
+	setString(&c.Database.Name, "DB_NAME")
+	setString(&c.Database.SSLMode, "DB_SSLMODE")
+
+	setString(&c.Gateway.Addr, "GATEWAY_ADDR")
+	setString(&c.Gateway.JWTSecret, "JWT_SECRET_KEY")
+	setList(&c.Gateway.AllowOrigins, "CORS_ALLOW_ORIGINS")
+	setString(&c.Gateway.SiteURL, "SITE_URL")
+
+	services := []struct {
+		svc    *ServiceConfig
+		prefix string
+	}{
+		{&c.Services.Monolith, "MONOLITH"},
+		{&c.Services.Cms, "CMS"},
+		{&c.Services.User, "USER"},
+		{&c.Services.Product, "PRODUCT"},
+		{&c.Services.Order, "ORDER"},
+	}
+	for _, s := range services {
+		setString(&s.svc.Addr, s.prefix+"_SERVICE_ADDR")
+		setString(&s.svc.Host, s.prefix+"_SERVICE_HOST")
+		setString(&s.svc.MetricsAddr, s.prefix+"_METRICS_ADDR")
+	}
+
+	setString(&c.AWS.Region, "AWS_REGION")
+	setString(&c.AWS.Bucket, "AWS_BUCKET")
+	setString(&c.AWS.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	setString(&c.AWS.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+
+	setString(&c.Log.Level, "LOG_LEVEL")
+	setString(&c.Tracing.Exporter, "OTEL_TRACES_EXPORTER")
+	setString(&c.Tracing.File, "OTEL_TRACES_FILE")
+
+	return errors.Join(
+		setDuration(&c.Gateway.RequestTimeout, "REQUEST_TIMEOUT"),
+		setDuration(&c.Cms.SchedulerInterval, "CMS_SCHEDULER_INTERVAL"),
+		setInt(&c.Password.MinLength, "PASSWORD_MIN_LENGTH"),
+		setBool(&c.Password.RequireUpper, "PASSWORD_REQUIRE_UPPER"),
+		setBool(&c.Password.RequireLower, "PASSWORD_REQUIRE_LOWER"),
+		setBool(&c.Password.RequireDigit, "PASSWORD_REQUIRE_DIGIT"),
+		setBool(&c.Password.RequireSymbol, "PASSWORD_REQUIRE_SYMBOL"),
+	)
+}
+
+// setters leave the value alone when the variable is unset or empty, so an
+// empty line in .env does not wipe out a default.
+func setString(v *string, key string) {
+	if s := os.Getenv(key); s != "" {
+		*v = s
+	}
+}
+
+func setList(v *[]string, key string) {
+	s := os.Getenv(key)
+	if s == "" {
+		return
+	}
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*v = list
+}
+
+func setDuration(v *time.Duration, key string) error {
+	s := os.Getenv(key)
+	if s == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	*v = d
+	return nil
+}
+
+func setInt(v *int, key string) error {
+	s := os.Getenv(key)
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	*v = n
+	return nil
+}
+
+func setBool(v *bool, key string) error {
+	s := os.Getenv(key)
+	if s == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	*v = b
+	return nil
+}
+
+// Validate checks the settings every binary shares. Settings only some
+// binaries need are checked by DatabaseConfig.Validate and ValidateGateway.
+func (c Config) Validate() error {
+	var errs []error
+
+	addrs := []setting{
+		{"gateway.addr", c.Gateway.Addr},
+		{"services.monolith.addr", c.Services.Monolith.Addr},
+		{"services.monolith.metrics_addr", c.Services.Monolith.MetricsAddr},
+		{"services.cms.addr", c.Services.Cms.Addr},
+		{"services.cms.metrics_addr", c.Services.Cms.MetricsAddr},
+		{"services.user.addr", c.Services.User.Addr},
+		{"services.user.metrics_addr", c.Services.User.MetricsAddr},
+		{"services.product.addr", c.Services.Product.Addr},
+		{"services.product.metrics_addr", c.Services.Product.MetricsAddr},
+		{"services.order.addr", c.Services.Order.Addr},
+		{"services.order.metrics_addr", c.Services.Order.MetricsAddr},
+	}
+	for _, addr := range addrs {
+		if _, _, err := net.SplitHostPort(addr.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", addr.name, err))
+		}
+	}
+
+	if c.Gateway.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("gateway.request_timeout must be positive"))
+	}
+	if c.Cms.SchedulerInterval <= 0 {
+		errs = append(errs, errors.New("cms.scheduler_interval must be positive"))
+	}
+	for _, origin := range c.Gateway.AllowOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("gateway.allow_origins: %q is not an origin", origin))
+		}
+	}
+	if c.Gateway.SiteURL != "" {
+		if u, err := url.Parse(c.Gateway.SiteURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("gateway.site_url: %q is not a url", c.Gateway.SiteURL))
+		}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %q is not debug, info, warn or error", c.Log.Level))
+	}
+	switch c.Tracing.Exporter {
+	case "", "none", "otlp", "stdout":
+	case "file":
+		if c.Tracing.File == "" {
+			errs = append(errs, errors.New("tracing.file is required for the file exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: %q is not otlp, stdout, file or none", c.Tracing.Exporter))
+	}
+	if c.Password.MinLength <= 0 {
+		errs = append(errs, errors.New("password.min_length must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that there is enough to connect to the database.
+func (d DatabaseConfig) Validate() error {
+	return required(
+		setting{"database.host", d.Host},
+		setting{"database.port", d.Port},
+		setting{"database.user", d.User},
+		setting{"database.name", d.Name},
+	)
+}
+
+// DSN is the connection URL for the database. Building it as a URL escapes
+// whatever the password or name contain.
+func (d DatabaseConfig) DSN() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, d.Password),
+		Host:   net.JoinHostPort(d.Host, d.Port),
+		Path:   "/" + d.Name,
+	}
+	if d.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {d.SSLMode}}.Encode()
+	}
+	return u.String()
+}
+
+// ValidateGateway checks that the gateway can sign tokens and reach every
+// service.
+func (c Config) ValidateGateway() error {
+	return required(
+		setting{"gateway.jwt_secret", c.Gateway.JWTSecret},
+		setting{"services.cms.host", c.Services.Cms.Host},
+		setting{"services.user.host", c.Services.User.Host},
+		setting{"services.product.host", c.Services.Product.Host},
+		setting{"services.order.host", c.Services.Order.Host},
+	)
+}
+
+// setting is a value named the way it is in CONFIG_FILE, for errors.
+type setting struct {
+	name  string
+	value string
+}
+
+func required(settings ...setting) error {
+	var errs []error
+	for _, s := range settings {
+		if s.value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", s.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy with secrets masked, safe to print or log.
+func (c Config) Redacted() Config {
+	mask := func(s *string) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+	c.Gateway.AllowOrigins = append([]string(nil), c.Gateway.AllowOrigins...)
+	mask(&c.Database.Password)
+	mask(&c.Gateway.JWTSecret)
+	mask(&c.AWS.AccessKeyID)
+	mask(&c.AWS.SecretAccessKey)
+	return c
+}
+
+// String is the redacted configuration as YAML, in the CONFIG_FILE format.
+func (c Config) String() string {
+	b, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("could not print config: %v", err)
+	}
+	return string(b)
+}
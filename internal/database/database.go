@@ -3,15 +3,20 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DB only offers context-aware calls, so a cancelled request or an expired
+// deadline stops the query it was waiting on.
 type DB interface {
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	Exec(query string, args ...interface{}) (sql.Result, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
-	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Querier
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error
 	Close() error
 }
 
@@ -24,7 +29,17 @@ func NewRawDB(db *sql.DB) DB {
 }
 
 func NewDatabase(driverName, dataSourceName string) (DB, error) {
-	db, err := sql.Open(driverName, dataSourceName)
+	db, err := otelsql.Open(
+		driverName,
+		dataSourceName,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			SpanFilter:           inTrace,
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -36,3 +51,10 @@ func NewDatabase(driverName, dataSourceName string) (DB, error) {
 
 	return &Database{db}, nil
 }
+
+// inTrace only traces queries made on behalf of a traced request, so
+// background jobs and calls without a context do not start traces of their
+// own.
+func inTrace(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
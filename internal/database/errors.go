@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Errors repositories return in place of driver errors, so a service can
+// react to them the same way whichever store it runs on.
+var (
+	// ErrNotFound means the row asked for does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means a write would duplicate a unique value.
+	ErrConflict = errors.New("already exists")
+	// ErrForeignKey means a write points at a row that does not exist, or a
+	// delete would leave other rows pointing at nothing.
+	ErrForeignKey = errors.New("foreign key violation")
+	// ErrInvalid means a value cannot be stored in its column, such as an id
+	// that is not a uuid.
+	ErrInvalid = errors.New("invalid value")
+)
+
+// Translate turns the driver errors above stand in for into them. The
+// driver error stays in the chain, so WithTx still sees what aborted a
+// transaction.
+func Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505": // unique_violation
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		case "23503": // foreign_key_violation
+			return fmt.Errorf("%w: %w", ErrForeignKey, err)
+		case "22P02": // invalid_text_representation
+			return fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database/migrations"
+	"github.com/pressly/goose/v3"
+)
+
+// ErrSchemaVersion means the database is not at the version of the
+// migrations the binary was built with.
+var ErrSchemaVersion = errors.New("schema version mismatch")
+
+// Migrations returns a goose provider for the embedded migrations. It
+// shares goose's version table with the goose CLI, so either can be used.
+func Migrations(db DB) (*goose.Provider, error) {
+	d, ok := db.(*Database)
+	if !ok {
+		return nil, errors.New("migrations need a database/sql connection")
+	}
+	return goose.NewProvider(goose.DialectPostgres, d.DB, migrations.FS)
+}
+
+// CheckSchema fails with ErrSchemaVersion unless every embedded migration,
+// and nothing newer, has been applied.
+func CheckSchema(ctx context.Context, db DB) error {
+	provider, err := Migrations(db)
+	if err != nil {
+		return err
+	}
+
+	current, target, err := provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read the schema version: %w", err)
+	}
+	switch {
+	case current < target:
+		return fmt.Errorf(
+			"%w: database is at %d, want %d, run `migrate up`",
+			ErrSchemaVersion,
+			current,
+			target,
+		)
+	case current > target:
+		// a newer release migrated the database, this binary is out of date.
+		return fmt.Errorf(
+			"%w: database is at %d, newer than this build's %d",
+			ErrSchemaVersion,
+			current,
+			target,
+		)
+	}
+	return nil
+}
+
+// RunMigrate runs the migrate subcommand: up applies every pending
+// migration, down rolls back the latest one and status lists them all.
+func RunMigrate(ctx context.Context, db DB, args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: migrate up|down|status")
+	}
+
+	provider, err := Migrations(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := provider.Up(ctx)
+		for _, r := range results {
+			fmt.Fprintln(w, r)
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(w, "no migrations to apply")
+		}
+	case "down":
+		result, err := provider.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, result)
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.State == goose.StateApplied {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%-25s %s\n", applied, s.Source.Path)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, want up, down or status", args[0])
+	}
+	return nil
+}
@@ -18,4 +18,4 @@ CREATE TABLE productImages (
 -- +goose StatementBegin
 SELECT 'down SQL query';
 -- +goose StatementEnd
-DROP TABLE productImages
+DROP TABLE productImages;
@@ -22,3 +22,12 @@ SELECT
     'down SQL query';
 
 -- +goose StatementEnd
+ALTER TABLE product_sub_category
+DROP CONSTRAINT unique_sub_category_slug,
+ALTER COLUMN slug
+DROP NOT NULL;
+
+ALTER TABLE product_category
+DROP CONSTRAINT unique_category_slug,
+ALTER COLUMN slug
+DROP NOT NULL;
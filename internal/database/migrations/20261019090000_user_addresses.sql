@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT
+    'up SQL query';
+
+-- +goose StatementEnd
+CREATE TABLE user_addresses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+    user_id UUID NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    county VARCHAR(255) NOT NULL,
+    town VARCHAR(255) NOT NULL,
+    street VARCHAR(255) NOT NULL DEFAULT '',
+    landmark VARCHAR(255) NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    phone VARCHAR(255) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP
+    WITH
+        TIME ZONE NOT NULL DEFAULT NOW (),
+        updated_at TIMESTAMP
+    WITH
+        TIME ZONE NOT NULL DEFAULT NOW (),
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX user_addresses_user_id_index ON user_addresses (user_id);
+
+-- a user can only have one default address
+CREATE UNIQUE INDEX user_addresses_default_index ON user_addresses (user_id)
+WHERE
+    is_default;
+
+-- the address is copied onto the order so later edits don't rewrite history
+ALTER TABLE orders
+ADD COLUMN delivery_address JSONB;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT
+    'down SQL query';
+
+-- +goose StatementEnd
+ALTER TABLE orders
+DROP COLUMN IF EXISTS delivery_address;
+
+DROP TABLE user_addresses;
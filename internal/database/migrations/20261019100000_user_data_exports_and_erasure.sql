@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT
+    'up SQL query';
+
+-- +goose StatementEnd
+-- set when a user's PII has been anonymized on request
+ALTER TABLE users
+ADD COLUMN erased_at TIMESTAMP
+WITH
+    TIME ZONE;
+
+CREATE TABLE user_data_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+    user_id UUID NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    archive BYTEA,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP
+    WITH
+        TIME ZONE NOT NULL DEFAULT NOW (),
+        completed_at TIMESTAMP
+    WITH
+        TIME ZONE,
+        expires_at TIMESTAMP
+    WITH
+        TIME ZONE NOT NULL DEFAULT NOW () + INTERVAL '7 days',
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX user_data_exports_user_id_index ON user_data_exports (user_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT
+    'down SQL query';
+
+-- +goose StatementEnd
+DROP TABLE user_data_exports;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS erased_at;
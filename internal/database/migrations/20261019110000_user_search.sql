@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMPTZ;
+
+CREATE INDEX users_created_at_index ON users(created_at, id);
+CREATE INDEX users_role_index ON users(role);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP INDEX users_role_index;
+DROP INDEX users_created_at_index;
+ALTER TABLE users DROP COLUMN verified_at;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+UPDATE content SET status = LOWER(status);
+UPDATE content SET status = 'draft'
+WHERE status NOT IN ('draft', 'in_review', 'scheduled', 'published', 'archived');
+
+ALTER TABLE content
+    ALTER COLUMN status SET DEFAULT 'draft',
+    ADD CONSTRAINT content_status_check
+        CHECK (status IN ('draft', 'in_review', 'scheduled', 'published', 'archived')),
+    ALTER COLUMN published_date DROP NOT NULL,
+    ADD COLUMN publish_at TIMESTAMPTZ,
+    ADD COLUMN reviewed_by UUID REFERENCES users(id),
+    ADD COLUMN reviewed_at TIMESTAMPTZ,
+    ADD COLUMN review_note TEXT NOT NULL DEFAULT '';
+
+-- only posts that have actually gone live keep a published date.
+UPDATE content SET published_date = NULL WHERE status NOT IN ('published', 'archived');
+
+CREATE INDEX content_status_published_date_index ON content(status, published_date DESC);
+CREATE INDEX content_scheduled_index ON content(publish_at) WHERE status = 'scheduled';
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP INDEX content_scheduled_index;
+DROP INDEX content_status_published_date_index;
+UPDATE content SET published_date = updated_date WHERE published_date IS NULL;
+ALTER TABLE content
+    DROP COLUMN review_note,
+    DROP COLUMN reviewed_at,
+    DROP COLUMN reviewed_by,
+    DROP COLUMN publish_at,
+    ALTER COLUMN published_date SET NOT NULL,
+    DROP CONSTRAINT content_status_check,
+    ALTER COLUMN status DROP DEFAULT;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE post_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES content(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    editor_id UUID REFERENCES users(id),
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    cover_image VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (post_id, revision)
+);
+
+-- existing posts start their history at their current text.
+INSERT INTO post_revisions (post_id, revision, editor_id, title, description, slug, cover_image, content, created_at)
+SELECT c.id, 1, a.user_id, c.title, c.description, c.slug, c.cover_image, c.content, c.updated_date
+FROM content c LEFT JOIN authors a ON a.id = c.author_id;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE post_revisions;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+-- content keeps the markdown source, the rest is derived from it when the
+-- post is saved. Posts saved before this are rendered when first read.
+ALTER TABLE content
+    ADD COLUMN content_html TEXT NOT NULL DEFAULT '',
+    ADD COLUMN toc JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN word_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN reading_time_minutes INT NOT NULL DEFAULT 0;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+ALTER TABLE content
+    DROP COLUMN reading_time_minutes,
+    DROP COLUMN word_count,
+    DROP COLUMN toc,
+    DROP COLUMN content_html;
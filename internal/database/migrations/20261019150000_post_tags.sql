@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE post_tags (
+    post_id UUID NOT NULL REFERENCES content(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX post_tags_tag_id_index ON post_tags(tag_id);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION set_tag_slug()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.slug IS NULL OR TRIM(NEW.slug) = '' THEN
+    NEW.slug := LOWER(REGEXP_REPLACE(TRIM(NEW.name), '\s+', '-', 'g'));
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER create_tag_slug
+BEFORE INSERT OR UPDATE ON tags
+FOR EACH ROW
+EXECUTE FUNCTION set_tag_slug();
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TRIGGER IF EXISTS create_tag_slug ON tags;
+DROP FUNCTION IF EXISTS set_tag_slug();
+DROP TABLE post_tags;
+DROP TABLE tags;
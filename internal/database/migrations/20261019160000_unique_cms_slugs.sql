@@ -0,0 +1,119 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+ALTER TABLE authors ADD COLUMN slug VARCHAR(255) NOT NULL DEFAULT '';
+
+-- when a slug changes the old one keeps working by pointing at the row.
+CREATE TABLE slug_redirects (
+    entity VARCHAR(32) NOT NULL,
+    old_slug VARCHAR(255) NOT NULL,
+    target_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (entity, old_slug)
+);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION slugify(value TEXT)
+RETURNS TEXT AS $$
+    SELECT TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(TRIM(COALESCE(value, ''))), '[^a-z0-9]+', '-', 'g'));
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- set_unique_slug fills in the slug from the column named by TG_ARGV[0]
+-- (authors use their user's name), regenerates it when that column changes
+-- and the slug was left alone, suffixes -2, -3... until it is unique and
+-- records a redirect from the previous slug.
+CREATE OR REPLACE FUNCTION set_unique_slug()
+RETURNS TRIGGER AS $$
+DECLARE
+    source TEXT;
+    old_source TEXT;
+    base TEXT;
+    candidate TEXT;
+    n INT := 1;
+    taken BOOLEAN;
+BEGIN
+    IF TG_TABLE_NAME = 'authors' THEN
+        SELECT name INTO source FROM users WHERE id = NEW.user_id;
+        old_source := source;
+    ELSE
+        EXECUTE format('SELECT ($1).%I', TG_ARGV[0]) INTO source USING NEW;
+        IF TG_OP = 'UPDATE' THEN
+            EXECUTE format('SELECT ($1).%I', TG_ARGV[0]) INTO old_source USING OLD;
+        END IF;
+    END IF;
+
+    IF NEW.slug IS NULL OR TRIM(NEW.slug) = '' THEN
+        base := slugify(source);
+    ELSIF TG_OP = 'UPDATE' AND NEW.slug = OLD.slug AND source IS DISTINCT FROM old_source THEN
+        base := slugify(source);
+    ELSE
+        base := slugify(NEW.slug);
+    END IF;
+
+    IF base = '' THEN
+        base := TG_ARGV[1];
+    END IF;
+
+    candidate := base;
+    LOOP
+        EXECUTE format('SELECT EXISTS (SELECT 1 FROM %I WHERE slug = $1 AND id <> $2)', TG_TABLE_NAME)
+            INTO taken USING candidate, NEW.id;
+        EXIT WHEN NOT taken;
+        n := n + 1;
+        candidate := base || '-' || n;
+    END LOOP;
+    NEW.slug := candidate;
+
+    IF TG_OP = 'UPDATE' AND OLD.slug <> '' AND OLD.slug <> NEW.slug THEN
+        INSERT INTO slug_redirects (entity, old_slug, target_id)
+        VALUES (TG_TABLE_NAME, OLD.slug, NEW.id)
+        ON CONFLICT (entity, old_slug)
+        DO UPDATE SET target_id = EXCLUDED.target_id, created_at = NOW();
+    END IF;
+    -- a slug that is in use again no longer redirects.
+    DELETE FROM slug_redirects WHERE entity = TG_TABLE_NAME AND old_slug = NEW.slug;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER content_slug_trigger
+BEFORE INSERT OR UPDATE ON content
+FOR EACH ROW EXECUTE FUNCTION set_unique_slug('title', 'post');
+
+CREATE TRIGGER categories_slug_trigger
+BEFORE INSERT OR UPDATE ON categories
+FOR EACH ROW EXECUTE FUNCTION set_unique_slug('name', 'category');
+
+CREATE TRIGGER authors_slug_trigger
+BEFORE INSERT OR UPDATE ON authors
+FOR EACH ROW EXECUTE FUNCTION set_unique_slug('slug', 'author');
+
+-- touching every row runs the triggers, which gives authors a slug and
+-- suffixes any duplicates that already exist.
+UPDATE authors SET slug = slug;
+UPDATE categories SET slug = slug;
+UPDATE content SET slug = slug;
+
+CREATE UNIQUE INDEX content_slug_unique ON content(slug);
+CREATE UNIQUE INDEX categories_slug_unique ON categories(slug);
+CREATE UNIQUE INDEX authors_slug_unique ON authors(slug);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP INDEX authors_slug_unique;
+DROP INDEX categories_slug_unique;
+DROP INDEX content_slug_unique;
+DROP TRIGGER IF EXISTS authors_slug_trigger ON authors;
+DROP TRIGGER IF EXISTS categories_slug_trigger ON categories;
+DROP TRIGGER IF EXISTS content_slug_trigger ON content;
+DROP FUNCTION IF EXISTS set_unique_slug();
+DROP FUNCTION IF EXISTS slugify(TEXT);
+DROP TABLE slug_redirects;
+ALTER TABLE authors DROP COLUMN slug;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+ALTER TABLE product_sub_category
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- +goose StatementBegin
+-- sitemaps use updated_at as lastmod, so keep it current on every update.
+CREATE OR REPLACE FUNCTION touch_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at := NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER products_touch_updated_at
+BEFORE UPDATE ON products
+FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+
+CREATE TRIGGER product_category_touch_updated_at
+BEFORE UPDATE ON product_category
+FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+
+CREATE TRIGGER product_sub_category_touch_updated_at
+BEFORE UPDATE ON product_sub_category
+FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TRIGGER IF EXISTS product_sub_category_touch_updated_at ON product_sub_category;
+DROP TRIGGER IF EXISTS product_category_touch_updated_at ON product_category;
+DROP TRIGGER IF EXISTS products_touch_updated_at ON products;
+DROP FUNCTION IF EXISTS touch_updated_at();
+ALTER TABLE product_sub_category DROP COLUMN updated_at;
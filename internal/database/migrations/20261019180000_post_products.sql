@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+-- products live in the product service, so product_id is not a foreign key.
+CREATE TABLE post_products (
+    post_id UUID NOT NULL REFERENCES content(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL,
+    source VARCHAR(16) NOT NULL CHECK (source IN ('explicit', 'shortcode')),
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (post_id, product_id)
+);
+
+CREATE INDEX post_products_product_id_index ON post_products(product_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE post_products;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE post_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id UUID NOT NULL REFERENCES content(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES post_comments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'spam')),
+    spam_score INT NOT NULL DEFAULT 0,
+    moderated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    moderated_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX post_comments_post_id_index ON post_comments(post_id, status, created_at);
+CREATE INDEX post_comments_parent_id_index ON post_comments(parent_id);
+-- rate limiting looks at a user's most recent comments.
+CREATE INDEX post_comments_user_id_index ON post_comments(user_id, created_at DESC);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE post_comments;
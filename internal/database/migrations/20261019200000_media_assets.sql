@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+-- posts and authors keep pointing at assets by url, so usage is worked out
+-- from content.cover_image, content.content and authors.avatar.
+CREATE TABLE media_assets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    storage_key VARCHAR(512) NOT NULL UNIQUE,
+    url VARCHAR(255) NOT NULL UNIQUE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    alt_text VARCHAR(255) NOT NULL DEFAULT '',
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX media_assets_created_at_index ON media_assets(created_at DESC);
+CREATE INDEX content_cover_image_index ON content(cover_image);
+CREATE INDEX authors_avatar_index ON authors(avatar);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP INDEX authors_avatar_index;
+DROP INDEX content_cover_image_index;
+DROP TABLE media_assets;
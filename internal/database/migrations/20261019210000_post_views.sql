@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+-- viewer is a hash of the reader's session or ip, a view is only counted
+-- again once the dedup window since the last counted view has passed.
+CREATE TABLE post_view_sessions (
+    post_id UUID NOT NULL REFERENCES content(id) ON DELETE CASCADE,
+    viewer VARCHAR(64) NOT NULL,
+    last_counted TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, viewer)
+);
+
+CREATE INDEX post_view_sessions_last_counted_index ON post_view_sessions(last_counted);
+
+-- days are UTC.
+CREATE TABLE post_daily_views (
+    post_id UUID NOT NULL REFERENCES content(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    views INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (post_id, day)
+);
+
+CREATE INDEX post_daily_views_day_index ON post_daily_views(day);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE post_daily_views;
+DROP TABLE post_view_sessions;
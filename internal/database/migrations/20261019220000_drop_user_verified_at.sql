@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+-- nothing ever verified a user, so the column was always NULL.
+ALTER TABLE users DROP COLUMN verified_at;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+ALTER TABLE users ADD COLUMN verified_at TIMESTAMPTZ;
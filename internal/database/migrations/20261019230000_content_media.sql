@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+-- the media a post uses as its cover or in its body, recorded when the post
+-- is saved so usage lookups don't search every post. The foreign key stops
+-- an asset being deleted while a post saved in the meantime uses it.
+CREATE TABLE content_media (
+    content_id UUID NOT NULL REFERENCES content(id) ON DELETE CASCADE,
+    media_id UUID NOT NULL REFERENCES media_assets(id),
+    PRIMARY KEY (content_id, media_id)
+);
+
+CREATE INDEX content_media_media_id_index ON content_media(media_id);
+
+INSERT INTO content_media (content_id, media_id)
+SELECT c.id, m.id FROM content c
+JOIN media_assets m ON c.cover_image = m.url OR strpos(c.content, m.url) > 0;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE content_media;
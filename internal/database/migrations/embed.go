@@ -0,0 +1,8 @@
+// Package migrations embeds the goose migrations so every binary carries
+// the schema it was built against.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
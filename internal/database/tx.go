@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/kelcheone/chemistke/pkg/codes"
+	"github.com/kelcheone/chemistke/pkg/status"
+	"github.com/lib/pq"
+)
+
+// maxTxAttempts is how many times WithTx runs a transaction that Postgres
+// keeps aborting with a serialization failure or deadlock.
+const maxTxAttempts = 3
+
+// Querier runs statements on either the database or a transaction, so a
+// helper can be shared by plain calls and calls inside WithTx.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// WithTx runs fn in a transaction, committing when it returns nil and
+// rolling back otherwise. fn is run again when the transaction fails with a
+// serialization failure or deadlock, so it must not have effects outside tx.
+// Errors from fn are returned as they are, failing to begin or commit comes
+// back as an Internal status.
+//
+// opts picks the isolation level, nil is Postgres' READ COMMITTED. There a
+// write to a row another transaction changed waits and then goes ahead, so
+// only deadlocks are retried. At REPEATABLE READ or SERIALIZABLE it fails
+// with a serialization failure instead and fn is run again on fresh data.
+// A transaction that still conflicts on its last attempt fails with an
+// Aborted status, so the caller knows to try again later.
+func (d *Database) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = d.runTx(ctx, opts, fn)
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if attempt == maxTxAttempts {
+			return status.Errorf(codes.Aborted, "transaction kept conflicting with others: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * 20 * time.Millisecond):
+		}
+	}
+	return err
+}
+
+func (d *Database) runTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := d.BeginTx(ctx, opts)
+	if err != nil {
+		return status.Errorf(codes.Internal, "could not begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return status.Errorf(codes.Internal, "could not commit transaction: %w", err)
+	}
+	return nil
+}
+
+// retryable reports whether err aborted the transaction for reasons that go
+// away when it is run again.
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return true
+	}
+	return false
+}
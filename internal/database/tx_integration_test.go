@@ -0,0 +1,71 @@
+//go:build integration
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/kelcheone/chemistke/internal/testenv"
+)
+
+// TestWithTxRetriesConflict has another connection change a row between the
+// transaction's first read and its write.
+func TestWithTxRetriesConflict(t *testing.T) {
+	db := testenv.StartPostgres(t)
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE tx_counter (id INT PRIMARY KEY, n INT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     *sql.TxOptions
+		attempts int
+	}{
+		// the write waits for the other one and then goes ahead.
+		{name: "read committed", attempts: 1},
+		{name: "repeatable read", opts: &sql.TxOptions{Isolation: sql.LevelRepeatableRead}, attempts: 2},
+		{name: "serializable", opts: &sql.TxOptions{Isolation: sql.LevelSerializable}, attempts: 2},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.ExecContext(ctx, `INSERT INTO tx_counter (id, n) VALUES ($1, 0)`, i); err != nil {
+				t.Fatal(err)
+			}
+
+			var attempts int
+			err := db.WithTx(ctx, tt.opts, func(tx *sql.Tx) error {
+				attempts++
+				var n int
+				if err := tx.QueryRowContext(ctx, `SELECT n FROM tx_counter WHERE id=$1`, i).Scan(&n); err != nil {
+					return err
+				}
+				if attempts == 1 {
+					if _, err := db.ExecContext(ctx, `UPDATE tx_counter SET n = n + 1 WHERE id=$1`, i); err != nil {
+						return err
+					}
+				}
+				_, err := tx.ExecContext(ctx, `UPDATE tx_counter SET n = n + 10 WHERE id=$1`, i)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("WithTx: %v", err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("ran %d times, want %d", attempts, tt.attempts)
+			}
+
+			var n int
+			if err := db.QueryRowContext(ctx, `SELECT n FROM tx_counter WHERE id=$1`, i).Scan(&n); err != nil {
+				t.Fatal(err)
+			}
+			if n != 11 {
+				t.Errorf("n = %d, want 11", n)
+			}
+		})
+	}
+}
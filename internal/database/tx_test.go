@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/kelcheone/chemistke/pkg/codes"
+	"github.com/kelcheone/chemistke/pkg/status"
+	"github.com/lib/pq"
+)
+
+// txDriver is a database/sql driver that only begins and ends
+// transactions, recording the options each one began with.
+type txDriver struct {
+	mu     sync.Mutex
+	began  []driver.TxOptions
+	commit error
+}
+
+func (d *txDriver) Open(string) (driver.Conn, error) { return txConn{d}, nil }
+
+type txConn struct{ d *txDriver }
+
+func (c txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c txConn) Close() error                        { return nil }
+func (c txConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c txConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.began = append(c.d.began, opts)
+	return txTx{c.d}, nil
+}
+
+type txTx struct{ d *txDriver }
+
+func (t txTx) Commit() error   { return t.d.commit }
+func (t txTx) Rollback() error { return nil }
+
+type txConnector struct{ d *txDriver }
+
+func (c txConnector) Connect(context.Context) (driver.Conn, error) { return txConn{c.d}, nil }
+func (c txConnector) Driver() driver.Driver                        { return c.d }
+
+func newTxDB(t *testing.T, d *txDriver) *Database {
+	t.Helper()
+	db := sql.OpenDB(txConnector{d})
+	t.Cleanup(func() { db.Close() })
+	return &Database{db}
+}
+
+func TestWithTx(t *testing.T) {
+	serializationFailure := &pq.Error{Code: "40001"}
+	deadlock := &pq.Error{Code: "40P01"}
+	uniqueViolation := &pq.Error{Code: "23505"}
+
+	tests := []struct {
+		name string
+		opts *sql.TxOptions
+		// errs are what fn returns on each attempt, nil once they run out.
+		errs     []error
+		commit   error
+		want     error
+		wantCode codes.Code
+		attempts int
+	}{
+		{name: "commits", attempts: 1},
+		{name: "fn error is returned", errs: []error{uniqueViolation}, want: uniqueViolation, attempts: 1},
+		{
+			name:     "serialization failure is retried",
+			opts:     &sql.TxOptions{Isolation: sql.LevelRepeatableRead},
+			errs:     []error{serializationFailure},
+			attempts: 2,
+		},
+		{name: "deadlock is retried", errs: []error{deadlock, deadlock}, attempts: 3},
+		{
+			name:     "gives up after the last attempt",
+			opts:     &sql.TxOptions{Isolation: sql.LevelSerializable},
+			errs:     []error{serializationFailure, serializationFailure, serializationFailure, nil},
+			want:     serializationFailure,
+			wantCode: codes.Aborted,
+			attempts: maxTxAttempts,
+		},
+		{
+			name: "wrapped serialization failure is retried",
+			errs: []error{
+				errors.Join(errors.New("could not take stock"), serializationFailure),
+			},
+			attempts: 2,
+		},
+		{
+			name:     "failed commit is retried",
+			commit:   serializationFailure,
+			want:     serializationFailure,
+			wantCode: codes.Aborted,
+			attempts: maxTxAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &txDriver{commit: tt.commit}
+			db := newTxDB(t, d)
+
+			var attempts int
+			err := db.WithTx(context.Background(), tt.opts, func(*sql.Tx) error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.want) && (tt.want != nil || err != nil) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+			if tt.wantCode != codes.OK && status.Convert(err).Code() != tt.wantCode {
+				t.Errorf("got code %v, want %v", status.Convert(err).Code(), tt.wantCode)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("fn ran %d times, want %d", attempts, tt.attempts)
+			}
+
+			// every attempt begins with the caller's options.
+			want := driver.TxOptions{}
+			if tt.opts != nil {
+				want = driver.TxOptions{Isolation: driver.IsolationLevel(tt.opts.Isolation), ReadOnly: tt.opts.ReadOnly}
+			}
+			for i, opts := range d.began {
+				if opts != want {
+					t.Errorf("attempt %d began with %+v, want %+v", i+1, opts, want)
+				}
+			}
+		})
+	}
+}
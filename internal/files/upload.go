@@ -4,29 +4,31 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/joho/godotenv"
+	appconfig "github.com/kelcheone/chemistke/internal/config"
 )
 
-func config2() (*aws.Config, error) {
-	err := godotenv.Load()
-	if err != nil {
-		return nil, err
-	}
+var storage appconfig.AWSConfig
+
+// Configure sets the bucket and credentials uploads use. The binaries call
+// it once at startup.
+func Configure(cfg appconfig.AWSConfig) {
+	storage = cfg
+}
 
+func config2() (*aws.Config, error) {
 	cfg, err := config.LoadDefaultConfig(
 		context.TODO(),
-		config.WithRegion("af-south-1"),
+		config.WithRegion(storage.Region),
 		config.WithCredentialsProvider(
 			credentials.NewStaticCredentialsProvider(
-				os.Getenv("AWS_ACCESS_KEY_ID"),
-				os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				storage.AccessKeyID,
+				storage.SecretAccessKey,
 				"",
 			),
 		),
@@ -43,19 +45,21 @@ func UploadImage(
 	productId string,
 	fileName string,
 ) (string, error) {
+	return Upload(file, fmt.Sprintf("products/%s/%s", productId, fileName))
+}
+
+// Upload stores file in the bucket under key and returns its public URL.
+func Upload(file io.Reader, key string) (string, error) {
 	cfg, err := config2()
 	if err != nil {
 		return "", err
 	}
-	bucketName := "chemistke"
-
-	key := fmt.Sprintf("products/%s/%s", productId, fileName)
 
 	svc := s3.NewFromConfig(*cfg)
 
 	uploader := manager.NewUploader(svc)
 	uploadOutput, err := uploader.Upload(context.TODO(), &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
+		Bucket: aws.String(storage.Bucket),
 		Key:    aws.String(key),
 		Body:   file,
 	})
@@ -65,3 +69,18 @@ func UploadImage(
 
 	return uploadOutput.Location, nil
 }
+
+// Delete removes the object stored under key.
+func Delete(key string) error {
+	cfg, err := config2()
+	if err != nil {
+		return err
+	}
+
+	svc := s3.NewFromConfig(*cfg)
+	_, err = svc.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(storage.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
@@ -0,0 +1,132 @@
+// Package logging sets up the structured logger shared by the gateway and
+// the gRPC services, and carries a request ID from the gateway through to
+// every service a request touches.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// RequestIDHeader is the HTTP header a request ID is read from and echoed
+// back in.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDMetadata is the gRPC metadata key the request ID travels in.
+const requestIDMetadata = "x-request-id"
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values never reach the logs. A key
+// is sensitive when it contains any of these, so "password_hash" and
+// "access_token" are caught too.
+var sensitiveKeys = []string{
+	"password",
+	"token",
+	"secret",
+	"authorization",
+	"cookie",
+	"email",
+	"phone",
+	"address",
+	"dsn",
+}
+
+type requestIDKey struct{}
+
+// level is the level of the logger Init installs. It starts at info so the
+// configuration can be loaded, and logged, before SetLevel is called.
+var level slog.LevelVar
+
+// Init installs a JSON logger for service as the slog default, which also
+// routes the standard log package through it.
+func Init(service string) *slog.Logger {
+	logger := New(os.Stdout, &level).With("service", service)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// SetLevel changes the level of the logger Init installed: debug, info, warn
+// or error. Anything else is info.
+func SetLevel(name string) {
+	level.Set(parseLevel(name))
+}
+
+// New returns a JSON logger writing to w that tags lines with the request
+// ID in their context and redacts sensitive attributes.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	})
+	return slog.New(requestIDHandler{handler})
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// WithRequestID returns a copy of ctx carrying id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a random request ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func parseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	return a
+}
+
+// requestIDHandler adds the request ID in a record's context to the record.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
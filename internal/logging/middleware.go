@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// EchoMiddleware takes the request ID from X-Request-ID, or makes one up,
+// echoes it back, stores it in the request context and logs the request
+// once it has been handled.
+func EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > 128 {
+				id = NewRequestID()
+			}
+			c.Response().Header().Set(RequestIDHeader, id)
+			ctx := WithRequestID(req.Context(), id)
+			c.SetRequest(req.WithContext(ctx))
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// let the error handler write the response so the status
+				// below is the one the client got.
+				c.Error(err)
+			}
+
+			level := slog.LevelInfo
+			code := c.Response().Status
+			if code >= 500 {
+				level = slog.LevelError
+			} else if code >= 400 {
+				level = slog.LevelWarn
+			}
+			slog.Log(ctx, level, "http request",
+				"method", req.Method,
+				"route", c.Path(),
+				"uri", req.URL.Path,
+				"status", code,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"remote_ip", c.RealIP(),
+			)
+			return nil
+		}
+	}
+}
+
+// UnaryClientInterceptor sends the request ID in ctx along with every call.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if id := RequestID(ctx); id != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, requestIDMetadata, id)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// UnaryServerInterceptor picks the request ID out of the incoming metadata,
+// or makes one up for callers that did not send one, and logs each call.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get(requestIDMetadata); len(ids) > 0 {
+				id = ids[0]
+			}
+		}
+		if id == "" {
+			id = NewRequestID()
+		}
+		ctx = WithRequestID(ctx, id)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		level := slog.LevelInfo
+		switch code {
+		case codes.OK:
+		case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unavailable:
+			level = slog.LevelError
+		default:
+			level = slog.LevelWarn
+		}
+		attrs := []any{
+			"method", info.FullMethod,
+			"code", code.String(),
+			"duration_ms", time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			attrs = append(attrs, "error", status.Convert(err).Message())
+		}
+		slog.Log(ctx, level, "grpc request", attrs...)
+
+		return resp, err
+	}
+}
@@ -0,0 +1,83 @@
+// Package memstore is shared by the services' in-memory repositories, which
+// let the services run without Postgres.
+package memstore
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Store keeps a repository's data behind one lock. A transaction holds the
+// lock for its whole run and puts the data back as it was when it fails.
+type Store[T any] struct {
+	mu    *sync.Mutex
+	data  *T
+	clone func(*T) *T
+	inTx  bool
+}
+
+// New returns a Store holding data. clone makes the deep copy a
+// transaction is rolled back to.
+func New[T any](data *T, clone func(*T) *T) *Store[T] {
+	return &Store[T]{mu: &sync.Mutex{}, data: data, clone: clone}
+}
+
+// Data returns the data, which is only safe to use under Lock.
+func (s *Store[T]) Data() *T {
+	return s.data
+}
+
+// Lock takes the lock and returns what releases it. Inside a transaction,
+// which already holds it, it does nothing.
+func (s *Store[T]) Lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// Tx runs fn on a Store that shares the data and holds the lock until fn
+// returns. When fn fails the data is put back as it was. A Tx inside a
+// transaction runs fn as part of it.
+func (s *Store[T]) Tx(fn func(*Store[T]) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	defer s.Lock()()
+
+	snapshot := s.clone(s.data)
+	if err := fn(&Store[T]{mu: s.mu, data: s.data, clone: s.clone, inTx: true}); err != nil {
+		*s.data = *snapshot
+		return err
+	}
+	return nil
+}
+
+// CloneAll deep copies messages.
+func CloneAll[T proto.Message](items []T) []T {
+	if items == nil {
+		return nil
+	}
+	cloned := make([]T, len(items))
+	for i, item := range items {
+		cloned[i] = proto.Clone(item).(T)
+	}
+	return cloned
+}
+
+// Page applies LIMIT and OFFSET the way Postgres does.
+func Page[T any](items []T, limit, offset int32) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if int(offset) >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && int(limit) < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,99 @@
+package memstore
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+type counters struct{ n []int }
+
+func (c *counters) clone() *counters { return &counters{n: slices.Clone(c.n)} }
+
+func TestTx(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name string
+		// fn appends to the data inside the transaction.
+		fn    func(*Store[counters]) error
+		want  error
+		wantN []int
+	}{
+		{
+			name: "keeps changes",
+			fn: func(s *Store[counters]) error {
+				s.Data().n = append(s.Data().n, 2)
+				return nil
+			},
+			wantN: []int{1, 2},
+		},
+		{
+			name: "puts the data back on error",
+			fn: func(s *Store[counters]) error {
+				s.Data().n = append(s.Data().n, 2)
+				return failed
+			},
+			want:  failed,
+			wantN: []int{1},
+		},
+		{
+			name: "nested transaction is part of the outer one",
+			fn: func(s *Store[counters]) error {
+				err := s.Tx(func(s *Store[counters]) error {
+					s.Data().n = append(s.Data().n, 2)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				return failed
+			},
+			want:  failed,
+			wantN: []int{1},
+		},
+		{
+			name: "lock inside the transaction does not block",
+			fn: func(s *Store[counters]) error {
+				defer s.Lock()()
+				s.Data().n[0] = 3
+				return nil
+			},
+			wantN: []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&counters{n: []int{1}}, (*counters).clone)
+			if err := s.Tx(tt.fn); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if got := s.Data().n; !slices.Equal(got, tt.wantN) {
+				t.Errorf("data = %v, want %v", got, tt.wantN)
+			}
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name          string
+		limit, offset int32
+		want          []int
+	}{
+		{name: "first page", limit: 2, offset: 0, want: []int{1, 2}},
+		{name: "last page", limit: 2, offset: 4, want: []int{5}},
+		{name: "past the end", limit: 2, offset: 5, want: nil},
+		{name: "negative offset", limit: 2, offset: -1, want: []int{1, 2}},
+		{name: "no limit", limit: -1, offset: 3, want: []int{4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Page(items, tt.limit, tt.offset); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
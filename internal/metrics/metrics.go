@@ -0,0 +1,163 @@
+// Package metrics exposes Prometheus metrics for the gateway and the gRPC
+// services, along with the business counters they bump.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "chemistke"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled by the gateway.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to answer HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being handled.",
+	})
+
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls handled by the service.",
+	}, []string{"method", "code"})
+
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Time taken to answer gRPC calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	grpcInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_in_flight",
+		Help:      "gRPC calls currently being handled.",
+	}, []string{"method"})
+
+	// OrdersCreated counts orders placed successfully.
+	OrdersCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_created_total",
+		Help:      "Orders placed.",
+	})
+
+	// LoginFailures counts sign in attempts turned away for bad credentials.
+	LoginFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Sign in attempts with an unknown email or wrong password.",
+	})
+)
+
+// Handler serves every registered metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve exposes /metrics on addr in the background, for the gRPC services
+// which have no HTTP server of their own.
+func Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+}
+
+// RegisterDB reports connection pool stats for db under the given name.
+// Databases that are not backed by database/sql are skipped.
+func RegisterDB(db database.DB, name string) {
+	d, ok := db.(*database.Database)
+	if !ok {
+		return
+	}
+	prometheus.MustRegister(collectors.NewDBStatsCollector(d.DB, name))
+}
+
+// EchoMiddleware records request counts, latencies and in-flight requests
+// by route template, so /posts/:id is one series rather than one per post.
+func EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Path() == "/metrics" {
+				return next(c)
+			}
+
+			httpInFlight.Inc()
+			defer httpInFlight.Dec()
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// let the error handler write the response so the status
+				// below is the one the client got, e.g. 404 for a NotFound
+				// from a service.
+				c.Error(err)
+			}
+			code := c.Response().Status
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			labels := []string{c.Request().Method, route, strconv.Itoa(code)}
+			httpRequests.WithLabelValues(labels...).Inc()
+			httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+			return nil
+		}
+	}
+}
+
+// UnaryServerInterceptor records call counts, latencies and in-flight calls
+// by full method name and status code.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		inFlight := grpcInFlight.WithLabelValues(info.FullMethod)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err).String()
+		grpcRequests.WithLabelValues(info.FullMethod, code).Inc()
+		grpcDuration.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+
+		return resp, err
+	}
+}
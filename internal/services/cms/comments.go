@@ -0,0 +1,347 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+const (
+	maxCommentLength = 5000
+	// a user can post commentBurst comments per commentWindow.
+	commentBurst  = 5
+	commentWindow = 10 * time.Minute
+	// comments scoring this much or more are marked as spam.
+	spamThreshold = 3
+)
+
+var (
+	linkPattern = regexp.MustCompile(`(?i)https?://|www\.`)
+	spamWords   = []string{
+		"viagra", "casino", "lottery", "betting", "forex", "bitcoin",
+		"crypto", "loan", "escort", "porn", "click here", "buy now",
+	}
+)
+
+// comment statuses are stored lower case in post_comments.status.
+func commentStatusToDB(s pb.CommentStatus) string {
+	return strings.ToLower(s.String())
+}
+
+func commentStatusFromDB(s string) pb.CommentStatus {
+	return pb.CommentStatus(pb.CommentStatus_value[strings.ToUpper(s)])
+}
+
+func (c *CmsService) CreateComment(
+	ctx context.Context,
+	req *pb.CreateCommentRequest,
+) (*pb.CreateCommentResponse, error) {
+	body := strings.TrimSpace(req.Body)
+	switch {
+	case req.UserId.GetValue() == "":
+		return nil, status.Errorf(codes.InvalidArgument, "user id was not provided")
+	case body == "":
+		return nil, status.Errorf(codes.InvalidArgument, "comment is empty")
+	case len(body) > maxCommentLength:
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"comment is longer than %d characters",
+			maxCommentLength,
+		)
+	}
+
+	if _, err := c.repo.GetPost(ctx, req.PostId.GetValue(), false); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "post does not exist")
+		}
+		return nil, status.Errorf(codes.Internal, "could not get post: %v", err)
+	}
+
+	if req.ParentId.GetValue() != "" {
+		parent, err := c.repo.GetComment(ctx, req.ParentId.Value)
+		if err != nil || parent.PostId.Value != req.PostId.Value || parent.Status != pb.CommentStatus_APPROVED {
+			return nil, status.Errorf(codes.InvalidArgument, "cannot reply to that comment")
+		}
+	}
+
+	// the rate limit is checked and the comment stored under one lock, so
+	// comments sent at the same time can't all get past the limit.
+	var commentId string
+	err := c.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.LockCommenter(ctx, req.UserId.Value); err != nil {
+			return status.Errorf(codes.Internal, "could not check comment history: %v", err)
+		}
+
+		recent, trusted, err := commentHistory(ctx, repo, req.UserId.Value)
+		if err != nil {
+			return err
+		}
+		if recent >= commentBurst {
+			return status.Errorf(
+				codes.ResourceExhausted,
+				"you can post %d comments every %s, try again later",
+				commentBurst,
+				commentWindow,
+			)
+		}
+
+		previous, err := recentCommentBodies(ctx, repo, req.UserId.Value)
+		if err != nil {
+			return err
+		}
+		score := spamScore(body, previous)
+
+		commentStatus := pb.CommentStatus_PENDING
+		switch {
+		case score >= spamThreshold:
+			commentStatus = pb.CommentStatus_SPAM
+		case score == 0 && trusted:
+			commentStatus = pb.CommentStatus_APPROVED
+		}
+
+		commentId, err = repo.CreateComment(ctx, &pb.Comment{
+			PostId:   req.PostId,
+			ParentId: req.ParentId,
+			UserId:   req.UserId,
+			Body:     body,
+			Status:   commentStatus,
+		}, score)
+		if err != nil {
+			return status.Errorf(codes.Internal, "could not create comment: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := c.getComment(ctx, commentId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateCommentResponse{Comment: comment}, nil
+}
+
+func (c *CmsService) ListComments(
+	ctx context.Context,
+	req *pb.ListCommentsRequest,
+) (*pb.ListCommentsResponse, error) {
+	var resp pb.ListCommentsResponse
+	var err error
+	resp.TotalThreads, resp.TotalComments, err = c.repo.CountComments(ctx, req.PostId.GetValue())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not count comments: %v", err)
+	}
+
+	limit, offset := pagination(req.Page, req.PerPage)
+	roots, err := c.repo.ListThreads(ctx, req.PostId.GetValue(), limit, offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch comments: %v", err)
+	}
+	if len(roots) == 0 {
+		return &resp, nil
+	}
+
+	var rootIds []string
+	byId := make(map[string]*pb.Comment)
+	for _, root := range roots {
+		rootIds = append(rootIds, root.CommentId.Value)
+		byId[root.CommentId.Value] = root
+	}
+
+	// replies to a comment that isn't approved stay hidden with it.
+	replies, err := c.repo.ListReplies(ctx, rootIds)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch comments: %v", err)
+	}
+	for _, reply := range replies {
+		byId[reply.CommentId.Value] = reply
+	}
+	// replies come back oldest first and keep that order under their parent.
+	for _, reply := range replies {
+		if parent, ok := byId[reply.ParentId.GetValue()]; ok {
+			parent.Replies = append(parent.Replies, reply)
+		}
+	}
+
+	resp.Comments = roots
+	return &resp, nil
+}
+
+func (c *CmsService) ListCommentsForModeration(
+	ctx context.Context,
+	req *pb.ListCommentsForModerationRequest,
+) (*pb.ListCommentsForModerationResponse, error) {
+	admin, err := c.moderatorRole(ctx, req.ModeratorId.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	// authors only moderate comments on their own posts.
+	filter := ModerationFilter{Status: req.Status}
+	if !admin {
+		filter.AuthorUserId = req.ModeratorId.Value
+	}
+	filter.Limit, filter.Offset = pagination(req.Page, req.PerPage)
+
+	comments, total, err := c.repo.ListModerationQueue(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch comments: %v", err)
+	}
+
+	return &pb.ListCommentsForModerationResponse{Comments: comments, Total: total}, nil
+}
+
+func (c *CmsService) ModerateComment(
+	ctx context.Context,
+	req *pb.ModerateCommentRequest,
+) (*pb.ModerateCommentResponse, error) {
+	admin, err := c.moderatorRole(ctx, req.ModeratorId.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	authorUserId, err := c.repo.CommentPostAuthor(ctx, req.CommentId.GetValue())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "comment does not exist")
+		}
+		return nil, status.Errorf(codes.Internal, "could not get comment: %v", err)
+	}
+	if !admin && authorUserId != req.ModeratorId.Value {
+		return nil, status.Errorf(
+			codes.PermissionDenied,
+			"authors can only moderate comments on their own posts",
+		)
+	}
+
+	err = c.repo.ModerateComment(ctx, req.CommentId.Value, req.Status, req.ModeratorId.Value)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not moderate comment: %v", err)
+	}
+
+	return &pb.ModerateCommentResponse{CommentId: req.CommentId, Status: req.Status}, nil
+}
+
+// moderatorRole reports whether the user is an admin, and fails unless they
+// are at least an author.
+func (c *CmsService) moderatorRole(ctx context.Context, userId string) (bool, error) {
+	role, err := c.repo.GetUserRole(ctx, userId)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return false, status.Errorf(codes.NotFound, "user does not exist")
+		}
+		return false, status.Errorf(codes.Internal, "could not get user: %v", err)
+	}
+
+	switch role {
+	case pb.UserRoles_ADMIN:
+		return true, nil
+	case pb.UserRoles_AUTHOR:
+		return false, nil
+	}
+	return false, status.Errorf(codes.PermissionDenied, "only admins and authors can moderate comments")
+}
+
+// commentHistory returns how many comments the user posted in the current
+// rate limit window and whether they have had a comment approved before.
+func commentHistory(ctx context.Context, repo Repository, userId string) (int, bool, error) {
+	recent, trusted, err := repo.CommentHistory(ctx, userId, time.Now().Add(-commentWindow))
+	if err != nil {
+		return 0, false, status.Errorf(codes.Internal, "could not check comment history: %v", err)
+	}
+	return recent, trusted, nil
+}
+
+func recentCommentBodies(ctx context.Context, repo Repository, userId string) ([]string, error) {
+	bodies, err := repo.RecentCommentBodies(ctx, userId, 10)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not check comment history: %v", err)
+	}
+	return bodies, nil
+}
+
+// spamScore adds up a few cheap signals. It is not meant to catch
+// everything, only to keep the obvious junk out of the moderation queue.
+func spamScore(body string, previous []string) int {
+	score := 0
+	lower := strings.ToLower(body)
+
+	switch links := len(linkPattern.FindAllString(body, -1)); {
+	case links >= 2:
+		score += 2
+	case links == 1:
+		score++
+	}
+
+	for _, word := range spamWords {
+		if strings.Contains(lower, word) {
+			score += 2
+			break
+		}
+	}
+
+	var letters, upper int
+	for _, r := range body {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= 20 && upper*10 > letters*7 {
+		score++
+	}
+
+	if longestRun(body) >= 6 {
+		score++
+	}
+
+	// posting the same thing again and again.
+	normalized := strings.Join(strings.Fields(lower), " ")
+	for _, p := range previous {
+		if strings.Join(strings.Fields(strings.ToLower(p)), " ") == normalized {
+			score += 3
+			break
+		}
+	}
+
+	return score
+}
+
+// longestRun is the length of the longest run of one repeated character,
+// e.g. 5 for "!!!!!".
+func longestRun(s string) int {
+	longest, run := 0, 0
+	var last rune
+	for i, r := range s {
+		if i > 0 && r == last {
+			run++
+		} else {
+			run = 1
+		}
+		last = r
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+func (c *CmsService) getComment(ctx context.Context, commentId string) (*pb.Comment, error) {
+	comment, err := c.repo.GetComment(ctx, commentId)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "comment does not exist")
+		}
+		return nil, status.Errorf(codes.Internal, "could not get comment: %v", err)
+	}
+	return comment, nil
+}
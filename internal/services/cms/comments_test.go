@@ -0,0 +1,156 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// publishPost creates a post and takes it through review to published.
+func (f *fixture) publishPost(t *testing.T, title string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	post := f.post(title)
+	post.Status = pb.PostStatus_IN_REVIEW
+	postId := f.createPost(t, post)
+	_, err := f.svc.ApprovePost(ctx, &pb.ApprovePostRequest{
+		PostId:     &pb.UUID{Value: postId},
+		ReviewerId: &pb.UUID{Value: f.userId},
+	})
+	if err != nil {
+		t.Fatalf("ApprovePost: %v", err)
+	}
+	return postId
+}
+
+// failingPosts is a Repository whose post reads fail.
+type failingPosts struct {
+	Repository
+}
+
+func (failingPosts) GetPost(context.Context, string, bool) (*pb.Post, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestCreateComment(t *testing.T) {
+	tests := []struct {
+		name string
+		// posted is how many comments the user already posted.
+		posted int
+		post   func(t *testing.T, f *fixture, postId string) string
+		body   string
+		repo   func(Repository) Repository
+		want   codes.Code
+	}{
+		{name: "first comment is pending", body: "Is this safe for children?", want: codes.OK},
+		{name: "empty", body: "   ", want: codes.InvalidArgument},
+		{
+			name: "missing post",
+			post: func(*testing.T, *fixture, string) string { return uuid.NewString() },
+			body: "Hello",
+			want: codes.NotFound,
+		},
+		{
+			name: "unpublished post",
+			post: func(t *testing.T, f *fixture, _ string) string { return f.createPost(t, f.post("Draft")) },
+			body: "Hello",
+			want: codes.NotFound,
+		},
+		{
+			name: "post read fails",
+			body: "Hello",
+			repo: func(r Repository) Repository { return failingPosts{r} },
+			want: codes.Internal,
+		},
+		{name: "under the rate limit", posted: commentBurst - 1, body: "One more", want: codes.OK},
+		{name: "rate limited", posted: commentBurst, body: "One too many", want: codes.ResourceExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			ctx := context.Background()
+			postId := f.publishPost(t, "Cough syrup")
+			commenter := uuid.NewString()
+			f.repo.AddUser(commenter, "John Doe", pb.UserRoles_USER)
+
+			for i := 0; i < tt.posted; i++ {
+				_, err := f.svc.CreateComment(ctx, &pb.CreateCommentRequest{
+					PostId: &pb.UUID{Value: postId},
+					UserId: &pb.UUID{Value: commenter},
+					Body:   fmt.Sprintf("comment %d", i),
+				})
+				if err != nil {
+					t.Fatalf("CreateComment %d: %v", i, err)
+				}
+			}
+
+			if tt.post != nil {
+				postId = tt.post(t, f, postId)
+			}
+			svc := f.svc
+			if tt.repo != nil {
+				svc = NewCmsService(tt.repo(f.repo))
+			}
+
+			resp, err := svc.CreateComment(ctx, &pb.CreateCommentRequest{
+				PostId: &pb.UUID{Value: postId},
+				UserId: &pb.UUID{Value: commenter},
+				Body:   tt.body,
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+			if err == nil && resp.Comment.Status != pb.CommentStatus_PENDING {
+				t.Errorf("got status %v, want %v", resp.Comment.Status, pb.CommentStatus_PENDING)
+			}
+		})
+	}
+}
+
+func TestCreateCommentRateLimitIsAtomic(t *testing.T) {
+	f := newFixture(t)
+	postId := f.publishPost(t, "Hay fever")
+	commenter := uuid.NewString()
+	f.repo.AddUser(commenter, "John Doe", pb.UserRoles_USER)
+
+	const sent = 4 * commentBurst
+	var wg sync.WaitGroup
+	codesSeen := make(chan codes.Code, sent)
+	for i := 0; i < sent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.svc.CreateComment(context.Background(), &pb.CreateCommentRequest{
+				PostId: &pb.UUID{Value: postId},
+				UserId: &pb.UUID{Value: commenter},
+				Body:   fmt.Sprintf("comment %d", i),
+			})
+			codesSeen <- status.Code(err)
+		}()
+	}
+	wg.Wait()
+	close(codesSeen)
+
+	var created int
+	for code := range codesSeen {
+		switch code {
+		case codes.OK:
+			created++
+		case codes.ResourceExhausted:
+		default:
+			t.Errorf("unexpected code %v", code)
+		}
+	}
+	if created != commentBurst {
+		t.Errorf("created %d comments, want %d", created, commentBurst)
+	}
+}
@@ -0,0 +1,121 @@
+package cmsservice
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strings"
+
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// wordsPerMinute is a typical adult reading speed for web articles.
+const wordsPerMinute = 200
+
+var (
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		// raw HTML is let through here and cleaned up by htmlPolicy.
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	productId = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+	htmlPolicy = newHTMLPolicy()
+
+	// strips everything, used to count words in the rendered text.
+	textPolicy = bluemonday.StrictPolicy()
+)
+
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	// keep the heading ids the table of contents links to.
+	policy.AllowAttrs("id").Matching(bluemonday.SpaceSeparatedTokens).
+		OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	// product shortcodes become placeholders the storefront fills in.
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^product-embed$`)).OnElements("div")
+	policy.AllowAttrs("data-product-id").Matching(productId).OnElements("div")
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+	return policy
+}
+
+// renderedPost is everything derived from a post's markdown.
+type renderedPost struct {
+	HTML               string
+	Toc                []*pb.TocEntry
+	WordCount          int32
+	ReadingTimeMinutes int32
+}
+
+// renderMarkdown turns an author's markdown into sanitized HTML along with
+// a table of contents and reading stats.
+func renderMarkdown(source string) (renderedPost, error) {
+	src := []byte(expandShortcodes(source))
+	doc := markdown.Parser().Parse(text.NewReader(src))
+
+	var toc []*pb.TocEntry
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		entry := &pb.TocEntry{
+			Level: int32(heading.Level),
+			Text:  nodeText(heading, src),
+		}
+		if id, ok := heading.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				entry.Anchor = string(b)
+			}
+		}
+		toc = append(toc, entry)
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return renderedPost{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, src, doc); err != nil {
+		return renderedPost{}, err
+	}
+	safe := htmlPolicy.Sanitize(buf.String())
+
+	words := int32(len(strings.Fields(textPolicy.Sanitize(safe))))
+	minutes := int32(math.Ceil(float64(words) / wordsPerMinute))
+
+	return renderedPost{
+		HTML:               safe,
+		Toc:                toc,
+		WordCount:          words,
+		ReadingTimeMinutes: minutes,
+	}, nil
+}
+
+func nodeText(n ast.Node, src []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Text:
+			sb.Write(t.Value(src))
+			if t.SoftLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
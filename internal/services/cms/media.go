@@ -0,0 +1,258 @@
+package cmsservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/files"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+	_ "golang.org/x/image/webp"
+)
+
+// the gateway forwards uploads in a single gRPC message, which tops out at
+// 4MB, so anything larger is turned away here.
+const maxMediaSize = 3 << 20
+
+const (
+	// media_assets.alt_text is a VARCHAR(255).
+	maxAltTextLength = 255
+	// media_assets.url is a VARCHAR(255) too. The S3 url before the key and
+	// the random prefix in the key take up to about 130 characters, so the
+	// file name gets what is left with some to spare.
+	maxMediaFileName = 100
+)
+
+var mediaContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (c *CmsService) UploadMedia(
+	ctx context.Context,
+	req *pb.UploadMediaRequest,
+) (*pb.UploadMediaResponse, error) {
+	if len(req.Data) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "file was not provided")
+	}
+	if len(req.Data) > maxMediaSize {
+		return nil, status.Errorf(codes.InvalidArgument, "file is larger than %dMB", maxMediaSize>>20)
+	}
+
+	contentType := http.DetectContentType(req.Data)
+	if !mediaContentTypes[contentType] {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported file type %s", contentType)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(req.Data))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "could not read image: %v", err)
+	}
+	altText, err := mediaAltText(req.AltText)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := mediaFileName(req.FileName)
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, status.Errorf(codes.Internal, "could not name upload: %v", err)
+	}
+	key := fmt.Sprintf("cms/media/%s-%s", hex.EncodeToString(suffix), fileName)
+
+	url, err := files.Upload(bytes.NewReader(req.Data), key)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not upload file: %v", err)
+	}
+
+	assetId, err := c.repo.CreateMedia(ctx, &pb.MediaAsset{
+		Url:         url,
+		FileName:    fileName,
+		ContentType: contentType,
+		SizeBytes:   int64(len(req.Data)),
+		Width:       int32(config.Width),
+		Height:      int32(config.Height),
+		AltText:     altText,
+		UploadedBy:  req.UploadedBy,
+	}, key)
+	if err != nil {
+		if err := files.Delete(key); err != nil {
+			slog.WarnContext(ctx, "could not remove orphaned upload", "key", key, "error", err)
+		}
+		return nil, status.Errorf(codes.Internal, "could not save media: %v", err)
+	}
+
+	asset, err := c.getMedia(ctx, assetId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UploadMediaResponse{Asset: asset}, nil
+}
+
+func (c *CmsService) GetMedia(
+	ctx context.Context,
+	req *pb.GetMediaRequest,
+) (*pb.GetMediaResponse, error) {
+	if req.AssetId.GetValue() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "asset id was not provided")
+	}
+
+	asset, err := c.getMedia(ctx, req.AssetId.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if asset.Usages, err = c.repo.ListMediaUsages(ctx, req.AssetId.Value); err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch media usage: %v", err)
+	}
+
+	return &pb.GetMediaResponse{Asset: asset}, nil
+}
+
+func (c *CmsService) ListMedia(
+	ctx context.Context,
+	req *pb.ListMediaRequest,
+) (*pb.ListMediaResponse, error) {
+	limit, offset := pagination(req.Page, req.PerPage)
+	assets, total, err := c.repo.ListMedia(ctx, strings.TrimSpace(req.Query), limit, offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch media: %v", err)
+	}
+
+	return &pb.ListMediaResponse{Assets: assets, Total: total}, nil
+}
+
+func (c *CmsService) UpdateMedia(
+	ctx context.Context,
+	req *pb.UpdateMediaRequest,
+) (*pb.UpdateMediaResponse, error) {
+	if req.AssetId.GetValue() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "asset id was not provided")
+	}
+
+	altText, err := mediaAltText(req.AltText)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.repo.UpdateMediaAltText(ctx, req.AssetId.Value, altText)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "media does not exist")
+		}
+		return nil, status.Errorf(codes.Internal, "could not update media: %v", err)
+	}
+
+	asset, err := c.getMedia(ctx, req.AssetId.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateMediaResponse{Asset: asset}, nil
+}
+
+// DeleteMedia refuses to remove an asset while a post or author still uses
+// it, see Repository.DeleteMedia for what a concurrent save can do.
+func (c *CmsService) DeleteMedia(
+	ctx context.Context,
+	req *pb.DeleteMediaRequest,
+) (*pb.DeleteMediaResponse, error) {
+	if req.AssetId.GetValue() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "asset id was not provided")
+	}
+
+	key, err := c.repo.DeleteMedia(ctx, req.AssetId.Value)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "media does not exist")
+	}
+	if errors.Is(err, ErrMediaInUse) {
+		asset, err := c.getMedia(ctx, req.AssetId.Value)
+		if err != nil {
+			return nil, err
+		}
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"media is still used by %d posts or authors",
+			asset.UsageCount,
+		)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not delete media: %v", err)
+	}
+
+	// the row is gone either way, a file left behind only costs storage.
+	if err := files.Delete(key); err != nil {
+		slog.WarnContext(ctx, "could not remove media file", "key", key, "error", err)
+	}
+
+	return &pb.DeleteMediaResponse{AssetId: req.AssetId}, nil
+}
+
+// syncPostMedia records the assets a post uses once its cover image or
+// content may have changed.
+func syncPostMedia(ctx context.Context, repo Repository, postId string) error {
+	if err := repo.SyncPostMedia(ctx, postId); err != nil {
+		return status.Errorf(codes.Internal, "could not update post media: %w", err)
+	}
+	return nil
+}
+
+func (c *CmsService) getMedia(ctx context.Context, assetId string) (*pb.MediaAsset, error) {
+	asset, err := c.repo.GetMedia(ctx, assetId)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "media does not exist")
+		}
+		return nil, status.Errorf(codes.Internal, "could not get media: %v", err)
+	}
+	return asset, nil
+}
+
+// mediaFileName keeps the base name of an upload, safe to use in a storage
+// key and url. Long names are cut short, keeping the extension.
+func mediaFileName(name string) string {
+	name = unsafeFileChars.ReplaceAllString(path.Base(strings.ReplaceAll(name, `\`, "/")), "-")
+	name = strings.Trim(name, "-.")
+	if len(name) > maxMediaFileName {
+		ext := path.Ext(name)
+		if len(ext) > maxMediaFileName/2 {
+			ext = ""
+		}
+		name = strings.TrimRight(name[:maxMediaFileName-len(ext)], "-.") + ext
+	}
+	if name == "" {
+		return "upload"
+	}
+	return name
+}
+
+// mediaAltText trims alt text and checks that it fits in the column.
+func mediaAltText(altText string) (string, error) {
+	altText = strings.TrimSpace(altText)
+	if utf8.RuneCountInString(altText) > maxAltTextLength {
+		return "", status.Errorf(
+			codes.InvalidArgument,
+			"alt text is longer than %d characters",
+			maxAltTextLength,
+		)
+	}
+	return altText, nil
+}
@@ -0,0 +1,122 @@
+package cmsservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+func TestMediaFileName(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "cover.png", "cover.png"},
+		{"path", `C:\Users\jane\My Photo.jpg`, "My-Photo.jpg"},
+		{"nothing left", "../", "upload"},
+		{"long keeps extension", long + ".jpeg", strings.Repeat("a", maxMediaFileName-5) + ".jpeg"},
+		{"long extension is cut", "a." + long, "a." + strings.Repeat("a", maxMediaFileName-2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mediaFileName(tt.in)
+			if got != tt.want {
+				t.Errorf("mediaFileName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if len(got) > maxMediaFileName {
+				t.Errorf("got %d characters, want at most %d", len(got), maxMediaFileName)
+			}
+		})
+	}
+}
+
+func TestMediaAltTextTooLong(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	// counted in characters, like the column.
+	altText := strings.Repeat("é", maxAltTextLength+1)
+	svc := NewCmsService(NewMemoryRepository())
+
+	// rejected before anything is uploaded.
+	_, err := svc.UploadMedia(context.Background(), &pb.UploadMediaRequest{
+		Data:     img.Bytes(),
+		FileName: "pixel.png",
+		AltText:  altText,
+	})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("UploadMedia: got code %v, want %v: %v", got, codes.InvalidArgument, err)
+	}
+
+	_, err = svc.UpdateMedia(context.Background(), &pb.UpdateMediaRequest{
+		AssetId: &pb.UUID{Value: "9f0b7a4e-2a43-4d8e-9a36-6f5b0e0f7c11"},
+		AltText: altText,
+	})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("UpdateMedia: got code %v, want %v: %v", got, codes.InvalidArgument, err)
+	}
+
+	if _, err := mediaAltText(strings.Repeat("é", maxAltTextLength)); err != nil {
+		t.Errorf("alt text of %d characters: %v", maxAltTextLength, err)
+	}
+}
+
+func TestMediaUsages(t *testing.T) {
+	const url = "https://cdn.example.com/media/cover.png"
+	tests := []struct {
+		name string
+		// cover and content are what the post is saved with last.
+		cover, content string
+		wantUsed       bool
+	}{
+		{name: "cover image", cover: url, content: "## plain", wantUsed: true},
+		{name: "in the body", content: "![cover](" + url + ")", wantUsed: true},
+		{name: "no longer used", content: "## plain", wantUsed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			ctx := context.Background()
+			assetId, err := f.repo.CreateMedia(ctx, &pb.MediaAsset{Url: url, FileName: "cover.png"}, "media/cover.png")
+			if err != nil {
+				t.Fatalf("CreateMedia: %v", err)
+			}
+
+			// the post starts out using the asset and is then saved with
+			// the test's cover and content.
+			post := f.post("Malaria")
+			post.CoverImage = url
+			postId := f.createPost(t, post)
+			update := f.post("Malaria")
+			update.CoverImage, update.Content = tt.cover, tt.content
+			f.updatePost(t, postId, update)
+
+			resp, err := f.svc.GetMedia(ctx, &pb.GetMediaRequest{AssetId: &pb.UUID{Value: assetId}})
+			if err != nil {
+				t.Fatalf("GetMedia: %v", err)
+			}
+			if used := resp.Asset.UsageCount == 1 && len(resp.Asset.Usages) == 1; used != tt.wantUsed {
+				t.Errorf("got %d usages %v, want used = %v", resp.Asset.UsageCount, resp.Asset.Usages, tt.wantUsed)
+			}
+
+			_, err = f.repo.DeleteMedia(ctx, assetId)
+			if inUse := errors.Is(err, ErrMediaInUse); inUse != tt.wantUsed {
+				t.Errorf("DeleteMedia: %v, want in use = %v", err, tt.wantUsed)
+			}
+		})
+	}
+}
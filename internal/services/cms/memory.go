@@ -0,0 +1,1498 @@
+package cmsservice
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/memstore"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"google.golang.org/protobuf/proto"
+)
+
+// MemoryRepository is a Repository that keeps everything in memory, so the
+// service can run without Postgres. The cms reads names and roles from the
+// user service's table, so users it should know about are added with
+// AddUser. Transactions hold a lock for their whole run and put the data
+// back as it was when they fail.
+type MemoryRepository struct {
+	store *memstore.Store[memoryCms]
+	// data is the store's, only touched under lock.
+	data *memoryCms
+}
+
+type memoryUser struct {
+	name string
+	role pb.UserRoles
+}
+
+// memoryPost is a post without its dates, which are kept as times, or its
+// tags and products.
+type memoryPost struct {
+	post       *pb.Post
+	updated    time.Time
+	published  time.Time
+	publishAt  time.Time
+	reviewedBy string
+	reviewedAt time.Time
+	tags       []string
+	explicit   []string
+	shortcode  []string
+	// media are the ids of the assets the post used when last synced.
+	media []string
+}
+
+type memoryComment struct {
+	comment     *pb.Comment
+	spamScore   int
+	createdAt   time.Time
+	moderatedBy string
+	moderatedAt time.Time
+}
+
+type memoryMedia struct {
+	asset      *pb.MediaAsset
+	storageKey string
+	createdAt  time.Time
+}
+
+type redirectKey struct {
+	entity, slug string
+}
+
+type viewKey struct {
+	postId, viewer string
+}
+
+type dayKey struct {
+	postId, day string
+}
+
+type memoryCms struct {
+	users      map[string]*memoryUser
+	posts      []*memoryPost
+	revisions  []*pb.PostRevision
+	categories []*pb.Category
+	authors    []*pb.Author
+	tags       []*pb.Tag
+	redirects  map[redirectKey]string
+	comments   []*memoryComment
+	media      []*memoryMedia
+	sessions   map[viewKey]time.Time
+	dailyViews map[dayKey]int64
+}
+
+func (d *memoryCms) clone() *memoryCms {
+	c := &memoryCms{
+		users:      make(map[string]*memoryUser, len(d.users)),
+		revisions:  memstore.CloneAll(d.revisions),
+		categories: memstore.CloneAll(d.categories),
+		authors:    memstore.CloneAll(d.authors),
+		tags:       memstore.CloneAll(d.tags),
+		redirects:  maps.Clone(d.redirects),
+		sessions:   maps.Clone(d.sessions),
+		dailyViews: maps.Clone(d.dailyViews),
+	}
+	for id, u := range d.users {
+		copied := *u
+		c.users[id] = &copied
+	}
+	for _, p := range d.posts {
+		copied := *p
+		copied.post = proto.Clone(p.post).(*pb.Post)
+		copied.tags = slices.Clone(p.tags)
+		copied.explicit = slices.Clone(p.explicit)
+		copied.shortcode = slices.Clone(p.shortcode)
+		copied.media = slices.Clone(p.media)
+		c.posts = append(c.posts, &copied)
+	}
+	for _, comment := range d.comments {
+		copied := *comment
+		copied.comment = proto.Clone(comment.comment).(*pb.Comment)
+		c.comments = append(c.comments, &copied)
+	}
+	for _, asset := range d.media {
+		copied := *asset
+		copied.asset = proto.Clone(asset.asset).(*pb.MediaAsset)
+		c.media = append(c.media, &copied)
+	}
+	return c
+}
+
+// NewMemoryRepository returns an empty MemoryRepository.
+func NewMemoryRepository() *MemoryRepository {
+	data := &memoryCms{
+		users:      make(map[string]*memoryUser),
+		redirects:  make(map[redirectKey]string),
+		sessions:   make(map[viewKey]time.Time),
+		dailyViews: make(map[dayKey]int64),
+	}
+	return &MemoryRepository{store: memstore.New(data, (*memoryCms).clone), data: data}
+}
+
+// AddUser adds or replaces a user the cms can see.
+func (m *MemoryRepository) AddUser(id, name string, role pb.UserRoles) {
+	defer m.lock()()
+	m.data.users[id] = &memoryUser{name: name, role: role}
+}
+
+func (m *MemoryRepository) lock() func() {
+	return m.store.Lock()
+}
+
+func (m *MemoryRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return m.store.Tx(func(tx *memstore.Store[memoryCms]) error {
+		return fn(&MemoryRepository{store: tx, data: m.data})
+	})
+}
+
+func (m *MemoryRepository) CreatePost(ctx context.Context, post *pb.Post) (string, error) {
+	defer m.lock()()
+
+	if m.findAuthor(post.AuthorId.GetValue()) == nil || m.findCategory(post.CategoryId.GetValue()) == nil {
+		return "", database.ErrForeignKey
+	}
+
+	id := uuid.NewString()
+	stored := &memoryPost{post: &pb.Post{
+		PostId:             &pb.UUID{Value: id},
+		CoverImage:         post.CoverImage,
+		Title:              post.Title,
+		Description:        post.Description,
+		Content:            post.Content,
+		Status:             post.Status,
+		AuthorId:           &pb.UUID{Value: post.AuthorId.GetValue()},
+		CategoryId:         &pb.UUID{Value: post.CategoryId.GetValue()},
+		ContentHtml:        post.ContentHtml,
+		Toc:                memstore.CloneAll(post.Toc),
+		WordCount:          post.WordCount,
+		ReadingTimeMinutes: post.ReadingTimeMinutes,
+	}, updated: time.Now()}
+	stored.post.Slug = m.setSlug("content", id, post.Slug, post.Title, "", "")
+	m.data.posts = append(m.data.posts, stored)
+	return id, nil
+}
+
+func (m *MemoryRepository) GetPost(ctx context.Context, id string, includeUnpublished bool) (*pb.Post, error) {
+	defer m.lock()()
+
+	p := m.findPost(id)
+	if p == nil || !includeUnpublished && p.post.Status != pb.PostStatus_PUBLISHED {
+		return nil, database.ErrNotFound
+	}
+	return postView(p), nil
+}
+
+func (m *MemoryRepository) GetPostBySlug(ctx context.Context, slug string, includeUnpublished bool) (*pb.Post, error) {
+	defer m.lock()()
+
+	for _, p := range m.data.posts {
+		if p.post.Slug == slug && (includeUnpublished || p.post.Status == pb.PostStatus_PUBLISHED) {
+			return postView(p), nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) UpdatePost(ctx context.Context, post *pb.Post) error {
+	defer m.lock()()
+
+	p := m.findPost(post.PostId.GetValue())
+	if p == nil {
+		return database.ErrNotFound
+	}
+	if m.findAuthor(post.AuthorId.GetValue()) == nil || m.findCategory(post.CategoryId.GetValue()) == nil {
+		return database.ErrForeignKey
+	}
+
+	p.post.Slug = m.setSlug("content", p.post.PostId.Value, post.Slug, post.Title, p.post.Slug, p.post.Title)
+	p.post.CoverImage = post.CoverImage
+	p.post.Title = post.Title
+	p.post.Description = post.Description
+	p.post.Content = post.Content
+	p.post.AuthorId = &pb.UUID{Value: post.AuthorId.GetValue()}
+	p.post.CategoryId = &pb.UUID{Value: post.CategoryId.GetValue()}
+	p.post.ContentHtml = post.ContentHtml
+	p.post.Toc = memstore.CloneAll(post.Toc)
+	p.post.WordCount = post.WordCount
+	p.post.ReadingTimeMinutes = post.ReadingTimeMinutes
+	p.updated = time.Now()
+	return nil
+}
+
+func (m *MemoryRepository) DeletePost(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	i := slices.IndexFunc(m.data.posts, func(p *memoryPost) bool { return p.post.PostId.Value == id })
+	if i < 0 {
+		return database.ErrNotFound
+	}
+	m.data.posts = slices.Delete(m.data.posts, i, i+1)
+
+	// everything hanging off the post goes with it.
+	m.data.revisions = slices.DeleteFunc(m.data.revisions, func(r *pb.PostRevision) bool {
+		return r.PostId.Value == id
+	})
+	m.data.comments = slices.DeleteFunc(m.data.comments, func(c *memoryComment) bool {
+		return c.comment.PostId.Value == id
+	})
+	for key := range m.data.sessions {
+		if key.postId == id {
+			delete(m.data.sessions, key)
+		}
+	}
+	for key := range m.data.dailyViews {
+		if key.postId == id {
+			delete(m.data.dailyViews, key)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) ListPosts(ctx context.Context, filter PostFilter) ([]*pb.Post, error) {
+	defer m.lock()()
+
+	matched := m.matchPosts(filter)
+	slices.SortFunc(matched, func(a, b *memoryPost) int {
+		// published_date DESC NULLS LAST, updated_date DESC, id
+		if a.published.IsZero() != b.published.IsZero() {
+			if a.published.IsZero() {
+				return 1
+			}
+			return -1
+		}
+		if c := b.published.Compare(a.published); c != 0 {
+			return c
+		}
+		if c := b.updated.Compare(a.updated); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.post.PostId.Value, b.post.PostId.Value)
+	})
+
+	var posts []*pb.Post
+	for _, p := range memstore.Page(matched, filter.Limit, filter.Offset) {
+		posts = append(posts, postView(p))
+	}
+	return posts, nil
+}
+
+func (m *MemoryRepository) CountPosts(ctx context.Context, filter PostFilter) (int32, error) {
+	defer m.lock()()
+	return int32(len(m.matchPosts(filter))), nil
+}
+
+func (m *MemoryRepository) matchPosts(filter PostFilter) []*memoryPost {
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []pb.PostStatus{pb.PostStatus_PUBLISHED}
+	}
+
+	var matched []*memoryPost
+	for _, p := range m.data.posts {
+		switch {
+		case !slices.Contains(statuses, p.post.Status):
+		case filter.CategoryId != "" && p.post.CategoryId.Value != filter.CategoryId:
+		case filter.AuthorId != "" && p.post.AuthorId.Value != filter.AuthorId:
+		case filter.ProductId != "" &&
+			!slices.Contains(p.explicit, filter.ProductId) && !slices.Contains(p.shortcode, filter.ProductId):
+		case len(filter.TagSlugs) > 0 && !m.hasTags(p, filter.TagSlugs, filter.AllTags):
+		default:
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+func (m *MemoryRepository) hasTags(p *memoryPost, slugs []string, all bool) bool {
+	found := 0
+	for _, tag := range m.data.tags {
+		if slices.Contains(slugs, tag.Slug) && slices.Contains(p.tags, tag.TagId.Value) {
+			found++
+		}
+	}
+	if all {
+		return found == len(slugs)
+	}
+	return found > 0
+}
+
+func (m *MemoryRepository) TransitionPost(
+	ctx context.Context,
+	id string,
+	transition PostTransition,
+) (pb.PostStatus, error) {
+	defer m.lock()()
+
+	p := m.findPost(id)
+	if p == nil {
+		return 0, database.ErrNotFound
+	}
+	if !slices.Contains(transition.From, p.post.Status) {
+		return p.post.Status, ErrPostStatus
+	}
+
+	now := time.Now()
+	p.post.Status = transition.To
+	p.updated = now
+	if transition.ReviewerId != "" {
+		p.reviewedBy = transition.ReviewerId
+		p.reviewedAt = now
+	}
+	if transition.ReviewNote != nil {
+		p.post.ReviewNote = *transition.ReviewNote
+	}
+	if transition.PublishAt != nil {
+		p.publishAt = *transition.PublishAt
+		if transition.To == pb.PostStatus_PUBLISHED {
+			p.published = *transition.PublishAt
+		}
+	}
+	return transition.To, nil
+}
+
+func (m *MemoryRepository) PublishScheduledPosts(ctx context.Context) (int64, error) {
+	defer m.lock()()
+
+	now := time.Now()
+	var n int64
+	for _, p := range m.data.posts {
+		if p.post.Status == pb.PostStatus_SCHEDULED && !p.publishAt.IsZero() && !p.publishAt.After(now) {
+			p.post.Status = pb.PostStatus_PUBLISHED
+			p.published = p.publishAt
+			p.updated = now
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryRepository) SetPostTags(ctx context.Context, postId string, tagIds []string) error {
+	defer m.lock()()
+
+	var tags []string
+	for _, id := range tagIds {
+		id, err := canonicalId(id)
+		if err != nil {
+			return err
+		}
+		if m.findTag(id) != nil && !slices.Contains(tags, id) {
+			tags = append(tags, id)
+		}
+	}
+
+	p := m.findPost(postId)
+	if p == nil {
+		if len(tags) > 0 {
+			return database.ErrForeignKey
+		}
+		return nil
+	}
+	p.tags = tags
+	return nil
+}
+
+func (m *MemoryRepository) ListPostTags(ctx context.Context, postId string) ([]*pb.Tag, error) {
+	defer m.lock()()
+
+	p := m.findPost(postId)
+	if p == nil {
+		return nil, nil
+	}
+	var tags []*pb.Tag
+	for _, tag := range m.data.tags {
+		if slices.Contains(p.tags, tag.TagId.Value) {
+			tags = append(tags, m.tagView(tag))
+		}
+	}
+	slices.SortStableFunc(tags, func(a, b *pb.Tag) int { return cmp.Compare(a.Name, b.Name) })
+	return tags, nil
+}
+
+func (m *MemoryRepository) SyncPostMedia(ctx context.Context, postId string) error {
+	defer m.lock()()
+
+	p := m.findPost(postId)
+	if p == nil {
+		return nil
+	}
+	p.media = nil
+	for _, a := range m.data.media {
+		if url := a.asset.Url; p.post.CoverImage == url || strings.Contains(p.post.Content, url) {
+			p.media = append(p.media, a.asset.AssetId.Value)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) SetPostProducts(ctx context.Context, postId string, explicit bool, ids []string) error {
+	defer m.lock()()
+
+	var products []string
+	for _, id := range ids {
+		id, err := canonicalId(id)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(products, id) {
+			products = append(products, id)
+		}
+	}
+
+	p := m.findPost(postId)
+	if p == nil {
+		if len(products) > 0 {
+			return database.ErrForeignKey
+		}
+		return nil
+	}
+
+	linked, other := &p.shortcode, p.explicit
+	if explicit {
+		linked, other = &p.explicit, p.shortcode
+	}
+	// a product linked by hand and embedded is kept as explicit.
+	*linked = slices.DeleteFunc(products, func(id string) bool { return slices.Contains(other, id) })
+	return nil
+}
+
+func (m *MemoryRepository) ListPostProducts(ctx context.Context, postId string) ([]PostProduct, error) {
+	defer m.lock()()
+
+	p := m.findPost(postId)
+	if p == nil {
+		return nil, nil
+	}
+	var products []PostProduct
+	for _, id := range p.explicit {
+		products = append(products, PostProduct{ProductId: id, Explicit: true})
+	}
+	for _, id := range p.shortcode {
+		products = append(products, PostProduct{ProductId: id})
+	}
+	return products, nil
+}
+
+func (m *MemoryRepository) RecordRevision(ctx context.Context, postId, editorId string) (int32, error) {
+	defer m.lock()()
+
+	p := m.findPost(postId)
+	if p == nil {
+		return 0, database.ErrNotFound
+	}
+	if editorId == "" {
+		if author := m.findAuthor(p.post.AuthorId.Value); author != nil {
+			editorId = author.UserId.Value
+		}
+	} else if m.data.users[editorId] == nil {
+		return 0, database.ErrForeignKey
+	}
+
+	var revision int32
+	for _, r := range m.data.revisions {
+		if r.PostId.Value == postId {
+			revision = max(revision, r.Revision)
+		}
+	}
+	revision++
+
+	rev := &pb.PostRevision{
+		RevisionId:  &pb.UUID{Value: uuid.NewString()},
+		PostId:      &pb.UUID{Value: postId},
+		Revision:    revision,
+		CreatedAt:   time.Now().Format(time.RFC3339),
+		Title:       p.post.Title,
+		Description: p.post.Description,
+		Slug:        p.post.Slug,
+		CoverImage:  p.post.CoverImage,
+		Content:     p.post.Content,
+	}
+	if editorId != "" {
+		rev.EditorId = &pb.UUID{Value: editorId}
+	}
+	m.data.revisions = append(m.data.revisions, rev)
+	return revision, nil
+}
+
+func (m *MemoryRepository) ListRevisions(ctx context.Context, postId string) ([]*pb.PostRevision, error) {
+	defer m.lock()()
+
+	var revisions []*pb.PostRevision
+	for _, r := range m.data.revisions {
+		if r.PostId.Value == postId {
+			rev := proto.Clone(r).(*pb.PostRevision)
+			rev.Content = ""
+			revisions = append(revisions, rev)
+		}
+	}
+	slices.SortFunc(revisions, func(a, b *pb.PostRevision) int { return cmp.Compare(b.Revision, a.Revision) })
+	return revisions, nil
+}
+
+func (m *MemoryRepository) GetRevision(ctx context.Context, postId string, revision int32) (*pb.PostRevision, error) {
+	defer m.lock()()
+
+	for _, r := range m.data.revisions {
+		if r.PostId.Value == postId && r.Revision == revision {
+			return proto.Clone(r).(*pb.PostRevision), nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) RedirectSlug(
+	ctx context.Context,
+	entity, slug string,
+	includeUnpublished bool,
+) (string, error) {
+	defer m.lock()()
+
+	target, ok := m.data.redirects[redirectKey{entity, slug}]
+	if !ok {
+		return "", nil
+	}
+	switch entity {
+	case "content":
+		if p := m.findPost(target); p != nil && (includeUnpublished || p.post.Status == pb.PostStatus_PUBLISHED) {
+			return p.post.Slug, nil
+		}
+	case "categories":
+		if c := m.findCategory(target); c != nil {
+			return c.Slug, nil
+		}
+	case "authors":
+		if a := m.findAuthor(target); a != nil {
+			return a.Slug, nil
+		}
+	}
+	return "", nil
+}
+
+func (m *MemoryRepository) CreateCategory(ctx context.Context, category *pb.Category) (string, error) {
+	defer m.lock()()
+
+	id := uuid.NewString()
+	m.data.categories = append(m.data.categories, &pb.Category{
+		CategoryId:  &pb.UUID{Value: id},
+		Name:        category.Name,
+		Slug:        m.setSlug("categories", id, category.Slug, category.Name, "", ""),
+		Description: category.Description,
+	})
+	return id, nil
+}
+
+func (m *MemoryRepository) GetCategory(ctx context.Context, id string) (*pb.Category, error) {
+	defer m.lock()()
+
+	if c := m.findCategory(id); c != nil {
+		return proto.Clone(c).(*pb.Category), nil
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) GetCategoryBySlug(ctx context.Context, slug string) (*pb.Category, error) {
+	defer m.lock()()
+
+	for _, c := range m.data.categories {
+		if c.Slug == slug {
+			return proto.Clone(c).(*pb.Category), nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) UpdateCategory(ctx context.Context, category *pb.Category) error {
+	defer m.lock()()
+
+	c := m.findCategory(category.CategoryId.GetValue())
+	if c == nil {
+		return database.ErrNotFound
+	}
+	c.Slug = m.setSlug("categories", c.CategoryId.Value, category.Slug, category.Name, c.Slug, c.Name)
+	c.Name = category.Name
+	c.Description = category.Description
+	return nil
+}
+
+func (m *MemoryRepository) DeleteCategory(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	i := slices.IndexFunc(m.data.categories, func(c *pb.Category) bool { return c.CategoryId.Value == id })
+	if i < 0 {
+		return database.ErrNotFound
+	}
+	if slices.ContainsFunc(m.data.posts, func(p *memoryPost) bool { return p.post.CategoryId.Value == id }) {
+		return database.ErrForeignKey
+	}
+	m.data.categories = slices.Delete(m.data.categories, i, i+1)
+	return nil
+}
+
+func (m *MemoryRepository) ListCategories(ctx context.Context, limit, offset int32) ([]*pb.Category, error) {
+	defer m.lock()()
+	return memstore.CloneAll(memstore.Page(m.data.categories, limit, offset)), nil
+}
+
+func (m *MemoryRepository) CreateAuthor(ctx context.Context, author *pb.Author) (string, error) {
+	defer m.lock()()
+
+	user := m.data.users[author.UserId.GetValue()]
+	if user == nil {
+		return "", database.ErrForeignKey
+	}
+	if slices.ContainsFunc(m.data.authors, func(a *pb.Author) bool { return a.UserId.Value == author.UserId.Value }) {
+		return "", database.ErrConflict
+	}
+
+	id := uuid.NewString()
+	m.data.authors = append(m.data.authors, &pb.Author{
+		AuthorId: &pb.UUID{Value: id},
+		Bio:      author.Bio,
+		Avatar:   author.Avatar,
+		Url:      author.Url,
+		UserId:   &pb.UUID{Value: author.UserId.Value},
+		Slug:     m.setSlug("authors", id, author.Slug, user.name, "", ""),
+	})
+	return id, nil
+}
+
+func (m *MemoryRepository) GetAuthor(ctx context.Context, id string) (*pb.Author, error) {
+	defer m.lock()()
+
+	if a := m.findAuthor(id); a != nil {
+		return proto.Clone(a).(*pb.Author), nil
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) GetAuthorBySlug(ctx context.Context, slug string) (*pb.Author, error) {
+	defer m.lock()()
+
+	for _, a := range m.data.authors {
+		if a.Slug == slug {
+			return proto.Clone(a).(*pb.Author), nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) UpdateAuthor(ctx context.Context, author *pb.Author) error {
+	defer m.lock()()
+
+	a := m.findAuthor(author.AuthorId.GetValue())
+	if a == nil {
+		return database.ErrNotFound
+	}
+
+	slug := author.Slug
+	if slug == "" {
+		slug = a.Slug
+	}
+	var name string
+	if user := m.data.users[a.UserId.Value]; user != nil {
+		name = user.name
+	}
+	a.Slug = m.setSlug("authors", a.AuthorId.Value, slug, name, a.Slug, name)
+	a.Bio = author.Bio
+	a.Avatar = author.Avatar
+	a.Url = author.Url
+	return nil
+}
+
+func (m *MemoryRepository) DeleteAuthor(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	i := slices.IndexFunc(m.data.authors, func(a *pb.Author) bool { return a.AuthorId.Value == id })
+	if i < 0 {
+		return database.ErrNotFound
+	}
+	if slices.ContainsFunc(m.data.posts, func(p *memoryPost) bool { return p.post.AuthorId.Value == id }) {
+		return database.ErrForeignKey
+	}
+	m.data.authors = slices.Delete(m.data.authors, i, i+1)
+	return nil
+}
+
+func (m *MemoryRepository) ListAuthors(ctx context.Context, limit, offset int32) ([]*pb.Author, error) {
+	defer m.lock()()
+	return memstore.CloneAll(memstore.Page(m.data.authors, limit, offset)), nil
+}
+
+func (m *MemoryRepository) CreateTag(ctx context.Context, tag *pb.Tag) (string, error) {
+	defer m.lock()()
+
+	id := uuid.NewString()
+	slug := tagSlug(tag.Name, tag.Slug)
+	if m.tagSlugTaken(slug, id) {
+		return "", database.ErrConflict
+	}
+	m.data.tags = append(m.data.tags, &pb.Tag{
+		TagId:       &pb.UUID{Value: id},
+		Name:        tag.Name,
+		Slug:        slug,
+		Description: tag.Description,
+	})
+	return id, nil
+}
+
+func (m *MemoryRepository) GetTag(ctx context.Context, id string) (*pb.Tag, error) {
+	defer m.lock()()
+
+	if t := m.findTag(id); t != nil {
+		return m.tagView(t), nil
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) GetTagBySlug(ctx context.Context, slug string) (*pb.Tag, error) {
+	defer m.lock()()
+
+	for _, t := range m.data.tags {
+		if t.Slug == slug {
+			return m.tagView(t), nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) UpdateTag(ctx context.Context, tag *pb.Tag) error {
+	defer m.lock()()
+
+	t := m.findTag(tag.TagId.GetValue())
+	if t == nil {
+		return database.ErrNotFound
+	}
+	slug := tagSlug(tag.Name, tag.Slug)
+	if m.tagSlugTaken(slug, t.TagId.Value) {
+		return database.ErrConflict
+	}
+	t.Name = tag.Name
+	t.Slug = slug
+	t.Description = tag.Description
+	return nil
+}
+
+func (m *MemoryRepository) DeleteTag(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	i := slices.IndexFunc(m.data.tags, func(t *pb.Tag) bool { return t.TagId.Value == id })
+	if i < 0 {
+		return database.ErrNotFound
+	}
+	m.data.tags = slices.Delete(m.data.tags, i, i+1)
+	for _, p := range m.data.posts {
+		p.tags = slices.DeleteFunc(p.tags, func(tagId string) bool { return tagId == id })
+	}
+	return nil
+}
+
+func (m *MemoryRepository) ListTags(ctx context.Context, limit, offset int32) ([]*pb.Tag, error) {
+	defer m.lock()()
+
+	sorted := slices.Clone(m.data.tags)
+	slices.SortFunc(sorted, func(a, b *pb.Tag) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.TagId.Value, b.TagId.Value))
+	})
+	var tags []*pb.Tag
+	for _, t := range memstore.Page(sorted, limit, offset) {
+		tags = append(tags, m.tagView(t))
+	}
+	return tags, nil
+}
+
+func (m *MemoryRepository) GetUserRole(ctx context.Context, userId string) (pb.UserRoles, error) {
+	defer m.lock()()
+
+	user := m.data.users[userId]
+	if user == nil {
+		return 0, database.ErrNotFound
+	}
+	return user.role, nil
+}
+
+func (m *MemoryRepository) UpdateUserRole(ctx context.Context, userId string, role pb.UserRoles) error {
+	defer m.lock()()
+
+	user := m.data.users[userId]
+	if user == nil {
+		return database.ErrNotFound
+	}
+	user.role = role
+	return nil
+}
+
+func (m *MemoryRepository) CreateComment(ctx context.Context, comment *pb.Comment, spamScore int) (string, error) {
+	defer m.lock()()
+
+	parentId := comment.ParentId.GetValue()
+	if m.findPost(comment.PostId.GetValue()) == nil ||
+		m.data.users[comment.UserId.GetValue()] == nil ||
+		parentId != "" && m.findComment(parentId) == nil {
+		return "", database.ErrForeignKey
+	}
+
+	id := uuid.NewString()
+	now := time.Now()
+	stored := &memoryComment{comment: &pb.Comment{
+		CommentId: &pb.UUID{Value: id},
+		PostId:    &pb.UUID{Value: comment.PostId.Value},
+		UserId:    &pb.UUID{Value: comment.UserId.Value},
+		Body:      comment.Body,
+		Status:    comment.Status,
+		CreatedAt: now.Format(time.RFC3339),
+	}, spamScore: spamScore, createdAt: now}
+	if parentId != "" {
+		stored.comment.ParentId = &pb.UUID{Value: parentId}
+	}
+	m.data.comments = append(m.data.comments, stored)
+	return id, nil
+}
+
+func (m *MemoryRepository) GetComment(ctx context.Context, id string) (*pb.Comment, error) {
+	defer m.lock()()
+
+	c := m.findComment(id)
+	if c == nil || m.data.users[c.comment.UserId.Value] == nil {
+		return nil, database.ErrNotFound
+	}
+	return m.commentView(c), nil
+}
+
+func (m *MemoryRepository) CountComments(ctx context.Context, postId string) (int32, int32, error) {
+	defer m.lock()()
+
+	var threads, total int32
+	for _, c := range m.data.comments {
+		if c.comment.PostId.Value == postId && c.comment.Status == pb.CommentStatus_APPROVED {
+			total++
+			if c.comment.ParentId == nil {
+				threads++
+			}
+		}
+	}
+	return threads, total, nil
+}
+
+func (m *MemoryRepository) ListThreads(ctx context.Context, postId string, limit, offset int32) ([]*pb.Comment, error) {
+	defer m.lock()()
+
+	var roots []*memoryComment
+	for _, c := range m.data.comments {
+		if c.comment.PostId.Value == postId && c.comment.ParentId == nil && m.visible(c) {
+			roots = append(roots, c)
+		}
+	}
+	slices.SortFunc(roots, oldestFirst)
+
+	var comments []*pb.Comment
+	for _, c := range memstore.Page(roots, limit, offset) {
+		comments = append(comments, m.commentView(c))
+	}
+	return comments, nil
+}
+
+func (m *MemoryRepository) ListReplies(ctx context.Context, rootIds []string) ([]*pb.Comment, error) {
+	defer m.lock()()
+
+	// walk down a level at a time until no approved reply is left.
+	parents := slices.Clone(rootIds)
+	var replies []*memoryComment
+	for len(parents) > 0 {
+		var next []string
+		for _, c := range m.data.comments {
+			if c.comment.ParentId != nil && slices.Contains(parents, c.comment.ParentId.Value) &&
+				c.comment.Status == pb.CommentStatus_APPROVED {
+				next = append(next, c.comment.CommentId.Value)
+				if m.data.users[c.comment.UserId.Value] != nil {
+					replies = append(replies, c)
+				}
+			}
+		}
+		parents = next
+	}
+	slices.SortFunc(replies, oldestFirst)
+
+	var comments []*pb.Comment
+	for _, c := range replies {
+		comments = append(comments, m.commentView(c))
+	}
+	return comments, nil
+}
+
+func (m *MemoryRepository) ListModerationQueue(
+	ctx context.Context,
+	filter ModerationFilter,
+) ([]*pb.Comment, int32, error) {
+	defer m.lock()()
+
+	var matched []*memoryComment
+	for _, c := range m.data.comments {
+		if c.comment.Status != filter.Status || m.data.users[c.comment.UserId.Value] == nil {
+			continue
+		}
+		authorUserId, ok := m.postAuthorUser(c.comment.PostId.Value)
+		if ok && (filter.AuthorUserId == "" || authorUserId == filter.AuthorUserId) {
+			matched = append(matched, c)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *memoryComment) int {
+		return cmp.Or(b.createdAt.Compare(a.createdAt), cmp.Compare(a.comment.CommentId.Value, b.comment.CommentId.Value))
+	})
+
+	var comments []*pb.Comment
+	for _, c := range memstore.Page(matched, filter.Limit, filter.Offset) {
+		comments = append(comments, m.commentView(c))
+	}
+	return comments, int32(len(matched)), nil
+}
+
+func (m *MemoryRepository) CommentPostAuthor(ctx context.Context, commentId string) (string, error) {
+	defer m.lock()()
+
+	c := m.findComment(commentId)
+	if c == nil {
+		return "", database.ErrNotFound
+	}
+	userId, ok := m.postAuthorUser(c.comment.PostId.Value)
+	if !ok {
+		return "", database.ErrNotFound
+	}
+	return userId, nil
+}
+
+func (m *MemoryRepository) ModerateComment(
+	ctx context.Context,
+	commentId string,
+	status pb.CommentStatus,
+	moderatorId string,
+) error {
+	defer m.lock()()
+
+	c := m.findComment(commentId)
+	if c == nil {
+		return database.ErrNotFound
+	}
+	if m.data.users[moderatorId] == nil {
+		return database.ErrForeignKey
+	}
+	c.comment.Status = status
+	c.moderatedBy = moderatorId
+	c.moderatedAt = time.Now()
+	return nil
+}
+
+// LockCommenter has nothing to do, WithTx already holds the lock.
+func (m *MemoryRepository) LockCommenter(ctx context.Context, userId string) error {
+	return nil
+}
+
+func (m *MemoryRepository) CommentHistory(ctx context.Context, userId string, since time.Time) (int, bool, error) {
+	defer m.lock()()
+
+	var recent int
+	var trusted bool
+	for _, c := range m.data.comments {
+		if c.comment.UserId.Value != userId {
+			continue
+		}
+		if c.createdAt.After(since) {
+			recent++
+		}
+		if c.comment.Status == pb.CommentStatus_APPROVED {
+			trusted = true
+		}
+	}
+	return recent, trusted, nil
+}
+
+func (m *MemoryRepository) RecentCommentBodies(ctx context.Context, userId string, limit int) ([]string, error) {
+	defer m.lock()()
+
+	var theirs []*memoryComment
+	for _, c := range m.data.comments {
+		if c.comment.UserId.Value == userId {
+			theirs = append(theirs, c)
+		}
+	}
+	slices.SortStableFunc(theirs, func(a, b *memoryComment) int { return b.createdAt.Compare(a.createdAt) })
+
+	var bodies []string
+	for _, c := range memstore.Page(theirs, int32(limit), 0) {
+		bodies = append(bodies, c.comment.Body)
+	}
+	return bodies, nil
+}
+
+func (m *MemoryRepository) CreateMedia(ctx context.Context, asset *pb.MediaAsset, storageKey string) (string, error) {
+	defer m.lock()()
+
+	if slices.ContainsFunc(m.data.media, func(a *memoryMedia) bool {
+		return a.storageKey == storageKey || a.asset.Url == asset.Url
+	}) {
+		return "", database.ErrConflict
+	}
+	uploadedBy := asset.UploadedBy.GetValue()
+	if uploadedBy != "" && m.data.users[uploadedBy] == nil {
+		return "", database.ErrForeignKey
+	}
+
+	id := uuid.NewString()
+	now := time.Now()
+	stored := &memoryMedia{asset: &pb.MediaAsset{
+		AssetId:     &pb.UUID{Value: id},
+		Url:         asset.Url,
+		FileName:    asset.FileName,
+		ContentType: asset.ContentType,
+		SizeBytes:   asset.SizeBytes,
+		Width:       asset.Width,
+		Height:      asset.Height,
+		AltText:     asset.AltText,
+		CreatedAt:   now.Format(time.RFC3339),
+	}, storageKey: storageKey, createdAt: now}
+	if uploadedBy != "" {
+		stored.asset.UploadedBy = &pb.UUID{Value: uploadedBy}
+	}
+	m.data.media = append(m.data.media, stored)
+	return id, nil
+}
+
+func (m *MemoryRepository) GetMedia(ctx context.Context, id string) (*pb.MediaAsset, error) {
+	defer m.lock()()
+
+	a := m.findMedia(id)
+	if a == nil {
+		return nil, database.ErrNotFound
+	}
+	return m.mediaView(a), nil
+}
+
+func (m *MemoryRepository) ListMediaUsages(ctx context.Context, id string) ([]*pb.MediaUsage, error) {
+	defer m.lock()()
+
+	a := m.findMedia(id)
+	if a == nil {
+		return nil, nil
+	}
+	usages := m.usages(a)
+	slices.SortStableFunc(usages, func(a, b *pb.MediaUsage) int {
+		return cmp.Or(cmp.Compare(b.Kind, a.Kind), cmp.Compare(a.Title, b.Title))
+	})
+	return usages, nil
+}
+
+func (m *MemoryRepository) ListMedia(
+	ctx context.Context,
+	query string,
+	limit, offset int32,
+) ([]*pb.MediaAsset, int32, error) {
+	defer m.lock()()
+
+	query = strings.ToLower(query)
+	var matched []*memoryMedia
+	for _, a := range m.data.media {
+		if strings.Contains(strings.ToLower(a.asset.FileName), query) ||
+			strings.Contains(strings.ToLower(a.asset.AltText), query) {
+			matched = append(matched, a)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *memoryMedia) int {
+		return cmp.Or(b.createdAt.Compare(a.createdAt), cmp.Compare(a.asset.AssetId.Value, b.asset.AssetId.Value))
+	})
+
+	var assets []*pb.MediaAsset
+	for _, a := range memstore.Page(matched, limit, offset) {
+		assets = append(assets, m.mediaView(a))
+	}
+	return assets, int32(len(matched)), nil
+}
+
+func (m *MemoryRepository) UpdateMediaAltText(ctx context.Context, id, altText string) error {
+	defer m.lock()()
+
+	a := m.findMedia(id)
+	if a == nil {
+		return database.ErrNotFound
+	}
+	a.asset.AltText = altText
+	return nil
+}
+
+func (m *MemoryRepository) DeleteMedia(ctx context.Context, id string) (string, error) {
+	defer m.lock()()
+
+	i := slices.IndexFunc(m.data.media, func(a *memoryMedia) bool { return a.asset.AssetId.Value == id })
+	if i < 0 {
+		return "", database.ErrNotFound
+	}
+	a := m.data.media[i]
+	if len(m.usages(a)) > 0 {
+		return "", ErrMediaInUse
+	}
+	m.data.media = slices.Delete(m.data.media, i, i+1)
+	return a.storageKey, nil
+}
+
+func (m *MemoryRepository) RecordPostView(
+	ctx context.Context,
+	postId, viewer string,
+	window time.Duration,
+) (bool, error) {
+	defer m.lock()()
+
+	postId, err := canonicalId(postId)
+	if err != nil {
+		return false, err
+	}
+	p := m.findPost(postId)
+	if p == nil || p.post.Status != pb.PostStatus_PUBLISHED {
+		return false, nil
+	}
+
+	now := time.Now()
+	key := viewKey{postId, viewer}
+	if last, ok := m.data.sessions[key]; ok && !last.Before(now.Add(-window)) {
+		return false, nil
+	}
+	m.data.sessions[key] = now
+	m.data.dailyViews[dayKey{postId, now.UTC().Format(time.DateOnly)}]++
+	return true, nil
+}
+
+func (m *MemoryRepository) PopularPosts(
+	ctx context.Context,
+	days, limit int32,
+	categoryId string,
+) ([]*pb.PopularPost, error) {
+	defer m.lock()()
+
+	// today counts as one of the days.
+	since := time.Now().UTC().AddDate(0, 0, -int(days)).Format(time.DateOnly)
+	views := make(map[string]int64)
+	for key, n := range m.data.dailyViews {
+		if key.day > since {
+			views[key.postId] += n
+		}
+	}
+
+	var matched []*memoryPost
+	for _, p := range m.data.posts {
+		if views[p.post.PostId.Value] > 0 && p.post.Status == pb.PostStatus_PUBLISHED &&
+			(categoryId == "" || p.post.CategoryId.Value == categoryId) {
+			matched = append(matched, p)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *memoryPost) int {
+		return cmp.Or(
+			cmp.Compare(views[b.post.PostId.Value], views[a.post.PostId.Value]),
+			b.published.Compare(a.published),
+		)
+	})
+
+	var posts []*pb.PopularPost
+	for _, p := range memstore.Page(matched, limit, 0) {
+		posts = append(posts, &pb.PopularPost{Post: postView(p), Views: views[p.post.PostId.Value]})
+	}
+	return posts, nil
+}
+
+func (m *MemoryRepository) PruneViewSessions(ctx context.Context, window time.Duration) (int64, error) {
+	defer m.lock()()
+
+	cutoff := time.Now().Add(-window)
+	var n int64
+	for key, last := range m.data.sessions {
+		if last.Before(cutoff) {
+			delete(m.data.sessions, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryRepository) SitemapChunks(ctx context.Context, chunkSize int32) ([]*pb.SitemapChunk, error) {
+	defer m.lock()()
+
+	var chunks []*pb.SitemapChunk
+	var lastModified time.Time
+	for i, p := range m.sitemapPosts() {
+		if i%int(chunkSize) == 0 {
+			lastModified = time.Time{}
+			chunks = append(chunks, &pb.SitemapChunk{Index: int32(i / int(chunkSize))})
+		}
+		if modified := postLastModifiedAt(p); modified.After(lastModified) {
+			lastModified = modified
+			chunks[len(chunks)-1].LastModified = modified.Format(time.RFC3339)
+		}
+	}
+	return chunks, nil
+}
+
+func (m *MemoryRepository) SitemapEntries(ctx context.Context, limit, offset int32) ([]*pb.SitemapEntry, error) {
+	defer m.lock()()
+
+	var entries []*pb.SitemapEntry
+	for _, p := range memstore.Page(m.sitemapPosts(), limit, offset) {
+		entries = append(entries, &pb.SitemapEntry{
+			Slug:      p.post.Slug,
+			UpdatedAt: postLastModifiedAt(p).Format(time.RFC3339),
+		})
+	}
+	return entries, nil
+}
+
+// sitemapPosts returns the published posts in id order.
+func (m *MemoryRepository) sitemapPosts() []*memoryPost {
+	var posts []*memoryPost
+	for _, p := range m.data.posts {
+		if p.post.Status == pb.PostStatus_PUBLISHED {
+			posts = append(posts, p)
+		}
+	}
+	slices.SortFunc(posts, func(a, b *memoryPost) int { return cmp.Compare(a.post.PostId.Value, b.post.PostId.Value) })
+	return posts
+}
+
+// postLastModifiedAt mirrors postLastModified.
+func postLastModifiedAt(p *memoryPost) time.Time {
+	if p.published.After(p.updated) {
+		return p.published
+	}
+	return p.updated
+}
+
+func postView(p *memoryPost) *pb.Post {
+	post := proto.Clone(p.post).(*pb.Post)
+	post.UpdatedDate = p.updated.Format(time.RFC3339Nano)
+	if !p.published.IsZero() {
+		post.PublishedDate = p.published.Format(time.RFC3339)
+	}
+	if !p.publishAt.IsZero() {
+		post.PublishAt = p.publishAt.Format(time.RFC3339)
+	}
+	return post
+}
+
+func (m *MemoryRepository) tagView(t *pb.Tag) *pb.Tag {
+	tag := proto.Clone(t).(*pb.Tag)
+	for _, p := range m.data.posts {
+		if p.post.Status == pb.PostStatus_PUBLISHED && slices.Contains(p.tags, t.TagId.Value) {
+			tag.PostCount++
+		}
+	}
+	return tag
+}
+
+func (m *MemoryRepository) commentView(c *memoryComment) *pb.Comment {
+	comment := proto.Clone(c.comment).(*pb.Comment)
+	if user := m.data.users[comment.UserId.Value]; user != nil {
+		comment.UserName = user.name
+	}
+	return comment
+}
+
+func (m *MemoryRepository) mediaView(a *memoryMedia) *pb.MediaAsset {
+	asset := proto.Clone(a.asset).(*pb.MediaAsset)
+	asset.UsageCount = int32(len(m.usages(a)))
+	return asset
+}
+
+// usages mirrors mediaUsages.
+func (m *MemoryRepository) usages(asset *memoryMedia) []*pb.MediaUsage {
+	url := asset.asset.Url
+	var usages []*pb.MediaUsage
+	for _, p := range m.data.posts {
+		if slices.Contains(p.media, asset.asset.AssetId.Value) {
+			usages = append(usages, &pb.MediaUsage{
+				Kind:  "post",
+				Id:    &pb.UUID{Value: p.post.PostId.Value},
+				Title: p.post.Title,
+			})
+		}
+	}
+	for _, a := range m.data.authors {
+		if user := m.data.users[a.UserId.Value]; user != nil && a.Avatar == url {
+			usages = append(usages, &pb.MediaUsage{
+				Kind:  "author",
+				Id:    &pb.UUID{Value: a.AuthorId.Value},
+				Title: user.name,
+			})
+		}
+	}
+	return usages
+}
+
+// visible reports whether a comment is approved and its writer still
+// exists, as the comment queries join on users.
+func (m *MemoryRepository) visible(c *memoryComment) bool {
+	return c.comment.Status == pb.CommentStatus_APPROVED && m.data.users[c.comment.UserId.Value] != nil
+}
+
+// postAuthorUser returns the user behind the author of a post.
+func (m *MemoryRepository) postAuthorUser(postId string) (string, bool) {
+	p := m.findPost(postId)
+	if p == nil {
+		return "", false
+	}
+	a := m.findAuthor(p.post.AuthorId.Value)
+	if a == nil {
+		return "", false
+	}
+	return a.UserId.Value, true
+}
+
+func oldestFirst(a, b *memoryComment) int {
+	return cmp.Or(a.createdAt.Compare(b.createdAt), cmp.Compare(a.comment.CommentId.Value, b.comment.CommentId.Value))
+}
+
+func (m *MemoryRepository) findPost(id string) *memoryPost {
+	for _, p := range m.data.posts {
+		if p.post.PostId.Value == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findCategory(id string) *pb.Category {
+	for _, c := range m.data.categories {
+		if c.CategoryId.Value == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findAuthor(id string) *pb.Author {
+	for _, a := range m.data.authors {
+		if a.AuthorId.Value == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findTag(id string) *pb.Tag {
+	for _, t := range m.data.tags {
+		if t.TagId.Value == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findComment(id string) *memoryComment {
+	for _, c := range m.data.comments {
+		if c.comment.CommentId.Value == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findMedia(id string) *memoryMedia {
+	for _, a := range m.data.media {
+		if a.asset.AssetId.Value == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// slugFallbacks are the slugs set_unique_slug uses when there is nothing
+// to make one from.
+var slugFallbacks = map[string]string{
+	"content":    "post",
+	"categories": "category",
+	"authors":    "author",
+}
+
+// setSlug mirrors the set_unique_slug trigger for row id of entity and
+// returns the slug the row ends up with. slug and source are what the row
+// is being saved with, oldSlug and oldSource what it had, empty for a new
+// row.
+func (m *MemoryRepository) setSlug(entity, id, slug, source, oldSlug, oldSource string) string {
+	var base string
+	switch {
+	case strings.Trim(slug, " ") == "":
+		base = slugify(source)
+	case slug == oldSlug && source != oldSource:
+		base = slugify(source)
+	default:
+		base = slugify(slug)
+	}
+	if base == "" {
+		base = slugFallbacks[entity]
+	}
+
+	candidate := base
+	for n := 2; m.slugTaken(entity, candidate, id); n++ {
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	if oldSlug != "" && oldSlug != candidate {
+		m.data.redirects[redirectKey{entity, oldSlug}] = id
+	}
+	// a slug that is in use again no longer redirects.
+	delete(m.data.redirects, redirectKey{entity, candidate})
+	return candidate
+}
+
+func (m *MemoryRepository) slugTaken(entity, slug, id string) bool {
+	switch entity {
+	case "content":
+		return slices.ContainsFunc(m.data.posts, func(p *memoryPost) bool {
+			return p.post.Slug == slug && p.post.PostId.Value != id
+		})
+	case "categories":
+		return slices.ContainsFunc(m.data.categories, func(c *pb.Category) bool {
+			return c.Slug == slug && c.CategoryId.Value != id
+		})
+	case "authors":
+		return slices.ContainsFunc(m.data.authors, func(a *pb.Author) bool {
+			return a.Slug == slug && a.AuthorId.Value != id
+		})
+	}
+	return false
+}
+
+func (m *MemoryRepository) tagSlugTaken(slug, id string) bool {
+	return slices.ContainsFunc(m.data.tags, func(t *pb.Tag) bool {
+		return t.Slug == slug && t.TagId.Value != id
+	})
+}
+
+var (
+	nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+	whitespace      = regexp.MustCompile(`\s+`)
+)
+
+// slugify mirrors the slugify SQL function.
+func slugify(value string) string {
+	slug := nonAlphanumeric.ReplaceAllString(strings.ToLower(strings.Trim(value, " ")), "-")
+	return strings.Trim(slug, "-")
+}
+
+// tagSlug mirrors the set_tag_slug trigger.
+func tagSlug(name, slug string) string {
+	if strings.Trim(slug, " ") != "" {
+		return slug
+	}
+	return strings.ToLower(whitespace.ReplaceAllString(strings.Trim(name, " "), "-"))
+}
+
+// canonicalId writes id the way Postgres reads a uuid back, failing with
+// database.ErrInvalid when it is not one.
+func canonicalId(id string) (string, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", database.ErrInvalid, err)
+	}
+	return parsed.String(), nil
+}
@@ -0,0 +1,1237 @@
+package cmsservice
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/lib/pq"
+)
+
+const postColumns = `id, published_date, updated_date, cover_image, title, description,
+  slug, content, status, author_id, category_id, publish_at, review_note,
+  content_html, toc, word_count, reading_time_minutes`
+
+const authorColumns = `id, bio, avatar, url, user_id, slug`
+
+// tagColumns selects a tag along with its count of published posts.
+const tagColumns = `t.id, t.name, t.slug, t.description,
+  (SELECT COUNT(*) FROM post_tags pt JOIN content c ON c.id = pt.post_id
+   WHERE pt.tag_id = t.id AND c.status = 'published')`
+
+const commentColumns = `pc.id, pc.post_id, pc.parent_id, pc.user_id, u.name, pc.body, pc.status, pc.created_at`
+
+// mediaUsages lists the posts and authors pointing at asset m, as a cover
+// image, inside a post body or as an avatar. Posts are found through the
+// references SyncPostMedia records.
+const mediaUsages = `SELECT 'post' AS kind, c.id, c.title FROM content_media cm
+  JOIN content c ON c.id = cm.content_id WHERE cm.media_id = m.id
+  UNION ALL
+  SELECT 'author', a.id, u.name FROM authors a JOIN users u ON u.id = a.user_id
+  WHERE a.avatar = m.url`
+
+const mediaColumns = `m.id, m.url, m.file_name, m.content_type, m.size_bytes, m.width, m.height,
+  m.alt_text, m.uploaded_by, m.created_at, (SELECT COUNT(*) FROM (` + mediaUsages + `) used)`
+
+// a post's lastmod is the later of when it went live and when it was last
+// edited. Posts are walked in id order so chunks and entries line up.
+const postLastModified = `GREATEST(updated_date, COALESCE(published_date, updated_date))`
+
+type postgresRepository struct {
+	db   database.DB
+	q    database.Querier
+	inTx bool
+}
+
+// NewPostgresRepository returns a Repository that keeps the cms in db.
+func NewPostgresRepository(db database.DB) Repository {
+	return &postgresRepository{db: db, q: db}
+}
+
+func (r *postgresRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+	return r.db.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		return fn(&postgresRepository{db: r.db, q: tx, inTx: true})
+	})
+}
+
+func (r *postgresRepository) CreatePost(ctx context.Context, post *pb.Post) (string, error) {
+	toc, err := tocJSON(post.Toc)
+	if err != nil {
+		return "", err
+	}
+
+	stmt := `INSERT INTO content (
+  updated_date,
+  cover_image,
+  title,
+  description,
+  slug,
+  content,
+  status,
+  author_id,
+  category_id,
+  content_html,
+  toc,
+  word_count,
+  reading_time_minutes
+  )VALUES (NOW(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	var postId string
+	err = r.q.QueryRowContext(ctx,
+		stmt,
+		post.CoverImage,
+		post.Title,
+		post.Description,
+		post.Slug,
+		post.Content,
+		statusToDB(post.Status),
+		post.AuthorId.GetValue(),
+		post.CategoryId.GetValue(),
+		post.ContentHtml,
+		toc,
+		post.WordCount,
+		post.ReadingTimeMinutes,
+	).Scan(&postId)
+	return postId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetPost(ctx context.Context, id string, includeUnpublished bool) (*pb.Post, error) {
+	return r.getPost(ctx, "id", id, includeUnpublished)
+}
+
+func (r *postgresRepository) GetPostBySlug(ctx context.Context, slug string, includeUnpublished bool) (*pb.Post, error) {
+	return r.getPost(ctx, "slug", slug, includeUnpublished)
+}
+
+func (r *postgresRepository) getPost(ctx context.Context, column, value string, includeUnpublished bool) (*pb.Post, error) {
+	stmt := `SELECT ` + postColumns + ` FROM content WHERE ` + column + `=$1`
+	if !includeUnpublished {
+		stmt += ` AND status='published'`
+	}
+	post, err := scanPost(r.q.QueryRowContext(ctx, stmt, value))
+	return post, database.Translate(err)
+}
+
+func (r *postgresRepository) UpdatePost(ctx context.Context, post *pb.Post) error {
+	toc, err := tocJSON(post.Toc)
+	if err != nil {
+		return err
+	}
+
+	stmt := `UPDATE content SET updated_date=NOW(), cover_image=$1, title=$2, slug=$3, content=$4, author_id=$5, category_id=$6, description=$7,
+	content_html=$8, toc=$9, word_count=$10, reading_time_minutes=$11 WHERE id=$12`
+	return r.exec(ctx,
+		stmt,
+		post.CoverImage,
+		post.Title,
+		post.Slug,
+		post.Content,
+		post.AuthorId.GetValue(),
+		post.CategoryId.GetValue(),
+		post.Description,
+		post.ContentHtml,
+		toc,
+		post.WordCount,
+		post.ReadingTimeMinutes,
+		post.PostId.GetValue(),
+	)
+}
+
+func (r *postgresRepository) DeletePost(ctx context.Context, id string) error {
+	return r.exec(ctx, `DELETE FROM content WHERE id=$1`, id)
+}
+
+func (r *postgresRepository) ListPosts(ctx context.Context, filter PostFilter) ([]*pb.Post, error) {
+	where, args := postWhere(filter)
+	stmt := fmt.Sprintf(`SELECT %s FROM content WHERE %s
+  ORDER BY published_date DESC NULLS LAST, updated_date DESC, id LIMIT $%d OFFSET $%d`,
+		postColumns, where, len(args)+1, len(args)+2,
+	)
+	return r.queryPosts(ctx, stmt, append(args, filter.Limit, filter.Offset)...)
+}
+
+func (r *postgresRepository) CountPosts(ctx context.Context, filter PostFilter) (int32, error) {
+	where, args := postWhere(filter)
+	var total int32
+	err := r.q.QueryRowContext(ctx, `SELECT COUNT(*) FROM content WHERE `+where, args...).Scan(&total)
+	return total, err
+}
+
+// postWhere turns filter into a WHERE clause and its arguments.
+func postWhere(filter PostFilter) (string, []interface{}) {
+	statuses := []string{statusToDB(pb.PostStatus_PUBLISHED)}
+	if len(filter.Statuses) > 0 {
+		statuses = statuses[:0]
+		for _, st := range filter.Statuses {
+			statuses = append(statuses, statusToDB(st))
+		}
+	}
+
+	conditions := []string{`status = ANY($1)`}
+	args := []interface{}{pq.Array(statuses)}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.CategoryId != "" {
+		add(`category_id=$%d`, filter.CategoryId)
+	}
+	if filter.AuthorId != "" {
+		add(`author_id=$%d`, filter.AuthorId)
+	}
+	if filter.ProductId != "" {
+		add(`id IN (SELECT post_id FROM post_products WHERE product_id=$%d)`, filter.ProductId)
+	}
+	if len(filter.TagSlugs) > 0 {
+		// with AllTags a post has to carry as many of the tags as were
+		// asked for.
+		having := ``
+		if filter.AllTags {
+			having = ` HAVING COUNT(DISTINCT t.id) = cardinality($%[1]d::text[])`
+		}
+		add(`id IN (SELECT pt.post_id FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+	WHERE t.slug = ANY($%[1]d::text[]) GROUP BY pt.post_id`+having+`)`, pq.Array(filter.TagSlugs))
+	}
+
+	return strings.Join(conditions, ` AND `), args
+}
+
+func (r *postgresRepository) queryPosts(ctx context.Context, stmt string, args ...interface{}) ([]*pb.Post, error) {
+	rows, err := r.q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*pb.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+func (r *postgresRepository) TransitionPost(
+	ctx context.Context,
+	id string,
+	transition PostTransition,
+) (pb.PostStatus, error) {
+	set := []string{`status=$1`, `updated_date=NOW()`}
+	args := []interface{}{statusToDB(transition.To), id}
+	add := func(column string, arg interface{}) {
+		args = append(args, arg)
+		set = append(set, fmt.Sprintf(`%s=$%d`, column, len(args)))
+	}
+
+	if transition.ReviewerId != "" {
+		add(`reviewed_by`, transition.ReviewerId)
+		set = append(set, `reviewed_at=NOW()`)
+	}
+	if transition.ReviewNote != nil {
+		add(`review_note`, *transition.ReviewNote)
+	}
+	if transition.PublishAt != nil {
+		args = append(args, sql.NullTime{Time: *transition.PublishAt, Valid: !transition.PublishAt.IsZero()})
+		set = append(set, fmt.Sprintf(`publish_at=$%d::timestamptz`, len(args)))
+		if transition.To == pb.PostStatus_PUBLISHED {
+			set = append(set, fmt.Sprintf(`published_date=$%d::timestamptz`, len(args)))
+		}
+	}
+
+	var from []string
+	for _, st := range transition.From {
+		from = append(from, statusToDB(st))
+	}
+	args = append(args, pq.Array(from))
+
+	stmt := fmt.Sprintf(`UPDATE content SET %s WHERE id=$2 AND status = ANY($%d) RETURNING id`,
+		strings.Join(set, ", "), len(args))
+	err := r.q.QueryRowContext(ctx, stmt, args...).Scan(new(string))
+	if err == nil {
+		return transition.To, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	// tell a missing post apart from one in the wrong state.
+	var current string
+	err = r.q.QueryRowContext(ctx, `SELECT status FROM content WHERE id=$1`, id).Scan(&current)
+	if err != nil {
+		return 0, database.Translate(err)
+	}
+	return statusFromDB(current), ErrPostStatus
+}
+
+func (r *postgresRepository) PublishScheduledPosts(ctx context.Context) (int64, error) {
+	stmt := `UPDATE content SET status='published', published_date=publish_at, updated_date=NOW()
+	WHERE status='scheduled' AND publish_at <= NOW()`
+	result, err := r.q.ExecContext(ctx, stmt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *postgresRepository) SetPostTags(ctx context.Context, postId string, tagIds []string) error {
+	return r.WithTx(ctx, func(repo Repository) error {
+		tx := repo.(*postgresRepository)
+		if _, err := tx.q.ExecContext(ctx, `DELETE FROM post_tags WHERE post_id=$1`, postId); err != nil {
+			return database.Translate(err)
+		}
+		if len(tagIds) == 0 {
+			return nil
+		}
+
+		stmt := `INSERT INTO post_tags (post_id, tag_id)
+	SELECT $1, id FROM tags WHERE id = ANY($2::uuid[]) ON CONFLICT DO NOTHING`
+		_, err := tx.q.ExecContext(ctx, stmt, postId, pq.Array(tagIds))
+		return database.Translate(err)
+	})
+}
+
+func (r *postgresRepository) ListPostTags(ctx context.Context, postId string) ([]*pb.Tag, error) {
+	stmt := `SELECT ` + tagColumns + ` FROM tags t JOIN post_tags p ON p.tag_id = t.id
+	WHERE p.post_id=$1 ORDER BY t.name`
+	return r.queryTags(ctx, stmt, postId)
+}
+
+func (r *postgresRepository) SetPostProducts(ctx context.Context, postId string, explicit bool, ids []string) error {
+	source := "shortcode"
+	if explicit {
+		source = "explicit"
+	}
+
+	return r.WithTx(ctx, func(repo Repository) error {
+		tx := repo.(*postgresRepository)
+		_, err := tx.q.ExecContext(ctx, `DELETE FROM post_products WHERE post_id=$1 AND source=$2`, postId, source)
+		if err != nil || len(ids) == 0 {
+			return database.Translate(err)
+		}
+
+		// a product linked by hand and embedded is kept as explicit.
+		stmt := `INSERT INTO post_products (post_id, product_id, source, position)
+	SELECT $1, t.id, $2, t.ord - 1 FROM unnest($3::uuid[]) WITH ORDINALITY AS t(id, ord)
+	ON CONFLICT DO NOTHING`
+		_, err = tx.q.ExecContext(ctx, stmt, postId, source, pq.Array(ids))
+		return database.Translate(err)
+	})
+}
+
+func (r *postgresRepository) SyncPostMedia(ctx context.Context, postId string) error {
+	return r.WithTx(ctx, func(repo Repository) error {
+		tx := repo.(*postgresRepository)
+		if _, err := tx.q.ExecContext(ctx, `DELETE FROM content_media WHERE content_id=$1`, postId); err != nil {
+			return database.Translate(err)
+		}
+
+		stmt := `INSERT INTO content_media (content_id, media_id)
+	SELECT c.id, m.id FROM content c
+	JOIN media_assets m ON c.cover_image = m.url OR strpos(c.content, m.url) > 0
+	WHERE c.id=$1`
+		_, err := tx.q.ExecContext(ctx, stmt, postId)
+		return database.Translate(err)
+	})
+}
+
+func (r *postgresRepository) ListPostProducts(ctx context.Context, postId string) ([]PostProduct, error) {
+	stmt := `SELECT product_id, source FROM post_products WHERE post_id=$1
+	ORDER BY source='shortcode', position`
+	rows, err := r.q.QueryContext(ctx, stmt, postId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []PostProduct
+	for rows.Next() {
+		var productId, source string
+		if err := rows.Scan(&productId, &source); err != nil {
+			return nil, err
+		}
+		products = append(products, PostProduct{ProductId: productId, Explicit: source == "explicit"})
+	}
+	return products, rows.Err()
+}
+
+func (r *postgresRepository) RecordRevision(ctx context.Context, postId, editorId string) (int32, error) {
+	stmt := `INSERT INTO post_revisions (post_id, revision, editor_id, title, description, slug, cover_image, content)
+	SELECT c.id,
+		COALESCE((SELECT MAX(revision) FROM post_revisions WHERE post_id=c.id), 0) + 1,
+		COALESCE($2::uuid, (SELECT user_id FROM authors WHERE id=c.author_id)),
+		c.title, c.description, c.slug, c.cover_image, c.content
+	FROM content c WHERE c.id=$1
+	RETURNING revision`
+
+	editor := sql.NullString{String: editorId, Valid: editorId != ""}
+
+	var revision int32
+	err := r.q.QueryRowContext(ctx, stmt, postId, editor).Scan(&revision)
+	return revision, database.Translate(err)
+}
+
+func (r *postgresRepository) ListRevisions(ctx context.Context, postId string) ([]*pb.PostRevision, error) {
+	stmt := `SELECT id, post_id, revision, editor_id, created_at, title, description, slug, cover_image, ''
+	FROM post_revisions WHERE post_id=$1 ORDER BY revision DESC`
+
+	rows, err := r.q.QueryContext(ctx, stmt, postId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*pb.PostRevision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (r *postgresRepository) GetRevision(ctx context.Context, postId string, revision int32) (*pb.PostRevision, error) {
+	stmt := `SELECT id, post_id, revision, editor_id, created_at, title, description, slug, cover_image, content
+	FROM post_revisions WHERE post_id=$1 AND revision=$2`
+
+	rev, err := scanRevision(r.q.QueryRowContext(ctx, stmt, postId, revision))
+	return rev, database.Translate(err)
+}
+
+// entity is one of content, categories or authors and is never user input,
+// which is what makes it safe to use as a table name.
+func (r *postgresRepository) RedirectSlug(
+	ctx context.Context,
+	entity, slug string,
+	includeUnpublished bool,
+) (string, error) {
+	stmt := `SELECT t.slug FROM slug_redirects r JOIN ` + entity + ` t ON t.id = r.target_id
+	WHERE r.entity=$1 AND r.old_slug=$2`
+	// don't hand out the new slug of a post the reader can't see.
+	if entity == "content" && !includeUnpublished {
+		stmt += ` AND t.status='published'`
+	}
+
+	var current string
+	err := r.q.QueryRowContext(ctx, stmt, entity, slug).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return current, err
+}
+
+func (r *postgresRepository) CreateCategory(ctx context.Context, category *pb.Category) (string, error) {
+	stmt := `INSERT INTO categories (name, slug, description) VALUES($1, $2, $3) RETURNING id`
+	var categoryId string
+	err := r.q.QueryRowContext(ctx,
+		stmt,
+		category.Name,
+		category.Slug,
+		category.Description,
+	).Scan(&categoryId)
+	return categoryId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetCategory(ctx context.Context, id string) (*pb.Category, error) {
+	stmt := `SELECT id, name, slug, description FROM categories WHERE id=$1`
+	category, err := scanCategory(r.q.QueryRowContext(ctx, stmt, id))
+	return category, database.Translate(err)
+}
+
+func (r *postgresRepository) GetCategoryBySlug(ctx context.Context, slug string) (*pb.Category, error) {
+	stmt := `SELECT id, name, slug, description FROM categories WHERE slug=$1`
+	category, err := scanCategory(r.q.QueryRowContext(ctx, stmt, slug))
+	return category, database.Translate(err)
+}
+
+func (r *postgresRepository) UpdateCategory(ctx context.Context, category *pb.Category) error {
+	stmt := `UPDATE categories SET name=$1, slug=$2, description=$3 WHERE id=$4`
+	return r.exec(ctx,
+		stmt,
+		category.Name,
+		category.Slug,
+		category.Description,
+		category.CategoryId.GetValue(),
+	)
+}
+
+func (r *postgresRepository) DeleteCategory(ctx context.Context, id string) error {
+	return r.exec(ctx, `DELETE FROM categories WHERE id=$1`, id)
+}
+
+func (r *postgresRepository) ListCategories(ctx context.Context, limit, offset int32) ([]*pb.Category, error) {
+	stmt := `SELECT id, name, slug, description FROM categories LIMIT $1 OFFSET $2`
+	rows, err := r.q.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*pb.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *postgresRepository) CreateAuthor(ctx context.Context, author *pb.Author) (string, error) {
+	// an empty slug is filled in from the user's name by the database.
+	stmt := `INSERT INTO authors (bio, avatar, url, user_id, slug) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	var authorId string
+	err := r.q.QueryRowContext(ctx,
+		stmt,
+		author.Bio,
+		author.Avatar,
+		author.Url,
+		author.UserId.GetValue(),
+		author.Slug,
+	).Scan(&authorId)
+	return authorId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetAuthor(ctx context.Context, id string) (*pb.Author, error) {
+	stmt := `SELECT ` + authorColumns + ` FROM authors WHERE id=$1`
+	author, err := scanAuthor(r.q.QueryRowContext(ctx, stmt, id))
+	return author, database.Translate(err)
+}
+
+func (r *postgresRepository) GetAuthorBySlug(ctx context.Context, slug string) (*pb.Author, error) {
+	stmt := `SELECT ` + authorColumns + ` FROM authors WHERE slug=$1`
+	author, err := scanAuthor(r.q.QueryRowContext(ctx, stmt, slug))
+	return author, database.Translate(err)
+}
+
+func (r *postgresRepository) UpdateAuthor(ctx context.Context, author *pb.Author) error {
+	stmt := `UPDATE authors SET bio=$1, avatar=$2, url=$3, slug=COALESCE(NULLIF($4, ''), slug) WHERE id=$5`
+	return r.exec(ctx,
+		stmt,
+		author.Bio,
+		author.Avatar,
+		author.Url,
+		author.Slug,
+		author.AuthorId.GetValue(),
+	)
+}
+
+func (r *postgresRepository) DeleteAuthor(ctx context.Context, id string) error {
+	return r.exec(ctx, `DELETE FROM authors WHERE id=$1`, id)
+}
+
+func (r *postgresRepository) ListAuthors(ctx context.Context, limit, offset int32) ([]*pb.Author, error) {
+	stmt := `SELECT ` + authorColumns + ` FROM authors LIMIT $1 OFFSET $2`
+	rows, err := r.q.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var authors []*pb.Author
+	for rows.Next() {
+		author, err := scanAuthor(rows)
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	return authors, rows.Err()
+}
+
+func (r *postgresRepository) CreateTag(ctx context.Context, tag *pb.Tag) (string, error) {
+	stmt := `INSERT INTO tags (name, slug, description) VALUES ($1, $2, $3) RETURNING id`
+	var tagId string
+	err := r.q.QueryRowContext(ctx, stmt, tag.Name, tag.Slug, tag.Description).Scan(&tagId)
+	return tagId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetTag(ctx context.Context, id string) (*pb.Tag, error) {
+	tag, err := scanTag(r.q.QueryRowContext(ctx, `SELECT `+tagColumns+` FROM tags t WHERE t.id=$1`, id))
+	return tag, database.Translate(err)
+}
+
+func (r *postgresRepository) GetTagBySlug(ctx context.Context, slug string) (*pb.Tag, error) {
+	tag, err := scanTag(r.q.QueryRowContext(ctx, `SELECT `+tagColumns+` FROM tags t WHERE t.slug=$1`, slug))
+	return tag, database.Translate(err)
+}
+
+func (r *postgresRepository) UpdateTag(ctx context.Context, tag *pb.Tag) error {
+	stmt := `UPDATE tags SET name=$1, slug=$2, description=$3 WHERE id=$4`
+	return r.exec(ctx, stmt, tag.Name, tag.Slug, tag.Description, tag.TagId.GetValue())
+}
+
+func (r *postgresRepository) DeleteTag(ctx context.Context, id string) error {
+	return r.exec(ctx, `DELETE FROM tags WHERE id=$1`, id)
+}
+
+func (r *postgresRepository) ListTags(ctx context.Context, limit, offset int32) ([]*pb.Tag, error) {
+	stmt := `SELECT ` + tagColumns + ` FROM tags t ORDER BY t.name, t.id LIMIT $1 OFFSET $2`
+	return r.queryTags(ctx, stmt, limit, offset)
+}
+
+func (r *postgresRepository) queryTags(ctx context.Context, stmt string, args ...interface{}) ([]*pb.Tag, error) {
+	rows, err := r.q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*pb.Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *postgresRepository) GetUserRole(ctx context.Context, userId string) (pb.UserRoles, error) {
+	var role int32
+	err := r.q.QueryRowContext(ctx, `SELECT role FROM users WHERE id=$1`, userId).Scan(&role)
+	return pb.UserRoles(role), database.Translate(err)
+}
+
+func (r *postgresRepository) UpdateUserRole(ctx context.Context, userId string, role pb.UserRoles) error {
+	return r.exec(ctx, `UPDATE users SET role=$1 WHERE id=$2`, role, userId)
+}
+
+func (r *postgresRepository) CreateComment(ctx context.Context, comment *pb.Comment, spamScore int) (string, error) {
+	parentId := sql.NullString{String: comment.ParentId.GetValue(), Valid: comment.ParentId.GetValue() != ""}
+
+	stmt := `INSERT INTO post_comments (post_id, parent_id, user_id, body, status, spam_score)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	var commentId string
+	err := r.q.QueryRowContext(ctx,
+		stmt,
+		comment.PostId.GetValue(),
+		parentId,
+		comment.UserId.GetValue(),
+		comment.Body,
+		commentStatusToDB(comment.Status),
+		spamScore,
+	).Scan(&commentId)
+	return commentId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetComment(ctx context.Context, id string) (*pb.Comment, error) {
+	stmt := `SELECT ` + commentColumns + ` FROM post_comments pc JOIN users u ON u.id = pc.user_id
+	WHERE pc.id=$1`
+	comment, err := scanComment(r.q.QueryRowContext(ctx, stmt, id))
+	return comment, database.Translate(err)
+}
+
+func (r *postgresRepository) CountComments(ctx context.Context, postId string) (int32, int32, error) {
+	stmt := `SELECT COUNT(*) FILTER (WHERE parent_id IS NULL), COUNT(*)
+	FROM post_comments WHERE post_id=$1 AND status='approved'`
+	var threads, total int32
+	err := r.q.QueryRowContext(ctx, stmt, postId).Scan(&threads, &total)
+	return threads, total, err
+}
+
+func (r *postgresRepository) ListThreads(ctx context.Context, postId string, limit, offset int32) ([]*pb.Comment, error) {
+	stmt := `SELECT ` + commentColumns + ` FROM post_comments pc JOIN users u ON u.id = pc.user_id
+	WHERE pc.post_id=$1 AND pc.parent_id IS NULL AND pc.status='approved'
+	ORDER BY pc.created_at, pc.id LIMIT $2 OFFSET $3`
+	return r.queryComments(ctx, stmt, postId, limit, offset)
+}
+
+func (r *postgresRepository) ListReplies(ctx context.Context, rootIds []string) ([]*pb.Comment, error) {
+	stmt := `WITH RECURSIVE thread AS (
+		SELECT * FROM post_comments WHERE parent_id = ANY($1::uuid[]) AND status='approved'
+		UNION ALL
+		SELECT r.* FROM post_comments r JOIN thread t ON r.parent_id = t.id WHERE r.status='approved'
+	)
+	SELECT ` + commentColumns + ` FROM thread pc JOIN users u ON u.id = pc.user_id
+	ORDER BY pc.created_at, pc.id`
+	return r.queryComments(ctx, stmt, pq.Array(rootIds))
+}
+
+func (r *postgresRepository) ListModerationQueue(
+	ctx context.Context,
+	filter ModerationFilter,
+) ([]*pb.Comment, int32, error) {
+	from := ` FROM post_comments pc JOIN users u ON u.id = pc.user_id
+	JOIN content c ON c.id = pc.post_id JOIN authors a ON a.id = c.author_id
+	WHERE pc.status=$1`
+	args := []interface{}{commentStatusToDB(filter.Status)}
+	if filter.AuthorUserId != "" {
+		args = append(args, filter.AuthorUserId)
+		from += ` AND a.user_id=$2`
+	}
+
+	var total int32
+	if err := r.q.QueryRowContext(ctx, `SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	stmt := fmt.Sprintf(`SELECT %s%s ORDER BY pc.created_at DESC, pc.id LIMIT $%d OFFSET $%d`,
+		commentColumns, from, len(args)+1, len(args)+2)
+	comments, err := r.queryComments(ctx, stmt, append(args, filter.Limit, filter.Offset)...)
+	return comments, total, err
+}
+
+func (r *postgresRepository) CommentPostAuthor(ctx context.Context, commentId string) (string, error) {
+	var authorUserId string
+	err := r.q.QueryRowContext(ctx, `SELECT a.user_id FROM post_comments pc
+	JOIN content c ON c.id = pc.post_id JOIN authors a ON a.id = c.author_id
+	WHERE pc.id=$1`, commentId).Scan(&authorUserId)
+	return authorUserId, database.Translate(err)
+}
+
+func (r *postgresRepository) ModerateComment(
+	ctx context.Context,
+	commentId string,
+	status pb.CommentStatus,
+	moderatorId string,
+) error {
+	stmt := `UPDATE post_comments SET status=$1, moderated_by=$2, moderated_at=NOW() WHERE id=$3`
+	return r.exec(ctx, stmt, commentStatusToDB(status), moderatorId, commentId)
+}
+
+func (r *postgresRepository) LockCommenter(ctx context.Context, userId string) error {
+	// the lock is released when the transaction commits or rolls back.
+	_, err := r.q.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('post_comments:' || $1::text))`, userId)
+	return err
+}
+
+func (r *postgresRepository) CommentHistory(ctx context.Context, userId string, since time.Time) (int, bool, error) {
+	stmt := `SELECT
+	  COUNT(*) FILTER (WHERE created_at > $2),
+	  COUNT(*) FILTER (WHERE status='approved') > 0
+	FROM post_comments WHERE user_id=$1`
+
+	var recent int
+	var trusted bool
+	err := r.q.QueryRowContext(ctx, stmt, userId, since).Scan(&recent, &trusted)
+	return recent, trusted, err
+}
+
+func (r *postgresRepository) RecentCommentBodies(ctx context.Context, userId string, limit int) ([]string, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT body FROM post_comments WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2`,
+		userId,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bodies []string
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, rows.Err()
+}
+
+func (r *postgresRepository) queryComments(ctx context.Context, stmt string, args ...interface{}) ([]*pb.Comment, error) {
+	rows, err := r.q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*pb.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (r *postgresRepository) CreateMedia(ctx context.Context, asset *pb.MediaAsset, storageKey string) (string, error) {
+	uploadedBy := sql.NullString{String: asset.UploadedBy.GetValue(), Valid: asset.UploadedBy.GetValue() != ""}
+
+	stmt := `INSERT INTO media_assets (storage_key, url, file_name, content_type, size_bytes, width, height, alt_text, uploaded_by)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	var assetId string
+	err := r.q.QueryRowContext(ctx,
+		stmt,
+		storageKey,
+		asset.Url,
+		asset.FileName,
+		asset.ContentType,
+		asset.SizeBytes,
+		asset.Width,
+		asset.Height,
+		asset.AltText,
+		uploadedBy,
+	).Scan(&assetId)
+	return assetId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetMedia(ctx context.Context, id string) (*pb.MediaAsset, error) {
+	stmt := `SELECT ` + mediaColumns + ` FROM media_assets m WHERE m.id=$1`
+	asset, err := scanMedia(r.q.QueryRowContext(ctx, stmt, id))
+	return asset, database.Translate(err)
+}
+
+func (r *postgresRepository) ListMediaUsages(ctx context.Context, id string) ([]*pb.MediaUsage, error) {
+	stmt := `SELECT used.kind, used.id, used.title FROM media_assets m,
+	LATERAL (` + mediaUsages + `) used WHERE m.id=$1 ORDER BY used.kind DESC, used.title`
+	rows, err := r.q.QueryContext(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []*pb.MediaUsage
+	for rows.Next() {
+		var usage pb.MediaUsage
+		var usageId string
+		if err := rows.Scan(&usage.Kind, &usageId, &usage.Title); err != nil {
+			return nil, err
+		}
+		usage.Id = &pb.UUID{Value: usageId}
+		usages = append(usages, &usage)
+	}
+	return usages, rows.Err()
+}
+
+func (r *postgresRepository) ListMedia(
+	ctx context.Context,
+	query string,
+	limit, offset int32,
+) ([]*pb.MediaAsset, int32, error) {
+	where := `TRUE`
+	args := []interface{}{}
+	if query != "" {
+		args = append(args, "%"+escapeLike(query)+"%")
+		where = `(m.file_name ILIKE $1 OR m.alt_text ILIKE $1)`
+	}
+
+	var total int32
+	countStmt := `SELECT COUNT(*) FROM media_assets m WHERE ` + where
+	if err := r.q.QueryRowContext(ctx, countStmt, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	stmt := fmt.Sprintf(
+		`SELECT %s FROM media_assets m WHERE %s ORDER BY m.created_at DESC, m.id LIMIT $%d OFFSET $%d`,
+		mediaColumns, where, len(args)+1, len(args)+2,
+	)
+	rows, err := r.q.QueryContext(ctx, stmt, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var assets []*pb.MediaAsset
+	for rows.Next() {
+		asset, err := scanMedia(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		assets = append(assets, asset)
+	}
+	return assets, total, rows.Err()
+}
+
+func (r *postgresRepository) UpdateMediaAltText(ctx context.Context, id, altText string) error {
+	return r.exec(ctx, `UPDATE media_assets SET alt_text=$2 WHERE id=$1`, id, altText)
+}
+
+// DeleteMedia checks for usages and deletes in one statement. A post saved
+// in between that starts using the asset makes the delete fail on the
+// content_media foreign key. Avatars have no such reference, an author can
+// still be given the asset's url while it is being deleted.
+func (r *postgresRepository) DeleteMedia(ctx context.Context, id string) (string, error) {
+	stmt := `DELETE FROM media_assets m WHERE m.id=$1 AND NOT EXISTS (` + mediaUsages + `)
+	RETURNING m.storage_key`
+	var key string
+	err := database.Translate(r.q.QueryRowContext(ctx, stmt, id).Scan(&key))
+	switch {
+	case errors.Is(err, database.ErrForeignKey):
+		return "", ErrMediaInUse
+	case !errors.Is(err, database.ErrNotFound):
+		return key, err
+	}
+
+	var exists bool
+	err = r.q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM media_assets WHERE id=$1)`, id).Scan(&exists)
+	switch {
+	case err != nil:
+		return "", err
+	case !exists:
+		return "", database.ErrNotFound
+	}
+	return "", ErrMediaInUse
+}
+
+func (r *postgresRepository) RecordPostView(
+	ctx context.Context,
+	postId, viewer string,
+	window time.Duration,
+) (bool, error) {
+	stmt := `WITH counted AS (
+		INSERT INTO post_view_sessions (post_id, viewer)
+		SELECT id, $2 FROM content WHERE id=$1 AND status='published'
+		ON CONFLICT (post_id, viewer) DO UPDATE SET last_counted = NOW()
+		WHERE post_view_sessions.last_counted < NOW() - make_interval(secs => $3)
+		RETURNING post_id
+	)
+	INSERT INTO post_daily_views (post_id, day, views)
+	SELECT post_id, (NOW() AT TIME ZONE 'UTC')::date, 1 FROM counted
+	ON CONFLICT (post_id, day) DO UPDATE SET views = post_daily_views.views + 1`
+
+	result, err := r.q.ExecContext(ctx, stmt, postId, viewer, window.Seconds())
+	if err != nil {
+		return false, database.Translate(err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *postgresRepository) PopularPosts(
+	ctx context.Context,
+	days, limit int32,
+	categoryId string,
+) ([]*pb.PopularPost, error) {
+	args := []interface{}{days, limit}
+	filter := ``
+	if categoryId != "" {
+		args = append(args, categoryId)
+		filter = ` AND category_id=$3`
+	}
+
+	stmt := `SELECT ` + postColumns + `, v.views FROM content JOIN (
+		SELECT post_id, SUM(views) AS views FROM post_daily_views
+		WHERE day > (NOW() AT TIME ZONE 'UTC')::date - $1::int
+		GROUP BY post_id
+	) v ON v.post_id = content.id
+	WHERE status='published'` + filter + `
+	ORDER BY v.views DESC, published_date DESC LIMIT $2`
+
+	rows, err := r.q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*pb.PopularPost
+	for rows.Next() {
+		var views int64
+		post, err := scanPost(rows, &views)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, &pb.PopularPost{Post: post, Views: views})
+	}
+	return posts, rows.Err()
+}
+
+func (r *postgresRepository) PruneViewSessions(ctx context.Context, window time.Duration) (int64, error) {
+	stmt := `DELETE FROM post_view_sessions WHERE last_counted < NOW() - make_interval(secs => $1)`
+	result, err := r.q.ExecContext(ctx, stmt, window.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *postgresRepository) SitemapChunks(ctx context.Context, chunkSize int32) ([]*pb.SitemapChunk, error) {
+	stmt := `SELECT chunk, MAX(modified) FROM (
+		SELECT (ROW_NUMBER() OVER (ORDER BY id) - 1) / $1 AS chunk, ` + postLastModified + ` AS modified
+		FROM content WHERE status='published'
+	) numbered GROUP BY chunk ORDER BY chunk`
+
+	rows, err := r.q.QueryContext(ctx, stmt, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*pb.SitemapChunk
+	for rows.Next() {
+		var index int32
+		var lastModified time.Time
+		if err := rows.Scan(&index, &lastModified); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, &pb.SitemapChunk{
+			Index:        index,
+			LastModified: lastModified.Format(time.RFC3339),
+		})
+	}
+	return chunks, rows.Err()
+}
+
+func (r *postgresRepository) SitemapEntries(ctx context.Context, limit, offset int32) ([]*pb.SitemapEntry, error) {
+	stmt := `SELECT slug, ` + postLastModified + ` FROM content WHERE status='published'
+	ORDER BY id LIMIT $1 OFFSET $2`
+
+	rows, err := r.q.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*pb.SitemapEntry
+	for rows.Next() {
+		var slug string
+		var updatedAt time.Time
+		if err := rows.Scan(&slug, &updatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &pb.SitemapEntry{
+			Slug:      slug,
+			UpdatedAt: updatedAt.Format(time.RFC3339),
+		})
+	}
+	return entries, rows.Err()
+}
+
+// exec runs a write that has to change a row, failing with
+// database.ErrNotFound when it changes none.
+func (r *postgresRepository) exec(ctx context.Context, stmt string, args ...interface{}) error {
+	result, err := r.q.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return database.Translate(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return database.ErrNotFound
+	}
+	return nil
+}
+
+// tocJSON is what goes in content.toc, an empty toc is stored as [].
+// It is a string because lib/pq sends []byte as bytea.
+func tocJSON(toc []*pb.TocEntry) (string, error) {
+	if len(toc) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(toc)
+	return string(b), err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost reads a row selected with postColumns, and any columns after it
+// into extra.
+func scanPost(row rowScanner, extra ...interface{}) (*pb.Post, error) {
+	var post pb.Post
+	var postId, categoryId, authorId, postStatus string
+	var publishedDate, publishAt sql.NullTime
+	var toc []byte
+
+	err := row.Scan(append([]interface{}{
+		&postId,
+		&publishedDate,
+		&post.UpdatedDate,
+		&post.CoverImage,
+		&post.Title,
+		&post.Description,
+		&post.Slug,
+		&post.Content,
+		&postStatus,
+		&authorId,
+		&categoryId,
+		&publishAt,
+		&post.ReviewNote,
+		&post.ContentHtml,
+		&toc,
+		&post.WordCount,
+		&post.ReadingTimeMinutes,
+	}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	post.PostId = &pb.UUID{Value: postId}
+	post.AuthorId = &pb.UUID{Value: authorId}
+	post.CategoryId = &pb.UUID{Value: categoryId}
+	post.Status = statusFromDB(postStatus)
+	if publishedDate.Valid {
+		post.PublishedDate = publishedDate.Time.Format(time.RFC3339)
+	}
+	if publishAt.Valid {
+		post.PublishAt = publishAt.Time.Format(time.RFC3339)
+	}
+
+	if post.ContentHtml == "" && post.Content != "" {
+		// saved before rendering existed, render it on the way out.
+		rendered, err := renderMarkdown(post.Content)
+		if err != nil {
+			return nil, fmt.Errorf("could not render post: %w", err)
+		}
+		post.ContentHtml = rendered.HTML
+		post.Toc = rendered.Toc
+		post.WordCount = rendered.WordCount
+		post.ReadingTimeMinutes = rendered.ReadingTimeMinutes
+	} else if err := json.Unmarshal(toc, &post.Toc); err != nil {
+		return nil, fmt.Errorf("could not read table of contents: %w", err)
+	}
+
+	return &post, nil
+}
+
+func scanCategory(row rowScanner) (*pb.Category, error) {
+	var category pb.Category
+	var categoryId string
+	err := row.Scan(
+		&categoryId,
+		&category.Name,
+		&category.Slug,
+		&category.Description,
+	)
+	if err != nil {
+		return nil, err
+	}
+	category.CategoryId = &pb.UUID{Value: categoryId}
+	return &category, nil
+}
+
+func scanAuthor(row rowScanner) (*pb.Author, error) {
+	var author pb.Author
+	var authorId, userId string
+	err := row.Scan(
+		&authorId,
+		&author.Bio,
+		&author.Avatar,
+		&author.Url,
+		&userId,
+		&author.Slug,
+	)
+	if err != nil {
+		return nil, err
+	}
+	author.AuthorId = &pb.UUID{Value: authorId}
+	author.UserId = &pb.UUID{Value: userId}
+	return &author, nil
+}
+
+func scanTag(row rowScanner) (*pb.Tag, error) {
+	var tag pb.Tag
+	var tagId string
+	err := row.Scan(&tagId, &tag.Name, &tag.Slug, &tag.Description, &tag.PostCount)
+	if err != nil {
+		return nil, err
+	}
+	tag.TagId = &pb.UUID{Value: tagId}
+	return &tag, nil
+}
+
+func scanComment(row rowScanner) (*pb.Comment, error) {
+	var comment pb.Comment
+	var commentId, postId, userId, commentStatus string
+	var parentId sql.NullString
+	var createdAt time.Time
+
+	err := row.Scan(
+		&commentId,
+		&postId,
+		&parentId,
+		&userId,
+		&comment.UserName,
+		&comment.Body,
+		&commentStatus,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	comment.CommentId = &pb.UUID{Value: commentId}
+	comment.PostId = &pb.UUID{Value: postId}
+	if parentId.Valid {
+		comment.ParentId = &pb.UUID{Value: parentId.String}
+	}
+	comment.UserId = &pb.UUID{Value: userId}
+	comment.Status = commentStatusFromDB(commentStatus)
+	comment.CreatedAt = createdAt.Format(time.RFC3339)
+	return &comment, nil
+}
+
+func scanMedia(row rowScanner) (*pb.MediaAsset, error) {
+	var asset pb.MediaAsset
+	var assetId string
+	var uploadedBy sql.NullString
+	var createdAt time.Time
+
+	err := row.Scan(
+		&assetId,
+		&asset.Url,
+		&asset.FileName,
+		&asset.ContentType,
+		&asset.SizeBytes,
+		&asset.Width,
+		&asset.Height,
+		&asset.AltText,
+		&uploadedBy,
+		&createdAt,
+		&asset.UsageCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	asset.AssetId = &pb.UUID{Value: assetId}
+	if uploadedBy.Valid {
+		asset.UploadedBy = &pb.UUID{Value: uploadedBy.String}
+	}
+	asset.CreatedAt = createdAt.Format(time.RFC3339)
+	return &asset, nil
+}
+
+func scanRevision(row rowScanner) (*pb.PostRevision, error) {
+	var rev pb.PostRevision
+	var revisionId, postId string
+	var editorId sql.NullString
+	var createdAt time.Time
+
+	err := row.Scan(
+		&revisionId,
+		&postId,
+		&rev.Revision,
+		&editorId,
+		&createdAt,
+		&rev.Title,
+		&rev.Description,
+		&rev.Slug,
+		&rev.CoverImage,
+		&rev.Content,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rev.RevisionId = &pb.UUID{Value: revisionId}
+	rev.PostId = &pb.UUID{Value: postId}
+	if editorId.Valid {
+		rev.EditorId = &pb.UUID{Value: editorId.String}
+	}
+	rev.CreatedAt = createdAt.Format(time.RFC3339)
+
+	return &rev, nil
+}
+
+// escapeLike stops user input from being read as LIKE wildcards.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,135 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	productpb "github.com/kelcheone/chemistke/pkg/grpc/product"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// productShortcode matches {{< product id="<uuid>" >}} in a post's markdown.
+var productShortcode = regexp.MustCompile(
+	`\{\{<\s*product\s+id="([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})"\s*>\}\}`,
+)
+
+// shortcodeProducts lists the products embedded in content, first mention
+// first.
+func shortcodeProducts(content string) []string {
+	var ids []string
+	for _, match := range productShortcode.FindAllStringSubmatch(content, -1) {
+		ids = append(ids, strings.ToLower(match[1]))
+	}
+	return uniqueStrings(ids)
+}
+
+// expandShortcodes swaps product shortcodes for an element the storefront
+// fills in with the product card.
+func expandShortcodes(content string) string {
+	return productShortcode.ReplaceAllStringFunc(content, func(code string) string {
+		id := strings.ToLower(productShortcode.FindStringSubmatch(code)[1])
+		return `<div class="product-embed" data-product-id="` + id + `"></div>`
+	})
+}
+
+// setPostProducts replaces the products linked to a post by hand and
+// re-reads the ones embedded in content.
+func setPostProducts(ctx context.Context, repo Repository, postId string, productIds []*pb.UUID, content string) error {
+	var ids []string
+	for _, id := range productIds {
+		ids = append(ids, strings.ToLower(id.GetValue()))
+	}
+
+	if err := linkProducts(ctx, repo, postId, true, uniqueStrings(ids)); err != nil {
+		return err
+	}
+	return syncShortcodeProducts(ctx, repo, postId, content)
+}
+
+// syncShortcodeProducts links the products embedded in content, dropping
+// any that are no longer there.
+func syncShortcodeProducts(ctx context.Context, repo Repository, postId, content string) error {
+	return linkProducts(ctx, repo, postId, false, shortcodeProducts(content))
+}
+
+func linkProducts(ctx context.Context, repo Repository, postId string, explicit bool, ids []string) error {
+	if err := repo.SetPostProducts(ctx, postId, explicit, ids); err != nil {
+		if errors.Is(err, database.ErrInvalid) {
+			return status.Errorf(codes.InvalidArgument, "invalid product id: %v", err)
+		}
+		return status.Errorf(codes.Internal, "could not update post products: %w", err)
+	}
+	return nil
+}
+
+// postProducts fills in the ids of the products linked to a post by hand
+// and, when the product service is reachable, cards for every linked
+// product. A product service outage leaves the cards out rather than
+// failing the read.
+func (c *CmsService) postProducts(ctx context.Context, post *pb.Post) error {
+	linked, err := c.repo.ListPostProducts(ctx, post.PostId.Value)
+	if err != nil {
+		return status.Errorf(codes.Internal, "could not fetch post products: %v", err)
+	}
+
+	var ids []*productpb.UUID
+	for _, product := range linked {
+		if product.Explicit {
+			post.ProductIds = append(post.ProductIds, &pb.UUID{Value: product.ProductId})
+		}
+		ids = append(ids, &productpb.UUID{Value: product.ProductId})
+	}
+
+	if len(ids) == 0 || c.Products == nil {
+		return nil
+	}
+
+	resp, err := c.Products.GetProductSummaries(
+		ctx,
+		&productpb.GetProductSummariesRequest{Ids: ids},
+	)
+	if err != nil {
+		slog.WarnContext(ctx, "could not fetch products for post", "post_id", post.PostId.Value, "error", err)
+		return nil
+	}
+	for _, product := range resp.Products {
+		post.Products = append(post.Products, &pb.ProductSummary{
+			ProductId: &pb.UUID{Value: product.Id.GetValue()},
+			Name:      product.Name,
+			Slug:      product.Slug,
+			Price:     product.Price,
+			ImageUrl:  product.ImageUrl,
+			InStock:   product.InStock,
+		})
+	}
+	return nil
+}
+
+func (c *CmsService) GetProductPosts(
+	ctx context.Context,
+	req *pb.GetProductPostsRequest,
+) (*pb.GetProductPostsResponse, error) {
+	if req.ProductId.GetValue() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "product id was not provided")
+	}
+
+	filter := PostFilter{ProductId: req.ProductId.Value}
+	total, err := c.repo.CountPosts(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not count posts: %v", err)
+	}
+
+	filter.Limit, filter.Offset = pagination(req.Page, req.PerPage)
+	posts, err := c.listPosts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetProductPostsResponse{Posts: posts, Total: total}, nil
+}
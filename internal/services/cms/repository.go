@@ -0,0 +1,233 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+)
+
+var (
+	// ErrPostStatus is returned by TransitionPost when the post is not in
+	// one of the statuses it may move from.
+	ErrPostStatus = errors.New("post cannot make that transition")
+	// ErrMediaInUse is returned by DeleteMedia while a post or author still
+	// uses the asset.
+	ErrMediaInUse = errors.New("media is in use")
+)
+
+// PostFilter picks the posts ListPosts and CountPosts return. Empty fields
+// leave a filter out, and no Statuses means published posts only.
+type PostFilter struct {
+	Statuses   []pb.PostStatus
+	CategoryId string
+	AuthorId   string
+	ProductId  string
+	// TagSlugs matches posts carrying any of the tags, or all of them with
+	// AllTags.
+	TagSlugs []string
+	AllTags  bool
+
+	Limit  int32
+	Offset int32
+}
+
+// PostTransition moves a post from one of the From statuses to To.
+type PostTransition struct {
+	From []pb.PostStatus
+	To   pb.PostStatus
+	// ReviewerId, when set, records who reviewed the post and when.
+	ReviewerId string
+	// ReviewNote replaces the post's review note when not nil.
+	ReviewNote *string
+	// PublishAt replaces when the post goes live when not nil, a zero time
+	// clears it. A post moving to published is dated PublishAt too.
+	PublishAt *time.Time
+}
+
+// ModerationFilter picks the comments ListModerationQueue returns.
+type ModerationFilter struct {
+	Status pb.CommentStatus
+	// AuthorUserId keeps only comments on posts by that user, empty keeps
+	// every post.
+	AuthorUserId string
+
+	Limit  int32
+	Offset int32
+}
+
+// PostProduct is a product linked to a post, by hand or through a
+// shortcode in its content.
+type PostProduct struct {
+	ProductId string
+	Explicit  bool
+}
+
+// Repository is where the cms keeps its data. Lookups of a missing row fail
+// with database.ErrNotFound, as do updates and deletes that match nothing.
+// Slugs of posts, categories and authors are made unique, and generated
+// when left empty, with the old slug redirecting whenever one changes.
+type Repository interface {
+	// CreatePost stores post along with its rendered content and returns
+	// its id.
+	CreatePost(ctx context.Context, post *pb.Post) (string, error)
+	// GetPost returns a post without its tags and products. Unless
+	// includeUnpublished, only a published post is found.
+	GetPost(ctx context.Context, id string, includeUnpublished bool) (*pb.Post, error)
+	GetPostBySlug(ctx context.Context, slug string, includeUnpublished bool) (*pb.Post, error)
+	// UpdatePost changes everything about a post but its status and dates.
+	UpdatePost(ctx context.Context, post *pb.Post) error
+	DeletePost(ctx context.Context, id string) error
+	// ListPosts returns a page of the posts matching filter, latest
+	// published first.
+	ListPosts(ctx context.Context, filter PostFilter) ([]*pb.Post, error)
+	// CountPosts returns how many posts match filter, whatever the page.
+	CountPosts(ctx context.Context, filter PostFilter) (int32, error)
+	// TransitionPost applies transition to a post and returns the status
+	// it is left in. It fails with ErrPostStatus when the post is not in
+	// one of transition.From.
+	TransitionPost(ctx context.Context, id string, transition PostTransition) (pb.PostStatus, error)
+	// PublishScheduledPosts publishes every scheduled post whose publish
+	// time has passed and returns how many went live.
+	PublishScheduledPosts(ctx context.Context) (int64, error)
+
+	// SetPostTags replaces the tags on a post, skipping ids of tags that do
+	// not exist.
+	SetPostTags(ctx context.Context, postId string, tagIds []string) error
+	// ListPostTags returns a post's tags by name.
+	ListPostTags(ctx context.Context, postId string) ([]*pb.Tag, error)
+	// SetPostProducts replaces the products linked to a post by hand, or
+	// through shortcodes when explicit is false, keeping the order of ids.
+	// A product already linked the other way keeps that link. Ids that are
+	// not uuids fail with database.ErrInvalid.
+	SetPostProducts(ctx context.Context, postId string, explicit bool, ids []string) error
+	// ListPostProducts returns the products linked to a post, those linked
+	// by hand first.
+	ListPostProducts(ctx context.Context, postId string) ([]PostProduct, error)
+	// SyncPostMedia records the assets a post uses as its cover image or in
+	// its content, as the post is stored now.
+	SyncPostMedia(ctx context.Context, postId string) error
+
+	// RecordRevision snapshots a post as it is stored now and returns the
+	// revision number. An empty editorId credits the post's author.
+	RecordRevision(ctx context.Context, postId, editorId string) (int32, error)
+	// ListRevisions returns a post's revisions newest first, without their
+	// content.
+	ListRevisions(ctx context.Context, postId string) ([]*pb.PostRevision, error)
+	GetRevision(ctx context.Context, postId string, revision int32) (*pb.PostRevision, error)
+
+	// RedirectSlug returns the current slug of whatever used to be found at
+	// slug, or "" when nothing was. entity is content, categories or
+	// authors. Unless includeUnpublished, only published posts are found.
+	RedirectSlug(ctx context.Context, entity, slug string, includeUnpublished bool) (string, error)
+
+	CreateCategory(ctx context.Context, category *pb.Category) (string, error)
+	GetCategory(ctx context.Context, id string) (*pb.Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*pb.Category, error)
+	UpdateCategory(ctx context.Context, category *pb.Category) error
+	DeleteCategory(ctx context.Context, id string) error
+	ListCategories(ctx context.Context, limit, offset int32) ([]*pb.Category, error)
+
+	// CreateAuthor stores author and returns its id. An empty slug is made
+	// from the name of the author's user.
+	CreateAuthor(ctx context.Context, author *pb.Author) (string, error)
+	GetAuthor(ctx context.Context, id string) (*pb.Author, error)
+	GetAuthorBySlug(ctx context.Context, slug string) (*pb.Author, error)
+	// UpdateAuthor changes an author's bio, avatar and url, and their slug
+	// unless it is empty.
+	UpdateAuthor(ctx context.Context, author *pb.Author) error
+	DeleteAuthor(ctx context.Context, id string) error
+	ListAuthors(ctx context.Context, limit, offset int32) ([]*pb.Author, error)
+
+	// CreateTag stores tag and returns its id. An empty slug is made from
+	// the name, a slug that is taken fails with database.ErrConflict.
+	CreateTag(ctx context.Context, tag *pb.Tag) (string, error)
+	// GetTag returns a tag with its count of published posts, as do the
+	// other tag reads.
+	GetTag(ctx context.Context, id string) (*pb.Tag, error)
+	GetTagBySlug(ctx context.Context, slug string) (*pb.Tag, error)
+	UpdateTag(ctx context.Context, tag *pb.Tag) error
+	DeleteTag(ctx context.Context, id string) error
+	// ListTags returns a page of tags by name.
+	ListTags(ctx context.Context, limit, offset int32) ([]*pb.Tag, error)
+
+	GetUserRole(ctx context.Context, userId string) (pb.UserRoles, error)
+	UpdateUserRole(ctx context.Context, userId string, role pb.UserRoles) error
+
+	// CreateComment stores comment with its spam score and returns its id.
+	CreateComment(ctx context.Context, comment *pb.Comment, spamScore int) (string, error)
+	// GetComment returns a comment and the name of who wrote it, without
+	// replies.
+	GetComment(ctx context.Context, id string) (*pb.Comment, error)
+	// CountComments returns how many approved threads and comments a post
+	// has.
+	CountComments(ctx context.Context, postId string) (int32, int32, error)
+	// ListThreads returns a page of a post's approved top level comments,
+	// oldest first.
+	ListThreads(ctx context.Context, postId string, limit, offset int32) ([]*pb.Comment, error)
+	// ListReplies returns the approved replies below the comments with
+	// rootIds, oldest first. A reply to a comment that is not approved is
+	// left out with it.
+	ListReplies(ctx context.Context, rootIds []string) ([]*pb.Comment, error)
+	// ListModerationQueue returns a page of the comments matching filter,
+	// newest first, and how many match in all.
+	ListModerationQueue(ctx context.Context, filter ModerationFilter) ([]*pb.Comment, int32, error)
+	// CommentPostAuthor returns the user behind the author of the post a
+	// comment is on.
+	CommentPostAuthor(ctx context.Context, commentId string) (string, error)
+	// ModerateComment sets a comment's status and records who moderated it.
+	ModerateComment(ctx context.Context, commentId string, status pb.CommentStatus, moderatorId string) error
+	// LockCommenter holds back other comments by userId until the
+	// transaction ends, so a rate limit check stays true until the comment
+	// is stored. It only has an effect inside WithTx.
+	LockCommenter(ctx context.Context, userId string) error
+	// CommentHistory returns how many comments a user posted after since
+	// and whether any of theirs was ever approved.
+	CommentHistory(ctx context.Context, userId string, since time.Time) (int, bool, error)
+	// RecentCommentBodies returns the bodies of a user's last limit
+	// comments.
+	RecentCommentBodies(ctx context.Context, userId string, limit int) ([]string, error)
+
+	// CreateMedia stores an asset uploaded under storageKey and returns its
+	// id.
+	CreateMedia(ctx context.Context, asset *pb.MediaAsset, storageKey string) (string, error)
+	// GetMedia returns an asset with how many posts and authors use it, as
+	// does ListMedia.
+	GetMedia(ctx context.Context, id string) (*pb.MediaAsset, error)
+	// ListMediaUsages returns the posts and then the authors using an
+	// asset, by title.
+	ListMediaUsages(ctx context.Context, id string) ([]*pb.MediaUsage, error)
+	// ListMedia returns a page of the assets whose file name or alt text
+	// contains query, newest first, and how many match in all.
+	ListMedia(ctx context.Context, query string, limit, offset int32) ([]*pb.MediaAsset, int32, error)
+	UpdateMediaAltText(ctx context.Context, id, altText string) error
+	// DeleteMedia removes an asset and returns the key its file is stored
+	// under. It fails with ErrMediaInUse while a post or author uses it,
+	// including a post saved with the asset while it is being deleted. An
+	// avatar set at the same time is not caught.
+	DeleteMedia(ctx context.Context, id string) (string, error)
+
+	// RecordPostView counts a view of a published post unless viewer was
+	// already counted within window, and reports whether it counted. A
+	// postId that is not a uuid fails with database.ErrInvalid.
+	RecordPostView(ctx context.Context, postId, viewer string, window time.Duration) (bool, error)
+	// PopularPosts returns up to limit published posts, most viewed first,
+	// counting views on today and the days-1 days before it. An empty
+	// categoryId means any category.
+	PopularPosts(ctx context.Context, days, limit int32, categoryId string) ([]*pb.PopularPost, error)
+	// PruneViewSessions forgets viewers last counted before window and
+	// returns how many it forgot.
+	PruneViewSessions(ctx context.Context, window time.Duration) (int64, error)
+
+	// SitemapChunks splits the published posts, in id order, into chunks of
+	// chunkSize and returns when each chunk last changed.
+	SitemapChunks(ctx context.Context, chunkSize int32) ([]*pb.SitemapChunk, error)
+	// SitemapEntries pages through the slugs of published posts in id
+	// order.
+	SitemapEntries(ctx context.Context, limit, offset int32) ([]*pb.SitemapEntry, error)
+
+	// WithTx runs fn on a Repository whose writes are kept only if fn
+	// returns nil.
+	WithTx(ctx context.Context, fn func(Repository) error) error
+}
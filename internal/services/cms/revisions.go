@@ -0,0 +1,210 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// recordRevision snapshots the post as it is stored now. When editorId is
+// empty the revision is attributed to the post's author.
+func recordRevision(ctx context.Context, repo Repository, postId, editorId string) (int32, error) {
+	revision, err := repo.RecordRevision(ctx, postId, editorId)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return 0, status.Errorf(codes.NotFound, "post does not exist")
+		}
+		return 0, status.Errorf(codes.Internal, "could not record revision: %w", err)
+	}
+	return revision, nil
+}
+
+func (c *CmsService) ListPostRevisions(
+	ctx context.Context,
+	req *pb.ListPostRevisionsRequest,
+) (*pb.ListPostRevisionsResponse, error) {
+	revisions, err := c.repo.ListRevisions(ctx, req.PostId.Value)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch revisions: %v", err)
+	}
+
+	return &pb.ListPostRevisionsResponse{Revisions: revisions}, nil
+}
+
+func (c *CmsService) DiffPostRevisions(
+	ctx context.Context,
+	req *pb.DiffPostRevisionsRequest,
+) (*pb.DiffPostRevisionsResponse, error) {
+	from, err := getRevision(ctx, c.repo, req.PostId.Value, req.FromRevision)
+	if err != nil {
+		return nil, err
+	}
+	to, err := getRevision(ctx, c.repo, req.PostId.Value, req.ToRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, f := range []struct {
+		name     string
+		from, to string
+	}{
+		{"title", from.Title, to.Title},
+		{"description", from.Description, to.Description},
+		{"slug", from.Slug, to.Slug},
+		{"cover_image", from.CoverImage, to.CoverImage},
+	} {
+		if f.from != f.to {
+			changed = append(changed, f.name)
+		}
+	}
+
+	return &pb.DiffPostRevisionsResponse{
+		From:          from,
+		To:            to,
+		Lines:         diffLines(from.Content, to.Content),
+		ChangedFields: changed,
+	}, nil
+}
+
+func (c *CmsService) RestorePostRevision(
+	ctx context.Context,
+	req *pb.RestorePostRevisionRequest,
+) (*pb.RestorePostRevisionResponse, error) {
+	var revision int32
+	err := c.repo.WithTx(ctx, func(repo Repository) error {
+		post, err := repo.GetPost(ctx, req.PostId.Value, true)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return status.Errorf(codes.NotFound, "post not found")
+			}
+			return status.Errorf(codes.Internal, "could not restore revision: %w", err)
+		}
+		rev, err := getRevision(ctx, repo, req.PostId.Value, req.Revision)
+		if err != nil {
+			return err
+		}
+
+		post.Title = rev.Title
+		post.Description = rev.Description
+		post.Slug = rev.Slug
+		post.CoverImage = rev.CoverImage
+		post.Content = rev.Content
+		if err := renderForStorage(post); err != nil {
+			return err
+		}
+		if err := repo.UpdatePost(ctx, post); err != nil {
+			return status.Errorf(codes.Internal, "could not restore revision: %w", err)
+		}
+
+		if err := syncShortcodeProducts(ctx, repo, req.PostId.Value, rev.Content); err != nil {
+			return err
+		}
+		if err := syncPostMedia(ctx, repo, req.PostId.Value); err != nil {
+			return err
+		}
+
+		// restoring is itself an edit, so it goes on top of the history.
+		revision, err = recordRevision(ctx, repo, req.PostId.Value, req.EditorId.GetValue())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.RestorePostRevisionResponse{PostId: req.PostId, Revision: revision}, nil
+}
+
+func getRevision(ctx context.Context, repo Repository, postId string, revision int32) (*pb.PostRevision, error) {
+	rev, err := repo.GetRevision(ctx, postId, revision)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "revision %d not found", revision)
+		}
+		return nil, status.Errorf(codes.Internal, "could not get revision: %v", err)
+	}
+	return rev, nil
+}
+
+// maxDiffCells caps the table diffLines builds for the part of the texts
+// that differs. Past it that part is shown as removed and added again
+// rather than lined up.
+const maxDiffCells = 1 << 20
+
+// diffLines is a plain longest-common-subsequence diff over lines, which is
+// plenty for article sized text. Lines the texts start and end with are
+// matched up front, so a small edit to a long post only diffs the edit.
+func diffLines(a, b string) []*pb.DiffLine {
+	from := strings.Split(a, "\n")
+	to := strings.Split(b, "\n")
+
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix &&
+		from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	var lines []*pb.DiffLine
+	for _, line := range from[:prefix] {
+		lines = append(lines, &pb.DiffLine{Op: pb.DiffLine_EQUAL, Text: line})
+	}
+	lines = append(lines, lcsDiff(from[prefix:len(from)-suffix], to[prefix:len(to)-suffix])...)
+	for _, line := range from[len(from)-suffix:] {
+		lines = append(lines, &pb.DiffLine{Op: pb.DiffLine_EQUAL, Text: line})
+	}
+	return lines
+}
+
+func lcsDiff(from, to []string) []*pb.DiffLine {
+	var lines []*pb.DiffLine
+	i, j := 0, 0
+
+	if (len(from)+1)*(len(to)+1) <= maxDiffCells {
+		// lcs[i][j] is the LCS length of from[i:] and to[j:].
+		lcs := make([][]int, len(from)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(to)+1)
+		}
+		for i := len(from) - 1; i >= 0; i-- {
+			for j := len(to) - 1; j >= 0; j-- {
+				if from[i] == to[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		for i < len(from) && j < len(to) {
+			switch {
+			case from[i] == to[j]:
+				lines = append(lines, &pb.DiffLine{Op: pb.DiffLine_EQUAL, Text: from[i]})
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lines = append(lines, &pb.DiffLine{Op: pb.DiffLine_DELETE, Text: from[i]})
+				i++
+			default:
+				lines = append(lines, &pb.DiffLine{Op: pb.DiffLine_INSERT, Text: to[j]})
+				j++
+			}
+		}
+	}
+	for ; i < len(from); i++ {
+		lines = append(lines, &pb.DiffLine{Op: pb.DiffLine_DELETE, Text: from[i]})
+	}
+	for ; j < len(to); j++ {
+		lines = append(lines, &pb.DiffLine{Op: pb.DiffLine_INSERT, Text: to[j]})
+	}
+
+	return lines
+}
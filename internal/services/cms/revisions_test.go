@@ -0,0 +1,116 @@
+package cmsservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// numbered returns n lines, "<prefix>0" to "<prefix>n-1".
+func numbered(prefix string, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprint(prefix, i)
+	}
+	return lines
+}
+
+func TestDiffLines(t *testing.T) {
+	// too many differing lines on each side to line up.
+	wide := 1100
+
+	tests := []struct {
+		name     string
+		from, to []string
+		// want is the ops in order, E, D or I per line.
+		want string
+	}{
+		{name: "same", from: []string{"a", "b"}, to: []string{"a", "b"}, want: "EE"},
+		{name: "line changed", from: []string{"a", "b", "c"}, to: []string{"a", "x", "c"}, want: "EDIE"},
+		{name: "line added", from: []string{"a", "c"}, to: []string{"a", "b", "c"}, want: "EIE"},
+		{name: "moved line", from: []string{"a", "b", "c", "d"}, to: []string{"b", "c", "a", "d"}, want: "DEEIE"},
+		{
+			name: "past the cap",
+			from: append(append([]string{"title"}, numbered("old ", wide)...), "end"),
+			to:   append(append([]string{"title"}, numbered("new ", wide)...), "end"),
+			want: "E" + strings.Repeat("D", wide) + strings.Repeat("I", wide) + "E",
+		},
+	}
+
+	ops := map[pb.DiffLine_Op]string{pb.DiffLine_EQUAL: "E", pb.DiffLine_DELETE: "D", pb.DiffLine_INSERT: "I"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := diffLines(strings.Join(tt.from, "\n"), strings.Join(tt.to, "\n"))
+
+			var got strings.Builder
+			var from, to []string
+			for _, line := range lines {
+				got.WriteString(ops[line.Op])
+				if line.Op != pb.DiffLine_INSERT {
+					from = append(from, line.Text)
+				}
+				if line.Op != pb.DiffLine_DELETE {
+					to = append(to, line.Text)
+				}
+			}
+			if got.String() != tt.want {
+				t.Errorf("got ops %s, want %s", got.String(), tt.want)
+			}
+			// either side can be read back out of the diff.
+			if !slices.Equal(from, tt.from) || !slices.Equal(to, tt.to) {
+				t.Errorf("diff does not rebuild the texts")
+			}
+		})
+	}
+}
+
+func TestRestorePostRevision(t *testing.T) {
+	tests := []struct {
+		name     string
+		post     string
+		revision int32
+		want     codes.Code
+		wantMsg  string
+	}{
+		{name: "restores the first revision", revision: 1, want: codes.OK},
+		{name: "unknown revision", revision: 9, want: codes.NotFound, wantMsg: "revision 9 not found"},
+		{name: "unknown post", post: uuid.NewString(), revision: 1, want: codes.NotFound, wantMsg: "post not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			ctx := context.Background()
+			postId := f.createPost(t, f.post("Malaria"))
+			f.updatePost(t, postId, f.post("Cholera"))
+			post := postId
+			if tt.post != "" {
+				post = tt.post
+			}
+
+			_, err := f.svc.RestorePostRevision(ctx, &pb.RestorePostRevisionRequest{
+				PostId:   &pb.UUID{Value: post},
+				Revision: tt.revision,
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+			if err != nil {
+				if msg := status.Convert(err).Message(); msg != tt.wantMsg {
+					t.Errorf("got message %q, want %q", msg, tt.wantMsg)
+				}
+				return
+			}
+			if got := f.getPost(t, postId); got.Title != "Malaria" {
+				t.Errorf("title = %q after restoring, want %q", got.Title, "Malaria")
+			}
+		})
+	}
+}
@@ -2,63 +2,80 @@ package cmsservice
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
+	"errors"
 
 	"github.com/kelcheone/chemistke/internal/database"
 	"github.com/kelcheone/chemistke/pkg/codes"
 	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	productpb "github.com/kelcheone/chemistke/pkg/grpc/product"
 	"github.com/kelcheone/chemistke/pkg/status"
 )
 
 type CmsService struct {
 	pb.UnimplementedCmsServiceServer
-	db database.DB
+	repo Repository
+	// Products hydrates the products linked to a post, it is optional.
+	Products productpb.ProductServiceClient
 }
 
-func NewCmsService(db database.DB) *CmsService {
-	return &CmsService{db: db}
+func NewCmsService(repo Repository) *CmsService {
+	return &CmsService{repo: repo}
 }
 
 func (c *CmsService) CreatePost(
 	ctx context.Context,
 	req *pb.CreatePostRequest,
 ) (*pb.CreatePostResponse, error) {
-	stmt := `INSERT INTO content (
-  published_date,
-  updated_date,
-  cover_image,
-  title,
-  description,
-  slug,
-  content,
-  status,
-  author_id,
-  category_id
-  )VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
 	post := req.Post
-	row := c.db.QueryRow(
-		stmt,
-		post.PublishedDate,
-		post.UpdatedDate,
-		post.CoverImage,
-		post.Title,
-		post.Description,
-		post.Slug,
-		post.Content,
-		post.Status,
-		post.AuthorId.Value,
-		post.CategoryId.Value,
-	)
-	var postId string
-	if err := row.Scan(&postId); err != nil {
+	if post.Status != pb.PostStatus_DRAFT && post.Status != pb.PostStatus_IN_REVIEW {
 		return nil, status.Errorf(
-			codes.Internal,
-			"could not get post Id: %v",
-			err,
+			codes.InvalidArgument,
+			"new posts can only be drafts or in review, got %s",
+			post.Status,
 		)
 	}
 
+	if err := renderForStorage(post); err != nil {
+		return nil, err
+	}
+
+	// the post, its tags and products and its first revision are saved
+	// together or not at all.
+	var postId string
+	err := c.repo.WithTx(ctx, func(repo Repository) error {
+		var err error
+		postId, err = repo.CreatePost(ctx, post)
+		if err != nil {
+			if errors.Is(err, database.ErrConflict) {
+				return status.Errorf(codes.AlreadyExists, "a post with that slug already exists")
+			}
+			return status.Errorf(
+				codes.Internal,
+				"could not get post Id: %w",
+				err,
+			)
+		}
+
+		if len(post.TagIds) > 0 {
+			if err := setPostTags(ctx, repo, postId, post.TagIds); err != nil {
+				return err
+			}
+		}
+
+		if err := setPostProducts(ctx, repo, postId, post.ProductIds, post.Content); err != nil {
+			return err
+		}
+		if err := syncPostMedia(ctx, repo, postId); err != nil {
+			return err
+		}
+
+		_, err = recordRevision(ctx, repo, postId, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &pb.CreatePostResponse{
 		PostId: &pb.UUID{Value: postId},
 	}, nil
@@ -68,27 +85,9 @@ func (c *CmsService) GetPost(
 	ctx context.Context,
 	req *pb.GetPostRequest,
 ) (*pb.GetPostResponse, error) {
-	stmt := `SELECT id, published_date, updated_date, cover_image, title, description, slug, content, status, author_id, category_id FROM content WHERE id=$1`
-	// stmt := `SELECT * FROM content WHERE id=$1`
-	var post pb.Post
-	var postId, categoryId, authorId string
-	fmt.Println(req.PostId.Value)
-	fmt.Println(stmt)
-	err := c.db.QueryRow(stmt, req.PostId.Value).Scan(
-		&postId,
-		&post.PublishedDate,
-		&post.UpdatedDate,
-		&post.CoverImage,
-		&post.Title,
-		&post.Description,
-		&post.Slug,
-		&post.Content,
-		&post.Status,
-		&authorId,
-		&categoryId,
-	)
+	post, err := c.repo.GetPost(ctx, req.PostId.Value, req.IncludeUnpublished)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(
 				codes.NotFound,
 				"post with id %s not found",
@@ -98,54 +97,77 @@ func (c *CmsService) GetPost(
 		return nil, status.Errorf(codes.Internal, "error getting post: %v", err)
 	}
 
-	post.PostId = &pb.UUID{Value: postId}
-	post.AuthorId = &pb.UUID{Value: authorId}
-	post.CategoryId = &pb.UUID{Value: categoryId}
-	return &pb.GetPostResponse{Post: &post}, nil
+	if post.Tags, err = c.postTags(ctx, post.PostId.Value); err != nil {
+		return nil, err
+	}
+	if err := c.postProducts(ctx, post); err != nil {
+		return nil, err
+	}
+
+	return &pb.GetPostResponse{Post: post}, nil
 }
 
 func (c *CmsService) UpdatePost(
 	ctx context.Context,
 	req *pb.UpdatePostRequest,
 ) (*pb.UpdatePostResponse, error) {
-	stmt := `UPDATE content SET published_date=$1, updated_date=$2, cover_image=$3, title=$4, slug=$5, content=$6, status=$7, author_id=$8, category_id=$9, description=$10 WHERE id=$11 RETURNING id`
+	// status and publish dates only change through the workflow rpcs.
 	post := req.Post
-	_, err := c.db.Exec(
-		stmt,
-		post.PublishedDate,
-		post.UpdatedDate,
-		post.CoverImage,
-		post.Title,
-		post.Slug,
-		post.Content,
-		post.Status,
-		post.AuthorId.Value,
-		post.CategoryId.Value,
-		post.Description,
-		req.PostId.Value,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Errorf(codes.NotFound, "post does not exist")
+	post.PostId = req.PostId
+	if err := renderForStorage(post); err != nil {
+		return nil, err
+	}
+
+	err := c.repo.WithTx(ctx, func(repo Repository) error {
+		if err := repo.UpdatePost(ctx, post); err != nil {
+			switch {
+			case errors.Is(err, database.ErrNotFound):
+				return status.Errorf(codes.NotFound, "post does not exist")
+			case errors.Is(err, database.ErrConflict):
+				return status.Errorf(codes.AlreadyExists, "a post with that slug already exists")
+			}
+			return status.Errorf(
+				codes.Internal,
+				"could not update post: %w",
+				err,
+			)
 		}
-		return nil, status.Errorf(
-			codes.Internal,
-			"could not update post: %v",
-			err,
-		)
+
+		// tags and hand linked products are only replaced when the caller
+		// sent them, so a partial update keeps them.
+		if req.ReplaceTags {
+			if err := setPostTags(ctx, repo, req.PostId.Value, post.TagIds); err != nil {
+				return err
+			}
+		}
+
+		if req.ReplaceProducts {
+			if err := setPostProducts(ctx, repo, req.PostId.Value, post.ProductIds, post.Content); err != nil {
+				return err
+			}
+		} else if err := syncShortcodeProducts(ctx, repo, req.PostId.Value, post.Content); err != nil {
+			return err
+		}
+		if err := syncPostMedia(ctx, repo, req.PostId.Value); err != nil {
+			return err
+		}
+
+		_, err := recordRevision(ctx, repo, req.PostId.Value, req.EditorId.GetValue())
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return &pb.UpdatePostResponse{PostId: post.PostId}, nil
+	return &pb.UpdatePostResponse{PostId: req.PostId}, nil
 }
 
 func (c *CmsService) DeletePost(
 	ctx context.Context,
 	req *pb.DeletePostRequest,
 ) (*pb.DeletePostResponse, error) {
-	stmt := `DELETE FROM content WHERE id=$1`
-	_, err := c.db.Exec(stmt, req.PostId.Value)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	if err := c.repo.DeletePost(ctx, req.PostId.Value); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(codes.NotFound, "post does not exist")
 		}
 		return nil, status.Errorf(codes.Internal, "could not delet post ")
@@ -157,47 +179,39 @@ func (c *CmsService) ListPosts(
 	ctx context.Context,
 	req *pb.ListPostsRequest,
 ) (*pb.ListPostsResponse, error) {
-	stmt := `SELECT id, published_date, updated_date, cover_image, title, description,
-  slug, content, status, author_id, category_id FROM content LIMIT $1 OFFSET $2`
+	posts, err := c.listPosts(ctx, PostFilter{
+		Statuses: req.Statuses,
+		Limit:    req.PerPage,
+		Offset:   req.Page,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListPostsResponse{Posts: posts}, nil
+}
 
-	rows, err := c.db.Query(stmt, req.PerPage, req.Page)
+// listPosts returns the posts matching filter.
+func (c *CmsService) listPosts(ctx context.Context, filter PostFilter) ([]*pb.Post, error) {
+	posts, err := c.repo.ListPosts(ctx, filter)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
 			"query not successful: %v",
-			err.Error(),
+			err,
 		)
 	}
-
-	var posts []*pb.Post
-	for rows.Next() {
-
-		post, err := PostRowScanner(rows)
-		if err != nil {
-			return nil, err
-		}
-
-		posts = append(posts, post)
-
-	}
-
-	return &pb.ListPostsResponse{Posts: posts}, nil
+	return posts, nil
 }
 
 func (c *CmsService) CreateCategory(
 	ctx context.Context,
 	req *pb.CreateCategoryRequest,
 ) (*pb.CreateCategoryResponse, error) {
-	stmt := `INSERT INTO categories (name, slug, description) VALUES($1, $2, $3) RETURNING id`
-	row := c.db.QueryRow(
-		stmt,
-		req.Category.Name,
-		req.Category.Slug,
-		req.Category.Description,
-	)
-	var categoryId string
-	err := row.Scan(&categoryId)
+	categoryId, err := c.repo.CreateCategory(ctx, req.Category)
 	if err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			return nil, status.Errorf(codes.AlreadyExists, "a category with that slug already exists")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not get the category id: %v",
@@ -213,18 +227,9 @@ func (c *CmsService) GetCategory(
 	ctx context.Context,
 	req *pb.GetCategoryRequest,
 ) (*pb.GetCategoryResponse, error) {
-	stmt := `SELECT id, name, slug, description FROM categories WHERE id=$1`
-	row := c.db.QueryRow(stmt, req.CategoryId.Value)
-	var category pb.Category
-	var categoryId string
-	err := row.Scan(
-		&categoryId,
-		&category.Name,
-		&category.Slug,
-		&category.Description,
-	)
+	category, err := c.repo.GetCategory(ctx, req.CategoryId.Value)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(
 				codes.NotFound,
 				"category with id %s not found",
@@ -234,33 +239,27 @@ func (c *CmsService) GetCategory(
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not get category: %v",
-			err.Error(),
+			err,
 		)
 	}
-	category.CategoryId = &pb.UUID{Value: categoryId}
-	return &pb.GetCategoryResponse{Category: &category}, nil
+	return &pb.GetCategoryResponse{Category: category}, nil
 }
 
 func (c *CmsService) UpdateCategory(
 	ctx context.Context,
 	req *pb.UpdateCategoryRequest,
 ) (*pb.UpdateCategoryResponse, error) {
-	stmt := `UPDATE categories SET name=$1, slug=$2, description=$3 WHERE id=$4`
-	_, err := c.db.Exec(
-		stmt,
-		req.Category.Name,
-		req.Category.Slug,
-		req.Category.Description,
-		req.Category.CategoryId.Value,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	if err := c.repo.UpdateCategory(ctx, req.Category); err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
 			return nil, status.Errorf(codes.NotFound, "category does not exist")
+		case errors.Is(err, database.ErrConflict):
+			return nil, status.Errorf(codes.AlreadyExists, "a category with that slug already exists")
 		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not update category: %v",
-			err.Error(),
+			err,
 		)
 	}
 	return nil, nil
@@ -270,16 +269,14 @@ func (c *CmsService) DeleteCategory(
 	ctx context.Context,
 	req *pb.DeleteCategoryRequest,
 ) (*pb.DeleteCategoryResponse, error) {
-	stmt := `DELETE FROM categories WHERE id=$1`
-	_, err := c.db.Exec(stmt, req.CategoryId.Value)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	if err := c.repo.DeleteCategory(ctx, req.CategoryId.Value); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(codes.NotFound, "category does not exist")
 		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not delete category: %v",
-			err.Error(),
+			err,
 		)
 	}
 	return &pb.DeleteCategoryResponse{CategoryId: req.CategoryId}, nil
@@ -289,25 +286,14 @@ func (c *CmsService) ListCategories(
 	ctx context.Context,
 	req *pb.ListCategoriesRequest,
 ) (*pb.ListCategoriesResponse, error) {
-	stmt := `SELECT id, name, slug, description FROM categories LIMIT $1 OFFSET $2`
-
-	rows, err := c.db.Query(stmt, req.PerPage, req.Page)
+	categories, err := c.repo.ListCategories(ctx, req.PerPage, req.Page)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not fetch categories: %v",
-			err.Error(),
+			err,
 		)
 	}
-
-	var categories []*pb.Category
-	for rows.Next() {
-		category, err := CategoryRowScanner(rows)
-		if err != nil {
-			return nil, err
-		}
-		categories = append(categories, category)
-	}
 	return &pb.ListCategoriesResponse{Categories: categories}, nil
 }
 
@@ -315,26 +301,13 @@ func (c *CmsService) GetCategoryPosts(
 	ctx context.Context,
 	req *pb.GetCategoryPostsRequest,
 ) (*pb.GetCategoryPostsResponse, error) {
-	stmt := `SELECT id, published_date, updated_date, cover_image, title, description,
-  slug, content, status, author_id, category_id FROM content WHERE category_id=$1 LIMIT $2 OFFSET $3 `
-
-	rows, err := c.db.Query(stmt, req.CategoryId.Value, req.PerPage, req.Page)
+	posts, err := c.listPosts(ctx, PostFilter{
+		CategoryId: req.CategoryId.Value,
+		Limit:      req.PerPage,
+		Offset:     req.Page,
+	})
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"query not successful: %v",
-			err.Error(),
-		)
-	}
-
-	var posts []*pb.Post
-	for rows.Next() {
-		post, err := PostRowScanner(rows)
-		if err != nil {
-			return nil, err
-		}
-
-		posts = append(posts, post)
+		return nil, err
 	}
 	return &pb.GetCategoryPostsResponse{Posts: posts}, nil
 }
@@ -343,22 +316,13 @@ func (c *CmsService) CreateAuthor(
 	ctx context.Context,
 	req *pb.CreateAuthorRequest,
 ) (*pb.CreateAuthorResponse, error) {
-	stmt := `INSERT INTO authors (bio, avatar, url, user_id) VALUES ($1, $2, $3, $4) RETURNING id`
-	author := req.Author
-	row := c.db.QueryRow(
-		stmt,
-		author.Bio,
-		author.Avatar,
-		author.Url,
-		author.UserId.Value,
-	)
-	var authorId string
-	err := row.Scan(&authorId)
+	// an empty slug is filled in from the user's name.
+	authorId, err := c.repo.CreateAuthor(ctx, req.Author)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not create author: %v",
-			err.Error(),
+			err,
 		)
 	}
 
@@ -369,19 +333,9 @@ func (c *CmsService) GetAuthor(
 	ctx context.Context,
 	req *pb.GetAuthorRequest,
 ) (*pb.GetAuthorResponse, error) {
-	stmt := `SELECT id, bio, avatar, url, user_id FROM authors WHERE id=$1`
-	var author pb.Author
-	var authorId, userId string
-	row := c.db.QueryRow(stmt, req.AuthorId.Value)
-	err := row.Scan(
-		&authorId,
-		&author.Bio,
-		&author.Avatar,
-		&author.Url,
-		&userId,
-	)
+	author, err := c.repo.GetAuthor(ctx, req.AuthorId.Value)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(
 				codes.NotFound,
 				"could not find author with id %s",
@@ -391,34 +345,27 @@ func (c *CmsService) GetAuthor(
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not get author: %v",
-			err.Error(),
+			err,
 		)
 	}
-	author.AuthorId = &pb.UUID{Value: authorId}
-	author.UserId = &pb.UUID{Value: userId}
-	return &pb.GetAuthorResponse{Author: &author}, nil
+	return &pb.GetAuthorResponse{Author: author}, nil
 }
 
 func (c *CmsService) UpdateAuthor(
 	ctx context.Context,
 	req *pb.UpdateAuthorRequest,
 ) (*pb.UpdateAuthorResponse, error) {
-	stmt := `UPDATE authors SET bio=$1, avatar=$2, url=$3 WHERE id=$4`
-	_, err := c.db.Exec(
-		stmt,
-		req.Author.Bio,
-		req.Author.Avatar,
-		req.Author.Url,
-		req.AuthorId.Value,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	// leaving the slug empty keeps the current one.
+	author := req.Author
+	author.AuthorId = req.AuthorId
+	if err := c.repo.UpdateAuthor(ctx, author); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(codes.NotFound, "author does not exist")
 		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not update author: %v",
-			err.Error(),
+			err,
 		)
 	}
 	return &pb.UpdateAuthorResponse{AuthorId: req.AuthorId}, nil
@@ -428,11 +375,8 @@ func (c *CmsService) DeleteAuthor(
 	ctx context.Context,
 	req *pb.DeleteAuthorRequest,
 ) (*pb.DeleteAuthorResponse, error) {
-	stmt := `DELETE FROM authors WHERE id=$1`
-
-	_, err := c.db.Exec(stmt, req.AuthorId.Value)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	if err := c.repo.DeleteAuthor(ctx, req.AuthorId.Value); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(codes.NotFound, "author does not exist")
 		}
 		return nil, status.Errorf(
@@ -448,38 +392,13 @@ func (c *CmsService) ListAuthors(
 	ctx context.Context,
 	req *pb.ListAuthorsRequest,
 ) (*pb.ListAuthorsResponse, error) {
-	stmt := `SELECT id, bio, avatar, url, user_id FROM authors LIMIT $1 OFFSET $2`
-	rows, err := c.db.Query(stmt, req.PerPage, req.Page)
+	authors, err := c.repo.ListAuthors(ctx, req.PerPage, req.Page)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
 			"could get authors: %v",
-			err.Error(),
-		)
-	}
-
-	var authors []*pb.Author
-	for rows.Next() {
-		var author pb.Author
-		var authorId, userId string
-		err := rows.Scan(
-			&authorId,
-			&author.Bio,
-			&author.Avatar,
-			&author.Url,
-			&userId,
+			err,
 		)
-		if err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"could not get author: %v",
-				err.Error(),
-			)
-		}
-		author.AuthorId = &pb.UUID{Value: authorId}
-		author.UserId = &pb.UUID{Value: userId}
-
-		authors = append(authors, &author)
 	}
 	return &pb.ListAuthorsResponse{Authors: authors}, nil
 }
@@ -488,26 +407,13 @@ func (c *CmsService) GetAuthorPosts(
 	ctx context.Context,
 	req *pb.GetAuthorPostsRequest,
 ) (*pb.GetAuthorPostsResponse, error) {
-	stmt := `SELECT id, published_date, updated_date, cover_image, title, description,
-  slug, content, status, author_id, category_id FROM content WHERE author_id=$1 LIMIT $2 OFFSET $3 `
-
-	rows, err := c.db.Query(stmt, req.AuthorId.Value, req.PerPage, req.Page)
+	posts, err := c.listPosts(ctx, PostFilter{
+		AuthorId: req.AuthorId.Value,
+		Limit:    req.PerPage,
+		Offset:   req.Page,
+	})
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"query not successful: %v",
-			err.Error(),
-		)
-	}
-
-	var posts []*pb.Post
-	for rows.Next() {
-		post, err := PostRowScanner(rows)
-		if err != nil {
-			return nil, err
-		}
-
-		posts = append(posts, post)
+		return nil, err
 	}
 	return &pb.GetAuthorPostsResponse{Posts: posts}, nil
 }
@@ -516,31 +422,14 @@ func (c *CmsService) GetAuthorCategoryPosts(
 	ctx context.Context,
 	req *pb.GetAuthorCategoryPostsRequest,
 ) (*pb.GetAuthorCategoryPostsResponse, error) {
-	stmt := `SELECT id, published_date, updated_date, cover_image, title, description,
-  slug, content, status, author_id, category_id FROM content WHERE category_id=$1 AND author_id=$2 LIMIT $3 OFFSET $4 `
-
-	rows, err := c.db.Query(
-		stmt,
-		req.CategoryId.Value,
-		req.AuthorId.Value,
-		req.PerPage,
-		req.Page,
-	)
+	posts, err := c.listPosts(ctx, PostFilter{
+		CategoryId: req.CategoryId.Value,
+		AuthorId:   req.AuthorId.Value,
+		Limit:      req.PerPage,
+		Offset:     req.Page,
+	})
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"query not successful: %v",
-			err.Error(),
-		)
-	}
-
-	var posts []*pb.Post
-	for rows.Next() {
-		post, err := PostRowScanner(rows)
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
+		return nil, err
 	}
 	return &pb.GetAuthorCategoryPostsResponse{Posts: posts}, nil
 }
@@ -549,71 +438,34 @@ func (c *CmsService) UpdateUserRole(
 	ctx context.Context,
 	req *pb.UpdateUserRoleRequest,
 ) (*pb.UpdateUserRoleResponse, error) {
-	stmt := `UPDATE users SET role=$1 WHERE id=$2`
-
-	_, err := c.db.Exec(stmt, req.Role, req.UserId.Value)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	if err := c.repo.UpdateUserRole(ctx, req.UserId.Value, req.Role); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(codes.NotFound, "user does not exist")
 		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"could not update user: %v",
-			err.Error(),
-		)
-	}
-
-	return &pb.UpdateUserRoleResponse{}, nil
-}
-
-func PostRowScanner(rows *sql.Rows) (*pb.Post, error) {
-	var post pb.Post
-	var postId, categoryId, authorId string
-
-	err := rows.Scan(
-		&postId,
-		&post.PublishedDate,
-		&post.UpdatedDate,
-		&post.CoverImage,
-		&post.Title,
-		&post.Description,
-		&post.Slug,
-		&post.Content,
-		&post.Status,
-		&authorId,
-		&categoryId,
-	)
-	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"could not scan post: %v",
 			err,
 		)
 	}
 
-	post.PostId = &pb.UUID{Value: postId}
-	post.AuthorId = &pb.UUID{Value: authorId}
-	post.CategoryId = &pb.UUID{Value: categoryId}
-
-	return &post, nil
+	return &pb.UpdateUserRoleResponse{}, nil
 }
 
-func CategoryRowScanner(rows *sql.Rows) (*pb.Category, error) {
-	var category pb.Category
-	var categoryId string
-	err := rows.Scan(
-		&categoryId,
-		&category.Name,
-		&category.Slug,
-		&category.Description,
-	)
+// renderForStorage renders a post's markdown into the fields stored
+// alongside it.
+func renderForStorage(post *pb.Post) error {
+	rendered, err := renderMarkdown(post.Content)
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"could not scan category: %v",
+		return status.Errorf(
+			codes.InvalidArgument,
+			"could not render content: %v",
 			err,
 		)
 	}
-	category.CategoryId = &pb.UUID{Value: categoryId}
-	return &category, nil
+	post.ContentHtml = rendered.HTML
+	post.Toc = rendered.Toc
+	post.WordCount = rendered.WordCount
+	post.ReadingTimeMinutes = rendered.ReadingTimeMinutes
+	return nil
 }
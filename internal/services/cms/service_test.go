@@ -0,0 +1,199 @@
+package cmsservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+)
+
+// fixture is a CmsService on an in-memory repository with an admin who is
+// also an author, and one category.
+type fixture struct {
+	svc        *CmsService
+	repo       *MemoryRepository
+	userId     string
+	authorId   string
+	categoryId string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	f := &fixture{repo: NewMemoryRepository(), userId: uuid.NewString()}
+	f.svc = NewCmsService(f.repo)
+	f.repo.AddUser(f.userId, "Jane Doe", pb.UserRoles_ADMIN)
+
+	author, err := f.svc.CreateAuthor(ctx, &pb.CreateAuthorRequest{
+		Author: &pb.Author{UserId: &pb.UUID{Value: f.userId}, Bio: "pharmacist"},
+	})
+	if err != nil {
+		t.Fatalf("CreateAuthor: %v", err)
+	}
+	f.authorId = author.AuthorId.Value
+
+	category, err := f.svc.CreateCategory(ctx, &pb.CreateCategoryRequest{
+		Category: &pb.Category{Name: "Health"},
+	})
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	f.categoryId = category.CategoryId.Value
+
+	return f
+}
+
+func (f *fixture) createTag(t *testing.T, name string) string {
+	t.Helper()
+	resp, err := f.svc.CreateTag(context.Background(), &pb.CreateTagRequest{Tag: &pb.Tag{Name: name}})
+	if err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	return resp.TagId.Value
+}
+
+// post returns a post by the fixture's author in its category.
+func (f *fixture) post(title string) *pb.Post {
+	return &pb.Post{
+		Title:       title,
+		Description: "about " + title,
+		Content:     "## " + title,
+		AuthorId:    &pb.UUID{Value: f.authorId},
+		CategoryId:  &pb.UUID{Value: f.categoryId},
+	}
+}
+
+func (f *fixture) createPost(t *testing.T, post *pb.Post) string {
+	t.Helper()
+	resp, err := f.svc.CreatePost(context.Background(), &pb.CreatePostRequest{Post: post})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	return resp.PostId.Value
+}
+
+// updatePost saves post over postId, keeping its tags and hand linked
+// products.
+func (f *fixture) updatePost(t *testing.T, postId string, post *pb.Post) {
+	t.Helper()
+	_, err := f.svc.UpdatePost(context.Background(), &pb.UpdatePostRequest{
+		PostId: &pb.UUID{Value: postId},
+		Post:   post,
+	})
+	if err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+}
+
+func (f *fixture) getPost(t *testing.T, postId string) *pb.Post {
+	t.Helper()
+	resp, err := f.svc.GetPost(context.Background(), &pb.GetPostRequest{
+		PostId:             &pb.UUID{Value: postId},
+		IncludeUnpublished: true,
+	})
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	return resp.Post
+}
+
+func TestUpdatePostTagsAndProducts(t *testing.T) {
+	productA, productB := uuid.NewString(), uuid.NewString()
+
+	tests := []struct {
+		name            string
+		replaceTags     bool
+		replaceProducts bool
+		// tags and products sent with the update, by index into the
+		// post's tags and into {productA, productB}.
+		tags, products []int
+		wantTags       int
+		wantProducts   []string
+	}{
+		{name: "partial update keeps both", wantTags: 2, wantProducts: []string{productA}},
+		{name: "replace tags", replaceTags: true, tags: []int{1}, wantTags: 1, wantProducts: []string{productA}},
+		{name: "clear tags", replaceTags: true, wantTags: 0, wantProducts: []string{productA}},
+		{name: "replace products", replaceProducts: true, products: []int{1}, wantTags: 2, wantProducts: []string{productB}},
+		{name: "clear products", replaceProducts: true, wantTags: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			tagIds := []string{f.createTag(t, "Diabetes"), f.createTag(t, "Nutrition")}
+			productIds := []string{productA, productB}
+
+			post := f.post("Managing blood sugar")
+			post.TagIds = []*pb.UUID{{Value: tagIds[0]}, {Value: tagIds[1]}}
+			post.ProductIds = []*pb.UUID{{Value: productA}}
+			postId := f.createPost(t, post)
+
+			update := f.post("Managing blood sugar, revised")
+			for _, i := range tt.tags {
+				update.TagIds = append(update.TagIds, &pb.UUID{Value: tagIds[i]})
+			}
+			for _, i := range tt.products {
+				update.ProductIds = append(update.ProductIds, &pb.UUID{Value: productIds[i]})
+			}
+			_, err := f.svc.UpdatePost(context.Background(), &pb.UpdatePostRequest{
+				PostId:          &pb.UUID{Value: postId},
+				Post:            update,
+				EditorId:        &pb.UUID{Value: f.userId},
+				ReplaceTags:     tt.replaceTags,
+				ReplaceProducts: tt.replaceProducts,
+			})
+			if err != nil {
+				t.Fatalf("UpdatePost: %v", err)
+			}
+
+			got := f.getPost(t, postId)
+			if got.Title != update.Title {
+				t.Errorf("title = %q, want %q", got.Title, update.Title)
+			}
+			if len(got.Tags) != tt.wantTags {
+				t.Errorf("got %d tags, want %d", len(got.Tags), tt.wantTags)
+			}
+			var gotProducts []string
+			for _, id := range got.ProductIds {
+				gotProducts = append(gotProducts, id.Value)
+			}
+			if len(gotProducts) != len(tt.wantProducts) ||
+				len(gotProducts) > 0 && gotProducts[0] != tt.wantProducts[0] {
+				t.Errorf("products = %v, want %v", gotProducts, tt.wantProducts)
+			}
+		})
+	}
+}
+
+func TestUpdatePostSyncsShortcodeProducts(t *testing.T) {
+	f := newFixture(t)
+	embedded := uuid.NewString()
+
+	post := f.post("Sunscreen")
+	post.ProductIds = []*pb.UUID{{Value: uuid.NewString()}}
+	postId := f.createPost(t, post)
+
+	// the content changes without product_ids, the hand linked product is
+	// kept and the embedded one is picked up.
+	update := f.post("Sunscreen")
+	update.Content = `Try {{< product id="` + embedded + `" >}}`
+	f.updatePost(t, postId, update)
+
+	linked, err := f.repo.ListPostProducts(context.Background(), postId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var explicit, shortcode int
+	for _, p := range linked {
+		if p.Explicit {
+			explicit++
+		} else if p.ProductId == embedded {
+			shortcode++
+		}
+	}
+	if explicit != 1 || shortcode != 1 {
+		t.Errorf("got %d hand linked and %d embedded products, want 1 and 1", explicit, shortcode)
+	}
+}
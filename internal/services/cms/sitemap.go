@@ -0,0 +1,37 @@
+package cmsservice
+
+import (
+	"context"
+
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+func (c *CmsService) GetSitemapIndex(
+	ctx context.Context,
+	req *pb.GetSitemapIndexRequest,
+) (*pb.GetSitemapIndexResponse, error) {
+	if req.ChunkSize <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "chunk size must be positive")
+	}
+
+	chunks, err := c.repo.SitemapChunks(ctx, req.ChunkSize)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not build sitemap index: %v", err)
+	}
+
+	return &pb.GetSitemapIndexResponse{Chunks: chunks}, nil
+}
+
+func (c *CmsService) GetSitemapEntries(
+	ctx context.Context,
+	req *pb.GetSitemapEntriesRequest,
+) (*pb.GetSitemapEntriesResponse, error) {
+	entries, err := c.repo.SitemapEntries(ctx, req.Limit, req.Offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch sitemap entries: %v", err)
+	}
+
+	return &pb.GetSitemapEntriesResponse{Entries: entries}, nil
+}
@@ -0,0 +1,108 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// slugs are generated and kept unique by the set_unique_slug trigger, which
+// also records the old slug in slug_redirects whenever one changes.
+
+func (c *CmsService) GetPostBySlug(
+	ctx context.Context,
+	req *pb.GetPostBySlugRequest,
+) (*pb.GetPostBySlugResponse, error) {
+	if req.Slug == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "slug was not provided")
+	}
+
+	post, err := c.repo.GetPostBySlug(ctx, req.Slug, req.IncludeUnpublished)
+	if err == nil {
+		if post.Tags, err = c.postTags(ctx, post.PostId.Value); err != nil {
+			return nil, err
+		}
+		if err := c.postProducts(ctx, post); err != nil {
+			return nil, err
+		}
+		return &pb.GetPostBySlugResponse{Post: post}, nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return nil, status.Errorf(codes.Internal, "error getting post: %v", err)
+	}
+
+	redirect, err := c.redirectSlug(ctx, "content", req.Slug, req.IncludeUnpublished)
+	if err != nil {
+		return nil, err
+	}
+	if redirect == "" {
+		return nil, status.Errorf(codes.NotFound, "post with slug %s not found", req.Slug)
+	}
+	return &pb.GetPostBySlugResponse{RedirectSlug: redirect}, nil
+}
+
+func (c *CmsService) GetCategoryBySlug(
+	ctx context.Context,
+	req *pb.GetCategoryBySlugRequest,
+) (*pb.GetCategoryBySlugResponse, error) {
+	if req.Slug == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "slug was not provided")
+	}
+
+	category, err := c.repo.GetCategoryBySlug(ctx, req.Slug)
+	if err == nil {
+		return &pb.GetCategoryBySlugResponse{Category: category}, nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return nil, status.Errorf(codes.Internal, "could not get category: %v", err)
+	}
+
+	redirect, err := c.redirectSlug(ctx, "categories", req.Slug, false)
+	if err != nil {
+		return nil, err
+	}
+	if redirect == "" {
+		return nil, status.Errorf(codes.NotFound, "category with slug %s not found", req.Slug)
+	}
+	return &pb.GetCategoryBySlugResponse{RedirectSlug: redirect}, nil
+}
+
+func (c *CmsService) GetAuthorBySlug(
+	ctx context.Context,
+	req *pb.GetAuthorBySlugRequest,
+) (*pb.GetAuthorBySlugResponse, error) {
+	if req.Slug == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "slug was not provided")
+	}
+
+	author, err := c.repo.GetAuthorBySlug(ctx, req.Slug)
+	if err == nil {
+		return &pb.GetAuthorBySlugResponse{Author: author}, nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return nil, status.Errorf(codes.Internal, "could not get author: %v", err)
+	}
+
+	redirect, err := c.redirectSlug(ctx, "authors", req.Slug, false)
+	if err != nil {
+		return nil, err
+	}
+	if redirect == "" {
+		return nil, status.Errorf(codes.NotFound, "author with slug %s not found", req.Slug)
+	}
+	return &pb.GetAuthorBySlugResponse{RedirectSlug: redirect}, nil
+}
+
+// redirectSlug returns the current slug of whatever used to be reachable at
+// slug, or "" when there is no such redirect.
+func (c *CmsService) redirectSlug(ctx context.Context, entity, slug string, includeUnpublished bool) (string, error) {
+	current, err := c.repo.RedirectSlug(ctx, entity, slug, includeUnpublished)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "could not look up slug: %v", err)
+	}
+	return current, nil
+}
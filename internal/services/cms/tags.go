@@ -0,0 +1,171 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+func (c *CmsService) CreateTag(
+	ctx context.Context,
+	req *pb.CreateTagRequest,
+) (*pb.CreateTagResponse, error) {
+	if req.Tag == nil || req.Tag.Name == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "tag name was not provided")
+	}
+
+	tagId, err := c.repo.CreateTag(ctx, req.Tag)
+	if err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			return nil, status.Errorf(codes.AlreadyExists, "a tag with that slug already exists")
+		}
+		return nil, status.Errorf(codes.Internal, "could not create tag: %v", err)
+	}
+
+	return &pb.CreateTagResponse{TagId: &pb.UUID{Value: tagId}}, nil
+}
+
+func (c *CmsService) GetTag(
+	ctx context.Context,
+	req *pb.GetTagRequest,
+) (*pb.GetTagResponse, error) {
+	var tag *pb.Tag
+	var err error
+	switch {
+	case req.TagId.GetValue() != "":
+		tag, err = c.repo.GetTag(ctx, req.TagId.Value)
+	case req.Slug != "":
+		tag, err = c.repo.GetTagBySlug(ctx, req.Slug)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "tag id or slug was not provided")
+	}
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "tag not found")
+		}
+		return nil, status.Errorf(codes.Internal, "could not get tag: %v", err)
+	}
+
+	return &pb.GetTagResponse{Tag: tag}, nil
+}
+
+func (c *CmsService) UpdateTag(
+	ctx context.Context,
+	req *pb.UpdateTagRequest,
+) (*pb.UpdateTagResponse, error) {
+	tag := req.Tag
+	tag.TagId = req.TagId
+	if err := c.repo.UpdateTag(ctx, tag); err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return nil, status.Errorf(codes.NotFound, "tag does not exist")
+		case errors.Is(err, database.ErrConflict):
+			return nil, status.Errorf(codes.AlreadyExists, "a tag with that slug already exists")
+		}
+		return nil, status.Errorf(codes.Internal, "could not update tag: %v", err)
+	}
+
+	return &pb.UpdateTagResponse{TagId: req.TagId}, nil
+}
+
+func (c *CmsService) DeleteTag(
+	ctx context.Context,
+	req *pb.DeleteTagRequest,
+) (*pb.DeleteTagResponse, error) {
+	if err := c.repo.DeleteTag(ctx, req.TagId.Value); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "tag does not exist")
+		}
+		return nil, status.Errorf(codes.Internal, "could not delete tag: %v", err)
+	}
+
+	return &pb.DeleteTagResponse{TagId: req.TagId}, nil
+}
+
+func (c *CmsService) ListTags(
+	ctx context.Context,
+	req *pb.ListTagsRequest,
+) (*pb.ListTagsResponse, error) {
+	limit, offset := pagination(req.Page, req.PerPage)
+
+	tags, err := c.repo.ListTags(ctx, limit, offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch tags: %v", err)
+	}
+
+	return &pb.ListTagsResponse{Tags: tags}, nil
+}
+
+func (c *CmsService) GetTaggedPosts(
+	ctx context.Context,
+	req *pb.GetTaggedPostsRequest,
+) (*pb.GetTaggedPostsResponse, error) {
+	slugs := uniqueStrings(req.Slugs)
+	if len(slugs) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "no tags were provided")
+	}
+
+	// with ALL a post has to carry every one of the tags asked for.
+	filter := PostFilter{TagSlugs: slugs, AllTags: req.Match == pb.GetTaggedPostsRequest_ALL}
+	total, err := c.repo.CountPosts(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not count posts: %v", err)
+	}
+
+	filter.Limit, filter.Offset = pagination(req.Page, req.PerPage)
+	posts, err := c.listPosts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetTaggedPostsResponse{Posts: posts, Total: total}, nil
+}
+
+// setPostTags replaces the tags on a post.
+func setPostTags(ctx context.Context, repo Repository, postId string, tagIds []*pb.UUID) error {
+	var ids []string
+	for _, id := range tagIds {
+		ids = append(ids, id.GetValue())
+	}
+
+	if err := repo.SetPostTags(ctx, postId, ids); err != nil {
+		return status.Errorf(codes.Internal, "could not update post tags: %w", err)
+	}
+	return nil
+}
+
+func (c *CmsService) postTags(ctx context.Context, postId string) ([]*pb.Tag, error) {
+	tags, err := c.repo.ListPostTags(ctx, postId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch post tags: %v", err)
+	}
+	return tags, nil
+}
+
+// pagination turns a 1-based page into LIMIT and OFFSET values.
+func pagination(page, perPage int32) (int32, int32) {
+	if perPage <= 0 {
+		perPage = 20
+	}
+	if page <= 0 {
+		page = 1
+	}
+	return perPage, (page - 1) * perPage
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// a viewer reloading a post within the window only counts once.
+const viewDedupWindow = 30 * time.Minute
+
+const (
+	defaultPopularDays  = 7
+	maxPopularDays      = 365
+	defaultPopularLimit = 10
+	maxPopularLimit     = 50
+)
+
+// RecordPostView counts a view of a published post, unless the same viewer
+// was already counted within the dedup window. Views of posts that are not
+// published are dropped rather than reported, the endpoint is fire and
+// forget.
+func (c *CmsService) RecordPostView(
+	ctx context.Context,
+	req *pb.RecordPostViewRequest,
+) (*pb.RecordPostViewResponse, error) {
+	if req.PostId.GetValue() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "post id was not provided")
+	}
+	if req.Viewer == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "viewer was not provided")
+	}
+
+	counted, err := c.repo.RecordPostView(ctx, req.PostId.Value, req.Viewer, viewDedupWindow)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalid) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid post id: %v", err)
+		}
+		return nil, status.Errorf(codes.Internal, "could not record view: %v", err)
+	}
+
+	return &pb.RecordPostViewResponse{Counted: counted}, nil
+}
+
+func (c *CmsService) GetPopularPosts(
+	ctx context.Context,
+	req *pb.GetPopularPostsRequest,
+) (*pb.GetPopularPostsResponse, error) {
+	days := req.Days
+	if days <= 0 {
+		days = defaultPopularDays
+	}
+	if days > maxPopularDays {
+		return nil, status.Errorf(codes.InvalidArgument, "days can be at most %d", maxPopularDays)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPopularLimit
+	}
+	if limit > maxPopularLimit {
+		limit = maxPopularLimit
+	}
+
+	posts, err := c.repo.PopularPosts(ctx, days, limit, req.CategoryId.GetValue())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch popular posts: %v", err)
+	}
+
+	return &pb.GetPopularPostsResponse{Posts: posts}, nil
+}
+
+// PruneViewSessions forgets viewers whose dedup window has passed, they
+// would be counted again anyway.
+func (c *CmsService) PruneViewSessions(ctx context.Context) (int64, error) {
+	return c.repo.PruneViewSessions(ctx, viewDedupWindow)
+}
@@ -0,0 +1,173 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// statuses are stored lower case in content.status, e.g. "in_review".
+func statusToDB(s pb.PostStatus) string {
+	return strings.ToLower(s.String())
+}
+
+func statusFromDB(s string) pb.PostStatus {
+	return pb.PostStatus(pb.PostStatus_value[strings.ToUpper(s)])
+}
+
+// transitionPost moves a post to a new status if it is currently in one of
+// the statuses the transition starts from.
+func (c *CmsService) transitionPost(ctx context.Context, postId string, transition PostTransition) error {
+	current, err := c.repo.TransitionPost(ctx, postId, transition)
+	if err == nil {
+		return nil
+	}
+
+	// tell a missing post apart from one in the wrong state.
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return status.Errorf(codes.NotFound, "post does not exist")
+	case errors.Is(err, ErrPostStatus):
+		return status.Errorf(
+			codes.FailedPrecondition,
+			"cannot move a %s post to %s",
+			statusToDB(current),
+			statusToDB(transition.To),
+		)
+	}
+	return status.Errorf(codes.Internal, "could not update post status: %v", err)
+}
+
+func (c *CmsService) SubmitPostForReview(
+	ctx context.Context,
+	req *pb.SubmitPostForReviewRequest,
+) (*pb.SubmitPostForReviewResponse, error) {
+	clearNote := ""
+	err := c.transitionPost(ctx, req.PostId.Value, PostTransition{
+		From:       []pb.PostStatus{pb.PostStatus_DRAFT},
+		To:         pb.PostStatus_IN_REVIEW,
+		ReviewNote: &clearNote,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SubmitPostForReviewResponse{
+		PostId: req.PostId,
+		Status: pb.PostStatus_IN_REVIEW,
+	}, nil
+}
+
+func (c *CmsService) ApprovePost(
+	ctx context.Context,
+	req *pb.ApprovePostRequest,
+) (*pb.ApprovePostResponse, error) {
+	if req.ReviewerId == nil || req.ReviewerId.Value == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "reviewer id was not provided")
+	}
+
+	publishAt := time.Now()
+	if req.PublishAt != "" {
+		t, err := time.Parse(time.RFC3339, req.PublishAt)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid publish_at: %v", err)
+		}
+		publishAt = t
+	}
+
+	to := pb.PostStatus_PUBLISHED
+	if publishAt.After(time.Now()) {
+		// the scheduler sets published_date when the post goes live.
+		to = pb.PostStatus_SCHEDULED
+	}
+
+	clearNote := ""
+	err := c.transitionPost(ctx, req.PostId.Value, PostTransition{
+		From:       []pb.PostStatus{pb.PostStatus_IN_REVIEW},
+		To:         to,
+		ReviewerId: req.ReviewerId.Value,
+		ReviewNote: &clearNote,
+		PublishAt:  &publishAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ApprovePostResponse{PostId: req.PostId, Status: to}, nil
+}
+
+func (c *CmsService) RejectPost(
+	ctx context.Context,
+	req *pb.RejectPostRequest,
+) (*pb.RejectPostResponse, error) {
+	if req.ReviewerId == nil || req.ReviewerId.Value == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "reviewer id was not provided")
+	}
+
+	// scheduled posts can be pulled back before they go live.
+	var unscheduled time.Time
+	err := c.transitionPost(ctx, req.PostId.Value, PostTransition{
+		From:       []pb.PostStatus{pb.PostStatus_IN_REVIEW, pb.PostStatus_SCHEDULED},
+		To:         pb.PostStatus_DRAFT,
+		ReviewerId: req.ReviewerId.Value,
+		ReviewNote: &req.Note,
+		PublishAt:  &unscheduled,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RejectPostResponse{PostId: req.PostId, Status: pb.PostStatus_DRAFT}, nil
+}
+
+func (c *CmsService) ArchivePost(
+	ctx context.Context,
+	req *pb.ArchivePostRequest,
+) (*pb.ArchivePostResponse, error) {
+	err := c.transitionPost(ctx, req.PostId.Value, PostTransition{
+		From: []pb.PostStatus{
+			pb.PostStatus_DRAFT,
+			pb.PostStatus_IN_REVIEW,
+			pb.PostStatus_SCHEDULED,
+			pb.PostStatus_PUBLISHED,
+		},
+		To: pb.PostStatus_ARCHIVED,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ArchivePostResponse{PostId: req.PostId, Status: pb.PostStatus_ARCHIVED}, nil
+}
+
+// PublishScheduledPosts flips every scheduled post whose publish time has
+// passed to published and returns how many went live.
+func (c *CmsService) PublishScheduledPosts(ctx context.Context) (int64, error) {
+	return c.repo.PublishScheduledPosts(ctx)
+}
+
+// RunScheduler calls PublishScheduledPosts and PruneViewSessions every
+// interval until ctx is done. A failure in one does not skip the other.
+func (c *CmsService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := c.PublishScheduledPosts(ctx); err != nil {
+				slog.Error("could not publish scheduled posts", "error", err)
+			} else if n > 0 {
+				slog.Info("published scheduled posts", "count", n)
+			}
+			if _, err := c.PruneViewSessions(ctx); err != nil {
+				slog.Error("could not prune view sessions", "error", err)
+			}
+		}
+	}
+}
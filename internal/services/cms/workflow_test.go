@@ -0,0 +1,42 @@
+package cmsservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingPublish is a repository that cannot publish scheduled posts and
+// sends on pruned every time view sessions are pruned.
+type failingPublish struct {
+	*MemoryRepository
+	pruned chan struct{}
+}
+
+func (failingPublish) PublishScheduledPosts(context.Context) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (r failingPublish) PruneViewSessions(ctx context.Context, window time.Duration) (int64, error) {
+	select {
+	case r.pruned <- struct{}{}:
+	case <-ctx.Done():
+	}
+	return 0, nil
+}
+
+func TestRunSchedulerPrunesWhenPublishingFails(t *testing.T) {
+	repo := failingPublish{MemoryRepository: NewMemoryRepository(), pruned: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewCmsService(repo).RunScheduler(ctx, time.Millisecond)
+
+	for range 2 {
+		select {
+		case <-repo.pruned:
+		case <-time.After(5 * time.Second):
+			t.Fatal("view sessions were not pruned")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package orderservice
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/memstore"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/order"
+	"google.golang.org/protobuf/proto"
+)
+
+// MemoryRepository is a Repository that keeps everything in memory, so the
+// service can run without Postgres. Stock is taken from a Stock, such as
+// the products service's in-memory repository. Transactions hold a lock
+// for their whole run and put the orders and the stock back as they were
+// when they fail.
+type MemoryRepository struct {
+	store *memstore.Store[memoryOrders]
+	// data is the store's, only touched under lock.
+	data  *memoryOrders
+	stock Stock
+	// taken is what the transaction took from stock, nil outside one.
+	taken map[string]int32
+}
+
+// Stock keeps product stock for a MemoryRepository.
+type Stock interface {
+	// TakeStock removes quantity units of a product, or puts them back
+	// when quantity is negative. It reports false, changing nothing, when
+	// there are fewer units than quantity and returns database.ErrNotFound
+	// for an unknown product.
+	TakeStock(ctx context.Context, productId string, quantity int32) (bool, error)
+}
+
+type memoryOrders struct {
+	orders []*pb.Order
+}
+
+func (d *memoryOrders) clone() *memoryOrders {
+	orders := make([]*pb.Order, len(d.orders))
+	for i, order := range d.orders {
+		orders[i] = proto.Clone(order).(*pb.Order)
+	}
+	return &memoryOrders{orders: orders}
+}
+
+// NewMemoryRepository returns a MemoryRepository with no orders that takes
+// stock from stock.
+func NewMemoryRepository(stock Stock) *MemoryRepository {
+	data := &memoryOrders{}
+	return &MemoryRepository{
+		store: memstore.New(data, (*memoryOrders).clone),
+		data:  data,
+		stock: stock,
+	}
+}
+
+func (m *MemoryRepository) lock() func() {
+	return m.store.Lock()
+}
+
+func (m *MemoryRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	if m.taken != nil {
+		return fn(m)
+	}
+	return m.store.Tx(func(tx *memstore.Store[memoryOrders]) error {
+		inTx := &MemoryRepository{store: tx, data: m.data, stock: m.stock, taken: map[string]int32{}}
+		err := fn(inTx)
+		if err != nil {
+			for productId, quantity := range inTx.taken {
+				if _, putErr := m.stock.TakeStock(ctx, productId, -quantity); putErr != nil {
+					return errors.Join(err, putErr)
+				}
+			}
+		}
+		return err
+	})
+}
+
+func (m *MemoryRepository) CreateOrder(ctx context.Context, order *pb.Order) (string, error) {
+	defer m.lock()()
+
+	if _, err := m.stock.TakeStock(ctx, order.ProductId.GetValue(), 0); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return "", database.ErrForeignKey
+		}
+		return "", err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	stored := proto.Clone(order).(*pb.Order)
+	stored.Id = &pb.UUID{Value: uuid.NewString()}
+	stored.Status = "pending"
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	m.data.orders = append(m.data.orders, stored)
+	return stored.Id.Value, nil
+}
+
+func (m *MemoryRepository) GetOrder(ctx context.Context, id string) (*pb.Order, error) {
+	defer m.lock()()
+
+	i := m.find(id)
+	if i < 0 {
+		return nil, database.ErrNotFound
+	}
+	return proto.Clone(m.data.orders[i]).(*pb.Order), nil
+}
+
+func (m *MemoryRepository) ListOrders(
+	ctx context.Context,
+	userId string,
+	limit, offset int32,
+) ([]*pb.Order, error) {
+	defer m.lock()()
+
+	var orders []*pb.Order
+	for _, order := range m.data.orders {
+		if userId == "" || order.UserId.GetValue() == userId {
+			orders = append(orders, proto.Clone(order).(*pb.Order))
+		}
+	}
+	return memstore.Page(orders, limit, offset), nil
+}
+
+func (m *MemoryRepository) UpdateOrder(
+	ctx context.Context,
+	id, status string,
+	quantity int32,
+	total float32,
+) (*pb.Order, int32, error) {
+	defer m.lock()()
+
+	i := m.find(id)
+	if i < 0 {
+		return nil, 0, database.ErrNotFound
+	}
+	order := m.data.orders[i]
+	oldQuantity := order.Quantity
+	order.Status = status
+	order.Quantity = quantity
+	order.Total = total
+	return proto.Clone(order).(*pb.Order), oldQuantity, nil
+}
+
+func (m *MemoryRepository) DeleteOrder(ctx context.Context, id string) (*pb.Order, error) {
+	defer m.lock()()
+
+	i := m.find(id)
+	if i < 0 {
+		return nil, database.ErrNotFound
+	}
+	order := m.data.orders[i]
+	m.data.orders = append(m.data.orders[:i], m.data.orders[i+1:]...)
+	return order, nil
+}
+
+func (m *MemoryRepository) TakeStock(ctx context.Context, productId string, quantity int32) error {
+	defer m.lock()()
+
+	ok, err := m.stock.TakeStock(ctx, productId, quantity)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOutOfStock
+	}
+	if m.taken != nil {
+		m.taken[productId] += quantity
+	}
+	return nil
+}
+
+func (m *MemoryRepository) find(id string) int {
+	for i, order := range m.data.orders {
+		if order.Id.GetValue() == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,231 @@
+package orderservice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/order"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const orderColumns = `id, user_id, product_id, status, quantity, total, created_at, updated_at, delivery_address`
+
+type postgresRepository struct {
+	db   database.DB
+	q    database.Querier
+	inTx bool
+}
+
+// NewPostgresRepository returns a Repository that keeps orders in db.
+func NewPostgresRepository(db database.DB) Repository {
+	return &postgresRepository{db: db, q: db}
+}
+
+func (r *postgresRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+	return r.db.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		return fn(&postgresRepository{db: r.db, q: tx, inTx: true})
+	})
+}
+
+func (r *postgresRepository) CreateOrder(ctx context.Context, order *pb.Order) (string, error) {
+	stmt := `INSERT INTO orders (user_id, product_id, quantity, total, delivery_address) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	deliveryAddress, err := marshalDeliveryAddress(order.DeliveryAddress)
+	if err != nil {
+		return "", err
+	}
+
+	var orderId string
+	err = r.q.QueryRowContext(ctx,
+		stmt,
+		order.UserId.GetValue(),
+		order.ProductId.GetValue(),
+		order.Quantity,
+		order.Total,
+		deliveryAddress,
+	).Scan(&orderId)
+	return orderId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetOrder(ctx context.Context, id string) (*pb.Order, error) {
+	stmt := `SELECT ` + orderColumns + ` FROM orders WHERE id=$1`
+
+	var order pb.Order
+	var orderId, userId, productId string
+	var createdAt, updatedAt time.Time
+	var deliveryAddress []byte
+
+	err := r.q.QueryRowContext(ctx, stmt, id).Scan(
+		&orderId,
+		&userId,
+		&productId,
+		&order.Status,
+		&order.Quantity,
+		&order.Total,
+		&createdAt,
+		&updatedAt,
+		&deliveryAddress,
+	)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	order.Id = &pb.UUID{Value: orderId}
+	order.UserId = &pb.UUID{Value: userId}
+	order.ProductId = &pb.UUID{Value: productId}
+	order.CreatedAt = createdAt.String()
+	order.UpdatedAt = updatedAt.String()
+	if order.DeliveryAddress, err = unmarshalDeliveryAddress(deliveryAddress); err != nil {
+		return nil, fmt.Errorf("could not decode delivery address: %w", err)
+	}
+	return &order, nil
+}
+
+func (r *postgresRepository) ListOrders(
+	ctx context.Context,
+	userId string,
+	limit, offset int32,
+) ([]*pb.Order, error) {
+	stmt := `SELECT ` + orderColumns + ` FROM orders LIMIT $1 OFFSET $2`
+	args := []interface{}{limit, offset}
+	if userId != "" {
+		stmt = `SELECT ` + orderColumns + ` FROM orders WHERE user_id=$3 LIMIT $1 OFFSET $2`
+		args = append(args, userId)
+	}
+
+	rows, err := r.q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*pb.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func (r *postgresRepository) UpdateOrder(
+	ctx context.Context,
+	id, status string,
+	quantity int32,
+	total float32,
+) (*pb.Order, int32, error) {
+	stmt := `UPDATE orders o SET status=$1, quantity=$2, total=$3
+	FROM (SELECT id, quantity FROM orders WHERE id=$4 FOR UPDATE) old
+	WHERE o.id=old.id
+	RETURNING o.id, o.user_id, o.product_id, o.status, o.quantity, o.total, o.created_at, o.updated_at, o.delivery_address, old.quantity`
+
+	var oldQuantity int32
+	order, err := scanOrder(r.q.QueryRowContext(ctx, stmt, status, quantity, total, id), &oldQuantity)
+	if err != nil {
+		return nil, 0, database.Translate(err)
+	}
+	return order, oldQuantity, nil
+}
+
+func (r *postgresRepository) DeleteOrder(ctx context.Context, id string) (*pb.Order, error) {
+	stmt := `DELETE FROM orders WHERE id=$1 RETURNING ` + orderColumns
+	order, err := scanOrder(r.q.QueryRowContext(ctx, stmt, id))
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	return order, nil
+}
+
+// TakeStock checks and updates stock in one statement so two orders cannot
+// both take the last unit.
+func (r *postgresRepository) TakeStock(ctx context.Context, productId string, quantity int32) error {
+	if quantity == 0 {
+		return nil
+	}
+
+	result, err := r.q.ExecContext(ctx,
+		`UPDATE products SET quantity = quantity - $2 WHERE id=$1 AND quantity >= $2`,
+		productId,
+		quantity,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = r.q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id=$1)`, productId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return database.ErrNotFound
+	}
+	return ErrOutOfStock
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOrder reads orderColumns, and any columns after them into extra.
+func scanOrder(row rowScanner, extra ...interface{}) (*pb.Order, error) {
+	var order pb.Order
+	var orderId, userId, productId string
+	var deliveryAddress []byte
+
+	dest := []interface{}{
+		&orderId,
+		&userId,
+		&productId,
+		&order.Status,
+		&order.Quantity,
+		&order.Total,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&deliveryAddress,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	order.Id = &pb.UUID{Value: orderId}
+	order.UserId = &pb.UUID{Value: userId}
+	order.ProductId = &pb.UUID{Value: productId}
+
+	var err error
+	if order.DeliveryAddress, err = unmarshalDeliveryAddress(deliveryAddress); err != nil {
+		return nil, fmt.Errorf("could not decode delivery address: %w", err)
+	}
+	return &order, nil
+}
+
+// marshalDeliveryAddress turns the address snapshot into the JSONB value
+// stored on the order, or NULL when there is none.
+func marshalDeliveryAddress(address *pb.DeliveryAddress) (interface{}, error) {
+	if address == nil {
+		return nil, nil
+	}
+	return protojson.Marshal(address)
+}
+
+func unmarshalDeliveryAddress(data []byte) (*pb.DeliveryAddress, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var address pb.DeliveryAddress
+	if err := protojson.Unmarshal(data, &address); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
@@ -0,0 +1,150 @@
+//go:build integration
+
+package orderservice_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	orderservice "github.com/kelcheone/chemistke/internal/services/orders"
+	"github.com/kelcheone/chemistke/internal/testenv"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/order"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// seed adds a user and a product with stock units and returns their ids.
+func seed(t *testing.T, db database.DB, stock int32) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	var userId, productId string
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO users (name, email, phone) VALUES ('Jane Doe', 'jane@example.com', '+254700000000') RETURNING id`,
+	).Scan(&userId)
+	if err != nil {
+		t.Fatalf("could not add user: %v", err)
+	}
+
+	err = db.QueryRowContext(ctx, `WITH
+	  c AS (INSERT INTO product_category (name, slug) VALUES ('Pain relief', 'pain-relief') RETURNING id),
+	  s AS (INSERT INTO product_sub_category (name, category_id, slug) SELECT 'Tablets', id, 'tablets' FROM c RETURNING id),
+	  b AS (INSERT INTO product_brand (name) VALUES ('Panadol') RETURNING id)
+	INSERT INTO products (name, description, category_id, sub_category_id, brand_id, price, quantity)
+	SELECT 'Panadol Extra', '500mg tablets', c.id, s.id, b.id, 250, $1 FROM c, s, b
+	RETURNING id`, stock).Scan(&productId)
+	if err != nil {
+		t.Fatalf("could not add product: %v", err)
+	}
+	return userId, productId
+}
+
+func stockOf(t *testing.T, db database.DB, productId string) int32 {
+	t.Helper()
+	var stock int32
+	err := db.QueryRowContext(context.Background(), `SELECT quantity FROM products WHERE id=$1`, productId).Scan(&stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stock
+}
+
+func TestStockChecks(t *testing.T) {
+	db := testenv.StartPostgres(t)
+	svc := orderservice.NewOrderService(orderservice.NewPostgresRepository(db))
+	ctx := context.Background()
+	userId, productId := seed(t, db, 5)
+
+	placed, err := svc.OrderProduct(ctx, &pb.OrderProductRequest{
+		UserId:    &pb.UUID{Value: userId},
+		ProductId: &pb.UUID{Value: productId},
+		Quantity:  2,
+		Total:     500,
+	})
+	if err != nil {
+		t.Fatalf("OrderProduct: %v", err)
+	}
+	orderId := placed.Order.Id
+
+	steps := []struct {
+		name      string
+		call      func() error
+		want      codes.Code
+		wantStock int32
+	}{
+		{"order more than is left", func() error {
+			_, err := svc.OrderProduct(ctx, &pb.OrderProductRequest{
+				UserId:    &pb.UUID{Value: userId},
+				ProductId: &pb.UUID{Value: productId},
+				Quantity:  4,
+			})
+			return err
+		}, codes.FailedPrecondition, 3},
+		{"update takes the difference", func() error {
+			_, err := svc.UpdateOrder(ctx, &pb.UpdateOrderRequest{OrderId: orderId, Status: "pending", Quantity: 4, Total: 1000})
+			return err
+		}, codes.OK, 1},
+		{"update beyond the stock", func() error {
+			_, err := svc.UpdateOrder(ctx, &pb.UpdateOrderRequest{OrderId: orderId, Status: "pending", Quantity: 6, Total: 1500})
+			return err
+		}, codes.FailedPrecondition, 1},
+		{"delete puts the stock back", func() error {
+			_, err := svc.DeleteOrder(ctx, &pb.DeleteOrderRequest{OrderId: orderId})
+			return err
+		}, codes.OK, 5},
+	}
+
+	for _, step := range steps {
+		if got := status.Code(step.call()); got != step.want {
+			t.Fatalf("%s: got code %v, want %v", step.name, got, step.want)
+		}
+		if got := stockOf(t, db, productId); got != step.wantStock {
+			t.Fatalf("%s: stock = %d, want %d", step.name, got, step.wantStock)
+		}
+	}
+}
+
+func TestStockUnderConcurrentOrders(t *testing.T) {
+	db := testenv.StartPostgres(t)
+	svc := orderservice.NewOrderService(orderservice.NewPostgresRepository(db))
+	const stock, buyers = 3, 8
+	userId, productId := seed(t, db, stock)
+
+	var wg sync.WaitGroup
+	results := make(chan codes.Code, buyers)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.OrderProduct(context.Background(), &pb.OrderProductRequest{
+				UserId:    &pb.UUID{Value: userId},
+				ProductId: &pb.UUID{Value: productId},
+				Quantity:  1,
+			})
+			results <- status.Code(err)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	// the conditional update hands out the last units one at a time, so
+	// exactly stock orders go through and nothing is aborted.
+	var placed int32
+	for c := range results {
+		switch c {
+		case codes.OK:
+			placed++
+		case codes.FailedPrecondition:
+		default:
+			t.Errorf("unexpected code %v", c)
+		}
+	}
+	if placed != stock {
+		t.Errorf("placed %d orders from %d units", placed, stock)
+	}
+	if got := stockOf(t, db, productId); got != stock-placed {
+		t.Errorf("stock = %d, want %d", got, stock-placed)
+	}
+}
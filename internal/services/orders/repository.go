@@ -0,0 +1,36 @@
+package orderservice
+
+import (
+	"context"
+	"errors"
+
+	pb "github.com/kelcheone/chemistke/pkg/grpc/order"
+)
+
+// ErrOutOfStock is returned by TakeStock when the product has fewer units
+// left than asked for.
+var ErrOutOfStock = errors.New("not enough stock")
+
+// Repository is where the order service keeps its data. Lookups of a
+// missing order or product fail with database.ErrNotFound.
+type Repository interface {
+	// CreateOrder stores order and returns its id.
+	CreateOrder(ctx context.Context, order *pb.Order) (string, error)
+	GetOrder(ctx context.Context, id string) (*pb.Order, error)
+	// ListOrders pages through every order, or only userId's when it is
+	// not empty.
+	ListOrders(ctx context.Context, userId string, limit, offset int32) ([]*pb.Order, error)
+	// UpdateOrder changes an order's status, quantity and total. It returns
+	// the updated order and the quantity it had before.
+	UpdateOrder(ctx context.Context, id, status string, quantity int32, total float32) (*pb.Order, int32, error)
+	// DeleteOrder removes an order and returns what it was.
+	DeleteOrder(ctx context.Context, id string) (*pb.Order, error)
+
+	// TakeStock removes quantity units of a product from stock, or puts
+	// them back when quantity is negative.
+	TakeStock(ctx context.Context, productId string, quantity int32) error
+
+	// WithTx runs fn on a Repository whose writes are kept only if fn
+	// returns nil.
+	WithTx(ctx context.Context, fn func(Repository) error) error
+}
@@ -2,48 +2,63 @@ package orderservice
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
-	"time"
+	"errors"
 
 	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/metrics"
 	"github.com/kelcheone/chemistke/pkg/codes"
 	pb "github.com/kelcheone/chemistke/pkg/grpc/order"
 	"github.com/kelcheone/chemistke/pkg/status"
 )
 
 type OrderService struct {
-	db database.DB
+	repo Repository
 	pb.UnimplementedOrderServiceServer
 }
 
-func NewOrderService(db database.DB) *OrderService {
-	return &OrderService{db: db}
+func NewOrderService(repo Repository) *OrderService {
+	return &OrderService{repo: repo}
 }
 
 func (s *OrderService) OrderProduct(
 	ctx context.Context,
 	req *pb.OrderProductRequest,
 ) (*pb.OrderProductResponse, error) {
-	stmt := `INSERT INTO orders (user_id, product_id, quantity, total) VALUES ($1, $2, $3, $4) RETURNING id`
-
-	var orderId string
-	err := s.db.QueryRow(stmt, req.UserId.Value, req.ProductId.Value, req.Quantity, req.Total).
-		Scan(&orderId)
-	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to insert order: %v",
-			err,
-		)
+	if req.Quantity <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must be at least 1")
 	}
+
 	order := &pb.Order{
-		Id:        &pb.UUID{Value: orderId},
-		UserId:    &pb.UUID{Value: req.UserId.Value},
-		ProductId: &pb.UUID{Value: req.ProductId.Value},
+		UserId:    &pb.UUID{Value: req.UserId.GetValue()},
+		ProductId: &pb.UUID{Value: req.ProductId.GetValue()},
 		Quantity:  req.Quantity,
 		Total:     req.Total,
+
+		DeliveryAddress: req.DeliveryAddress,
+	}
+
+	// the order is only placed if its stock can be taken in the same
+	// transaction.
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		if err := takeStock(ctx, repo, order.ProductId.Value, order.Quantity); err != nil {
+			return err
+		}
+
+		orderId, err := repo.CreateOrder(ctx, order)
+		if err != nil {
+			return status.Errorf(
+				codes.Internal,
+				"failed to insert order: %w",
+				err,
+			)
+		}
+		order.Id = &pb.UUID{Value: orderId}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	metrics.OrdersCreated.Inc()
 
 	return &pb.OrderProductResponse{
 		Order:   order,
@@ -55,8 +70,7 @@ func (s *OrderService) GetUserOrders(
 	ctx context.Context,
 	req *pb.GetUserOrdersRequest,
 ) (*pb.GetUserOrdersResponse, error) {
-	stmt := `SELECT id, user_id, product_id, status, quantity, total, created_at, updated_at FROM orders WHERE user_id=$1 LIMIT $2 OFFSET $3`
-	rows, err := s.db.Query(stmt, req.UserId.Value, req.Limit, req.Page)
+	orders, err := s.repo.ListOrders(ctx, req.UserId.GetValue(), req.Limit, req.Page)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -64,44 +78,6 @@ func (s *OrderService) GetUserOrders(
 			err,
 		)
 	}
-	defer rows.Close()
-
-	var orders []*pb.Order
-
-	for rows.Next() {
-		var order pb.Order
-		var userId, productId, orderId string
-		err := rows.Scan(
-			&orderId,
-			&userId,
-			&productId,
-			&order.Status,
-			&order.Quantity,
-			&order.Total,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		)
-		if err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"failed to scan order row: %v",
-				err,
-			)
-		}
-		order.Id = &pb.UUID{Value: orderId}
-		order.ProductId = &pb.UUID{Value: productId}
-		order.UserId = &pb.UUID{Value: userId}
-
-		orders = append(orders, &order)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error iterating over rows: %v",
-			err,
-		)
-	}
 
 	return &pb.GetUserOrdersResponse{
 		Orders:  orders,
@@ -113,29 +89,13 @@ func (s *OrderService) GetOrder(
 	ctx context.Context,
 	req *pb.GetOrderRequest,
 ) (*pb.GetOrderResponse, error) {
-	stmt := `SELECT id, user_id, product_id, status, quantity, total, created_at, updated_at FROM orders WHERE id=$1`
-	fmt.Println(stmt)
-
-	var order pb.Order
-	var id, userID, productID string
-	var createdAt, updatedAt time.Time
-
-	err := s.db.QueryRowContext(ctx, stmt, req.OrderId.Value).Scan(
-		&id,
-		&userID,
-		&productID,
-		&order.Status,
-		&order.Quantity,
-		&order.Total,
-		&createdAt,
-		&updatedAt,
-	)
+	order, err := s.repo.GetOrder(ctx, req.OrderId.GetValue())
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(
 				codes.NotFound,
 				"order with ID %s not found",
-				req.OrderId.Value,
+				req.OrderId.GetValue(),
 			)
 		}
 		return nil, status.Errorf(
@@ -145,21 +105,14 @@ func (s *OrderService) GetOrder(
 		)
 	}
 
-	order.Id = &pb.UUID{Value: id}
-	order.UserId = &pb.UUID{Value: userID}
-	order.ProductId = &pb.UUID{Value: productID}
-	order.CreatedAt = createdAt.String()
-	order.UpdatedAt = updatedAt.String()
-
-	return &pb.GetOrderResponse{Order: &order, Message: "query successful"}, nil
+	return &pb.GetOrderResponse{Order: order, Message: "query successful"}, nil
 }
 
 func (s *OrderService) GetOrders(
 	ctx context.Context,
 	req *pb.GetOrdersRequest,
 ) (*pb.GetOrdersResponse, error) {
-	stmt := `SELECT id, user_id, product_id, status, quantity, total, created_at, updated_at FROM orders LIMIT $1 OFFSET $2`
-	rows, err := s.db.Query(stmt, req.Limit, req.Page)
+	orders, err := s.repo.ListOrders(ctx, "", req.Limit, req.Page)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -167,44 +120,6 @@ func (s *OrderService) GetOrders(
 			err,
 		)
 	}
-	defer rows.Close()
-
-	var orders []*pb.Order
-
-	for rows.Next() {
-		var order pb.Order
-		var userId, productId, orderId string
-		err := rows.Scan(
-			&orderId,
-			&userId,
-			&productId,
-			&order.Status,
-			&order.Quantity,
-			&order.Total,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		)
-		if err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"failed to scan order row: %v",
-				err,
-			)
-		}
-		order.Id = &pb.UUID{Value: orderId}
-		order.ProductId = &pb.UUID{Value: productId}
-		order.UserId = &pb.UUID{Value: userId}
-
-		orders = append(orders, &order)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error iterating over rows: %v",
-			err,
-		)
-	}
 
 	return &pb.GetOrdersResponse{
 		Orders:  orders,
@@ -216,44 +131,45 @@ func (s *OrderService) UpdateOrder(
 	ctx context.Context,
 	req *pb.UpdateOrderRequest,
 ) (*pb.UpdateOrderResponse, error) {
-	stmt := `UPDATE orders SET status=$1, quantity=$2, total=$3 WHERE id=$4 RETURNING *`
-	var order pb.Order
-	var userId, productId, orderId string
-	fmt.Println(stmt)
-	fmt.Printf("%+v\n", req.Status)
+	if req.Quantity <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must be at least 1")
+	}
 
-	err := s.db.QueryRow(stmt, req.Status, req.Quantity, req.Total, req.OrderId.Value).
-		Scan(
-			&orderId,
-			&userId,
-			&productId,
-			&order.Status,
-			&order.Quantity,
-			&order.Total,
-			&order.CreatedAt,
-			&order.UpdatedAt,
+	// a change in quantity takes or returns the difference in stock.
+	var order *pb.Order
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		var oldQuantity int32
+		var err error
+		order, oldQuantity, err = repo.UpdateOrder(
+			ctx,
+			req.OrderId.GetValue(),
+			req.Status,
+			req.Quantity,
+			req.Total,
 		)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Errorf(
-				codes.NotFound,
-				"order with ID %s not found",
-				req.OrderId.Value,
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return status.Errorf(
+					codes.NotFound,
+					"order with ID %s not found",
+					req.OrderId.GetValue(),
+				)
+			}
+			return status.Errorf(
+				codes.Internal,
+				"failed to update order: %w",
+				err,
 			)
 		}
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to update order: %v",
-			err,
-		)
-	}
 
-	order.Id = &pb.UUID{Value: orderId}
-	order.UserId = &pb.UUID{Value: userId}
-	order.ProductId = &pb.UUID{Value: productId}
+		return takeStock(ctx, repo, order.ProductId.Value, order.Quantity-oldQuantity)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &pb.UpdateOrderResponse{
-		Order:   &order,
+		Order:   order,
 		Message: "order updated successfully",
 	}, nil
 }
@@ -262,34 +178,47 @@ func (s *OrderService) DeleteOrder(
 	ctx context.Context,
 	req *pb.DeleteOrderRequest,
 ) (*pb.DeleteOrderResponse, error) {
-	stmt := `DELETE FROM orders WHERE id=$1`
-	result, err := s.db.Exec(stmt, req.OrderId.Value)
-	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to delete order: %v",
-			err,
-		)
-	}
+	// the order's stock goes back on the shelf with it.
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		order, err := repo.DeleteOrder(ctx, req.OrderId.GetValue())
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return status.Errorf(
+					codes.NotFound,
+					"order with ID %s not found",
+					req.OrderId.GetValue(),
+				)
+			}
+			return status.Errorf(
+				codes.Internal,
+				"failed to delete order: %w",
+				err,
+			)
+		}
 
-	rowsAffected, err := result.RowsAffected()
+		return takeStock(ctx, repo, order.ProductId.Value, -order.Quantity)
+	})
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"failed to get rows affected: %v",
-			err,
-		)
-	}
-
-	if rowsAffected == 0 {
-		return nil, status.Errorf(
-			codes.NotFound,
-			"order with ID %s not found",
-			req.OrderId.Value,
-		)
+		return nil, err
 	}
 
 	return &pb.DeleteOrderResponse{
 		Message: "order deleted successfully",
 	}, nil
 }
+
+// takeStock removes quantity units of a product from stock, or puts them
+// back when quantity is negative.
+func takeStock(ctx context.Context, repo Repository, productId string, quantity int32) error {
+	err := repo.TakeStock(ctx, productId, quantity)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, database.ErrNotFound):
+		return status.Errorf(codes.NotFound, "product with ID %s not found", productId)
+	case errors.Is(err, ErrOutOfStock):
+		return status.Errorf(codes.FailedPrecondition, "not enough stock for product %s", productId)
+	default:
+		return status.Errorf(codes.Internal, "failed to update stock: %w", err)
+	}
+}
@@ -0,0 +1,267 @@
+package orderservice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	productservice "github.com/kelcheone/chemistke/internal/services/products"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/order"
+	productpb "github.com/kelcheone/chemistke/pkg/grpc/product"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// addProduct adds a product holding stock units to the catalogue.
+func addProduct(t *testing.T, products *productservice.MemoryRepository, stock int32) string {
+	t.Helper()
+
+	categoryId, subCategoryId, brandId := products.AddCatalogue()
+	productId, err := products.CreateProduct(context.Background(), &productpb.Product{
+		Name:          "Panadol Extra",
+		CategoryId:    &productpb.UUID{Value: categoryId},
+		SubCategoryId: &productpb.UUID{Value: subCategoryId},
+		BrandId:       &productpb.UUID{Value: brandId},
+		Price:         250,
+		Quantity:      stock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return productId
+}
+
+// stockOf is how many units of productId the catalogue holds.
+func stockOf(t *testing.T, products *productservice.MemoryRepository, productId string) int32 {
+	t.Helper()
+	product, err := products.GetProduct(context.Background(), productId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product.Quantity
+}
+
+// newOrders returns a service with one product holding stock units and an
+// order for placed of them, unless placed is 0.
+func newOrders(t *testing.T, stock, placed int32) (*OrderService, *productservice.MemoryRepository, string, string) {
+	t.Helper()
+
+	products := productservice.NewMemoryRepository()
+	svc := NewOrderService(NewMemoryRepository(products))
+	productId := addProduct(t, products, stock+placed)
+
+	var orderId string
+	if placed > 0 {
+		resp, err := svc.OrderProduct(context.Background(), &pb.OrderProductRequest{
+			UserId:    &pb.UUID{Value: uuid.NewString()},
+			ProductId: &pb.UUID{Value: productId},
+			Quantity:  placed,
+			Total:     float32(placed) * 250,
+		})
+		if err != nil {
+			t.Fatalf("OrderProduct: %v", err)
+		}
+		orderId = resp.Order.Id.Value
+	}
+	return svc, products, productId, orderId
+}
+
+func TestOrderProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		stock     int32
+		quantity  int32
+		product   string
+		want      codes.Code
+		wantStock int32
+	}{
+		{name: "takes stock", stock: 5, quantity: 2, want: codes.OK, wantStock: 3},
+		{name: "last units", stock: 2, quantity: 2, want: codes.OK, wantStock: 0},
+		{name: "out of stock", stock: 1, quantity: 2, want: codes.FailedPrecondition, wantStock: 1},
+		{name: "no quantity", stock: 5, quantity: 0, want: codes.InvalidArgument, wantStock: 5},
+		{name: "unknown product", stock: 5, quantity: 1, product: uuid.NewString(), want: codes.NotFound, wantStock: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, products, productId, _ := newOrders(t, tt.stock, 0)
+			product := productId
+			if tt.product != "" {
+				product = tt.product
+			}
+
+			_, err := svc.OrderProduct(context.Background(), &pb.OrderProductRequest{
+				UserId:    &pb.UUID{Value: uuid.NewString()},
+				ProductId: &pb.UUID{Value: product},
+				Quantity:  tt.quantity,
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+			if got := stockOf(t, products, productId); got != tt.wantStock {
+				t.Errorf("stock = %d, want %d", got, tt.wantStock)
+			}
+
+			// an order is only kept when its stock was taken.
+			orders, _ := svc.GetOrders(context.Background(), &pb.GetOrdersRequest{Limit: 10})
+			if placed := len(orders.Orders) == 1; placed != (tt.want == codes.OK) {
+				t.Errorf("got %d orders after a %v", len(orders.Orders), tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		stock     int32
+		quantity  int32
+		order     string
+		want      codes.Code
+		wantStock int32
+	}{
+		{name: "more takes the difference", stock: 4, quantity: 5, want: codes.OK, wantStock: 2},
+		{name: "fewer puts the difference back", stock: 4, quantity: 1, want: codes.OK, wantStock: 6},
+		{name: "same quantity", stock: 4, quantity: 3, want: codes.OK, wantStock: 4},
+		{name: "more than is left", stock: 1, quantity: 5, want: codes.FailedPrecondition, wantStock: 1},
+		{name: "no quantity", stock: 4, quantity: 0, want: codes.InvalidArgument, wantStock: 4},
+		{name: "unknown order", stock: 4, quantity: 1, order: uuid.NewString(), want: codes.NotFound, wantStock: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, products, productId, orderId := newOrders(t, tt.stock, 3)
+			if tt.order != "" {
+				orderId = tt.order
+			}
+
+			_, err := svc.UpdateOrder(context.Background(), &pb.UpdateOrderRequest{
+				OrderId:  &pb.UUID{Value: orderId},
+				Status:   "confirmed",
+				Quantity: tt.quantity,
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+			if got := stockOf(t, products, productId); got != tt.wantStock {
+				t.Errorf("stock = %d, want %d", got, tt.wantStock)
+			}
+		})
+	}
+
+	// a failed update leaves the order as it was.
+	svc, _, _, orderId := newOrders(t, 0, 3)
+	_, err := svc.UpdateOrder(context.Background(), &pb.UpdateOrderRequest{
+		OrderId:  &pb.UUID{Value: orderId},
+		Status:   "confirmed",
+		Quantity: 4,
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("got %v, want %v", err, codes.FailedPrecondition)
+	}
+	resp, err := svc.GetOrder(context.Background(), &pb.GetOrderRequest{OrderId: &pb.UUID{Value: orderId}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Order.Quantity != 3 || resp.Order.Status != "pending" {
+		t.Errorf("order changed to %d units %s", resp.Order.Quantity, resp.Order.Status)
+	}
+}
+
+func TestDeleteOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		order     string
+		want      codes.Code
+		wantStock int32
+	}{
+		{name: "puts the stock back", want: codes.OK, wantStock: 5},
+		{name: "unknown order", order: uuid.NewString(), want: codes.NotFound, wantStock: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, products, productId, orderId := newOrders(t, 2, 3)
+			if tt.order != "" {
+				orderId = tt.order
+			}
+
+			_, err := svc.DeleteOrder(context.Background(), &pb.DeleteOrderRequest{
+				OrderId: &pb.UUID{Value: orderId},
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+			if got := stockOf(t, products, productId); got != tt.wantStock {
+				t.Errorf("stock = %d, want %d", got, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestOrderProductLastUnits(t *testing.T) {
+	const stock, buyers = 3, 10
+	svc, products, productId, _ := newOrders(t, stock, 0)
+
+	var wg sync.WaitGroup
+	results := make(chan codes.Code, buyers)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.OrderProduct(context.Background(), &pb.OrderProductRequest{
+				UserId:    &pb.UUID{Value: uuid.NewString()},
+				ProductId: &pb.UUID{Value: productId},
+				Quantity:  1,
+			})
+			results <- status.Code(err)
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var placed int
+	for c := range results {
+		switch c {
+		case codes.OK:
+			placed++
+		case codes.FailedPrecondition:
+		default:
+			t.Errorf("unexpected code %v", c)
+		}
+	}
+	if placed != stock {
+		t.Errorf("placed %d orders, want %d", placed, stock)
+	}
+	if got := stockOf(t, products, productId); got != 0 {
+		t.Errorf("stock = %d, want 0", got)
+	}
+}
+
+func TestWithTxPutsStockBack(t *testing.T) {
+	products := productservice.NewMemoryRepository()
+	repo := NewMemoryRepository(products)
+	productId := addProduct(t, products, 5)
+	failed := errors.New("failed")
+
+	err := repo.WithTx(context.Background(), func(tx Repository) error {
+		if err := tx.TakeStock(context.Background(), productId, 2); err != nil {
+			return err
+		}
+		// a nested transaction is part of this one.
+		return tx.WithTx(context.Background(), func(tx Repository) error {
+			if err := tx.TakeStock(context.Background(), productId, 1); err != nil {
+				return err
+			}
+			return failed
+		})
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+	if got := stockOf(t, products, productId); got != 5 {
+		t.Errorf("stock = %d, want 5", got)
+	}
+}
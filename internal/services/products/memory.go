@@ -0,0 +1,781 @@
+package productservice
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/memstore"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/product"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MemoryRepository is a Repository that keeps everything in memory, so the
+// service can run without Postgres. It holds no users, so reviews come back
+// without a user name. Transactions hold a lock for their whole run and put
+// the data back as it was when they fail.
+type MemoryRepository struct {
+	store *memstore.Store[memoryCatalog]
+	// data is the store's, only touched under lock.
+	data *memoryCatalog
+}
+
+type memoryProduct struct {
+	product *pb.Product
+	images  []*pb.Image
+}
+
+// memoryRow is a category or sub-category with the updated_at its table
+// keeps for sitemaps.
+type memoryRow[T proto.Message] struct {
+	row       T
+	updatedAt time.Time
+}
+
+type memoryCatalog struct {
+	products      []*memoryProduct
+	reviews       []*pb.Review
+	categories    []*memoryRow[*pb.Category]
+	subCategories []*memoryRow[*pb.SubCategory]
+	brands        []*pb.Brand
+}
+
+func (d *memoryCatalog) clone() *memoryCatalog {
+	c := &memoryCatalog{
+		reviews: memstore.CloneAll(d.reviews),
+		brands:  memstore.CloneAll(d.brands),
+	}
+	for _, p := range d.products {
+		c.products = append(c.products, &memoryProduct{
+			product: proto.Clone(p.product).(*pb.Product),
+			images:  memstore.CloneAll(p.images),
+		})
+	}
+	for _, category := range d.categories {
+		copied := *category
+		copied.row = proto.Clone(category.row).(*pb.Category)
+		c.categories = append(c.categories, &copied)
+	}
+	for _, subCategory := range d.subCategories {
+		copied := *subCategory
+		copied.row = proto.Clone(subCategory.row).(*pb.SubCategory)
+		c.subCategories = append(c.subCategories, &copied)
+	}
+	return c
+}
+
+// NewMemoryRepository returns an empty MemoryRepository.
+func NewMemoryRepository() *MemoryRepository {
+	data := &memoryCatalog{}
+	return &MemoryRepository{store: memstore.New(data, (*memoryCatalog).clone), data: data}
+}
+
+// AddCatalogue adds a category, a sub-category of it and a brand to put
+// products in, and returns their ids.
+func (m *MemoryRepository) AddCatalogue() (categoryId, subCategoryId, brandId string) {
+	defer m.lock()()
+
+	now := time.Now()
+	category := &pb.Category{Id: &pb.UUID{Value: uuid.NewString()}, Name: "Pain relief", Slug: "pain-relief"}
+	subCategory := &pb.SubCategory{
+		Id:         &pb.UUID{Value: uuid.NewString()},
+		CategoryId: category.Id,
+		Name:       "Tablets",
+		Slug:       "tablets",
+	}
+	brand := &pb.Brand{Id: &pb.UUID{Value: uuid.NewString()}, Name: "Panadol"}
+
+	m.data.categories = append(m.data.categories, &memoryRow[*pb.Category]{row: category, updatedAt: now})
+	m.data.subCategories = append(m.data.subCategories, &memoryRow[*pb.SubCategory]{row: subCategory, updatedAt: now})
+	m.data.brands = append(m.data.brands, brand)
+	return category.Id.Value, subCategory.Id.Value, brand.Id.Value
+}
+
+func (m *MemoryRepository) lock() func() {
+	return m.store.Lock()
+}
+
+func (m *MemoryRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return m.store.Tx(func(tx *memstore.Store[memoryCatalog]) error {
+		return fn(&MemoryRepository{store: tx, data: m.data})
+	})
+}
+
+func (m *MemoryRepository) CreateProduct(ctx context.Context, product *pb.Product) (string, error) {
+	defer m.lock()()
+
+	if m.findCategory(product.CategoryId.GetValue()) == nil ||
+		m.findSubCategory(product.SubCategoryId.GetValue()) == nil ||
+		m.findBrand(product.BrandId.GetValue()) == nil {
+		return "", database.ErrForeignKey
+	}
+
+	id := uuid.NewString()
+	now := timestamppb.Now()
+	m.data.products = append(m.data.products, &memoryProduct{product: &pb.Product{
+		Id:            &pb.UUID{Value: id},
+		Name:          product.Name,
+		Description:   product.Description,
+		CategoryId:    &pb.UUID{Value: product.CategoryId.GetValue()},
+		SubCategoryId: &pb.UUID{Value: product.SubCategoryId.GetValue()},
+		BrandId:       &pb.UUID{Value: product.BrandId.GetValue()},
+		Price:         product.Price,
+		Quantity:      product.Quantity,
+		Featured:      product.Featured,
+		Slug:          productSlug(id, product.Name),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}})
+	return id, nil
+}
+
+func (m *MemoryRepository) GetProduct(ctx context.Context, id string) (*pb.Product, error) {
+	defer m.lock()()
+
+	p := m.findProduct(id)
+	if p == nil {
+		return nil, database.ErrNotFound
+	}
+	return m.view(p, []*pb.Image{}), nil
+}
+
+func (m *MemoryRepository) GetProductBySlug(ctx context.Context, slug string) (*pb.Product, error) {
+	defer m.lock()()
+
+	for _, p := range m.data.products {
+		if p.product.Slug == slug {
+			return m.view(p, []*pb.Image{}), nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) UpdateProduct(ctx context.Context, product *pb.Product) error {
+	defer m.lock()()
+
+	p := m.findProduct(product.Id.GetValue())
+	if p == nil {
+		return database.ErrNotFound
+	}
+	if m.findCategory(product.CategoryId.GetValue()) == nil ||
+		m.findSubCategory(product.SubCategoryId.GetValue()) == nil ||
+		m.findBrand(product.BrandId.GetValue()) == nil {
+		return database.ErrForeignKey
+	}
+
+	p.product.Name = product.Name
+	p.product.Description = product.Description
+	p.product.CategoryId = &pb.UUID{Value: product.CategoryId.GetValue()}
+	p.product.SubCategoryId = &pb.UUID{Value: product.SubCategoryId.GetValue()}
+	p.product.BrandId = &pb.UUID{Value: product.BrandId.GetValue()}
+	p.product.Price = product.Price
+	p.product.Quantity = product.Quantity
+	p.product.Featured = product.Featured
+	p.product.UpdatedAt = timestamppb.Now()
+	return nil
+}
+
+func (m *MemoryRepository) DeleteProduct(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	i := slices.IndexFunc(m.data.products, func(p *memoryProduct) bool {
+		return p.product.Id.Value == id
+	})
+	if i < 0 {
+		return database.ErrNotFound
+	}
+	m.data.products = slices.Delete(m.data.products, i, i+1)
+	m.data.reviews = slices.DeleteFunc(m.data.reviews, func(r *pb.Review) bool {
+		return r.ProductId.GetValue() == id
+	})
+	return nil
+}
+
+// TakeStock removes quantity units of a product from stock, or puts them
+// back when quantity is negative, so the in-memory orders share the
+// catalogue's stock. It reports false, changing nothing, when there are
+// fewer units than quantity.
+func (m *MemoryRepository) TakeStock(ctx context.Context, productId string, quantity int32) (bool, error) {
+	defer m.lock()()
+
+	p := m.findProduct(productId)
+	if p == nil {
+		return false, database.ErrNotFound
+	}
+	if p.product.Quantity < quantity {
+		return false, nil
+	}
+	p.product.Quantity -= quantity
+	return true, nil
+}
+
+func (m *MemoryRepository) ListProducts(
+	ctx context.Context,
+	filter ProductFilter,
+) ([]*pb.Product, int32, error) {
+	defer m.lock()()
+
+	var matched []*memoryProduct
+	for _, p := range m.data.products {
+		switch {
+		case filter.CategoryId != "" && p.product.CategoryId.Value != filter.CategoryId:
+		case filter.SubCategoryId != "" && p.product.SubCategoryId.Value != filter.SubCategoryId:
+		case filter.BrandId != "" && p.product.BrandId.Value != filter.BrandId:
+		case filter.Featured && !p.product.Featured:
+		default:
+			matched = append(matched, p)
+		}
+	}
+	total := int32(len(matched))
+
+	// like the query, the page is cut in id order and then sorted newest
+	// first.
+	slices.SortFunc(matched, func(a, b *memoryProduct) int {
+		return cmp.Compare(a.product.Id.Value, b.product.Id.Value)
+	})
+	var products []*pb.Product
+	for _, p := range memstore.Page(matched, filter.Limit, filter.Offset) {
+		product := m.view(p, nil)
+		product.TotalCount = total
+		products = append(products, product)
+	}
+	slices.SortStableFunc(products, func(a, b *pb.Product) int {
+		return b.CreatedAt.AsTime().Compare(a.CreatedAt.AsTime())
+	})
+
+	if len(products) == 0 {
+		return nil, 0, nil
+	}
+	return products, total, nil
+}
+
+func (m *MemoryRepository) GetProductSummaries(
+	ctx context.Context,
+	ids []string,
+) ([]*pb.ProductSummary, error) {
+	defer m.lock()()
+
+	var products []*pb.ProductSummary
+	for _, p := range m.data.products {
+		if !slices.Contains(ids, p.product.Id.Value) {
+			continue
+		}
+		summary := &pb.ProductSummary{
+			Id:      &pb.UUID{Value: p.product.Id.Value},
+			Name:    p.product.Name,
+			Slug:    p.product.Slug,
+			Price:   p.product.Price,
+			InStock: p.product.Quantity > 0,
+		}
+		if len(p.images) > 0 {
+			summary.ImageUrl = p.images[0].Url
+		}
+		products = append(products, summary)
+	}
+	return products, nil
+}
+
+func (m *MemoryRepository) AddProductImage(ctx context.Context, productId string, image *pb.Image) error {
+	defer m.lock()()
+
+	p := m.findProduct(productId)
+	if p == nil {
+		return database.ErrForeignKey
+	}
+	p.images = append(p.images, proto.Clone(image).(*pb.Image))
+	return nil
+}
+
+func (m *MemoryRepository) ListProductImages(ctx context.Context, productId string) ([]*pb.Image, error) {
+	defer m.lock()()
+
+	p := m.findProduct(productId)
+	if p == nil {
+		return nil, nil
+	}
+	return memstore.CloneAll(p.images), nil
+}
+
+func (m *MemoryRepository) CreateReview(ctx context.Context, review *pb.Review) error {
+	defer m.lock()()
+
+	if m.findProduct(review.ProductId.GetValue()) == nil {
+		return database.ErrForeignKey
+	}
+	m.data.reviews = append(m.data.reviews, &pb.Review{
+		Id:        &pb.UUID{Value: uuid.NewString()},
+		ProductId: &pb.UUID{Value: review.ProductId.GetValue()},
+		UserId:    &pb.UUID{Value: review.UserId.GetValue()},
+		Title:     review.Title,
+		Content:   review.Content,
+		Rating:    review.Rating,
+	})
+	return nil
+}
+
+func (m *MemoryRepository) GetReview(ctx context.Context, id string) (*pb.Review, error) {
+	defer m.lock()()
+
+	for _, review := range m.data.reviews {
+		if review.Id.Value == id {
+			return proto.Clone(review).(*pb.Review), nil
+		}
+	}
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) ListReviews(ctx context.Context, productId string) ([]*pb.Review, error) {
+	defer m.lock()()
+
+	var reviews []*pb.Review
+	for _, review := range m.data.reviews {
+		if review.ProductId.GetValue() == productId {
+			reviews = append(reviews, proto.Clone(review).(*pb.Review))
+		}
+	}
+	return reviews, nil
+}
+
+func (m *MemoryRepository) GetProductRating(ctx context.Context, productId string) (float32, int32, error) {
+	defer m.lock()()
+
+	rating, count := m.rating(productId)
+	return rating, count, nil
+}
+
+func (m *MemoryRepository) CreateCategory(ctx context.Context, category *pb.Category) (string, error) {
+	defer m.lock()()
+
+	stored := &pb.Category{
+		Id:          &pb.UUID{Value: uuid.NewString()},
+		Name:        category.Name,
+		Description: category.Description,
+		Featured:    category.Featured,
+		Slug:        category.Slug,
+	}
+	if strings.TrimSpace(stored.Slug) == "" {
+		stored.Slug = categorySlug(stored.Name)
+	}
+	if m.findCategoryBySlug(stored.Slug) != nil {
+		return "", database.ErrConflict
+	}
+	m.data.categories = append(m.data.categories, &memoryRow[*pb.Category]{row: stored, updatedAt: time.Now()})
+	return stored.Id.Value, nil
+}
+
+func (m *MemoryRepository) GetCategory(ctx context.Context, id string) (*pb.Category, error) {
+	defer m.lock()()
+
+	c := m.findCategory(id)
+	if c == nil {
+		return nil, database.ErrNotFound
+	}
+	return proto.Clone(c.row).(*pb.Category), nil
+}
+
+func (m *MemoryRepository) GetCategoryBySlug(ctx context.Context, slug string) (*pb.Category, error) {
+	defer m.lock()()
+
+	c := m.findCategoryBySlug(slug)
+	if c == nil {
+		return nil, database.ErrNotFound
+	}
+	return proto.Clone(c.row).(*pb.Category), nil
+}
+
+func (m *MemoryRepository) ListCategories(ctx context.Context, limit, offset int32) ([]*pb.Category, error) {
+	defer m.lock()()
+
+	var categories []*pb.Category
+	for _, c := range m.data.categories {
+		categories = append(categories, proto.Clone(c.row).(*pb.Category))
+	}
+	return memstore.Page(categories, limit, offset), nil
+}
+
+func (m *MemoryRepository) ListFeaturedCategories(ctx context.Context) ([]*pb.Category, error) {
+	defer m.lock()()
+
+	var categories []*pb.Category
+	for _, c := range m.data.categories {
+		if c.row.Featured {
+			categories = append(categories, proto.Clone(c.row).(*pb.Category))
+		}
+	}
+	return categories, nil
+}
+
+func (m *MemoryRepository) UpdateCategory(ctx context.Context, category *pb.Category) error {
+	defer m.lock()()
+
+	c := m.findCategory(category.Id.GetValue())
+	if c == nil {
+		return database.ErrNotFound
+	}
+	c.row.Name = category.Name
+	c.row.Description = category.Description
+	c.row.Featured = category.Featured
+	c.updatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryRepository) DeleteCategory(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	if m.findCategory(id) == nil {
+		return database.ErrNotFound
+	}
+	for _, p := range m.data.products {
+		if p.product.CategoryId.Value == id {
+			return database.ErrForeignKey
+		}
+		if s := m.findSubCategory(p.product.SubCategoryId.Value); s != nil && s.row.CategoryId.Value == id {
+			return database.ErrForeignKey
+		}
+	}
+
+	m.data.subCategories = slices.DeleteFunc(m.data.subCategories, func(s *memoryRow[*pb.SubCategory]) bool {
+		return s.row.CategoryId.Value == id
+	})
+	m.data.categories = slices.DeleteFunc(m.data.categories, func(c *memoryRow[*pb.Category]) bool {
+		return c.row.Id.Value == id
+	})
+	return nil
+}
+
+func (m *MemoryRepository) CreateSubCategory(ctx context.Context, subCategory *pb.SubCategory) error {
+	defer m.lock()()
+
+	if m.findCategory(subCategory.CategoryId.GetValue()) == nil {
+		return database.ErrForeignKey
+	}
+	stored := &pb.SubCategory{
+		Id:          &pb.UUID{Value: uuid.NewString()},
+		CategoryId:  &pb.UUID{Value: subCategory.CategoryId.GetValue()},
+		Name:        subCategory.Name,
+		Description: subCategory.Description,
+		Slug:        subCategory.Slug,
+	}
+	if strings.TrimSpace(stored.Slug) == "" {
+		stored.Slug = categorySlug(stored.Name)
+	}
+	if m.findSubCategoryBySlug(stored.Slug) != nil {
+		return database.ErrConflict
+	}
+	m.data.subCategories = append(m.data.subCategories, &memoryRow[*pb.SubCategory]{row: stored, updatedAt: time.Now()})
+	return nil
+}
+
+func (m *MemoryRepository) GetSubCategory(ctx context.Context, id string) (*pb.SubCategory, error) {
+	defer m.lock()()
+
+	s := m.findSubCategory(id)
+	if s == nil {
+		return nil, database.ErrNotFound
+	}
+	return proto.Clone(s.row).(*pb.SubCategory), nil
+}
+
+func (m *MemoryRepository) GetSubCategoryBySlug(ctx context.Context, slug string) (*pb.SubCategory, error) {
+	defer m.lock()()
+
+	s := m.findSubCategoryBySlug(slug)
+	if s == nil {
+		return nil, database.ErrNotFound
+	}
+	return proto.Clone(s.row).(*pb.SubCategory), nil
+}
+
+func (m *MemoryRepository) ListSubCategories(
+	ctx context.Context,
+	categoryId string,
+	limit, offset int32,
+) ([]*pb.SubCategory, error) {
+	defer m.lock()()
+
+	var subCategories []*pb.SubCategory
+	for _, s := range m.data.subCategories {
+		if s.row.CategoryId.Value == categoryId {
+			subCategories = append(subCategories, proto.Clone(s.row).(*pb.SubCategory))
+		}
+	}
+	return memstore.Page(subCategories, limit, offset), nil
+}
+
+func (m *MemoryRepository) UpdateSubCategory(ctx context.Context, subCategory *pb.SubCategory) error {
+	defer m.lock()()
+
+	s := m.findSubCategory(subCategory.Id.GetValue())
+	if s == nil {
+		return database.ErrNotFound
+	}
+	if other := m.findSubCategoryBySlug(subCategory.Slug); other != nil && other != s {
+		return database.ErrConflict
+	}
+	s.row.Name = subCategory.Name
+	s.row.Description = subCategory.Description
+	s.row.Slug = subCategory.Slug
+	s.updatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryRepository) DeleteSubCategory(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	if m.findSubCategory(id) == nil {
+		return database.ErrNotFound
+	}
+	for _, p := range m.data.products {
+		if p.product.SubCategoryId.Value == id {
+			return database.ErrForeignKey
+		}
+	}
+	m.data.subCategories = slices.DeleteFunc(m.data.subCategories, func(s *memoryRow[*pb.SubCategory]) bool {
+		return s.row.Id.Value == id
+	})
+	return nil
+}
+
+func (m *MemoryRepository) CreateBrand(ctx context.Context, brand *pb.Brand) (string, error) {
+	defer m.lock()()
+
+	stored := &pb.Brand{
+		Id:          &pb.UUID{Value: uuid.NewString()},
+		Name:        brand.Name,
+		Description: brand.Description,
+	}
+	m.data.brands = append(m.data.brands, stored)
+	return stored.Id.Value, nil
+}
+
+func (m *MemoryRepository) GetBrand(ctx context.Context, id string) (*pb.Brand, error) {
+	defer m.lock()()
+
+	b := m.findBrand(id)
+	if b == nil {
+		return nil, database.ErrNotFound
+	}
+	return proto.Clone(b).(*pb.Brand), nil
+}
+
+func (m *MemoryRepository) ListBrands(ctx context.Context, limit, offset int32) ([]*pb.Brand, error) {
+	defer m.lock()()
+
+	return memstore.Page(memstore.CloneAll(m.data.brands), limit, offset), nil
+}
+
+func (m *MemoryRepository) UpdateBrand(ctx context.Context, brand *pb.Brand) error {
+	defer m.lock()()
+
+	b := m.findBrand(brand.Id.GetValue())
+	if b == nil {
+		return database.ErrNotFound
+	}
+	b.Name = brand.Name
+	b.Description = brand.Description
+	return nil
+}
+
+func (m *MemoryRepository) DeleteBrand(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	if m.findBrand(id) == nil {
+		return database.ErrNotFound
+	}
+	for _, p := range m.data.products {
+		if p.product.BrandId.Value == id {
+			return database.ErrForeignKey
+		}
+	}
+	m.data.brands = slices.DeleteFunc(m.data.brands, func(b *pb.Brand) bool {
+		return b.Id.Value == id
+	})
+	return nil
+}
+
+func (m *MemoryRepository) SitemapChunks(
+	ctx context.Context,
+	kind pb.SitemapKind,
+	chunkSize int32,
+) ([]*pb.SitemapChunk, error) {
+	defer m.lock()()
+
+	entries, err := m.sitemapEntries(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []*pb.SitemapChunk
+	for i, entry := range entries {
+		index := int32(i) / chunkSize
+		if int(index) == len(chunks) {
+			chunks = append(chunks, &pb.SitemapChunk{Index: index, LastModified: entry.UpdatedAt})
+		} else if chunk := chunks[index]; entry.UpdatedAt.AsTime().After(chunk.LastModified.AsTime()) {
+			chunk.LastModified = entry.UpdatedAt
+		}
+	}
+	return chunks, nil
+}
+
+func (m *MemoryRepository) SitemapEntries(
+	ctx context.Context,
+	kind pb.SitemapKind,
+	limit, offset int32,
+) ([]*pb.SitemapEntry, error) {
+	defer m.lock()()
+
+	entries, err := m.sitemapEntries(kind)
+	if err != nil {
+		return nil, err
+	}
+	return memstore.Page(entries, limit, offset), nil
+}
+
+// sitemapEntries lists every row of kind in id order.
+func (m *MemoryRepository) sitemapEntries(kind pb.SitemapKind) ([]*pb.SitemapEntry, error) {
+	type row struct {
+		id    string
+		entry *pb.SitemapEntry
+	}
+	var rows []row
+
+	switch kind {
+	case pb.SitemapKind_PRODUCTS:
+		for _, p := range m.data.products {
+			rows = append(rows, row{p.product.Id.Value, &pb.SitemapEntry{Slug: p.product.Slug, UpdatedAt: p.product.UpdatedAt}})
+		}
+	case pb.SitemapKind_CATEGORIES:
+		for _, c := range m.data.categories {
+			rows = append(rows, row{c.row.Id.Value, &pb.SitemapEntry{Slug: c.row.Slug, UpdatedAt: timestamppb.New(c.updatedAt)}})
+		}
+	case pb.SitemapKind_SUB_CATEGORIES:
+		for _, s := range m.data.subCategories {
+			rows = append(rows, row{s.row.Id.Value, &pb.SitemapEntry{Slug: s.row.Slug, UpdatedAt: timestamppb.New(s.updatedAt)}})
+		}
+	default:
+		return nil, fmt.Errorf("unknown sitemap kind %s", kind)
+	}
+
+	slices.SortFunc(rows, func(a, b row) int { return cmp.Compare(a.id, b.id) })
+	entries := make([]*pb.SitemapEntry, len(rows))
+	for i, r := range rows {
+		entries[i] = proto.Clone(r.entry).(*pb.SitemapEntry)
+	}
+	return entries, nil
+}
+
+// view returns a copy of p with what the product queries join onto it.
+// images is what p has when it has no images.
+func (m *MemoryRepository) view(p *memoryProduct, images []*pb.Image) *pb.Product {
+	product := proto.Clone(p.product).(*pb.Product)
+	if len(p.images) > 0 {
+		images = memstore.CloneAll(p.images)
+	}
+	product.Images = images
+	product.AverageRating, product.ReviewCount = m.rating(product.Id.Value)
+	if c := m.findCategory(product.CategoryId.Value); c != nil {
+		product.CategoryName = c.row.Name
+	}
+	if s := m.findSubCategory(product.SubCategoryId.Value); s != nil {
+		product.SubCategoryName = s.row.Name
+	}
+	if b := m.findBrand(product.BrandId.Value); b != nil {
+		product.BrandName = b.Name
+	}
+	return product
+}
+
+func (m *MemoryRepository) rating(productId string) (float32, int32) {
+	var sum float32
+	var count int32
+	for _, review := range m.data.reviews {
+		if review.ProductId.GetValue() == productId {
+			sum += review.Rating
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return sum / float32(count), count
+}
+
+func (m *MemoryRepository) findProduct(id string) *memoryProduct {
+	for _, p := range m.data.products {
+		if p.product.Id.Value == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findCategory(id string) *memoryRow[*pb.Category] {
+	for _, c := range m.data.categories {
+		if c.row.Id.Value == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findCategoryBySlug(slug string) *memoryRow[*pb.Category] {
+	for _, c := range m.data.categories {
+		if c.row.Slug == slug {
+			return c
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findSubCategory(id string) *memoryRow[*pb.SubCategory] {
+	for _, s := range m.data.subCategories {
+		if s.row.Id.Value == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findSubCategoryBySlug(slug string) *memoryRow[*pb.SubCategory] {
+	for _, s := range m.data.subCategories {
+		if s.row.Slug == slug {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findBrand(id string) *pb.Brand {
+	for _, b := range m.data.brands {
+		if b.Id.Value == id {
+			return b
+		}
+	}
+	return nil
+}
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+	dashes     = regexp.MustCompile(`-{2,}`)
+)
+
+// productSlug mirrors the generate_product_slug trigger.
+func productSlug(id, name string) string {
+	suffix := strings.ReplaceAll(id, "-", "")
+	slug := strings.ToLower(whitespace.ReplaceAllString(strings.TrimSpace(name), "-"))
+	slug += "-" + suffix[len(suffix)-12:]
+	return strings.Trim(dashes.ReplaceAllString(slug, "-"), "-")
+}
+
+// categorySlug mirrors the set_category_slug and set_sub_category_slug
+// triggers.
+func categorySlug(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+}
@@ -0,0 +1,778 @@
+package productservice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/product"
+	"github.com/lib/pq"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// sitemapTables maps each sitemap kind to the table it lists. Rows are
+// always walked in id order so chunks and entries line up.
+var sitemapTables = map[pb.SitemapKind]string{
+	pb.SitemapKind_PRODUCTS:       "products",
+	pb.SitemapKind_CATEGORIES:     "product_category",
+	pb.SitemapKind_SUB_CATEGORIES: "product_sub_category",
+}
+
+const productQuery = `
+SELECT
+  p.id,
+  p.name,
+  p.description,
+  p.category_id,
+  p.sub_category_id,
+  p.brand_id,
+  p.price,
+  p.quantity,
+  p.featured,
+  p.slug,
+  pc.name AS category_name,
+  psc.name AS sub_category_name,
+  pb.name AS brand_name,
+  pi.url AS image_url,
+  pi.image_type,
+  pr.review_count,
+  pr.average_rating,
+  p.created_at,
+  p.updated_at
+FROM
+  products p
+LEFT JOIN (
+  SELECT
+    product_id,
+    COUNT(*) AS review_count,
+    AVG(rating) AS average_rating
+  FROM
+    product_reviews
+  GROUP BY
+    product_id
+) pr ON p.id = pr.product_id
+LEFT JOIN
+  productimages pi ON p.id = pi.product_id
+LEFT JOIN
+ product_category pc ON p.category_id = pc.id
+LEFT JOIN
+  product_sub_category psc ON p.sub_category_id = psc.id
+LEFT JOIN
+  product_brand pb ON p.brand_id = pb.id
+WHERE
+  %s;`
+
+type postgresRepository struct {
+	db   database.DB
+	q    database.Querier
+	inTx bool
+}
+
+// NewPostgresRepository returns a Repository that keeps products in db.
+func NewPostgresRepository(db database.DB) Repository {
+	return &postgresRepository{db: db, q: db}
+}
+
+func (r *postgresRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+	return r.db.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		return fn(&postgresRepository{db: r.db, q: tx, inTx: true})
+	})
+}
+
+func (r *postgresRepository) CreateProduct(ctx context.Context, product *pb.Product) (string, error) {
+	stmt := `INSERT INTO products (name,description, category_id, sub_category_id, brand_id, price, quantity, featured) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	var productId string
+	err := r.q.QueryRowContext(ctx, stmt,
+		product.Name,
+		product.Description,
+		product.CategoryId.GetValue(),
+		product.SubCategoryId.GetValue(),
+		product.BrandId.GetValue(),
+		product.Price,
+		product.Quantity,
+		product.Featured,
+	).Scan(&productId)
+	return productId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetProduct(ctx context.Context, id string) (*pb.Product, error) {
+	return r.getProduct(ctx, "p.id = $1", id)
+}
+
+func (r *postgresRepository) GetProductBySlug(ctx context.Context, slug string) (*pb.Product, error) {
+	return r.getProduct(ctx, "p.slug = $1", slug)
+}
+
+// getProduct reads the one product matching where, which gets a row for
+// each of its images.
+func (r *postgresRepository) getProduct(ctx context.Context, where, arg string) (*pb.Product, error) {
+	rows, err := r.q.QueryContext(ctx, fmt.Sprintf(productQuery, where), arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var product *pb.Product
+	for rows.Next() {
+		row, image, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			product = row
+			product.Images = []*pb.Image{}
+		}
+		if image != nil {
+			product.Images = append(product.Images, image)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, database.ErrNotFound
+	}
+	return product, nil
+}
+
+func (r *postgresRepository) UpdateProduct(ctx context.Context, product *pb.Product) error {
+	stmt := `UPDATE products SET name=$1, description=$2, category_id=$3, sub_category_id=$4, brand_id=$5, price=$6, quantity=$7, featured=$8 WHERE id=$9`
+	return r.exec(ctx, stmt,
+		product.Name,
+		product.Description,
+		product.CategoryId.GetValue(),
+		product.SubCategoryId.GetValue(),
+		product.BrandId.GetValue(),
+		product.Price,
+		product.Quantity,
+		product.Featured,
+		product.Id.GetValue(),
+	)
+}
+
+func (r *postgresRepository) DeleteProduct(ctx context.Context, id string) error {
+	// reviews cascade, images have to be removed first.
+	return r.WithTx(ctx, func(repo Repository) error {
+		tx := repo.(*postgresRepository)
+		if _, err := tx.q.ExecContext(ctx, `DELETE FROM productimages WHERE product_id=$1`, id); err != nil {
+			return fmt.Errorf("could not delete product images: %w", err)
+		}
+		return tx.exec(ctx, `DELETE FROM products WHERE id=$1`, id)
+	})
+}
+
+func (r *postgresRepository) ListProducts(
+	ctx context.Context,
+	filter ProductFilter,
+) ([]*pb.Product, int32, error) {
+	var where []string
+	args := []interface{}{filter.Limit, filter.Offset}
+	arg := func(column string, v interface{}) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf("%s=$%d", column, len(args)))
+	}
+
+	if filter.CategoryId != "" {
+		arg("category_id", filter.CategoryId)
+	}
+	if filter.SubCategoryId != "" {
+		arg("sub_category_id", filter.SubCategoryId)
+	}
+	if filter.BrandId != "" {
+		arg("brand_id", filter.BrandId)
+	}
+	if filter.Featured {
+		where = append(where, "featured=true")
+	}
+
+	stmt := BuildProductQuery(strings.Join(where, " AND "), "ORDER BY created_at DESC")
+	rows, err := r.q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var products []*pb.Product
+	seen := make(map[string]*pb.Product)
+	for rows.Next() {
+		var totalCount int32
+		product, image, err := scanProduct(rows, &totalCount)
+		if err != nil {
+			return nil, 0, err
+		}
+		product.TotalCount = totalCount
+
+		if exists, found := seen[product.Id.Value]; found {
+			product = exists
+		} else {
+			seen[product.Id.Value] = product
+			products = append(products, product)
+		}
+		if image != nil {
+			product.Images = append(product.Images, image)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if len(products) == 0 {
+		return nil, 0, nil
+	}
+	return products, products[0].TotalCount, nil
+}
+
+// BuildProductQuery returns the query for a page of products, with their
+// images, rating and names, matching whereClause. The limit and offset are
+// $1 and $2.
+func BuildProductQuery(whereClause string, additionalClauses string) string {
+	baseQuery := `
+	WITH paginated_products AS (
+		SELECT *,
+		COUNT(*) OVER() AS total_count
+		FROM products
+		%s -- WHERE clause will be inserted here
+		ORDER BY id
+		LIMIT $1
+		OFFSET $2
+	)
+	SELECT
+		p.id,
+		p.name,
+		p.description,
+		p.category_id,
+		p.sub_category_id,
+		p.brand_id,
+		p.price,
+		p.quantity,
+		p.featured,
+		p.slug,
+		pc.name as category_name,
+		psc.name as sub_category_name,
+		pb.name as brand_name,
+		pi.url AS image_url,
+		pi.image_type,
+		pr.review_count,
+		pr.average_rating,
+		p.created_at,
+		p.updated_at,
+		p.total_count
+	FROM
+		paginated_products p
+	LEFT JOIN productimages pi ON p.id = pi.product_id
+	LEFT JOIN (
+		SELECT
+			product_id,
+			COUNT(*) AS review_count,
+			AVG(rating) AS average_rating
+		FROM product_reviews
+		GROUP BY product_id
+	) pr ON p.id = pr.product_id
+	LEFT JOIN product_sub_category psc ON p.sub_category_id = psc.id
+	LEFT JOIN product_category pc ON p.category_id = pc.id
+	LEFT JOIN product_brand pb ON p.brand_id = pb.id
+	%s; -- Additional clauses like GROUP BY or ORDER BY etc.
+`
+	where := ""
+	if whereClause != "" {
+		where = fmt.Sprintf("WHERE %s", whereClause)
+	}
+
+	return fmt.Sprintf(baseQuery, where, additionalClauses)
+}
+
+func (r *postgresRepository) GetProductSummaries(
+	ctx context.Context,
+	ids []string,
+) ([]*pb.ProductSummary, error) {
+	// the first image uploaded is the one shown on cards.
+	stmt := `SELECT p.id, p.name, p.slug, p.price, p.quantity,
+	  COALESCE((SELECT url FROM productimages WHERE product_id = p.id ORDER BY created_at LIMIT 1), '')
+	FROM products p WHERE p.id = ANY($1::uuid[])`
+
+	rows, err := r.q.QueryContext(ctx, stmt, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*pb.ProductSummary
+	for rows.Next() {
+		var product pb.ProductSummary
+		var productId string
+		var quantity int32
+		err := rows.Scan(
+			&productId,
+			&product.Name,
+			&product.Slug,
+			&product.Price,
+			&quantity,
+			&product.ImageUrl,
+		)
+		if err != nil {
+			return nil, err
+		}
+		product.Id = &pb.UUID{Value: productId}
+		product.InStock = quantity > 0
+		products = append(products, &product)
+	}
+	return products, rows.Err()
+}
+
+func (r *postgresRepository) AddProductImage(ctx context.Context, productId string, image *pb.Image) error {
+	stmt := `INSERT INTO productimages(product_id, image_type, url) VALUES ($1, $2, $3)`
+	_, err := r.q.ExecContext(ctx, stmt, productId, image.ImageType, image.Url)
+	return database.Translate(err)
+}
+
+func (r *postgresRepository) ListProductImages(ctx context.Context, productId string) ([]*pb.Image, error) {
+	stmt := `SELECT url, image_type from productimages WHERE product_id=$1 ORDER BY created_at`
+	rows, err := r.q.QueryContext(ctx, stmt, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []*pb.Image
+	for rows.Next() {
+		var image pb.Image
+		if err := rows.Scan(&image.Url, &image.ImageType); err != nil {
+			return nil, err
+		}
+		images = append(images, &image)
+	}
+	return images, rows.Err()
+}
+
+func (r *postgresRepository) CreateReview(ctx context.Context, review *pb.Review) error {
+	stmt := `INSERT INTO product_reviews (product_id, user_id, rating, title, content) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.q.ExecContext(ctx,
+		stmt,
+		review.ProductId.GetValue(),
+		review.UserId.GetValue(),
+		review.Rating,
+		review.Title,
+		review.Content,
+	)
+	return database.Translate(err)
+}
+
+func (r *postgresRepository) GetReview(ctx context.Context, id string) (*pb.Review, error) {
+	stmt := `SELECT r.id, r.product_id, r.user_id, r.rating, r.title, r.content, u.name FROM product_reviews r JOIN users u ON r.user_id = u.id WHERE r.id=$1`
+	review, err := scanReview(r.q.QueryRowContext(ctx, stmt, id))
+	return review, database.Translate(err)
+}
+
+func (r *postgresRepository) ListReviews(ctx context.Context, productId string) ([]*pb.Review, error) {
+	// left join with user table
+	stmt := `SELECT r.id, r.product_id, r.user_id, r.rating, r.title, r.content, u.name as user_name
+	FROM product_reviews r
+	LEFT JOIN users u ON r.user_id = u.id
+	WHERE r.product_id = $1;`
+
+	rows, err := r.q.QueryContext(ctx, stmt, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []*pb.Review
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+func (r *postgresRepository) GetProductRating(ctx context.Context, productId string) (float32, int32, error) {
+	stmt := `SELECT AVG(rating), COUNT(*) FROM product_reviews WHERE product_id=$1`
+
+	// AVG is NULL for a product nobody has reviewed.
+	var rating sql.NullFloat64
+	var count int32
+	if err := r.q.QueryRowContext(ctx, stmt, productId).Scan(&rating, &count); err != nil {
+		return 0, 0, err
+	}
+	return float32(rating.Float64), count, nil
+}
+
+func (r *postgresRepository) CreateCategory(ctx context.Context, category *pb.Category) (string, error) {
+	stmt := `INSERT INTO product_category(name, description, featured) VALUES ($1, $2, $3) RETURNING id;`
+
+	var id string
+	err := r.q.QueryRowContext(ctx, stmt, category.Name, category.Description, category.Featured).
+		Scan(&id)
+	return id, database.Translate(err)
+}
+
+func (r *postgresRepository) GetCategory(ctx context.Context, id string) (*pb.Category, error) {
+	stmt := `SELECT id, name, description, featured, slug FROM product_category WHERE id = $1`
+	category, err := scanCategory(r.q.QueryRowContext(ctx, stmt, id))
+	return category, database.Translate(err)
+}
+
+func (r *postgresRepository) GetCategoryBySlug(ctx context.Context, slug string) (*pb.Category, error) {
+	stmt := `SELECT id, name, description, featured, slug FROM product_category WHERE slug = $1`
+	category, err := scanCategory(r.q.QueryRowContext(ctx, stmt, slug))
+	return category, database.Translate(err)
+}
+
+func (r *postgresRepository) ListCategories(ctx context.Context, limit, offset int32) ([]*pb.Category, error) {
+	stmt := `SELECT id, name, description, featured, slug FROM product_category LIMIT $1 OFFSET $2`
+	return r.listCategories(ctx, stmt, limit, offset)
+}
+
+func (r *postgresRepository) ListFeaturedCategories(ctx context.Context) ([]*pb.Category, error) {
+	stmt := `SELECT id, name, description, featured, slug FROM product_category WHERE featured = true`
+	return r.listCategories(ctx, stmt)
+}
+
+func (r *postgresRepository) listCategories(
+	ctx context.Context,
+	stmt string,
+	args ...interface{},
+) ([]*pb.Category, error) {
+	rows, err := r.q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*pb.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (r *postgresRepository) UpdateCategory(ctx context.Context, category *pb.Category) error {
+	stmt := `UPDATE product_category SET name = $1, description = $2, featured = $3 WHERE id = $4`
+	return r.exec(ctx, stmt, category.Name, category.Description, category.Featured, category.Id.GetValue())
+}
+
+func (r *postgresRepository) DeleteCategory(ctx context.Context, id string) error {
+	return r.WithTx(ctx, func(repo Repository) error {
+		tx := repo.(*postgresRepository)
+		_, err := tx.q.ExecContext(ctx, `DELETE FROM product_sub_category WHERE category_id = $1`, id)
+		if err != nil {
+			return database.Translate(err)
+		}
+		return tx.exec(ctx, `DELETE FROM product_category WHERE id = $1`, id)
+	})
+}
+
+func (r *postgresRepository) CreateSubCategory(ctx context.Context, subCategory *pb.SubCategory) error {
+	stmt := `INSERT INTO product_sub_category (name, description, category_id, slug) VALUES ($1, $2, $3, $4)`
+	_, err := r.q.ExecContext(ctx,
+		stmt,
+		subCategory.Name,
+		subCategory.Description,
+		subCategory.CategoryId.GetValue(),
+		subCategory.Slug,
+	)
+	return database.Translate(err)
+}
+
+func (r *postgresRepository) GetSubCategory(ctx context.Context, id string) (*pb.SubCategory, error) {
+	stmt := `SELECT id, name, description, category_id, slug FROM product_sub_category WHERE id = $1`
+	subCategory, err := scanSubCategory(r.q.QueryRowContext(ctx, stmt, id))
+	return subCategory, database.Translate(err)
+}
+
+func (r *postgresRepository) GetSubCategoryBySlug(ctx context.Context, slug string) (*pb.SubCategory, error) {
+	stmt := `SELECT id, name, description, category_id, slug FROM product_sub_category WHERE slug = $1`
+	subCategory, err := scanSubCategory(r.q.QueryRowContext(ctx, stmt, slug))
+	return subCategory, database.Translate(err)
+}
+
+func (r *postgresRepository) ListSubCategories(
+	ctx context.Context,
+	categoryId string,
+	limit, offset int32,
+) ([]*pb.SubCategory, error) {
+	stmt := `SELECT id, name, description, category_id, slug FROM product_sub_category WHERE category_id = $1 LIMIT $2 OFFSET $3`
+
+	rows, err := r.q.QueryContext(ctx, stmt, categoryId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subCategories []*pb.SubCategory
+	for rows.Next() {
+		subCategory, err := scanSubCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		subCategories = append(subCategories, subCategory)
+	}
+	return subCategories, rows.Err()
+}
+
+func (r *postgresRepository) UpdateSubCategory(ctx context.Context, subCategory *pb.SubCategory) error {
+	stmt := `UPDATE product_sub_category SET name = $1, description = $2, slug = $3 WHERE id = $4`
+	return r.exec(ctx,
+		stmt,
+		subCategory.Name,
+		subCategory.Description,
+		subCategory.Slug,
+		subCategory.Id.GetValue(),
+	)
+}
+
+func (r *postgresRepository) DeleteSubCategory(ctx context.Context, id string) error {
+	return r.exec(ctx, `DELETE FROM product_sub_category WHERE id = $1`, id)
+}
+
+func (r *postgresRepository) CreateBrand(ctx context.Context, brand *pb.Brand) (string, error) {
+	stmt := `INSERT INTO product_brand (name, description) VALUES ($1, $2) RETURNING id`
+
+	var id string
+	err := r.q.QueryRowContext(ctx, stmt, brand.Name, brand.Description).Scan(&id)
+	return id, database.Translate(err)
+}
+
+func (r *postgresRepository) GetBrand(ctx context.Context, id string) (*pb.Brand, error) {
+	stmt := `SELECT id, name, description FROM product_brand WHERE id = $1`
+	brand, err := scanBrand(r.q.QueryRowContext(ctx, stmt, id))
+	return brand, database.Translate(err)
+}
+
+func (r *postgresRepository) ListBrands(ctx context.Context, limit, offset int32) ([]*pb.Brand, error) {
+	stmt := `SELECT id, name, description FROM product_brand LIMIT $1 OFFSET $2`
+
+	rows, err := r.q.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var brands []*pb.Brand
+	for rows.Next() {
+		brand, err := scanBrand(rows)
+		if err != nil {
+			return nil, err
+		}
+		brands = append(brands, brand)
+	}
+	return brands, rows.Err()
+}
+
+func (r *postgresRepository) UpdateBrand(ctx context.Context, brand *pb.Brand) error {
+	stmt := `UPDATE product_brand SET name = $1, description = $2 WHERE id = $3`
+	return r.exec(ctx, stmt, brand.Name, brand.Description, brand.Id.GetValue())
+}
+
+func (r *postgresRepository) DeleteBrand(ctx context.Context, id string) error {
+	return r.exec(ctx, `DELETE FROM product_brand WHERE id = $1`, id)
+}
+
+func (r *postgresRepository) SitemapChunks(
+	ctx context.Context,
+	kind pb.SitemapKind,
+	chunkSize int32,
+) ([]*pb.SitemapChunk, error) {
+	table, ok := sitemapTables[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown sitemap kind %s", kind)
+	}
+
+	stmt := `SELECT chunk, MAX(updated_at) FROM (
+		SELECT (ROW_NUMBER() OVER (ORDER BY id) - 1) / $1 AS chunk, updated_at FROM ` + table + `
+	) numbered GROUP BY chunk ORDER BY chunk`
+
+	rows, err := r.q.QueryContext(ctx, stmt, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []*pb.SitemapChunk
+	for rows.Next() {
+		var index int32
+		var lastModified time.Time
+		if err := rows.Scan(&index, &lastModified); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, &pb.SitemapChunk{
+			Index:        index,
+			LastModified: timestamppb.New(lastModified),
+		})
+	}
+	return chunks, rows.Err()
+}
+
+func (r *postgresRepository) SitemapEntries(
+	ctx context.Context,
+	kind pb.SitemapKind,
+	limit, offset int32,
+) ([]*pb.SitemapEntry, error) {
+	table, ok := sitemapTables[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown sitemap kind %s", kind)
+	}
+
+	stmt := `SELECT slug, updated_at FROM ` + table + ` ORDER BY id LIMIT $1 OFFSET $2`
+	rows, err := r.q.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*pb.SitemapEntry
+	for rows.Next() {
+		var slug string
+		var updatedAt time.Time
+		if err := rows.Scan(&slug, &updatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &pb.SitemapEntry{
+			Slug:      slug,
+			UpdatedAt: timestamppb.New(updatedAt),
+		})
+	}
+	return entries, rows.Err()
+}
+
+// exec runs a write that has to match a row, failing with
+// database.ErrNotFound when it matches none.
+func (r *postgresRepository) exec(ctx context.Context, stmt string, args ...interface{}) error {
+	result, err := r.q.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return database.Translate(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return database.ErrNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProduct reads a row of productQuery, and any columns after it into
+// extra. The image is nil when the row has none.
+func scanProduct(row rowScanner, extra ...interface{}) (*pb.Product, *pb.Image, error) {
+	var product pb.Product
+	var productId, categoryId, subCategoryId, brandId string
+	var categoryName, subCategoryName, brandName, imageUrl, imageType sql.NullString
+	var reviewCount sql.NullInt32
+	var averageRating sql.NullFloat64
+	var createdAt, updatedAt sql.NullTime
+
+	dest := []interface{}{
+		&productId,
+		&product.Name,
+		&product.Description,
+		&categoryId,
+		&subCategoryId,
+		&brandId,
+		&product.Price,
+		&product.Quantity,
+		&product.Featured,
+		&product.Slug,
+		&categoryName,
+		&subCategoryName,
+		&brandName,
+		&imageUrl,
+		&imageType,
+		&reviewCount,
+		&averageRating,
+		&createdAt,
+		&updatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, nil, err
+	}
+
+	product.Id = &pb.UUID{Value: productId}
+	product.CategoryId = &pb.UUID{Value: categoryId}
+	product.SubCategoryId = &pb.UUID{Value: subCategoryId}
+	product.BrandId = &pb.UUID{Value: brandId}
+	product.CategoryName = categoryName.String
+	product.SubCategoryName = subCategoryName.String
+	product.BrandName = brandName.String
+	product.ReviewCount = reviewCount.Int32
+	product.AverageRating = float32(averageRating.Float64)
+	product.CreatedAt = timestamppb.New(createdAt.Time)
+	product.UpdatedAt = timestamppb.New(updatedAt.Time)
+
+	var image *pb.Image
+	if imageUrl.String != "" {
+		image = &pb.Image{Url: imageUrl.String, ImageType: imageType.String}
+	}
+	return &product, image, nil
+}
+
+func scanReview(row rowScanner) (*pb.Review, error) {
+	var review pb.Review
+	var reviewId, productId, userId string
+	var userName sql.NullString
+
+	err := row.Scan(
+		&reviewId,
+		&productId,
+		&userId,
+		&review.Rating,
+		&review.Title,
+		&review.Content,
+		&userName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	review.Id = &pb.UUID{Value: reviewId}
+	review.ProductId = &pb.UUID{Value: productId}
+	review.UserId = &pb.UUID{Value: userId}
+	review.UserName = userName.String
+	return &review, nil
+}
+
+func scanCategory(row rowScanner) (*pb.Category, error) {
+	var category pb.Category
+	var categoryId string
+	err := row.Scan(&categoryId, &category.Name, &category.Description, &category.Featured, &category.Slug)
+	if err != nil {
+		return nil, err
+	}
+	category.Id = &pb.UUID{Value: categoryId}
+	return &category, nil
+}
+
+func scanSubCategory(row rowScanner) (*pb.SubCategory, error) {
+	var subCategory pb.SubCategory
+	var subCategoryId, categoryId string
+	err := row.Scan(&subCategoryId, &subCategory.Name, &subCategory.Description, &categoryId, &subCategory.Slug)
+	if err != nil {
+		return nil, err
+	}
+	subCategory.Id = &pb.UUID{Value: subCategoryId}
+	subCategory.CategoryId = &pb.UUID{Value: categoryId}
+	return &subCategory, nil
+}
+
+func scanBrand(row rowScanner) (*pb.Brand, error) {
+	var brand pb.Brand
+	var brandId string
+	if err := row.Scan(&brandId, &brand.Name, &brand.Description); err != nil {
+		return nil, err
+	}
+	brand.Id = &pb.UUID{Value: brandId}
+	return &brand, nil
+}
@@ -0,0 +1,94 @@
+package productservice
+
+import (
+	"context"
+
+	pb "github.com/kelcheone/chemistke/pkg/grpc/product"
+)
+
+// ProductFilter picks the products ListProducts returns. Empty ids and a
+// false Featured leave a filter out.
+type ProductFilter struct {
+	CategoryId    string
+	SubCategoryId string
+	BrandId       string
+	Featured      bool
+
+	Limit  int32
+	Offset int32
+}
+
+// Repository is where the product service keeps its data. Lookups of a
+// missing row fail with database.ErrNotFound, as do updates and deletes
+// that match nothing. Deleting a row something else still points at fails
+// with database.ErrForeignKey.
+type Repository interface {
+	// CreateProduct stores product and returns its id. The slug is
+	// generated from the name.
+	CreateProduct(ctx context.Context, product *pb.Product) (string, error)
+	// GetProduct returns a product with its images, rating and the names
+	// of its category, sub-category and brand.
+	GetProduct(ctx context.Context, id string) (*pb.Product, error)
+	GetProductBySlug(ctx context.Context, slug string) (*pb.Product, error)
+	UpdateProduct(ctx context.Context, product *pb.Product) error
+	// DeleteProduct removes a product along with its images and reviews.
+	DeleteProduct(ctx context.Context, id string) error
+	// ListProducts returns a page of the products matching filter and how
+	// many match in all.
+	ListProducts(ctx context.Context, filter ProductFilter) ([]*pb.Product, int32, error)
+	// GetProductSummaries returns the products among ids that exist, in no
+	// particular order.
+	GetProductSummaries(ctx context.Context, ids []string) ([]*pb.ProductSummary, error)
+
+	AddProductImage(ctx context.Context, productId string, image *pb.Image) error
+	// ListProductImages returns a product's images oldest first.
+	ListProductImages(ctx context.Context, productId string) ([]*pb.Image, error)
+
+	CreateReview(ctx context.Context, review *pb.Review) error
+	GetReview(ctx context.Context, id string) (*pb.Review, error)
+	ListReviews(ctx context.Context, productId string) ([]*pb.Review, error)
+	// GetProductRating returns the average rating of a product and how many
+	// reviews it has.
+	GetProductRating(ctx context.Context, productId string) (float32, int32, error)
+
+	// CreateCategory stores category and returns its id. The slug is
+	// generated from the name when it is empty.
+	CreateCategory(ctx context.Context, category *pb.Category) (string, error)
+	GetCategory(ctx context.Context, id string) (*pb.Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*pb.Category, error)
+	ListCategories(ctx context.Context, limit, offset int32) ([]*pb.Category, error)
+	ListFeaturedCategories(ctx context.Context) ([]*pb.Category, error)
+	// UpdateCategory changes a category's name, description and featured
+	// flag.
+	UpdateCategory(ctx context.Context, category *pb.Category) error
+	// DeleteCategory removes a category and its sub-categories.
+	DeleteCategory(ctx context.Context, id string) error
+
+	// CreateSubCategory stores subCategory, generating its slug from the
+	// name when it is empty.
+	CreateSubCategory(ctx context.Context, subCategory *pb.SubCategory) error
+	GetSubCategory(ctx context.Context, id string) (*pb.SubCategory, error)
+	GetSubCategoryBySlug(ctx context.Context, slug string) (*pb.SubCategory, error)
+	ListSubCategories(ctx context.Context, categoryId string, limit, offset int32) ([]*pb.SubCategory, error)
+	// UpdateSubCategory changes a sub-category's name, description and
+	// slug.
+	UpdateSubCategory(ctx context.Context, subCategory *pb.SubCategory) error
+	DeleteSubCategory(ctx context.Context, id string) error
+
+	// CreateBrand stores brand and returns its id.
+	CreateBrand(ctx context.Context, brand *pb.Brand) (string, error)
+	GetBrand(ctx context.Context, id string) (*pb.Brand, error)
+	ListBrands(ctx context.Context, limit, offset int32) ([]*pb.Brand, error)
+	UpdateBrand(ctx context.Context, brand *pb.Brand) error
+	DeleteBrand(ctx context.Context, id string) error
+
+	// SitemapChunks splits the rows of kind, in id order, into chunks of
+	// chunkSize and returns when each chunk last changed.
+	SitemapChunks(ctx context.Context, kind pb.SitemapKind, chunkSize int32) ([]*pb.SitemapChunk, error)
+	// SitemapEntries pages through the slugs of kind in id order.
+	SitemapEntries(ctx context.Context, kind pb.SitemapKind, limit, offset int32) ([]*pb.SitemapEntry, error)
+
+	// WithTx runs fn on a Repository whose writes are kept only if fn
+	// returns nil.
+	WithTx(ctx context.Context, fn func(Repository) error) error
+}
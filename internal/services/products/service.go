@@ -3,29 +3,26 @@ package productservice
 import (
 	"bytes"
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"math"
 	"net/url"
-	"time"
 
 	"github.com/kelcheone/chemistke/internal/database"
 	"github.com/kelcheone/chemistke/internal/files"
 	"github.com/kelcheone/chemistke/pkg/codes"
 	pb "github.com/kelcheone/chemistke/pkg/grpc/product"
 	"github.com/kelcheone/chemistke/pkg/status"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type ProductService struct {
-	db database.DB
+	repo Repository
 	pb.UnimplementedProductServiceServer
 }
 
-func NewProductService(db database.DB) *ProductService {
+func NewProductService(repo Repository) *ProductService {
 	return &ProductService{
-		db: db,
+		repo: repo,
 	}
 }
 
@@ -33,23 +30,8 @@ func (s *ProductService) CreateProduct(
 	ctx context.Context,
 	req *pb.CreateProductRequest,
 ) (*pb.CreateProductResponse, error) {
-	stmt := `INSERT INTO products (name,description, category_id, sub_category_id, brand_id, price, quantity, featured) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
-	product := req.Product
-	var productId string
-	err := s.db.QueryRow(stmt,
-		product.Name,
-		product.Description,
-		product.CategoryId.Value,
-		product.SubCategoryId.Value,
-		product.BrandId.Value,
-		product.Price,
-		product.Quantity,
-		product.Featured,
-	).Scan(&productId)
+	productId, err := s.repo.CreateProduct(ctx, req.Product)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Errorf(codes.NotFound, "product not found")
-		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error creating product: %v",
@@ -69,166 +51,23 @@ func (s *ProductService) GetProduct(
 	ctx context.Context,
 	req *pb.GetProductRequest,
 ) (*pb.GetProductResponse, error) {
-	stmt := `
-SELECT
-  p.id,
-  p.name,
-  p.description,
-  p.category_id,
-  p.sub_category_id,
-  p.brand_id,
-  p.price,
-  p.quantity,
-  p.featured,
-  p.slug,
-  pc.name AS category_name,
-  psc.name AS sub_category_name,
-  pb.name AS brand_name,
-  pi.url AS image_url,
-  pi.image_type,
-  pr.review_count,
-  pr.average_rating,
-  p.created_at,
-  p.updated_at
-FROM
-  products p
-LEFT JOIN (
-  SELECT
-    product_id,
-    COUNT(*) AS review_count,
-    AVG(rating) AS average_rating
-  FROM
-    product_reviews
-  GROUP BY
-    product_id
-) pr ON p.id = pr.product_id
-LEFT JOIN
-  productimages pi ON p.id = pi.product_id
-LEFT JOIN
- product_category pc ON p.category_id = pc.id
-LEFT JOIN
-  product_sub_category psc ON p.sub_category_id = psc.id
-LEFT JOIN
-  product_brand pb ON p.brand_id = pb.id
-WHERE
-  p.id = $1;`
-	rows, err := s.db.Query(stmt, req.Id.Value)
+	product, err := s.repo.GetProduct(ctx, req.Id.GetValue())
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error getting product: %+v",
-			err,
-		)
-	}
-
-	defer rows.Close()
-
-	var product pb.Product
-	var productId string
-	product.Images = []*pb.Image{}
-
-	for rows.Next() {
-
-		var imageUrl, imageType sql.NullString
-		var reviewCount sql.NullInt32
-		var averageRating sql.NullFloat64
-		var categoryName, subCategoryName, brandName sql.NullString
-		var createdAt, updatedAt sql.NullTime
-		var brandId, categoryId, subCategoryId string
-
-		err := rows.Scan(
-			&productId,
-			&product.Name,
-			&product.Description,
-			&categoryId,
-			&subCategoryId,
-			&brandId,
-			&product.Price,
-			&product.Quantity,
-			&product.Featured,
-			&product.Slug,
-			&categoryName,
-			&subCategoryName,
-			&brandName,
-			&imageUrl,
-			&imageType,
-			&reviewCount,
-			&averageRating,
-			&createdAt,
-			&updatedAt,
-		)
-		if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning rows: %v",
-				err.Error(),
+				codes.NotFound,
+				"product with id %s not found.",
+				req.Id.GetValue(),
 			)
 		}
-		product.CategoryId = &pb.UUID{Value: categoryId}
-		product.SubCategoryId = &pb.UUID{Value: subCategoryId}
-		product.BrandId = &pb.UUID{Value: brandId}
-
-		image := pb.Image{
-			Url:       imageUrl.String,
-			ImageType: imageType.String,
-		}
-		if image.Url != "" {
-			product.Images = append(product.Images, &image)
-		}
-
-		if reviewCount.Valid {
-			product.ReviewCount = reviewCount.Int32
-		} else {
-			product.ReviewCount = 0
-		}
-		if averageRating.Valid {
-			product.AverageRating = float32(averageRating.Float64)
-		} else {
-			product.AverageRating = 0.0
-		}
-
-		if categoryName.Valid {
-			product.CategoryName = categoryName.String
-		} else {
-			product.CategoryName = ""
-		}
-
-		if subCategoryName.Valid {
-			product.SubCategoryName = subCategoryName.String
-		} else {
-			product.SubCategoryName = ""
-		}
-
-		if brandName.Valid {
-			product.BrandName = brandName.String
-		} else {
-			product.BrandName = ""
-		}
-
-		if createdAt.Valid {
-			product.CreatedAt = timestamppb.New(createdAt.Time)
-		} else {
-			product.CreatedAt = timestamppb.New(time.Time{})
-		}
-
-		if updatedAt.Valid {
-			product.UpdatedAt = timestamppb.New(updatedAt.Time)
-		} else {
-			product.UpdatedAt = timestamppb.New(time.Time{})
-		}
-
-		product.Id = &pb.UUID{Value: productId}
-
-	}
-	if productId == "" {
 		return nil, status.Errorf(
-			codes.NotFound,
-			"product with id %s not found.",
-			req.Id.Value,
+			codes.Internal,
+			"error getting product: %+v",
+			err,
 		)
 	}
 	return &pb.GetProductResponse{
-		Product: &product,
+		Product: product,
 	}, nil
 }
 
@@ -236,15 +75,17 @@ func (s *ProductService) DeleteProduct(
 	ctx context.Context,
 	req *pb.DeleteProductRequest,
 ) (*pb.DeleteProductResponse, error) {
-	stmt := `DELETE FROM products WHERE id=$1`
-	_, err := s.db.Exec(stmt, req.Id.Value)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	// the product's images go with it, or neither is removed.
+	if err := s.repo.DeleteProduct(ctx, req.Id.GetValue()); err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
 			return nil, status.Errorf(
 				codes.NotFound,
 				"product with id %s does not exist",
-				req.Id.Value,
+				req.Id.GetValue(),
 			)
+		case errors.Is(err, database.ErrForeignKey):
+			return nil, status.Errorf(codes.FailedPrecondition, "product has orders and cannot be deleted")
 		}
 		return nil, status.Errorf(
 			codes.Internal,
@@ -261,26 +102,14 @@ func (s *ProductService) UpdateProduct(
 	ctx context.Context,
 	req *pb.UpdateProductRequest,
 ) (*pb.UpdateProductResponse, error) {
-	stmt := `UPDATE products SET name=$1, description=$2, category_id=$3, sub_category_id=$4, brand_id=$5, price=$6, quantity=$7, featured=$8 WHERE id=$9`
 	product := req.Product
 
-	_, err := s.db.Exec(stmt,
-		product.Name,
-		product.Description,
-		product.CategoryId.Value,
-		product.SubCategoryId.Value,
-		product.BrandId.Value,
-		product.Price,
-		product.Quantity,
-		product.Featured,
-		product.Id.Value,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	if err := s.repo.UpdateProduct(ctx, product); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(
 				codes.NotFound,
 				"product with id %s not found",
-				product.Id.Value,
+				product.Id.GetValue(),
 			)
 		}
 		return nil, status.Errorf(
@@ -294,217 +123,47 @@ func (s *ProductService) UpdateProduct(
 	}, nil
 }
 
-// GetProductsQueryBuilder
-
-func BuildProductQuery(whereClause string, additionalClauses string) string {
-	baseQuery := `
-	WITH paginated_products AS (
-		SELECT *,
-		COUNT(*) OVER() AS total_count
-		FROM products
-		%s -- WHERE clause will be inserted here
-		ORDER BY id
-		LIMIT $1
-		OFFSET $2
-	)
-	SELECT
-		p.id,
-		p.name,
-		p.description,
-		p.category_id,
-		p.sub_category_id,
-		p.brand_id,
-		p.price,
-		p.quantity,
-		p.featured,
-		p.slug,
-		pc.name as category_name,
-		psc.name as sub_category_name,
-		pb.name as brand_name,
-		pi.url AS image_url,
-		pi.image_type,
-		pr.review_count,
-		pr.average_rating,
-		p.created_at,
-		p.updated_at,
-		p.total_count
-	FROM
-		paginated_products p
-	LEFT JOIN productimages pi ON p.id = pi.product_id
-	LEFT JOIN (
-		SELECT
-			product_id,
-			COUNT(*) AS review_count,
-			AVG(rating) AS average_rating
-		FROM product_reviews
-		GROUP BY product_id
-	) pr ON p.id = pr.product_id
-	LEFT JOIN product_sub_category psc ON p.sub_category_id = psc.id
-	LEFT JOIN product_category pc ON p.category_id = pc.id
-	LEFT JOIN product_brand pb ON p.brand_id = pb.id
-	%s; -- Additional clauses like GROUP BY or ORDER BY etc.
-`
-	where := ""
-	if whereClause != "" {
-		where = fmt.Sprintf("WHERE %s", whereClause)
-	}
-
-	return fmt.Sprintf(baseQuery, where, additionalClauses)
+func getMaxPages(total, limit int32) int32 {
+	return int32(math.Ceil(float64(total) / float64(limit)))
 }
 
-// a function that takes *sql.Rows and returns a slice of *pb.Product
-func scanProducts(rows *sql.Rows) ([]*pb.Product, error) {
-	defer rows.Close()
-
-	productsMap := make(map[string]*pb.Product)
-
-	for rows.Next() {
-		var product pb.Product
-		var productId, categoryId, subCategoryId, brandId string
-		var categoryName, subCategoryName, brandName, imageUrl, imageType sql.NullString
-		var reviewCount sql.NullInt32
-		var averageRating sql.NullFloat64
-		var createdAt, updatedAt sql.NullTime
-
-		err := rows.Scan(
-			&productId,
-			&product.Name,
-			&product.Description,
-			&categoryId,
-			&subCategoryId,
-			&brandId,
-			&product.Price,
-			&product.Quantity,
-			&product.Featured,
-			&product.Slug,
-			&categoryName,
-			&subCategoryName,
-			&brandName,
-			&imageUrl,
-			&imageType,
-			&reviewCount,
-			&averageRating,
-			&createdAt,
-			&updatedAt,
-			&product.TotalCount,
-		)
-		if err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning products: %v",
-				err,
-			)
-		}
-		product.Id = &pb.UUID{Value: productId}
-		product.CategoryId = &pb.UUID{Value: categoryId}
-		product.SubCategoryId = &pb.UUID{Value: subCategoryId}
-		product.BrandId = &pb.UUID{Value: brandId}
-
-		image := pb.Image{
-			Url:       imageUrl.String,
-			ImageType: imageType.String,
-		}
-
-		if reviewCount.Valid {
-			product.ReviewCount = reviewCount.Int32
-		} else {
-			product.ReviewCount = 0
-		}
-		if averageRating.Valid {
-			product.AverageRating = float32(averageRating.Float64)
-		} else {
-			product.AverageRating = 0.0
-		}
-
-		if categoryName.Valid {
-			product.CategoryName = categoryName.String
-		} else {
-			product.CategoryName = ""
-		}
-		if subCategoryName.Valid {
-			product.SubCategoryName = subCategoryName.String
-		} else {
-			product.SubCategoryName = ""
-		}
-		if brandName.Valid {
-			product.BrandName = brandName.String
-		} else {
-			product.BrandName = ""
-		}
-
-		if createdAt.Valid {
-			product.CreatedAt = timestamppb.New(createdAt.Time)
-		} else {
-			product.CreatedAt = timestamppb.New(time.Time{})
-		}
-		if updatedAt.Valid {
-			product.UpdatedAt = timestamppb.New(updatedAt.Time)
-		} else {
-			product.UpdatedAt = timestamppb.New(time.Time{})
-		}
-
-		if exists, found := productsMap[productId]; found {
-			if image.Url != "" {
-				exists.Images = append(exists.Images, &image)
-			}
-		} else {
-			product.Id = &pb.UUID{Value: productId}
-			if image.Url != "" {
-				product.Images = []*pb.Image{&image}
-			}
-			productsMap[productId] = &product
-		}
-
-	}
-
-	var products []*pb.Product
-	for _, p := range productsMap {
-		products = append(products, p)
-	}
-	return products, nil
-}
-
-func getMaxPages(product *pb.Product, limit int32) int32 {
-	total_count := product.GetTotalCount()
-	return int32(math.Ceil(float64(total_count) / float64(limit)))
-}
-
-func (s *ProductService) GetProducts(
+// listProducts returns the page of products matching filter and how many
+// pages there are. Pages start at 1.
+func (s *ProductService) listProducts(
 	ctx context.Context,
-	req *pb.GetProductsRequest,
-) (*pb.GetProductsResponse, error) {
-	stmt := BuildProductQuery("", "ORDER BY created_at DESC")
-	if req.Page <= 0 {
-		req.Page = 1
+	filter ProductFilter,
+	page *int32,
+) ([]*pb.Product, int32, error) {
+	if *page <= 0 {
+		*page = 1
 	}
-	offset := (req.Page - 1) * req.Limit
+	filter.Offset = (*page - 1) * filter.Limit
 
-	rows, err := s.db.Query(stmt, req.Limit, offset)
+	products, total, err := s.repo.ListProducts(ctx, filter)
 	if err != nil {
-		return nil, status.Errorf(
+		return nil, 0, status.Errorf(
 			codes.Internal,
 			"error getting products: %v",
 			err,
 		)
 	}
+	return products, getMaxPages(total, filter.Limit), nil
+}
 
-	defer rows.Close()
-
-	products, err := scanProducts(rows)
+func (s *ProductService) GetProducts(
+	ctx context.Context,
+	req *pb.GetProductsRequest,
+) (*pb.GetProductsResponse, error) {
+	products, maxPages, err := s.listProducts(ctx, ProductFilter{Limit: req.Limit}, &req.Page)
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error getting products: %v",
-			err,
-		)
+		return nil, err
 	}
-	max_pages := getMaxPages(products[0], req.Limit)
 
 	return &pb.GetProductsResponse{
 		Products: products,
 		Limit:    req.Limit,
 		Page:     req.Page,
-		MaxPages: max_pages,
+		MaxPages: maxPages,
 	}, nil
 }
 
@@ -512,38 +171,17 @@ func (s *ProductService) GetProductsByCategory(
 	ctx context.Context,
 	req *pb.GetProductsByCategoryRequest,
 ) (*pb.GetProductsByCategoryResponse, error) {
-	stmt := BuildProductQuery("category_id=$3", "ORDER BY created_at DESC")
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	offset := (req.Page - 1) * req.Limit
-
-	rows, err := s.db.Query(stmt, req.Limit, offset, req.CategoryId.Value)
+	filter := ProductFilter{CategoryId: req.CategoryId.GetValue(), Limit: req.Limit}
+	products, maxPages, err := s.listProducts(ctx, filter, &req.Page)
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error getting products: %v",
-			err,
-		)
-	}
-
-	defer rows.Close()
-
-	products, err := scanProducts(rows)
-	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error scanning products: %v",
-			err,
-		)
+		return nil, err
 	}
-	max_pages := getMaxPages(products[0], req.Limit)
 
 	return &pb.GetProductsByCategoryResponse{
 		Products: products,
 		Limit:    req.Limit,
 		Page:     req.Page,
-		MaxPages: max_pages,
+		MaxPages: maxPages,
 	}, nil
 }
 
@@ -551,38 +189,17 @@ func (s *ProductService) GetProductsBySubCategory(
 	ctx context.Context,
 	req *pb.GetProductsBySubCategoryRequest,
 ) (*pb.GetProductsBySubCategoryResponse, error) {
-	stmt := BuildProductQuery("sub_category_id=$3", "ORDER BY created_at DESC")
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	offset := (req.Page - 1) * req.Limit
-
-	rows, err := s.db.Query(stmt, req.Limit, offset, req.SubCategoryId.Value)
-	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error getting products: %v",
-			err,
-		)
-	}
-
-	defer rows.Close()
-
-	products, err := scanProducts(rows)
+	filter := ProductFilter{SubCategoryId: req.SubCategoryId.GetValue(), Limit: req.Limit}
+	products, maxPages, err := s.listProducts(ctx, filter, &req.Page)
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error scanning products: %v",
-			err,
-		)
+		return nil, err
 	}
 
-	max_pages := getMaxPages(products[0], req.Limit)
 	return &pb.GetProductsBySubCategoryResponse{
 		Products: products,
 		Limit:    req.Limit,
 		Page:     req.Page,
-		MaxPages: max_pages,
+		MaxPages: maxPages,
 	}, nil
 }
 
@@ -590,122 +207,35 @@ func (s *ProductService) GetProductsByBrand(
 	ctx context.Context,
 	req *pb.GetProductsByBrandRequest,
 ) (*pb.GetProductsByBrandResponse, error) {
-	stmt := BuildProductQuery("brand_id=$3", "ORDER BY created_at DESC")
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	offset := (req.Page - 1) * req.Limit
-
-	rows, err := s.db.Query(stmt, req.Limit, offset, req.BrandId.Value)
+	filter := ProductFilter{BrandId: req.BrandId.GetValue(), Limit: req.Limit}
+	products, maxPages, err := s.listProducts(ctx, filter, &req.Page)
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error getting products: %v",
-			err,
-		)
-	}
-
-	defer rows.Close()
-
-	products, err := scanProducts(rows)
-	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error scanning products: %v",
-			err,
-		)
+		return nil, err
 	}
 
-	max_pages := getMaxPages(products[0], req.Limit)
 	return &pb.GetProductsByBrandResponse{
 		Products: products,
 		Limit:    req.Limit,
 		Page:     req.Page,
-		MaxPages: max_pages,
+		MaxPages: maxPages,
 	}, nil
 }
 
 func (s *ProductService) GetFeaturedProducts(ctx context.Context, req *pb.GetFeaturedProductsRequest) (*pb.GetFeaturedProductsResponse, error) {
-	stmt := BuildProductQuery("featured=true", "ORDER BY created_at DESC")
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	offset := (req.Page - 1) * req.Limit
-
-	rows, err := s.db.Query(stmt, req.Limit, offset)
-	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error getting products: %v",
-			err,
-		)
-	}
-
-	defer rows.Close()
-
-	products, err := scanProducts(rows)
+	products, maxPages, err := s.listProducts(ctx, ProductFilter{Featured: true, Limit: req.Limit}, &req.Page)
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error scanning products: %v",
-			err,
-		)
+		return nil, err
 	}
 
-	max_pages := getMaxPages(products[0], req.Limit)
 	return &pb.GetFeaturedProductsResponse{
 		Products: products,
 		Limit:    req.Limit,
 		Page:     req.Page,
-		MaxPages: max_pages,
+		MaxPages: maxPages,
 	}, nil
 }
 
 func (s *ProductService) GetProductBySlug(ctx context.Context, req *pb.GetProductBySlugRequest) (*pb.GetProductBySlugResponse, error) {
-	stmt := `
-SELECT
-  p.id,
-  p.name,
-  p.description,
-  p.category_id,
-  p.sub_category_id,
-  p.brand_id,
-  p.price,
-  p.quantity,
-  p.featured,
-  p.slug,
-  pc.name AS category_name,
-  psc.name AS sub_category_name,
-  pb.name AS brand_name,
-  pi.url AS image_url,
-  pi.image_type,
-  pr.review_count,
-  pr.average_rating,
-  p.created_at,
-  p.updated_at
-FROM
-  products p
-LEFT JOIN (
-  SELECT
-    product_id,
-    COUNT(*) AS review_count,
-    AVG(rating) AS average_rating
-  FROM
-    product_reviews
-  GROUP BY
-    product_id
-) pr ON p.id = pr.product_id
-LEFT JOIN
-  productimages pi ON p.id = pi.product_id
-LEFT JOIN
- product_category pc ON p.category_id = pc.id
-LEFT JOIN
-  product_sub_category psc ON p.sub_category_id = psc.id
-LEFT JOIN
-  product_brand pb ON p.brand_id = pb.id
-WHERE
-  p.slug = $1;`
-
 	decodedSlug, err := url.QueryUnescape(req.Slug)
 	if err != nil {
 		return nil, status.Errorf(
@@ -715,123 +245,23 @@ WHERE
 		)
 	}
 
-	rows, err := s.db.Query(stmt, decodedSlug)
+	product, err := s.repo.GetProductBySlug(ctx, decodedSlug)
 	if err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error getting product: %+v",
-			err,
-		)
-	}
-
-	defer rows.Close()
-
-	var product pb.Product
-	var productId string
-	product.Images = []*pb.Image{}
-
-	for rows.Next() {
-
-		var imageUrl, imageType sql.NullString
-		var reviewCount sql.NullInt32
-		var averageRating sql.NullFloat64
-		var categoryName, subCategoryName, brandName sql.NullString
-		var createdAt, updatedAt sql.NullTime
-		var brandId, categoryId, subCategoryId string
-
-		err := rows.Scan(
-			&productId,
-			&product.Name,
-			&product.Description,
-			&categoryId,
-			&subCategoryId,
-			&brandId,
-			&product.Price,
-			&product.Quantity,
-			&product.Featured,
-			&product.Slug,
-			&categoryName,
-			&subCategoryName,
-			&brandName,
-			&imageUrl,
-			&imageType,
-			&reviewCount,
-			&averageRating,
-			&createdAt,
-			&updatedAt,
-		)
-		if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning rows: %v",
-				err.Error(),
+				codes.NotFound,
+				"product with id %s not found.",
+				req.Slug,
 			)
 		}
-		product.CategoryId = &pb.UUID{Value: categoryId}
-		product.SubCategoryId = &pb.UUID{Value: subCategoryId}
-		product.BrandId = &pb.UUID{Value: brandId}
-
-		image := pb.Image{
-			Url:       imageUrl.String,
-			ImageType: imageType.String,
-		}
-		if image.Url != "" {
-			product.Images = append(product.Images, &image)
-		}
-
-		if reviewCount.Valid {
-			product.ReviewCount = reviewCount.Int32
-		} else {
-			product.ReviewCount = 0
-		}
-		if averageRating.Valid {
-			product.AverageRating = float32(averageRating.Float64)
-		} else {
-			product.AverageRating = 0.0
-		}
-
-		if categoryName.Valid {
-			product.CategoryName = categoryName.String
-		} else {
-			product.CategoryName = ""
-		}
-
-		if subCategoryName.Valid {
-			product.SubCategoryName = subCategoryName.String
-		} else {
-			product.SubCategoryName = ""
-		}
-
-		if brandName.Valid {
-			product.BrandName = brandName.String
-		} else {
-			product.BrandName = ""
-		}
-
-		if createdAt.Valid {
-			product.CreatedAt = timestamppb.New(createdAt.Time)
-		} else {
-			product.CreatedAt = timestamppb.New(time.Time{})
-		}
-
-		if updatedAt.Valid {
-			product.UpdatedAt = timestamppb.New(updatedAt.Time)
-		} else {
-			product.UpdatedAt = timestamppb.New(time.Time{})
-		}
-
-		product.Id = &pb.UUID{Value: productId}
-
-	}
-	if productId == "" {
 		return nil, status.Errorf(
-			codes.NotFound,
-			"product with id %s not found.",
-			req.Slug,
+			codes.Internal,
+			"error getting product: %+v",
+			err,
 		)
 	}
 	return &pb.GetProductBySlugResponse{
-		Product: &product,
+		Product: product,
 	}, nil
 }
 
@@ -884,8 +314,7 @@ func (s *ProductService) UploadProdctImages(
 		)
 	}
 
-	stmt := `INSERT INTO productimages(product_id, image_type, url) VALUES ($1, $2, $3) RETURNING id;`
-	_, err = s.db.Exec(stmt, req.ProductId.Value, req.ImageType, resp)
+	err = s.repo.AddProductImage(ctx, req.ProductId.Value, &pb.Image{Url: resp, ImageType: req.ImageType})
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -903,21 +332,7 @@ func (s *ProductService) GetProductImages(
 	ctx context.Context,
 	req *pb.GetProductImagesRequest,
 ) (*pb.GetProductImagesResponse, error) {
-	urls, err := s.getProductImages(req.ProductId.Value)
-	if err != nil {
-		return nil, err
-	}
-
-	return &pb.GetProductImagesResponse{
-		Urls: urls,
-	}, nil
-}
-
-func (s *ProductService) getProductImages(
-	productId string,
-) ([]*pb.Image, error) {
-	stmt := `SELECT url, image_type from productimages WHERE product_id=$1;`
-	rows, err := s.db.Query(stmt, productId)
+	urls, err := s.repo.ListProductImages(ctx, req.ProductId.GetValue())
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -926,31 +341,21 @@ func (s *ProductService) getProductImages(
 		)
 	}
 
-	var urls []*pb.Image
-
-	for rows.Next() {
-		var image pb.Image
-		err := rows.Scan(&image.Url, &image.ImageType)
-		if err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning image %v",
-				err.Error(),
-			)
-		}
-
-		urls = append(urls, &image)
-	}
-
-	return urls, nil
+	return &pb.GetProductImagesResponse{
+		Urls: urls,
+	}, nil
 }
 
 // Reviews
 
 func (s *ProductService) CreateReview(ctx context.Context, request *pb.CreateReviewRequest) (*pb.CreateReviewResponse, error) {
-	stmt := `INSERT INTO product_reviews (product_id, user_id, rating, title, content) VALUES ($1, $2, $3, $4, $5)`
-	// convert the Ids from id.value to the required uuid format required by the database
-	_, err := s.db.Exec(stmt, request.ProductId.Value, request.UserId.Value, request.Rating, request.Title, request.Content)
+	err := s.repo.CreateReview(ctx, &pb.Review{
+		ProductId: request.ProductId,
+		UserId:    request.UserId,
+		Rating:    request.Rating,
+		Title:     request.Title,
+		Content:   request.Content,
+	})
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -965,13 +370,7 @@ func (s *ProductService) CreateReview(ctx context.Context, request *pb.CreateRev
 }
 
 func (s *ProductService) GetReviews(ctx context.Context, request *pb.GetReviewsRequest) (*pb.GetReviewsResponse, error) {
-	// left join with user table
-	stmt := `SELECT r.id, r.product_id, r.user_id, r.rating, r.title, r.content, u.name as user_name
-	FROM product_reviews r
-	LEFT JOIN users u ON r.user_id = u.id
-	WHERE r.product_id = $1;`
-
-	rows, err := s.db.Query(stmt, request.ProductId.Value)
+	reviews, err := s.repo.ListReviews(ctx, request.ProductId.GetValue())
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -980,51 +379,17 @@ func (s *ProductService) GetReviews(ctx context.Context, request *pb.GetReviewsR
 		)
 	}
 
-	var reviews []*pb.Review
-
-	for rows.Next() {
-		var review pb.Review
-		var reviewIdStr string
-		var reviewProductIdStr string
-		var reviewUserIdStr string
-
-		err := rows.Scan(&reviewIdStr, &reviewProductIdStr, &reviewUserIdStr, &review.Rating, &review.Title, &review.Content, &review.UserName)
-		if err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning review %v",
-				err.Error(),
-			)
-		}
-		review.Id = &pb.UUID{
-			Value: reviewIdStr,
-		}
-		review.ProductId = &pb.UUID{
-			Value: reviewProductIdStr,
-		}
-		review.UserId = &pb.UUID{
-			Value: reviewUserIdStr,
-		}
-
-		reviews = append(reviews, &review)
-	}
-
 	return &pb.GetReviewsResponse{
 		Reviews: reviews,
 	}, nil
 }
 
 func (s *ProductService) GetReview(ctx context.Context, request *pb.GetReviewRequest) (*pb.GetReviewResponse, error) {
-	stmt := `SELECT r.id, r.product_id, r.user_id, r.rating, r.title, r.content, u.name FROM product_reviews r JOIN users u ON r.user_id = u.id WHERE r.id=$1`
-	row := s.db.QueryRow(stmt, request.ReviewId.Value)
-
-	var review pb.Review
-	var reviewIdStr string
-	var productIdStr string
-	var userIdStr string
-	var userName string
-	err := row.Scan(&reviewIdStr, &productIdStr, &userIdStr, &review.Rating, &review.Title, &review.Content, &userName)
+	review, err := s.repo.GetReview(ctx, request.ReviewId.GetValue())
 	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "review not found")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error getting review: %v",
@@ -1032,32 +397,13 @@ func (s *ProductService) GetReview(ctx context.Context, request *pb.GetReviewReq
 		)
 	}
 
-	review.Id = &pb.UUID{
-		Value: reviewIdStr,
-	}
-	review.ProductId = &pb.UUID{
-		Value: productIdStr,
-	}
-	review.UserId = &pb.UUID{
-		Value: userIdStr,
-	}
-	review.UserName = userName
-
 	return &pb.GetReviewResponse{
-		Review: &review,
+		Review: review,
 	}, nil
 }
 
 func (s *ProductService) GetProductRating(ctx context.Context, request *pb.GetProductRatingRequest) (*pb.GetProductRatingResponse, error) {
-	stmt := `SELECT AVG(rating), COUNT(*) FROM product_reviews WHERE product_id=$1`
-
-	log.Printf("The productId is: %s ", request.ProductId.Value)
-
-	row := s.db.QueryRow(stmt, request.ProductId.Value)
-
-	var rating float64
-	var count int64
-	err := row.Scan(&rating, &count)
+	rating, count, err := s.repo.GetProductRating(ctx, request.ProductId.GetValue())
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -1067,17 +413,17 @@ func (s *ProductService) GetProductRating(ctx context.Context, request *pb.GetPr
 	}
 
 	return &pb.GetProductRatingResponse{
-		AverageRating:   float32(rating),
-		NumberOfReviews: int32(count),
+		AverageRating:   rating,
+		NumberOfReviews: count,
 	}, nil
 }
 
 func (s *ProductService) CreateCategory(ctx context.Context, req *pb.CreateCategoryRequest) (*pb.CreateCategoryResponse, error) {
-	stmt := `INSERT INTO product_category(name, description, featured) VALUES ($1, $2, $3) RETURNING id;`
-	row := s.db.QueryRow(stmt, req.Name, req.Description, req.Featured)
-
-	var id string
-	err := row.Scan(&id)
+	_, err := s.repo.CreateCategory(ctx, &pb.Category{
+		Name:        req.Name,
+		Description: req.Description,
+		Featured:    req.Featured,
+	})
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -1092,13 +438,12 @@ func (s *ProductService) CreateCategory(ctx context.Context, req *pb.CreateCateg
 }
 
 func (s *ProductService) GetCategories(ctx context.Context, req *pb.GetCategoriesRequest) (*pb.GetCategoriesResponse, error) {
-	stmt := `SELECT id, name, description, featured, slug FROM product_category LIMIT $1 OFFSET $2`
 	// make sure req.offset is not negative or zero make it 1
 	if req.Offset <= 0 {
 		req.Offset = 1
 	}
 	offset := (req.Offset - 1) * req.Limit
-	rows, err := s.db.Query(stmt, req.Limit, offset)
+	categories, err := s.repo.ListCategories(ctx, req.Limit, offset)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -1106,23 +451,6 @@ func (s *ProductService) GetCategories(ctx context.Context, req *pb.GetCategorie
 			err.Error(),
 		)
 	}
-	defer rows.Close()
-
-	var categories []*pb.Category
-	for rows.Next() {
-		var categoryId string
-		var category pb.Category
-		err := rows.Scan(&categoryId, &category.Name, &category.Description, &category.Featured, &category.Slug)
-		if err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning category: %v",
-				err.Error(),
-			)
-		}
-		category.Id = &pb.UUID{Value: categoryId}
-		categories = append(categories, &category)
-	}
 
 	return &pb.GetCategoriesResponse{
 		Categories: categories,
@@ -1130,9 +458,16 @@ func (s *ProductService) GetCategories(ctx context.Context, req *pb.GetCategorie
 }
 
 func (s *ProductService) UpdateCategory(ctx context.Context, req *pb.UpdateCategoryRequest) (*pb.UpdateCategoryResponse, error) {
-	stmt := `UPDATE product_category SET name = $1, description = $2, featured = $3 WHERE id = $4`
-	_, err := s.db.Exec(stmt, req.Name, req.Description, req.Featured, req.Id.Value)
+	err := s.repo.UpdateCategory(ctx, &pb.Category{
+		Id:          req.Id,
+		Name:        req.Name,
+		Description: req.Description,
+		Featured:    req.Featured,
+	})
 	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "category not found")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error updating category: %v",
@@ -1146,13 +481,19 @@ func (s *ProductService) UpdateCategory(ctx context.Context, req *pb.UpdateCateg
 }
 
 func (s *ProductService) DeleteCategory(ctx context.Context, req *pb.DeleteCategoryRequest) (*pb.DeleteCategoryResponse, error) {
-	stmt := `DELETE FROM product_category WHERE id = $1`
-	_, err := s.db.Exec(stmt, req.Id.Value)
-	if err != nil {
+	// sub-categories cannot outlive their category, so they go with it.
+	// Either still holding products stops both.
+	if err := s.repo.DeleteCategory(ctx, req.Id.GetValue()); err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return nil, status.Errorf(codes.NotFound, "category not found")
+		case errors.Is(err, database.ErrForeignKey):
+			return nil, status.Errorf(codes.FailedPrecondition, "category still has products")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error deleting category: %v",
-			err.Error(),
+			err,
 		)
 	}
 
@@ -1162,8 +503,7 @@ func (s *ProductService) DeleteCategory(ctx context.Context, req *pb.DeleteCateg
 }
 
 func (s *ProductService) GetFeaturedCategories(ctx context.Context, req *pb.GetFeaturedCategoriesRequest) (*pb.GetFeaturedCategoriesResponse, error) {
-	stmt := `SELECT id, name, description, featured, slug FROM product_category WHERE featured = true`
-	rows, err := s.db.Query(stmt)
+	categories, err := s.repo.ListFeaturedCategories(ctx)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -1171,23 +511,6 @@ func (s *ProductService) GetFeaturedCategories(ctx context.Context, req *pb.GetF
 			err.Error(),
 		)
 	}
-	defer rows.Close()
-
-	var categories []*pb.Category
-	for rows.Next() {
-		var category pb.Category
-		var categoryId string
-		err := rows.Scan(&categoryId, &category.Name, &category.Description, &category.Featured, &category.Slug)
-		if err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning category: %v",
-				err.Error(),
-			)
-		}
-		category.Id = &pb.UUID{Value: categoryId}
-		categories = append(categories, &category)
-	}
 
 	return &pb.GetFeaturedCategoriesResponse{
 		Categories: categories,
@@ -1195,12 +518,11 @@ func (s *ProductService) GetFeaturedCategories(ctx context.Context, req *pb.GetF
 }
 
 func (s *ProductService) GetCategory(ctx context.Context, req *pb.GetCategoryRequest) (*pb.GetCategoryResponse, error) {
-	stmt := `SELECT id, name, description, slug, featured FROM product_category WHERE id = $1`
-	row := s.db.QueryRow(stmt, req.Id.Value)
-	var category pb.Category
-	var categoryId string
-	err := row.Scan(&categoryId, &category.Name, &category.Description, &category.Slug, &category.Featured)
+	category, err := s.repo.GetCategory(ctx, req.Id.GetValue())
 	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "category not found")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error getting category: %v",
@@ -1208,14 +530,12 @@ func (s *ProductService) GetCategory(ctx context.Context, req *pb.GetCategoryReq
 		)
 	}
 
-	category.Id = &pb.UUID{Value: categoryId}
 	return &pb.GetCategoryResponse{
-		Category: &category,
+		Category: category,
 	}, nil
 }
 
 func (s *ProductService) GetCategoryBySlug(ctx context.Context, req *pb.GetCategoryBySlugRequest) (*pb.GetCategoryResponse, error) {
-	stmt := `SELECT id, name, description, slug, featured FROM product_category WHERE slug = $1`
 	decodedSlug, err := url.QueryUnescape(req.Slug)
 	if err != nil {
 		return nil, status.Errorf(
@@ -1224,11 +544,11 @@ func (s *ProductService) GetCategoryBySlug(ctx context.Context, req *pb.GetCateg
 			err.Error(),
 		)
 	}
-	row := s.db.QueryRow(stmt, decodedSlug)
-	var category pb.Category
-	var categoryId string
-	err = row.Scan(&categoryId, &category.Name, &category.Description, &category.Slug, &category.Featured)
+	category, err := s.repo.GetCategoryBySlug(ctx, decodedSlug)
 	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "category not found")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error getting category: %v",
@@ -1236,15 +556,18 @@ func (s *ProductService) GetCategoryBySlug(ctx context.Context, req *pb.GetCateg
 		)
 	}
 
-	category.Id = &pb.UUID{Value: categoryId}
 	return &pb.GetCategoryResponse{
-		Category: &category,
+		Category: category,
 	}, nil
 }
 
 func (s *ProductService) CreateSubCategory(ctx context.Context, req *pb.CreateSubCategoryRequest) (*pb.CreateSubCategoryResponse, error) {
-	stmt := `INSERT INTO product_sub_category (name, description, category_id, slug) VALUES ($1, $2, $3, $4)`
-	_, err := s.db.Exec(stmt, req.Name, req.Description, req.CategoryId.Value, req.Slug)
+	err := s.repo.CreateSubCategory(ctx, &pb.SubCategory{
+		Name:        req.Name,
+		Description: req.Description,
+		CategoryId:  req.CategoryId,
+		Slug:        req.Slug,
+	})
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -1259,13 +582,12 @@ func (s *ProductService) CreateSubCategory(ctx context.Context, req *pb.CreateSu
 }
 
 func (s *ProductService) GetSubCategories(ctx context.Context, req *pb.GetSubCategoriesRequest) (*pb.GetSubCategoriesResponse, error) {
-	stmt := `SELECT id, name, description, category_id, slug FROM product_sub_category WHERE category_id = $1 LIMIT $2 OFFSET $3`
 	if req.Offset <= 0 {
 		req.Offset = 1
 	}
 	offset := (req.Offset - 1) * req.Limit
 
-	rows, err := s.db.Query(stmt, req.CategoryId.Value, req.Limit, offset)
+	subCategories, err := s.repo.ListSubCategories(ctx, req.CategoryId.GetValue(), req.Limit, offset)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -1273,32 +595,6 @@ func (s *ProductService) GetSubCategories(ctx context.Context, req *pb.GetSubCat
 			err.Error(),
 		)
 	}
-	defer rows.Close()
-
-	var subCategories []*pb.SubCategory
-	var subCategoryId, categoryId string
-	for rows.Next() {
-		var subCategory pb.SubCategory
-		if err := rows.Scan(&subCategoryId, &subCategory.Name, &subCategory.Description, &categoryId, &subCategory.Slug); err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning subcategory: %v",
-				err.Error(),
-			)
-		}
-
-		subCategory.Id = &pb.UUID{Value: subCategoryId}
-		subCategory.CategoryId = &pb.UUID{Value: categoryId}
-		subCategories = append(subCategories, &subCategory)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, status.Errorf(
-			codes.Internal,
-			"error iterating over subcategories: %v",
-			err.Error(),
-		)
-	}
 
 	return &pb.GetSubCategoriesResponse{
 		SubCategories: subCategories,
@@ -1306,8 +602,16 @@ func (s *ProductService) GetSubCategories(ctx context.Context, req *pb.GetSubCat
 }
 
 func (s *ProductService) UpdateSubCategory(ctx context.Context, req *pb.UpdateSubCategoryRequest) (*pb.UpdateSubCategoryResponse, error) {
-	stmt := `UPDATE product_sub_category SET name = $1, description = $2, slug = $3 WHERE id = $4`
-	if _, err := s.db.Exec(stmt, req.Name, req.Description, req.Slug, req.Id.Value); err != nil {
+	err := s.repo.UpdateSubCategory(ctx, &pb.SubCategory{
+		Id:          req.Id,
+		Name:        req.Name,
+		Description: req.Description,
+		Slug:        req.Slug,
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "subcategory not found")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error updating subcategory: %v",
@@ -1321,8 +625,13 @@ func (s *ProductService) UpdateSubCategory(ctx context.Context, req *pb.UpdateSu
 }
 
 func (s *ProductService) DeleteSubCategory(ctx context.Context, req *pb.DeleteSubCategoryRequest) (*pb.DeleteSubCategoryResponse, error) {
-	stmt := `DELETE FROM product_sub_category WHERE id = $1`
-	if _, err := s.db.Exec(stmt, req.Id.Value); err != nil {
+	if err := s.repo.DeleteSubCategory(ctx, req.Id.GetValue()); err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return nil, status.Errorf(codes.NotFound, "subcategory not found")
+		case errors.Is(err, database.ErrForeignKey):
+			return nil, status.Errorf(codes.FailedPrecondition, "subcategory still has products")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error deleting subcategory: %v",
@@ -1336,12 +645,11 @@ func (s *ProductService) DeleteSubCategory(ctx context.Context, req *pb.DeleteSu
 }
 
 func (s *ProductService) GetSubCategory(ctx context.Context, req *pb.GetSubCategoryRequest) (*pb.GetSubCategoryResponse, error) {
-	stmt := `SELECT id, name, description, category_id, slug FROM product_sub_category WHERE id = $1`
-	row := s.db.QueryRow(stmt, req.Id.Value)
-	var subCategory pb.SubCategory
-	var subCategoryId string
-	var categoryId string
-	if err := row.Scan(&subCategoryId, &subCategory.Name, &subCategory.Description, &categoryId, &subCategory.Slug); err != nil {
+	subCategory, err := s.repo.GetSubCategory(ctx, req.Id.GetValue())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "subcategory not found")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error scanning subcategory: %v",
@@ -1349,16 +657,12 @@ func (s *ProductService) GetSubCategory(ctx context.Context, req *pb.GetSubCateg
 		)
 	}
 
-	subCategory.Id = &pb.UUID{Value: subCategoryId}
-	subCategory.CategoryId = &pb.UUID{Value: categoryId}
-
 	return &pb.GetSubCategoryResponse{
-		SubCategory: &subCategory,
+		SubCategory: subCategory,
 	}, nil
 }
 
 func (p *ProductService) GetSubCategoryBySlug(ctx context.Context, req *pb.GetSubCategoryBySlugRequest) (*pb.GetSubCategoryResponse, error) {
-	stmt := `SELECT id, name, description, category_id, slug FROM product_sub_category WHERE slug = $1`
 	decodedSlug, err := url.QueryUnescape(req.Slug)
 	if err != nil {
 		return nil, status.Errorf(
@@ -1367,11 +671,11 @@ func (p *ProductService) GetSubCategoryBySlug(ctx context.Context, req *pb.GetSu
 			err.Error(),
 		)
 	}
-	row := p.db.QueryRow(stmt, decodedSlug)
-	var subCategory pb.SubCategory
-	var subCategoryId string
-	var categoryId string
-	if err := row.Scan(&subCategoryId, &subCategory.Name, &subCategory.Description, &categoryId, &subCategory.Slug); err != nil {
+	subCategory, err := p.repo.GetSubCategoryBySlug(ctx, decodedSlug)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "subcategory not found")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error scanning subcategory: %v",
@@ -1379,19 +683,13 @@ func (p *ProductService) GetSubCategoryBySlug(ctx context.Context, req *pb.GetSu
 		)
 	}
 
-	subCategory.Id = &pb.UUID{Value: subCategoryId}
-	subCategory.CategoryId = &pb.UUID{Value: categoryId}
-
 	return &pb.GetSubCategoryResponse{
-		SubCategory: &subCategory,
+		SubCategory: subCategory,
 	}, nil
 }
 
 func (s *ProductService) CreateBrand(ctx context.Context, req *pb.CreateBrandRequest) (*pb.CreateBrandResponse, error) {
-	stmt := `INSERT INTO product_brand (name, description) VALUES ($1, $2) RETURNING id`
-
-	var id string
-	err := s.db.QueryRow(stmt, req.Name, req.Description).Scan(&id)
+	id, err := s.repo.CreateBrand(ctx, &pb.Brand{Name: req.Name, Description: req.Description})
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -1406,13 +704,12 @@ func (s *ProductService) CreateBrand(ctx context.Context, req *pb.CreateBrandReq
 }
 
 func (s *ProductService) GetBrands(ctx context.Context, req *pb.GetBrandsRequest) (*pb.GetBrandsResponse, error) {
-	stmt := `SELECT id, name, description FROM product_brand LIMIT $1 OFFSET $2`
 	if req.Offset <= 0 {
 		req.Offset = 1
 	}
 	offset := (req.Offset - 1) * req.Limit
 
-	rows, err := s.db.Query(stmt, req.Limit, offset)
+	brands, err := s.repo.ListBrands(ctx, req.Limit, offset)
 	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
@@ -1420,22 +717,6 @@ func (s *ProductService) GetBrands(ctx context.Context, req *pb.GetBrandsRequest
 			err.Error(),
 		)
 	}
-	defer rows.Close()
-
-	var brands []*pb.Brand
-	for rows.Next() {
-		var brand pb.Brand
-		var brandId string
-		if err := rows.Scan(&brandId, &brand.Name, &brand.Description); err != nil {
-			return nil, status.Errorf(
-				codes.Internal,
-				"error scanning brand: %v",
-				err.Error(),
-			)
-		}
-		brand.Id = &pb.UUID{Value: brandId}
-		brands = append(brands, &brand)
-	}
 
 	return &pb.GetBrandsResponse{
 		Brands: brands,
@@ -1443,8 +724,11 @@ func (s *ProductService) GetBrands(ctx context.Context, req *pb.GetBrandsRequest
 }
 
 func (s *ProductService) UpdateBrand(ctx context.Context, req *pb.UpdateBrandRequest) (*pb.UpdateBrandResponse, error) {
-	stmt := `UPDATE product_brand SET name = $1, description = $2 WHERE id = $3`
-	if _, err := s.db.Exec(stmt, req.Name, req.Description, req.Id.Value); err != nil {
+	err := s.repo.UpdateBrand(ctx, &pb.Brand{Id: req.Id, Name: req.Name, Description: req.Description})
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "brand not found")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error updating brand: %v",
@@ -1453,13 +737,18 @@ func (s *ProductService) UpdateBrand(ctx context.Context, req *pb.UpdateBrandReq
 	}
 
 	return &pb.UpdateBrandResponse{
-		Message: fmt.Sprintf("Brand updated successfully with ID %s", req.Id.Value),
+		Message: fmt.Sprintf("Brand updated successfully with ID %s", req.Id.GetValue()),
 	}, nil
 }
 
 func (s *ProductService) DeleteBrand(ctx context.Context, req *pb.DeleteBrandRequest) (*pb.DeleteBrandResponse, error) {
-	stmt := `DELETE FROM product_brand WHERE id = $1`
-	if _, err := s.db.Exec(stmt, req.Id.Value); err != nil {
+	if err := s.repo.DeleteBrand(ctx, req.Id.GetValue()); err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return nil, status.Errorf(codes.NotFound, "brand not found")
+		case errors.Is(err, database.ErrForeignKey):
+			return nil, status.Errorf(codes.FailedPrecondition, "brand still has products")
+		}
 		return nil, status.Errorf(
 			codes.Internal,
 			"error deleting brand: %v",
@@ -1468,16 +757,14 @@ func (s *ProductService) DeleteBrand(ctx context.Context, req *pb.DeleteBrandReq
 	}
 
 	return &pb.DeleteBrandResponse{
-		Message: fmt.Sprintf("Brand deleted successfully with ID %s", req.Id.Value),
+		Message: fmt.Sprintf("Brand deleted successfully with ID %s", req.Id.GetValue()),
 	}, nil
 }
 
 func (s *ProductService) GetBrand(ctx context.Context, req *pb.GetBrandRequest) (*pb.GetBrandResponse, error) {
-	stmt := `SELECT id, name, description FROM product_brand WHERE id = $1`
-	var brand pb.Brand
-	var brandId string
-	if err := s.db.QueryRow(stmt, req.Id.Value).Scan(&brandId, &brand.Name, &brand.Description); err != nil {
-		if err == sql.ErrNoRows {
+	brand, err := s.repo.GetBrand(ctx, req.Id.GetValue())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(codes.NotFound, "brand not found")
 		}
 		return nil, status.Errorf(
@@ -1487,8 +774,7 @@ func (s *ProductService) GetBrand(ctx context.Context, req *pb.GetBrandRequest)
 		)
 	}
 
-	brand.Id = &pb.UUID{Value: brandId}
 	return &pb.GetBrandResponse{
-		Brand: &brand,
+		Brand: brand,
 	}, nil
 }
@@ -0,0 +1,246 @@
+package productservice
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/product"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+type fixture struct {
+	svc           *ProductService
+	repo          *MemoryRepository
+	categoryId    string
+	subCategoryId string
+	brandId       string
+}
+
+// newFixture returns a catalogue with a category, a sub-category of it and
+// a brand, but no products.
+func newFixture() *fixture {
+	f := &fixture{repo: NewMemoryRepository()}
+	f.svc = NewProductService(f.repo)
+	f.categoryId, f.subCategoryId, f.brandId = f.repo.AddCatalogue()
+	return f
+}
+
+func (f *fixture) product(name string, quantity int32) *pb.Product {
+	return &pb.Product{
+		Name:          name,
+		Description:   "500mg tablets",
+		CategoryId:    &pb.UUID{Value: f.categoryId},
+		SubCategoryId: &pb.UUID{Value: f.subCategoryId},
+		BrandId:       &pb.UUID{Value: f.brandId},
+		Price:         250,
+		Quantity:      quantity,
+	}
+}
+
+func (f *fixture) createProduct(t *testing.T, name string, quantity int32) string {
+	t.Helper()
+	resp, err := f.svc.CreateProduct(context.Background(), &pb.CreateProductRequest{Product: f.product(name, quantity)})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	return resp.Id.Value
+}
+
+func TestCreateProduct(t *testing.T) {
+	unknown := &pb.UUID{Value: uuid.NewString()}
+	tests := []struct {
+		name   string
+		change func(*pb.Product)
+		want   codes.Code
+	}{
+		{name: "creates the product", change: func(*pb.Product) {}, want: codes.OK},
+		{name: "unknown category", change: func(p *pb.Product) { p.CategoryId = unknown }, want: codes.Internal},
+		{name: "unknown sub-category", change: func(p *pb.Product) { p.SubCategoryId = unknown }, want: codes.Internal},
+		{name: "unknown brand", change: func(p *pb.Product) { p.BrandId = unknown }, want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			product := f.product("Panadol Extra", 10)
+			tt.change(product)
+
+			resp, err := f.svc.CreateProduct(context.Background(), &pb.CreateProductRequest{Product: product})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+			if err != nil {
+				return
+			}
+
+			got, err := f.svc.GetProduct(context.Background(), &pb.GetProductRequest{Id: resp.Id})
+			if err != nil {
+				t.Fatalf("GetProduct: %v", err)
+			}
+			if got.Product.Name != "Panadol Extra" || got.Product.Quantity != 10 {
+				t.Errorf("got product %v", got.Product)
+			}
+
+			// the product can be found by its slug too.
+			bySlug, err := f.svc.GetProductBySlug(context.Background(), &pb.GetProductBySlugRequest{
+				Slug: url.QueryEscape(got.Product.Slug),
+			})
+			if err != nil || bySlug.Product.Id.Value != resp.Id.Value {
+				t.Errorf("GetProductBySlug(%q) = %v, %v", got.Product.Slug, bySlug, err)
+			}
+		})
+	}
+}
+
+func TestGetProducts(t *testing.T) {
+	f := newFixture()
+	for _, name := range []string{"Panadol Extra", "Panadol Advance", "Panadol Night", "Panadol Cold"} {
+		f.createProduct(t, name, 10)
+	}
+
+	tests := []struct {
+		name         string
+		limit, page  int32
+		wantProducts int
+		wantPage     int32
+		wantMaxPages int32
+	}{
+		{name: "first page", limit: 3, page: 1, wantProducts: 3, wantPage: 1, wantMaxPages: 2},
+		{name: "last page", limit: 3, page: 2, wantProducts: 1, wantPage: 2, wantMaxPages: 2},
+		// the count comes with the rows, like the query's window count.
+		{name: "past the last page", limit: 3, page: 3, wantProducts: 0, wantPage: 3, wantMaxPages: 0},
+		{name: "no page is the first", limit: 2, page: 0, wantProducts: 2, wantPage: 1, wantMaxPages: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := f.svc.GetProducts(context.Background(), &pb.GetProductsRequest{Limit: tt.limit, Page: tt.page})
+			if err != nil {
+				t.Fatalf("GetProducts: %v", err)
+			}
+			if len(resp.Products) != tt.wantProducts || resp.Page != tt.wantPage || resp.MaxPages != tt.wantMaxPages {
+				t.Errorf("got %d products, page %d of %d, want %d, page %d of %d",
+					len(resp.Products), resp.Page, resp.MaxPages, tt.wantProducts, tt.wantPage, tt.wantMaxPages)
+			}
+		})
+	}
+}
+
+func TestDeleteCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		products bool
+		category string
+		want     codes.Code
+	}{
+		{name: "takes its sub-categories", want: codes.OK},
+		{name: "still has products", products: true, want: codes.FailedPrecondition},
+		{name: "unknown category", category: uuid.NewString(), want: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			if tt.products {
+				f.createProduct(t, "Panadol Extra", 10)
+			}
+			category := f.categoryId
+			if tt.category != "" {
+				category = tt.category
+			}
+
+			_, err := f.svc.DeleteCategory(context.Background(), &pb.DeleteCategoryRequest{Id: &pb.UUID{Value: category}})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+
+			_, err = f.svc.GetSubCategory(context.Background(), &pb.GetSubCategoryRequest{Id: &pb.UUID{Value: f.subCategoryId}})
+			if gone := status.Code(err) == codes.NotFound; gone != (tt.want == codes.OK) {
+				t.Errorf("sub-category gone = %v after a %v", gone, tt.want)
+			}
+		})
+	}
+}
+
+func TestProductRating(t *testing.T) {
+	tests := []struct {
+		name       string
+		ratings    []float32
+		wantRating float32
+		wantCount  int32
+	}{
+		{name: "no reviews", wantRating: 0, wantCount: 0},
+		{name: "one review", ratings: []float32{4}, wantRating: 4, wantCount: 1},
+		{name: "average", ratings: []float32{5, 4, 3}, wantRating: 4, wantCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			productId := &pb.UUID{Value: f.createProduct(t, "Panadol Extra", 10)}
+			for _, rating := range tt.ratings {
+				_, err := f.svc.CreateReview(context.Background(), &pb.CreateReviewRequest{
+					ProductId: productId,
+					UserId:    &pb.UUID{Value: uuid.NewString()},
+					Title:     "Works",
+					Rating:    rating,
+				})
+				if err != nil {
+					t.Fatalf("CreateReview: %v", err)
+				}
+			}
+
+			resp, err := f.svc.GetProductRating(context.Background(), &pb.GetProductRatingRequest{ProductId: productId})
+			if err != nil {
+				t.Fatalf("GetProductRating: %v", err)
+			}
+			if resp.AverageRating != tt.wantRating || resp.NumberOfReviews != tt.wantCount {
+				t.Errorf("got %v from %d reviews, want %v from %d",
+					resp.AverageRating, resp.NumberOfReviews, tt.wantRating, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestTakeStock(t *testing.T) {
+	tests := []struct {
+		name      string
+		product   string
+		quantity  int32
+		wantOk    bool
+		wantErr   bool
+		wantStock int32
+	}{
+		{name: "takes units", quantity: 3, wantOk: true, wantStock: 2},
+		{name: "last units", quantity: 5, wantOk: true, wantStock: 0},
+		{name: "not enough", quantity: 6, wantOk: false, wantStock: 5},
+		{name: "puts units back", quantity: -2, wantOk: true, wantStock: 7},
+		{name: "unknown product", product: uuid.NewString(), quantity: 1, wantErr: true, wantStock: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			productId := f.createProduct(t, "Panadol Extra", 5)
+			product := productId
+			if tt.product != "" {
+				product = tt.product
+			}
+
+			ok, err := f.repo.TakeStock(context.Background(), product, tt.quantity)
+			if ok != tt.wantOk || (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, %v, want %v with error %v", ok, err, tt.wantOk, tt.wantErr)
+			}
+			got, err := f.repo.GetProduct(context.Background(), productId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Quantity != tt.wantStock {
+				t.Errorf("stock = %d, want %d", got.Quantity, tt.wantStock)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package productservice
+
+import (
+	"context"
+
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/product"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+func (s *ProductService) GetSitemapIndex(
+	ctx context.Context,
+	req *pb.GetSitemapIndexRequest,
+) (*pb.GetSitemapIndexResponse, error) {
+	if _, ok := sitemapTables[req.Kind]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sitemap kind %s", req.Kind)
+	}
+	if req.ChunkSize <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "chunk size must be positive")
+	}
+
+	chunks, err := s.repo.SitemapChunks(ctx, req.Kind, req.ChunkSize)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not build sitemap index: %v", err)
+	}
+
+	return &pb.GetSitemapIndexResponse{Chunks: chunks}, nil
+}
+
+func (s *ProductService) GetSitemapEntries(
+	ctx context.Context,
+	req *pb.GetSitemapEntriesRequest,
+) (*pb.GetSitemapEntriesResponse, error) {
+	if _, ok := sitemapTables[req.Kind]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sitemap kind %s", req.Kind)
+	}
+
+	entries, err := s.repo.SitemapEntries(ctx, req.Kind, req.Limit, req.Offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch sitemap entries: %v", err)
+	}
+
+	return &pb.GetSitemapEntriesResponse{Entries: entries}, nil
+}
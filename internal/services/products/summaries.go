@@ -0,0 +1,45 @@
+package productservice
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/product"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+func (s *ProductService) GetProductSummaries(
+	ctx context.Context,
+	req *pb.GetProductSummariesRequest,
+) (*pb.GetProductSummariesResponse, error) {
+	var ids []string
+	for _, id := range req.Ids {
+		if id.GetValue() != "" {
+			ids = append(ids, strings.ToLower(id.Value))
+		}
+	}
+	if len(ids) == 0 {
+		return &pb.GetProductSummariesResponse{}, nil
+	}
+
+	summaries, err := s.repo.GetProductSummaries(ctx, ids)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error getting products: %v", err)
+	}
+
+	found := make(map[string]*pb.ProductSummary, len(summaries))
+	for _, product := range summaries {
+		found[product.Id.GetValue()] = product
+	}
+
+	var products []*pb.ProductSummary
+	for _, id := range ids {
+		if product, ok := found[id]; ok {
+			products = append(products, product)
+			delete(found, id)
+		}
+	}
+
+	return &pb.GetProductSummariesResponse{Products: products}, nil
+}
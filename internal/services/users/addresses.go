@@ -0,0 +1,130 @@
+package userservice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/user"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+func (s *UserService) AddAddress(
+	ctx context.Context,
+	req *pb.AddAddressRequest,
+) (*pb.AddAddressResponse, error) {
+	address := req.Address
+	if address == nil || address.UserId == nil || address.UserId.Value == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user id was not provided")
+	}
+
+	var addressId string
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		if address.IsDefault {
+			if err := clearDefaultAddress(ctx, repo, address.UserId.Value); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		addressId, err = repo.AddAddress(ctx, address)
+		if err != nil {
+			return status.Errorf(codes.Internal, "could not add address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.AddAddressResponse{
+		Message: "address added sucessfully",
+		Id:      &pb.UUID{Value: addressId},
+	}, nil
+}
+
+func (s *UserService) GetAddress(
+	ctx context.Context,
+	req *pb.GetAddressRequest,
+) (*pb.GetAddressResponse, error) {
+	address, err := s.repo.GetAddress(ctx, req.Id.GetValue(), req.UserId.GetValue())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "address not found")
+		}
+		return nil, status.Errorf(codes.Internal, "error fetching address: %v", err)
+	}
+
+	return &pb.GetAddressResponse{Address: address}, nil
+}
+
+func (s *UserService) ListAddresses(
+	ctx context.Context,
+	req *pb.ListAddressesRequest,
+) (*pb.ListAddressesResponse, error) {
+	addresses, err := s.repo.ListAddresses(ctx, req.UserId.GetValue())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not fetch addresses: %v", err)
+	}
+
+	return &pb.ListAddressesResponse{Addresses: addresses}, nil
+}
+
+func (s *UserService) UpdateAddress(
+	ctx context.Context,
+	req *pb.UpdateAddressRequest,
+) (*pb.UpdateAddressResponse, error) {
+	address := req.Address
+	if address == nil || address.Id == nil || address.UserId == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "address id was not provided")
+	}
+
+	// rolling back keeps the old default when the address is not found.
+	err := s.repo.WithTx(ctx, func(repo Repository) error {
+		if address.IsDefault {
+			if err := clearDefaultAddress(ctx, repo, address.UserId.Value); err != nil {
+				return err
+			}
+		}
+
+		if err := repo.UpdateAddress(ctx, address); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return status.Errorf(codes.NotFound, "address not found")
+			}
+			return status.Errorf(codes.Internal, "could not update address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.UpdateAddressResponse{
+		Message: "address updated sucessfully",
+	}, nil
+}
+
+func (s *UserService) DeleteAddress(
+	ctx context.Context,
+	req *pb.DeleteAddressRequest,
+) (*pb.DeleteAddressResponse, error) {
+	err := s.repo.DeleteAddress(ctx, req.Id.GetValue(), req.UserId.GetValue())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "address not found")
+		}
+		return nil, status.Errorf(codes.Internal, "could not delete address")
+	}
+
+	return &pb.DeleteAddressResponse{
+		Message: "sucessfully deleted address",
+	}, nil
+}
+
+func clearDefaultAddress(ctx context.Context, repo Repository, userId string) error {
+	if err := repo.ClearDefaultAddress(ctx, userId); err != nil {
+		return status.Errorf(codes.Internal, "could not update default address: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,537 @@
+package userservice
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/memstore"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/user"
+	"google.golang.org/protobuf/proto"
+)
+
+// MemoryRepository is a Repository that keeps everything in memory, so the
+// service can run without Postgres. It holds no orders, reviews or author
+// profiles, so data exports only carry the profile and addresses.
+// Transactions hold a lock for their whole run and put the data back as it
+// was when they fail.
+type MemoryRepository struct {
+	store *memstore.Store[memoryUsers]
+	// data is the store's, only touched under lock.
+	data *memoryUsers
+}
+
+type memoryUser struct {
+	user         *pb.User
+	passwordHash string
+	createdAt    time.Time
+	erased       bool
+}
+
+type memoryUsers struct {
+	users     []*memoryUser
+	addresses []*pb.Address
+	exports   []*pb.DataExport
+	// comments belong to the cms service, they are only here to be
+	// exported and erased.
+	comments []memoryComment
+}
+
+type memoryComment struct {
+	userId  string
+	comment ExportComment
+}
+
+func (d *memoryUsers) clone() *memoryUsers {
+	c := &memoryUsers{}
+	for _, u := range d.users {
+		copied := *u
+		copied.user = proto.Clone(u.user).(*pb.User)
+		c.users = append(c.users, &copied)
+	}
+	for _, address := range d.addresses {
+		c.addresses = append(c.addresses, proto.Clone(address).(*pb.Address))
+	}
+	for _, e := range d.exports {
+		c.exports = append(c.exports, proto.Clone(e).(*pb.DataExport))
+	}
+	c.comments = slices.Clone(d.comments)
+	return c
+}
+
+// NewMemoryRepository returns an empty MemoryRepository.
+func NewMemoryRepository() *MemoryRepository {
+	data := &memoryUsers{}
+	return &MemoryRepository{store: memstore.New(data, (*memoryUsers).clone), data: data}
+}
+
+// AddComment adds an approved comment by the user on postId and returns
+// its id.
+func (m *MemoryRepository) AddComment(userId, postId, body string) string {
+	defer m.lock()()
+	id := uuid.NewString()
+	m.data.comments = append(m.data.comments, memoryComment{userId: userId, comment: ExportComment{
+		Id:        id,
+		PostId:    postId,
+		Body:      body,
+		Status:    "approved",
+		CreatedAt: time.Now(),
+	}})
+	return id
+}
+
+func (m *MemoryRepository) lock() func() {
+	return m.store.Lock()
+}
+
+func (m *MemoryRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return m.store.Tx(func(tx *memstore.Store[memoryUsers]) error {
+		return fn(&MemoryRepository{store: tx, data: m.data})
+	})
+}
+
+func (m *MemoryRepository) CreateUser(
+	ctx context.Context,
+	user *pb.User,
+	passwordHash string,
+) (string, error) {
+	defer m.lock()()
+
+	for _, u := range m.data.users {
+		if u.user.Email == user.Email {
+			return "", database.ErrConflict
+		}
+	}
+
+	stored := &pb.User{
+		Id:    &pb.UUID{Value: uuid.NewString()},
+		Name:  user.Name,
+		Email: user.Email,
+		Phone: user.Phone,
+		Role:  user.Role,
+	}
+	m.data.users = append(m.data.users, &memoryUser{
+		user:         stored,
+		passwordHash: passwordHash,
+		createdAt:    time.Now(),
+	})
+	return stored.Id.Value, nil
+}
+
+func (m *MemoryRepository) GetUser(ctx context.Context, id string) (*pb.User, error) {
+	defer m.lock()()
+
+	u := m.findUser(id)
+	if u == nil {
+		return nil, database.ErrNotFound
+	}
+	return u.public(), nil
+}
+
+func (m *MemoryRepository) GetUserByEmail(ctx context.Context, email string) (*pb.User, error) {
+	defer m.lock()()
+
+	u := m.findEmail(email)
+	if u == nil {
+		return nil, database.ErrNotFound
+	}
+	return u.public(), nil
+}
+
+func (m *MemoryRepository) GetCredentials(ctx context.Context, email string) (*pb.User, string, error) {
+	defer m.lock()()
+
+	u := m.findEmail(email)
+	if u == nil {
+		return nil, "", database.ErrNotFound
+	}
+	return u.public(), u.passwordHash, nil
+}
+
+func (m *MemoryRepository) GetPasswordHash(ctx context.Context, id string) (string, error) {
+	defer m.lock()()
+
+	u := m.findUser(id)
+	if u == nil {
+		return "", database.ErrNotFound
+	}
+	return u.passwordHash, nil
+}
+
+func (m *MemoryRepository) SetPasswordHash(ctx context.Context, id, passwordHash string) error {
+	defer m.lock()()
+
+	u := m.findUser(id)
+	if u == nil {
+		return database.ErrNotFound
+	}
+	u.passwordHash = passwordHash
+	return nil
+}
+
+func (m *MemoryRepository) UpdateUser(ctx context.Context, user *pb.User) error {
+	defer m.lock()()
+
+	u := m.findUser(user.Id.GetValue())
+	if u == nil {
+		return database.ErrNotFound
+	}
+	for _, other := range m.data.users {
+		if other != u && other.user.Email == user.Email {
+			return database.ErrConflict
+		}
+	}
+	u.user.Name = user.Name
+	u.user.Email = user.Email
+	u.user.Phone = user.Phone
+	return nil
+}
+
+func (m *MemoryRepository) UpdateUserRole(ctx context.Context, id string, role pb.UserRoles) error {
+	defer m.lock()()
+
+	u := m.findUser(id)
+	if u == nil {
+		return database.ErrNotFound
+	}
+	u.user.Role = role
+	return nil
+}
+
+func (m *MemoryRepository) ListUsers(ctx context.Context, limit, offset int32) ([]*pb.User, error) {
+	defer m.lock()()
+
+	var users []*pb.User
+	for _, u := range m.data.users {
+		users = append(users, u.public())
+	}
+	return memstore.Page(users, limit, offset), nil
+}
+
+func (m *MemoryRepository) SearchUsers(
+	ctx context.Context,
+	filter UserFilter,
+) ([]*pb.User, int32, error) {
+	defer m.lock()()
+
+	query := strings.ToLower(filter.Query)
+	var matched []*memoryUser
+	for _, u := range m.data.users {
+		switch {
+		case u.erased:
+		case query != "" &&
+			!strings.Contains(strings.ToLower(u.user.Name), query) &&
+			!strings.Contains(strings.ToLower(u.user.Email), query) &&
+			!strings.Contains(strings.ToLower(u.user.Phone), query):
+		case len(filter.Roles) > 0 && !slices.Contains(filter.Roles, u.user.Role):
+		case !filter.From.IsZero() && u.createdAt.Before(filter.From):
+		case !filter.To.IsZero() && u.createdAt.After(filter.To):
+		default:
+			matched = append(matched, u)
+		}
+	}
+
+	key := func(u *memoryUser) string {
+		switch filter.SortBy {
+		case "name":
+			return strings.ToLower(u.user.Name)
+		case "email":
+			return strings.ToLower(u.user.Email)
+		}
+		return u.createdAt.Format(time.RFC3339Nano)
+	}
+	slices.SortFunc(matched, func(a, b *memoryUser) int {
+		c := cmp.Or(cmp.Compare(key(a), key(b)), cmp.Compare(a.user.Id.Value, b.user.Id.Value))
+		if filter.Descending {
+			return -c
+		}
+		return c
+	})
+
+	var users []*pb.User
+	for _, u := range memstore.Page(matched, filter.Limit, filter.Offset) {
+		user := u.public()
+		user.CreatedAt = u.createdAt.Format(time.RFC3339)
+		users = append(users, user)
+	}
+	return users, int32(len(matched)), nil
+}
+
+func (m *MemoryRepository) EraseUser(ctx context.Context, id string) error {
+	defer m.lock()()
+
+	u := m.findUser(id)
+	if u == nil || u.erased {
+		return database.ErrNotFound
+	}
+	u.user.Name = "Deleted user"
+	u.user.Email = "erased-" + id + "@erased.invalid"
+	u.user.Phone = ""
+	u.passwordHash = ""
+	u.erased = true
+
+	m.data.addresses = slices.DeleteFunc(m.data.addresses, func(a *pb.Address) bool {
+		return a.UserId.GetValue() == id
+	})
+	m.data.exports = slices.DeleteFunc(m.data.exports, func(e *pb.DataExport) bool {
+		return e.UserId.GetValue() == id
+	})
+	for i, c := range m.data.comments {
+		if c.userId == id {
+			m.data.comments[i].comment.Body = erasedComment
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) AddAddress(ctx context.Context, address *pb.Address) (string, error) {
+	defer m.lock()()
+
+	if m.findUser(address.UserId.GetValue()) == nil {
+		return "", database.ErrForeignKey
+	}
+
+	stored := proto.Clone(address).(*pb.Address)
+	stored.Id = &pb.UUID{Value: uuid.NewString()}
+	stored.IsDefault = address.IsDefault || !slices.ContainsFunc(m.data.addresses, func(a *pb.Address) bool {
+		return a.UserId.GetValue() == address.UserId.GetValue()
+	})
+	if stored.IsDefault && m.defaultAddress(address.UserId.GetValue()) != nil {
+		return "", database.ErrConflict
+	}
+	m.data.addresses = append(m.data.addresses, stored)
+	return stored.Id.Value, nil
+}
+
+func (m *MemoryRepository) GetAddress(ctx context.Context, id, userId string) (*pb.Address, error) {
+	defer m.lock()()
+
+	i := m.findAddress(id, userId)
+	if i < 0 {
+		return nil, database.ErrNotFound
+	}
+	return proto.Clone(m.data.addresses[i]).(*pb.Address), nil
+}
+
+func (m *MemoryRepository) ListAddresses(ctx context.Context, userId string) ([]*pb.Address, error) {
+	defer m.lock()()
+
+	var addresses []*pb.Address
+	for _, address := range m.data.addresses {
+		if address.UserId.GetValue() == userId {
+			addresses = append(addresses, proto.Clone(address).(*pb.Address))
+		}
+	}
+	// addresses are kept in the order they were added.
+	slices.SortStableFunc(addresses, func(a, b *pb.Address) int {
+		switch {
+		case a.IsDefault == b.IsDefault:
+			return 0
+		case a.IsDefault:
+			return -1
+		}
+		return 1
+	})
+	return addresses, nil
+}
+
+func (m *MemoryRepository) UpdateAddress(ctx context.Context, address *pb.Address) error {
+	defer m.lock()()
+
+	i := m.findAddress(address.Id.GetValue(), address.UserId.GetValue())
+	if i < 0 {
+		return database.ErrNotFound
+	}
+	stored := m.data.addresses[i]
+	if current := m.defaultAddress(address.UserId.GetValue()); address.IsDefault && current != nil && current != stored {
+		return database.ErrConflict
+	}
+	m.data.addresses[i] = proto.Clone(address).(*pb.Address)
+	return nil
+}
+
+func (m *MemoryRepository) DeleteAddress(ctx context.Context, id, userId string) error {
+	defer m.lock()()
+
+	i := m.findAddress(id, userId)
+	if i < 0 {
+		return database.ErrNotFound
+	}
+	m.data.addresses = slices.Delete(m.data.addresses, i, i+1)
+	return nil
+}
+
+func (m *MemoryRepository) ClearDefaultAddress(ctx context.Context, userId string) error {
+	defer m.lock()()
+
+	if address := m.defaultAddress(userId); address != nil {
+		address.IsDefault = false
+	}
+	return nil
+}
+
+func (m *MemoryRepository) CreateDataExport(ctx context.Context, userId string) (*pb.DataExport, error) {
+	defer m.lock()()
+
+	if m.findUser(userId) == nil {
+		return nil, database.ErrForeignKey
+	}
+
+	now := time.Now()
+	e := &pb.DataExport{
+		Id:        &pb.UUID{Value: uuid.NewString()},
+		UserId:    &pb.UUID{Value: userId},
+		Status:    exportPending,
+		CreatedAt: now.Format(time.RFC3339),
+		ExpiresAt: now.Add(7 * 24 * time.Hour).Format(time.RFC3339),
+	}
+	m.data.exports = append(m.data.exports, e)
+	return proto.Clone(e).(*pb.DataExport), nil
+}
+
+func (m *MemoryRepository) GetDataExport(ctx context.Context, id, userId string) (*pb.DataExport, error) {
+	defer m.lock()()
+
+	e := m.findExport(id)
+	if e == nil || e.UserId.GetValue() != userId {
+		return nil, database.ErrNotFound
+	}
+	return proto.Clone(e).(*pb.DataExport), nil
+}
+
+func (m *MemoryRepository) SetDataExportStatus(ctx context.Context, id, status string) error {
+	defer m.lock()()
+
+	e := m.findExport(id)
+	if e == nil {
+		return database.ErrNotFound
+	}
+	e.Status = status
+	return nil
+}
+
+func (m *MemoryRepository) CompleteDataExport(ctx context.Context, id string, archive []byte) error {
+	defer m.lock()()
+
+	e := m.findExport(id)
+	if e == nil {
+		return database.ErrNotFound
+	}
+	e.Status = exportCompleted
+	e.Archive = archive
+	e.CompletedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+func (m *MemoryRepository) FailDataExport(ctx context.Context, id, reason string) error {
+	defer m.lock()()
+
+	e := m.findExport(id)
+	if e == nil {
+		return database.ErrNotFound
+	}
+	e.Status = exportFailed
+	e.Error = reason
+	e.CompletedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+func (m *MemoryRepository) ExportProfile(ctx context.Context, userId string) (*ExportProfile, error) {
+	defer m.lock()()
+
+	u := m.findUser(userId)
+	if u == nil {
+		return nil, database.ErrNotFound
+	}
+	return &ExportProfile{
+		Id:        u.user.Id.Value,
+		Name:      u.user.Name,
+		Email:     u.user.Email,
+		Phone:     u.user.Phone,
+		Role:      u.user.Role.String(),
+		CreatedAt: u.createdAt,
+	}, nil
+}
+
+func (m *MemoryRepository) ExportOrders(ctx context.Context, userId string) ([]ExportOrder, error) {
+	return []ExportOrder{}, nil
+}
+
+func (m *MemoryRepository) ExportReviews(ctx context.Context, userId string) ([]ExportReview, error) {
+	return []ExportReview{}, nil
+}
+
+func (m *MemoryRepository) ExportComments(ctx context.Context, userId string) ([]ExportComment, error) {
+	defer m.lock()()
+
+	comments := []ExportComment{}
+	for _, c := range m.data.comments {
+		if c.userId == userId {
+			comments = append(comments, c.comment)
+		}
+	}
+	return comments, nil
+}
+
+func (m *MemoryRepository) ExportAuthor(ctx context.Context, userId string) (*ExportAuthor, error) {
+	return nil, database.ErrNotFound
+}
+
+func (m *MemoryRepository) findUser(id string) *memoryUser {
+	for _, u := range m.data.users {
+		if u.user.Id.Value == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findEmail(email string) *memoryUser {
+	for _, u := range m.data.users {
+		if strings.EqualFold(u.user.Email, email) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findAddress(id, userId string) int {
+	return slices.IndexFunc(m.data.addresses, func(a *pb.Address) bool {
+		return a.Id.GetValue() == id && a.UserId.GetValue() == userId
+	})
+}
+
+// defaultAddress stands in for the unique index that allows one default
+// address per user.
+func (m *MemoryRepository) defaultAddress(userId string) *pb.Address {
+	for _, address := range m.data.addresses {
+		if address.UserId.GetValue() == userId && address.IsDefault {
+			return address
+		}
+	}
+	return nil
+}
+
+func (m *MemoryRepository) findExport(id string) *pb.DataExport {
+	for _, e := range m.data.exports {
+		if e.Id.Value == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// public returns a copy of the user with only the columns GetUser selects.
+func (u *memoryUser) public() *pb.User {
+	return &pb.User{
+		Id:    &pb.UUID{Value: u.user.Id.Value},
+		Name:  u.user.Name,
+		Email: u.user.Email,
+		Phone: u.user.Phone,
+		Role:  u.user.Role,
+	}
+}
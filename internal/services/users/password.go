@@ -0,0 +1,63 @@
+package userservice
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/kelcheone/chemistke/internal/config"
+)
+
+// PasswordPolicy describes the rules a new password has to satisfy.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// NewPasswordPolicy returns the policy cfg describes.
+func NewPasswordPolicy(cfg config.PasswordConfig) PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     cfg.MinLength,
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+	}
+}
+
+// Validate returns an error describing the first rule the password breaks.
+func (p PasswordPolicy) Validate(password string) error {
+	if len(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	if p.RequireUpper && !upper {
+		return fmt.Errorf("password must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		return fmt.Errorf("password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		return fmt.Errorf("password must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		return fmt.Errorf("password must contain a symbol")
+	}
+	return nil
+}
@@ -0,0 +1,602 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/user"
+)
+
+const (
+	userColumns    = `id, name, email, phone, role`
+	addressColumns = `id, user_id, label, county, town, street, landmark, latitude, longitude, phone, is_default`
+)
+
+// columns SearchUsers is allowed to sort by, keyed by the name clients send.
+var userSortColumns = map[string]string{
+	"":           "created_at",
+	"created_at": "created_at",
+	"name":       "LOWER(name)",
+	"email":      "LOWER(email)",
+}
+
+type postgresRepository struct {
+	db   database.DB
+	q    database.Querier
+	inTx bool
+}
+
+// NewPostgresRepository returns a Repository that keeps users in db.
+func NewPostgresRepository(db database.DB) Repository {
+	return &postgresRepository{db: db, q: db}
+}
+
+func (r *postgresRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+	return r.db.WithTx(ctx, nil, func(tx *sql.Tx) error {
+		return fn(&postgresRepository{db: r.db, q: tx, inTx: true})
+	})
+}
+
+func (r *postgresRepository) CreateUser(
+	ctx context.Context,
+	user *pb.User,
+	passwordHash string,
+) (string, error) {
+	stmt := `INSERT INTO users (name, email, phone, role, password) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	var userId string
+	err := r.q.QueryRowContext(ctx,
+		stmt,
+		user.Name,
+		user.Email,
+		user.Phone,
+		user.Role,
+		passwordHash,
+	).Scan(&userId)
+	return userId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetUser(ctx context.Context, id string) (*pb.User, error) {
+	stmt := `SELECT ` + userColumns + ` FROM users WHERE id=$1`
+	user, err := scanUser(r.q.QueryRowContext(ctx, stmt, id))
+	return user, database.Translate(err)
+}
+
+func (r *postgresRepository) GetUserByEmail(ctx context.Context, email string) (*pb.User, error) {
+	stmt := `SELECT ` + userColumns + ` FROM users WHERE LOWER(email)= LOWER($1)`
+	user, err := scanUser(r.q.QueryRowContext(ctx, stmt, email))
+	return user, database.Translate(err)
+}
+
+func (r *postgresRepository) GetCredentials(ctx context.Context, email string) (*pb.User, string, error) {
+	stmt := `SELECT ` + userColumns + `, password FROM users WHERE LOWER(email)= LOWER($1)`
+
+	var passwordHash string
+	user, err := scanUser(r.q.QueryRowContext(ctx, stmt, email), &passwordHash)
+	if err != nil {
+		return nil, "", database.Translate(err)
+	}
+	return user, passwordHash, nil
+}
+
+func (r *postgresRepository) GetPasswordHash(ctx context.Context, id string) (string, error) {
+	var passwordHash string
+	err := r.q.QueryRowContext(ctx, `SELECT password FROM users WHERE id=$1`, id).
+		Scan(&passwordHash)
+	return passwordHash, database.Translate(err)
+}
+
+func (r *postgresRepository) SetPasswordHash(ctx context.Context, id, passwordHash string) error {
+	return r.exec(ctx, `UPDATE users SET password=$1 WHERE id=$2`, passwordHash, id)
+}
+
+func (r *postgresRepository) UpdateUser(ctx context.Context, user *pb.User) error {
+	return r.exec(ctx,
+		`UPDATE users SET name=$1, email=$2, phone=$3 WHERE id=$4`,
+		user.Name,
+		user.Email,
+		user.Phone,
+		user.Id.GetValue(),
+	)
+}
+
+func (r *postgresRepository) UpdateUserRole(ctx context.Context, id string, role pb.UserRoles) error {
+	return r.exec(ctx, `UPDATE users SET role=$1 WHERE id=$2`, role, id)
+}
+
+func (r *postgresRepository) ListUsers(ctx context.Context, limit, offset int32) ([]*pb.User, error) {
+	stmt := `SELECT ` + userColumns + ` FROM users LIMIT $1 OFFSET $2`
+
+	rows, err := r.q.QueryContext(ctx, stmt, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*pb.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (r *postgresRepository) SearchUsers(
+	ctx context.Context,
+	filter UserFilter,
+) ([]*pb.User, int32, error) {
+	where := []string{"erased_at IS NULL"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Query != "" {
+		p := arg("%" + escapeLike(filter.Query) + "%")
+		where = append(where, fmt.Sprintf("(name ILIKE %[1]s OR email ILIKE %[1]s OR phone ILIKE %[1]s)", p))
+	}
+
+	if len(filter.Roles) > 0 {
+		var roles []string
+		for _, role := range filter.Roles {
+			roles = append(roles, arg(int32(role)))
+		}
+		where = append(where, fmt.Sprintf("role IN (%s)", strings.Join(roles, ", ")))
+	}
+
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at <= "+arg(filter.To))
+	}
+
+	whereClause := "WHERE " + strings.Join(where, " AND ")
+
+	sortColumn, ok := userSortColumns[filter.SortBy]
+	if !ok {
+		return nil, 0, fmt.Errorf("cannot sort users by %q", filter.SortBy)
+	}
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	// id breaks ties so pages stay stable when sort values repeat.
+	stmt := fmt.Sprintf(`SELECT id, name, email, phone, role, created_at,
+	COUNT(*) OVER() AS total_count
+	FROM users %s
+	ORDER BY %s %s NULLS LAST, id %s
+	LIMIT %s OFFSET %s`,
+		whereClause,
+		sortColumn,
+		direction,
+		direction,
+		arg(filter.Limit),
+		arg(filter.Offset),
+	)
+
+	rows, err := r.q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []*pb.User
+	var total int32
+	for rows.Next() {
+		var createdAt sql.NullTime
+		user, err := scanUser(rows, &createdAt, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		if createdAt.Valid {
+			user.CreatedAt = createdAt.Time.Format(time.RFC3339)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// COUNT(*) OVER() has no row to ride on when the page is past the end.
+	if len(users) == 0 && filter.Offset > 0 {
+		countStmt := fmt.Sprintf(`SELECT COUNT(*) FROM users %s`, whereClause)
+		if err := r.q.QueryRowContext(ctx, countStmt, args[:len(args)-2]...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("could not count users: %w", err)
+		}
+	}
+
+	return users, total, nil
+}
+
+func (r *postgresRepository) EraseUser(ctx context.Context, id string) error {
+	return r.WithTx(ctx, func(repo Repository) error {
+		tx := repo.(*postgresRepository)
+
+		err := tx.exec(ctx,
+			`UPDATE users SET name='Deleted user', email='erased-' || id || '@erased.invalid',
+			phone='', password='', erased_at=NOW() WHERE id=$1 AND erased_at IS NULL`,
+			id,
+		)
+		if err != nil {
+			return err
+		}
+
+		stmts := []string{
+			`DELETE FROM user_addresses WHERE user_id=$1`,
+			`DELETE FROM user_data_exports WHERE user_id=$1`,
+			`UPDATE orders SET delivery_address = jsonb_build_object(
+				'county', delivery_address->'county',
+				'town', delivery_address->'town')
+			WHERE user_id=$1 AND delivery_address IS NOT NULL`,
+			`UPDATE authors SET bio='', avatar='', url='' WHERE user_id=$1`,
+			// replies hang off comments, so they are blanked, not deleted.
+			`UPDATE post_comments SET body='` + erasedComment + `' WHERE user_id=$1`,
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.q.ExecContext(ctx, stmt, id); err != nil {
+				return fmt.Errorf("could not erase user data: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) AddAddress(ctx context.Context, address *pb.Address) (string, error) {
+	stmt := `INSERT INTO user_addresses (user_id, label, county, town, street, landmark, latitude, longitude, phone, is_default)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9,
+	$10 OR NOT EXISTS (SELECT 1 FROM user_addresses WHERE user_id=$1))
+	RETURNING id`
+
+	lat, lng := coordinates(address)
+
+	var addressId string
+	err := r.q.QueryRowContext(ctx,
+		stmt,
+		address.UserId.GetValue(),
+		address.Label,
+		address.County,
+		address.Town,
+		address.Street,
+		address.Landmark,
+		lat,
+		lng,
+		address.Phone,
+		address.IsDefault,
+	).Scan(&addressId)
+	return addressId, database.Translate(err)
+}
+
+func (r *postgresRepository) GetAddress(ctx context.Context, id, userId string) (*pb.Address, error) {
+	stmt := `SELECT ` + addressColumns + ` FROM user_addresses WHERE id=$1 AND user_id=$2`
+	address, err := scanAddress(r.q.QueryRowContext(ctx, stmt, id, userId))
+	return address, database.Translate(err)
+}
+
+func (r *postgresRepository) ListAddresses(ctx context.Context, userId string) ([]*pb.Address, error) {
+	stmt := `SELECT ` + addressColumns + ` FROM user_addresses WHERE user_id=$1 ORDER BY is_default DESC, created_at`
+	rows, err := r.q.QueryContext(ctx, stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []*pb.Address
+	for rows.Next() {
+		address, err := scanAddress(rows)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+func (r *postgresRepository) UpdateAddress(ctx context.Context, address *pb.Address) error {
+	stmt := `UPDATE user_addresses SET label=$1, county=$2, town=$3, street=$4, landmark=$5,
+	latitude=$6, longitude=$7, phone=$8, is_default=$9, updated_at=NOW() WHERE id=$10 AND user_id=$11`
+
+	lat, lng := coordinates(address)
+
+	return r.exec(ctx,
+		stmt,
+		address.Label,
+		address.County,
+		address.Town,
+		address.Street,
+		address.Landmark,
+		lat,
+		lng,
+		address.Phone,
+		address.IsDefault,
+		address.Id.GetValue(),
+		address.UserId.GetValue(),
+	)
+}
+
+func (r *postgresRepository) DeleteAddress(ctx context.Context, id, userId string) error {
+	return r.exec(ctx, `DELETE FROM user_addresses WHERE id=$1 AND user_id=$2`, id, userId)
+}
+
+func (r *postgresRepository) ClearDefaultAddress(ctx context.Context, userId string) error {
+	stmt := `UPDATE user_addresses SET is_default=false WHERE user_id=$1 AND is_default`
+	_, err := r.q.ExecContext(ctx, stmt, userId)
+	return err
+}
+
+func (r *postgresRepository) CreateDataExport(ctx context.Context, userId string) (*pb.DataExport, error) {
+	stmt := `INSERT INTO user_data_exports (user_id, status) VALUES ($1, $2) RETURNING id, created_at, expires_at`
+
+	var exportId string
+	var createdAt, expiresAt time.Time
+	err := r.q.QueryRowContext(ctx, stmt, userId, exportPending).
+		Scan(&exportId, &createdAt, &expiresAt)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	return &pb.DataExport{
+		Id:        &pb.UUID{Value: exportId},
+		UserId:    &pb.UUID{Value: userId},
+		Status:    exportPending,
+		CreatedAt: createdAt.Format(time.RFC3339),
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (r *postgresRepository) GetDataExport(ctx context.Context, id, userId string) (*pb.DataExport, error) {
+	stmt := `SELECT id, user_id, status, archive, error, created_at, completed_at, expires_at
+	FROM user_data_exports WHERE id=$1 AND user_id=$2`
+
+	var export pb.DataExport
+	var exportId, exportUserId string
+	var createdAt, expiresAt time.Time
+	var completedAt sql.NullTime
+
+	err := r.q.QueryRowContext(ctx, stmt, id, userId).Scan(
+		&exportId,
+		&exportUserId,
+		&export.Status,
+		&export.Archive,
+		&export.Error,
+		&createdAt,
+		&completedAt,
+		&expiresAt,
+	)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	export.Id = &pb.UUID{Value: exportId}
+	export.UserId = &pb.UUID{Value: exportUserId}
+	export.CreatedAt = createdAt.Format(time.RFC3339)
+	export.ExpiresAt = expiresAt.Format(time.RFC3339)
+	if completedAt.Valid {
+		export.CompletedAt = completedAt.Time.Format(time.RFC3339)
+	}
+	return &export, nil
+}
+
+func (r *postgresRepository) SetDataExportStatus(ctx context.Context, id, status string) error {
+	return r.exec(ctx, `UPDATE user_data_exports SET status=$1 WHERE id=$2`, status, id)
+}
+
+func (r *postgresRepository) CompleteDataExport(ctx context.Context, id string, archive []byte) error {
+	return r.exec(ctx,
+		`UPDATE user_data_exports SET status=$1, archive=$2, completed_at=NOW() WHERE id=$3`,
+		exportCompleted,
+		archive,
+		id,
+	)
+}
+
+func (r *postgresRepository) FailDataExport(ctx context.Context, id, reason string) error {
+	return r.exec(ctx,
+		`UPDATE user_data_exports SET status=$1, error=$2, completed_at=NOW() WHERE id=$3`,
+		exportFailed,
+		reason,
+		id,
+	)
+}
+
+func (r *postgresRepository) ExportProfile(ctx context.Context, userId string) (*ExportProfile, error) {
+	var profile ExportProfile
+	var role int32
+	var createdAt sql.NullTime
+	err := r.q.QueryRowContext(ctx,
+		`SELECT id, name, email, phone, role, created_at FROM users WHERE id=$1`,
+		userId,
+	).Scan(&profile.Id, &profile.Name, &profile.Email, &profile.Phone, &role, &createdAt)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	profile.Role = pb.UserRoles(role).String()
+	profile.CreatedAt = createdAt.Time
+	return &profile, nil
+}
+
+func (r *postgresRepository) ExportOrders(ctx context.Context, userId string) ([]ExportOrder, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT id, product_id, status, quantity, total, delivery_address, created_at, updated_at
+		FROM orders WHERE user_id=$1 ORDER BY created_at`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []ExportOrder{}
+	for rows.Next() {
+		var o ExportOrder
+		var deliveryAddress []byte
+		err := rows.Scan(
+			&o.Id,
+			&o.ProductId,
+			&o.Status,
+			&o.Quantity,
+			&o.Total,
+			&deliveryAddress,
+			&o.CreatedAt,
+			&o.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(deliveryAddress) > 0 {
+			o.DeliveryAddress = deliveryAddress
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (r *postgresRepository) ExportReviews(ctx context.Context, userId string) ([]ExportReview, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT id, product_id, title, rating, content, created_at
+		FROM product_reviews WHERE user_id=$1 ORDER BY created_at`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []ExportReview{}
+	for rows.Next() {
+		var r ExportReview
+		if err := rows.Scan(&r.Id, &r.ProductId, &r.Title, &r.Rating, &r.Content, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
+
+func (r *postgresRepository) ExportComments(ctx context.Context, userId string) ([]ExportComment, error) {
+	rows, err := r.q.QueryContext(ctx,
+		`SELECT id, post_id, COALESCE(parent_id::text, ''), body, status, created_at
+		FROM post_comments WHERE user_id=$1 ORDER BY created_at`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []ExportComment{}
+	for rows.Next() {
+		var c ExportComment
+		if err := rows.Scan(&c.Id, &c.PostId, &c.ParentId, &c.Body, &c.Status, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (r *postgresRepository) ExportAuthor(ctx context.Context, userId string) (*ExportAuthor, error) {
+	var a ExportAuthor
+	err := r.q.QueryRowContext(ctx,
+		`SELECT id, bio, avatar, url FROM authors WHERE user_id=$1`,
+		userId,
+	).Scan(&a.Id, &a.Bio, &a.Avatar, &a.Url)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	return &a, nil
+}
+
+// exec runs a write that has to match a row, failing with
+// database.ErrNotFound when it matches none.
+func (r *postgresRepository) exec(ctx context.Context, stmt string, args ...interface{}) error {
+	result, err := r.q.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return database.Translate(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return database.ErrNotFound
+	}
+	return nil
+}
+
+// coordinates returns NULLs when no location was pinned.
+func coordinates(address *pb.Address) (sql.NullFloat64, sql.NullFloat64) {
+	if address.Latitude == 0 && address.Longitude == 0 {
+		return sql.NullFloat64{}, sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: address.Latitude, Valid: true},
+		sql.NullFloat64{Float64: address.Longitude, Valid: true}
+}
+
+// escapeLike stops user input from being read as LIKE wildcards.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads userColumns, and any columns after them into extra.
+func scanUser(row rowScanner, extra ...interface{}) (*pb.User, error) {
+	var user pb.User
+	var userId string
+
+	dest := []interface{}{
+		&userId,
+		&user.Name,
+		&user.Email,
+		&user.Phone,
+		&user.Role,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	user.Id = &pb.UUID{Value: userId}
+	return &user, nil
+}
+
+func scanAddress(row rowScanner) (*pb.Address, error) {
+	var address pb.Address
+	var addressId, userId string
+	var lat, lng sql.NullFloat64
+
+	err := row.Scan(
+		&addressId,
+		&userId,
+		&address.Label,
+		&address.County,
+		&address.Town,
+		&address.Street,
+		&address.Landmark,
+		&lat,
+		&lng,
+		&address.Phone,
+		&address.IsDefault,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	address.Id = &pb.UUID{Value: addressId}
+	address.UserId = &pb.UUID{Value: userId}
+	address.Latitude = lat.Float64
+	address.Longitude = lng.Float64
+
+	return &address, nil
+}
@@ -0,0 +1,220 @@
+package userservice
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/user"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+// Everything in here exists to meet Kenya's Data Protection Act: a user can
+// download all the data we hold on them and ask for it to be erased.
+
+// erasedComment replaces the body of an erased user's comments.
+const erasedComment = "[deleted]"
+
+const (
+	exportPending    = "pending"
+	exportProcessing = "processing"
+	exportCompleted  = "completed"
+	exportFailed     = "failed"
+	exportExpired    = "expired"
+)
+
+func (s *UserService) RequestDataExport(
+	ctx context.Context,
+	req *pb.RequestDataExportRequest,
+) (*pb.RequestDataExportResponse, error) {
+	if req.UserId == nil || req.UserId.Value == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user id was not provided")
+	}
+
+	export, err := s.repo.CreateDataExport(ctx, req.UserId.Value)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not create data export: %v", err)
+	}
+
+	// the archive is built in the background, clients poll GetDataExport.
+	// it keeps the request ID and trace but must outlive the call itself.
+	go s.processDataExport(context.WithoutCancel(ctx), export.Id.Value, req.UserId.Value)
+
+	return &pb.RequestDataExportResponse{Export: export}, nil
+}
+
+func (s *UserService) GetDataExport(
+	ctx context.Context,
+	req *pb.GetDataExportRequest,
+) (*pb.GetDataExportResponse, error) {
+	export, err := s.repo.GetDataExport(ctx, req.Id.GetValue(), req.UserId.GetValue())
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "data export not found")
+		}
+		return nil, status.Errorf(codes.Internal, "error fetching data export: %v", err)
+	}
+
+	if expiresAt, err := time.Parse(time.RFC3339, export.ExpiresAt); err == nil && time.Now().After(expiresAt) {
+		export.Status = exportExpired
+		export.Archive = nil
+	}
+
+	return &pb.GetDataExportResponse{Export: export}, nil
+}
+
+func (s *UserService) processDataExport(ctx context.Context, exportId, userId string) {
+	if err := s.repo.SetDataExportStatus(ctx, exportId, exportProcessing); err != nil {
+		slog.Error("could not start data export", "export_id", exportId, "error", err)
+		return
+	}
+
+	archive, err := s.buildExportArchive(ctx, userId)
+	if err != nil {
+		slog.Error("data export failed", "export_id", exportId, "error", err)
+		if err := s.repo.FailDataExport(ctx, exportId, err.Error()); err != nil {
+			slog.Error("could not mark data export as failed", "export_id", exportId, "error", err)
+		}
+		return
+	}
+
+	if err := s.repo.CompleteDataExport(ctx, exportId, archive); err != nil {
+		slog.Error("could not store data export", "export_id", exportId, "error", err)
+	}
+}
+
+// The Export* types are what a user's data export holds, one JSON file
+// each.
+type ExportProfile struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportOrder struct {
+	Id              string          `json:"id"`
+	ProductId       string          `json:"product_id"`
+	Status          string          `json:"status"`
+	Quantity        int32           `json:"quantity"`
+	Total           float64         `json:"total"`
+	DeliveryAddress json.RawMessage `json:"delivery_address,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+type ExportReview struct {
+	Id        string    `json:"id"`
+	ProductId string    `json:"product_id"`
+	Title     string    `json:"title"`
+	Rating    int32     `json:"rating"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportComment struct {
+	Id        string    `json:"id"`
+	PostId    string    `json:"post_id"`
+	ParentId  string    `json:"parent_id,omitempty"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportAuthor struct {
+	Id     string `json:"id"`
+	Bio    string `json:"bio"`
+	Avatar string `json:"avatar"`
+	Url    string `json:"url"`
+}
+
+// buildExportArchive collects every record tied to the user into a zip with
+// one JSON file per kind of data. Consultations will be added once the
+// telehealth service stores any.
+func (s *UserService) buildExportArchive(ctx context.Context, userId string) ([]byte, error) {
+	profile, err := s.repo.ExportProfile(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not read profile: %w", err)
+	}
+
+	addresses, err := s.ListAddresses(ctx, &pb.ListAddressesRequest{
+		UserId: &pb.UUID{Value: userId},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read addresses: %w", err)
+	}
+
+	orders, err := s.repo.ExportOrders(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not read orders: %w", err)
+	}
+
+	reviews, err := s.repo.ExportReviews(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not read reviews: %w", err)
+	}
+
+	comments, err := s.repo.ExportComments(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not read comments: %w", err)
+	}
+
+	author, err := s.repo.ExportAuthor(ctx, userId)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, fmt.Errorf("could not read author profile: %w", err)
+	}
+
+	files := map[string]interface{}{
+		"profile.json":   profile,
+		"addresses.json": addresses.Addresses,
+		"orders.json":    orders,
+		"reviews.json":   reviews,
+		"comments.json":  comments,
+	}
+	if author != nil {
+		files["author.json"] = author
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(data); err != nil {
+			return nil, fmt.Errorf("could not encode %s: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// eraseUser anonymizes the user's PII in place. Orders are kept because we
+// are required to retain them, but their address snapshots are reduced to
+// county and town. Reviews stay up under the anonymized name, comments stay
+// in their threads with their body removed. It all runs in
+// one transaction so a failure cannot leave a user half erased.
+func (s *UserService) eraseUser(ctx context.Context, userId string) error {
+	if err := s.repo.EraseUser(ctx, userId); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return status.Errorf(codes.NotFound, "user not found")
+		}
+		return status.Errorf(codes.Internal, "could not erase user: %v", err)
+	}
+	return nil
+}
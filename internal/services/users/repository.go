@@ -0,0 +1,81 @@
+package userservice
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/kelcheone/chemistke/pkg/grpc/user"
+)
+
+// UserFilter narrows down and orders the users SearchUsers returns. Zero
+// values leave a filter out. Erased users are never returned.
+type UserFilter struct {
+	// Query matches part of a name, email or phone number.
+	Query    string
+	Roles    []pb.UserRoles
+	From, To time.Time
+
+	// SortBy is one of created_at, name or email.
+	SortBy     string
+	Descending bool
+	Limit      int32
+	Offset     int32
+}
+
+// Repository is where the user service keeps its data. Lookups of a missing
+// row fail with database.ErrNotFound, as do updates and deletes that match
+// nothing.
+type Repository interface {
+	// CreateUser stores user with passwordHash in place of its password and
+	// returns its id.
+	CreateUser(ctx context.Context, user *pb.User, passwordHash string) (string, error)
+	GetUser(ctx context.Context, id string) (*pb.User, error)
+	// GetUserByEmail ignores the case of email.
+	GetUserByEmail(ctx context.Context, email string) (*pb.User, error)
+	// GetCredentials returns the user with email and their password hash.
+	GetCredentials(ctx context.Context, email string) (*pb.User, string, error)
+	GetPasswordHash(ctx context.Context, id string) (string, error)
+	SetPasswordHash(ctx context.Context, id, passwordHash string) error
+	// UpdateUser changes a user's name, email and phone.
+	UpdateUser(ctx context.Context, user *pb.User) error
+	UpdateUserRole(ctx context.Context, id string, role pb.UserRoles) error
+	ListUsers(ctx context.Context, limit, offset int32) ([]*pb.User, error)
+	// SearchUsers returns a page of the users matching filter and how many
+	// match in all.
+	SearchUsers(ctx context.Context, filter UserFilter) ([]*pb.User, int32, error)
+	// EraseUser anonymizes a user and everything tied to them, see
+	// UserService.eraseUser.
+	EraseUser(ctx context.Context, id string) error
+
+	// AddAddress stores address and returns its id. A user's first address
+	// becomes their default whatever IsDefault says.
+	AddAddress(ctx context.Context, address *pb.Address) (string, error)
+	GetAddress(ctx context.Context, id, userId string) (*pb.Address, error)
+	// ListAddresses returns the default address first, then the rest
+	// oldest first.
+	ListAddresses(ctx context.Context, userId string) ([]*pb.Address, error)
+	UpdateAddress(ctx context.Context, address *pb.Address) error
+	DeleteAddress(ctx context.Context, id, userId string) error
+	// ClearDefaultAddress leaves the user without a default address.
+	ClearDefaultAddress(ctx context.Context, userId string) error
+
+	// CreateDataExport starts a pending export, which expires after a week.
+	CreateDataExport(ctx context.Context, userId string) (*pb.DataExport, error)
+	GetDataExport(ctx context.Context, id, userId string) (*pb.DataExport, error)
+	SetDataExportStatus(ctx context.Context, id, status string) error
+	CompleteDataExport(ctx context.Context, id string, archive []byte) error
+	FailDataExport(ctx context.Context, id, reason string) error
+
+	// The Export* methods read what goes into a user's data export.
+	// ExportAuthor fails with database.ErrNotFound when the user is not an
+	// author.
+	ExportProfile(ctx context.Context, userId string) (*ExportProfile, error)
+	ExportOrders(ctx context.Context, userId string) ([]ExportOrder, error)
+	ExportReviews(ctx context.Context, userId string) ([]ExportReview, error)
+	ExportComments(ctx context.Context, userId string) ([]ExportComment, error)
+	ExportAuthor(ctx context.Context, userId string) (*ExportAuthor, error)
+
+	// WithTx runs fn on a Repository whose writes are kept only if fn
+	// returns nil.
+	WithTx(ctx context.Context, fn func(Repository) error) error
+}
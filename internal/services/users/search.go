@@ -0,0 +1,73 @@
+package userservice
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/user"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+func (s *UserService) SearchUsers(
+	ctx context.Context,
+	req *pb.SearchUsersRequest,
+) (*pb.SearchUsersResponse, error) {
+	if _, ok := userSortColumns[req.SortBy]; !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot sort users by %q", req.SortBy)
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = defaultSearchLimit
+	}
+	if req.Limit > maxSearchLimit {
+		req.Limit = maxSearchLimit
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+
+	filter := UserFilter{
+		Query:      strings.TrimSpace(req.Query),
+		Roles:      req.Roles,
+		SortBy:     req.SortBy,
+		Descending: req.Descending,
+		Limit:      req.Limit,
+		Offset:     (req.Page - 1) * req.Limit,
+	}
+
+	if req.RegisteredFrom != "" {
+		from, err := time.Parse(time.RFC3339, req.RegisteredFrom)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid registered_from: %v", err)
+		}
+		filter.From = from
+	}
+	if req.RegisteredTo != "" {
+		to, err := time.Parse(time.RFC3339, req.RegisteredTo)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid registered_to: %v", err)
+		}
+		filter.To = to
+	}
+
+	users, total, err := s.repo.SearchUsers(ctx, filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "could not search users: %v", err)
+	}
+
+	return &pb.SearchUsersResponse{
+		Users:    users,
+		Limit:    req.Limit,
+		Page:     req.Page,
+		Total:    total,
+		MaxPages: int32(math.Ceil(float64(total) / float64(req.Limit))),
+	}, nil
+}
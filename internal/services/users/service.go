@@ -2,22 +2,25 @@ package userservice
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 
 	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
 	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/metrics"
 	"github.com/kelcheone/chemistke/pkg/codes"
 	pb "github.com/kelcheone/chemistke/pkg/grpc/user"
 	"github.com/kelcheone/chemistke/pkg/status"
 )
 
 type UserService struct {
-	db database.DB
+	repo   Repository
+	policy PasswordPolicy
 	pb.UnimplementedUserServiceServer
 }
 
-func NewService(db database.DB) *UserService {
-	return &UserService{db: db}
+func NewService(repo Repository, password config.PasswordConfig) *UserService {
+	return &UserService{repo: repo, policy: NewPasswordPolicy(password)}
 }
 
 func (s *UserService) AddUser(
@@ -25,9 +28,9 @@ func (s *UserService) AddUser(
 	req *pb.AddUserRequest,
 ) (*pb.AddUserResponse, error) {
 	user := req.User
-	stmt := `INSERT INTO users (name, email, phone, role, password) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	type result struct {
-		id string
+
+	if err := s.policy.Validate(user.Password); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
 	}
 
 	hashedPassword, err := utils.Hash(user.Password)
@@ -35,26 +38,17 @@ func (s *UserService) AddUser(
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
 
-	res := s.db.QueryRow(
-		stmt,
-		user.Name,
-		user.Email,
-		user.Phone,
-		user.Role,
-		hashedPassword,
-	)
-
-	var r result
-	if err := res.Scan(&r.id); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Errorf(codes.NotFound, "user not found")
+	userId, err := s.repo.CreateUser(ctx, user, hashedPassword)
+	if err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			return nil, status.Errorf(codes.AlreadyExists, "a user with that email already exists")
 		}
 		return nil, status.Errorf(codes.Internal, "could not create user")
 	}
 
 	response := &pb.AddUserResponse{
 		Message: "User Added sucessfully",
-		Id:      &pb.UUID{Value: r.id},
+		Id:      &pb.UUID{Value: userId},
 	}
 	return response, nil
 }
@@ -63,53 +57,24 @@ func (s *UserService) GetUser(
 	ctx context.Context,
 	req *pb.GetUserRequest,
 ) (*pb.GetUserResponse, error) {
-	stmt := `SELECT id, name, email, phone, role, password FROM users WHERE id=$1`
-	row := s.db.QueryRow(stmt, req.Id.Value)
-
-	var gUser pb.User
-
-	var userId string
-
-	err := row.Scan(
-		&userId,
-		&gUser.Name,
-		&gUser.Email,
-		&gUser.Phone,
-		&gUser.Role,
-		&gUser.Password,
-	)
+	user, err := s.repo.GetUser(ctx, req.Id.GetValue())
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(codes.NotFound, "user not found")
 		}
 		return nil, status.Errorf(codes.Internal, "error fetching user")
 	}
-	gUser.Id = &pb.UUID{Value: userId}
 
-	return &pb.GetUserResponse{User: &gUser}, nil
+	return &pb.GetUserResponse{User: user}, nil
 }
 
 func (s *UserService) GetUserByEmail(
 	ctx context.Context,
 	req *pb.GetUserByEmailRequest,
 ) (*pb.GetUserByEmailResponse, error) {
-	stmt := `SELECT id, name, email, phone, password, role FROM users WHERE LOWER(email)= LOWER($1)`
-	row := s.db.QueryRow(stmt, req.Email)
-
-	var gUser pb.User
-
-	var userId string
-
-	err := row.Scan(
-		&userId,
-		&gUser.Name,
-		&gUser.Email,
-		&gUser.Phone,
-		&gUser.Password,
-		&gUser.Role,
-	)
+	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(
 				codes.NotFound,
 				"user with email %s not found",
@@ -122,31 +87,21 @@ func (s *UserService) GetUserByEmail(
 			req.Email,
 		)
 	}
-	gUser.Id = &pb.UUID{Value: userId}
 
-	return &pb.GetUserByEmailResponse{User: &gUser}, nil
+	return &pb.GetUserByEmailResponse{User: user}, nil
 }
 
 func (s *UserService) UpdateUser(
 	ctx context.Context,
 	req *pb.UpdateUserRequest,
 ) (*pb.UpdateUserResponse, error) {
-	stmt := `UPDATE users SET name=$1, email=$2, phone=$3, role=$4 WHERE id=$5`
 	tUser := req.User
 
-	if tUser.Id.Value == "" {
+	if tUser.Id == nil || tUser.Id.Value == "" {
 		return nil, status.Errorf(codes.Aborted, "Id was not provided")
 	}
-	_, err := s.db.Exec(
-		stmt,
-		tUser.Name,
-		tUser.Email,
-		tUser.Phone,
-		tUser.Role,
-		tUser.Id.Value,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
+	if err := s.repo.UpdateUser(ctx, tUser); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
 			return nil, status.Errorf(codes.NotFound, "user not found")
 		}
 		return nil, status.Errorf(codes.Internal, "could not update user")
@@ -156,17 +111,33 @@ func (s *UserService) UpdateUser(
 	}, nil
 }
 
+func (s *UserService) UpdateUserRole(
+	ctx context.Context,
+	req *pb.UpdateUserRoleRequest,
+) (*pb.UpdateUserRoleResponse, error) {
+	if req.Id == nil || req.Id.Value == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Id was not provided")
+	}
+
+	if err := s.repo.UpdateUserRole(ctx, req.Id.Value, req.Role); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		}
+		return nil, status.Errorf(codes.Internal, "could not update user role")
+	}
+
+	return &pb.UpdateUserRoleResponse{
+		Message: "user role updated sucessfully",
+	}, nil
+}
+
 func (s *UserService) DeleteUser(
 	ctx context.Context,
 	req *pb.DeleteUserRequest,
 ) (*pb.DeleteUserResponse, error) {
-	stmt := `DELETE FROM users WHERE id=$1`
-	_, err := s.db.Exec(stmt, req.Id.Value)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, status.Errorf(codes.NotFound, "user not found")
-		}
-		return nil, status.Errorf(codes.Internal, "could not delete user")
+	// users are erased rather than deleted, see eraseUser.
+	if err := s.eraseUser(ctx, req.Id.GetValue()); err != nil {
+		return nil, err
 	}
 	return &pb.DeleteUserResponse{
 		Message: "sucessfully deleted user",
@@ -177,34 +148,70 @@ func (s *UserService) GetUsers(
 	ctx context.Context,
 	req *pb.GetUsersRequest,
 ) (*pb.GetUsersResponse, error) {
-	limit := req.Limit
-	page := req.Page
-	stmt := `SELECT id,name, email, phone, role FROM users LIMIT $1 OFFSET $2`
-
-	rows, err := s.db.Query(stmt, limit, page)
+	users, err := s.repo.ListUsers(ctx, req.Limit, req.Page)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "could not fetch users")
 	}
+	return &pb.GetUsersResponse{
+		Users: users,
+	}, nil
+}
+
+func (s *UserService) VerifyCredentials(
+	ctx context.Context,
+	req *pb.VerifyCredentialsRequest,
+) (*pb.VerifyCredentialsResponse, error) {
+	user, hashedPassword, err := s.repo.GetCredentials(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			metrics.LoginFailures.Inc()
+			return nil, status.Errorf(codes.Unauthenticated, "invalid credentials")
+		}
+		return nil, status.Errorf(codes.Internal, "error verifying credentials")
+	}
+
+	if err := utils.ComparePassword(req.Password, hashedPassword); err != nil {
+		metrics.LoginFailures.Inc()
+		return nil, status.Errorf(codes.Unauthenticated, "invalid credentials")
+	}
 
-	var users []*pb.User
+	return &pb.VerifyCredentialsResponse{User: user}, nil
+}
 
-	for rows.Next() {
-		var user pb.User
-		var userId string
-		err := rows.Scan(
-			&userId,
-			&user.Name,
-			&user.Email,
-			&user.Phone,
-			&user.Role,
-		)
-		if err != nil {
-			return nil, status.Errorf(codes.Internal, "error scanning user")
+func (s *UserService) ChangePassword(
+	ctx context.Context,
+	req *pb.ChangePasswordRequest,
+) (*pb.ChangePasswordResponse, error) {
+	if req.Id == nil || req.Id.Value == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Id was not provided")
+	}
+
+	hashedPassword, err := s.repo.GetPasswordHash(ctx, req.Id.Value)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "user not found")
 		}
-		user.Id = &pb.UUID{Value: userId}
-		users = append(users, &user)
+		return nil, status.Errorf(codes.Internal, "could not change password")
 	}
-	return &pb.GetUsersResponse{
-		Users: users,
+
+	if err := utils.ComparePassword(req.CurrentPassword, hashedPassword); err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "current password is incorrect")
+	}
+
+	if err := s.policy.Validate(req.NewPassword); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	newHash, err := utils.Hash(req.NewPassword)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	if err := s.repo.SetPasswordHash(ctx, req.Id.Value, newHash); err != nil {
+		return nil, status.Errorf(codes.Internal, "could not change password")
+	}
+
+	return &pb.ChangePasswordResponse{
+		Message: "password changed sucessfully",
 	}, nil
 }
@@ -0,0 +1,398 @@
+package userservice
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/kelcheone/chemistke/pkg/codes"
+	pb "github.com/kelcheone/chemistke/pkg/grpc/user"
+	"github.com/kelcheone/chemistke/pkg/status"
+)
+
+const password = "secret123"
+
+// newUsers returns a service holding one user, jane@example.com with
+// password, and that user's id.
+func newUsers(t *testing.T) (*UserService, string) {
+	t.Helper()
+
+	svc := NewService(NewMemoryRepository(), config.Default().Password)
+	resp, err := svc.AddUser(context.Background(), &pb.AddUserRequest{User: &pb.User{
+		Name:     "Jane Doe",
+		Email:    "jane@example.com",
+		Phone:    "+254700000000",
+		Password: password,
+		Role:     pb.UserRoles_USER,
+	}})
+	if err != nil {
+		t.Fatalf("AddUser: %v", err)
+	}
+	return svc, resp.Id.Value
+}
+
+func TestAddUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     codes.Code
+	}{
+		{name: "adds the user", email: "john@example.com", password: password, want: codes.OK},
+		{name: "short password", email: "john@example.com", password: "abc1", want: codes.InvalidArgument},
+		{name: "password without a digit", email: "john@example.com", password: "secretpassword", want: codes.InvalidArgument},
+		{name: "email taken", email: "jane@example.com", password: password, want: codes.AlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newUsers(t)
+
+			resp, err := svc.AddUser(context.Background(), &pb.AddUserRequest{User: &pb.User{
+				Name:     "John Doe",
+				Email:    tt.email,
+				Password: tt.password,
+			}})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+			if err != nil {
+				return
+			}
+
+			user, err := svc.GetUser(context.Background(), &pb.GetUserRequest{Id: resp.Id})
+			if err != nil {
+				t.Fatalf("GetUser: %v", err)
+			}
+			if user.User.Email != tt.email || user.User.Password != "" {
+				t.Errorf("got user %v", user.User)
+			}
+		})
+	}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     codes.Code
+	}{
+		{name: "right password", email: "jane@example.com", password: password, want: codes.OK},
+		{name: "wrong password", email: "jane@example.com", password: "secret124", want: codes.Unauthenticated},
+		{name: "unknown email", email: "john@example.com", password: password, want: codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, userId := newUsers(t)
+
+			resp, err := svc.VerifyCredentials(context.Background(), &pb.VerifyCredentialsRequest{
+				Email:    tt.email,
+				Password: tt.password,
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+			if err == nil && resp.User.Id.GetValue() != userId {
+				t.Errorf("got user %v, want %s", resp.User.Id, userId)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		current string
+		next    string
+		want    codes.Code
+	}{
+		{name: "changes it", current: password, next: "newsecret456", want: codes.OK},
+		{name: "wrong current password", current: "secret124", next: "newsecret456", want: codes.Unauthenticated},
+		{name: "new password breaks the policy", current: password, next: "short", want: codes.InvalidArgument},
+		{name: "unknown user", user: uuid.NewString(), current: password, next: "newsecret456", want: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, userId := newUsers(t)
+			user := userId
+			if tt.user != "" {
+				user = tt.user
+			}
+
+			_, err := svc.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
+				Id:              &pb.UUID{Value: user},
+				CurrentPassword: tt.current,
+				NewPassword:     tt.next,
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+
+			// only a changed password lets the user in with the new one.
+			_, err = svc.VerifyCredentials(context.Background(), &pb.VerifyCredentialsRequest{
+				Email:    "jane@example.com",
+				Password: tt.next,
+			})
+			if changed := err == nil; changed != (tt.want == codes.OK) {
+				t.Errorf("new password accepted = %v after a %v", changed, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	tests := []struct {
+		name string
+		id   *pb.UUID
+		want codes.Code
+	}{
+		{name: "updates the user", want: codes.OK},
+		{name: "no id", id: &pb.UUID{}, want: codes.Aborted},
+		{name: "unknown user", id: &pb.UUID{Value: uuid.NewString()}, want: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, userId := newUsers(t)
+			id := &pb.UUID{Value: userId}
+			if tt.id != nil {
+				id = tt.id
+			}
+
+			_, err := svc.UpdateUser(context.Background(), &pb.UpdateUserRequest{User: &pb.User{
+				Id:    id,
+				Name:  "Jane Wanjiku",
+				Email: "jane@example.com",
+			}})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("got code %v, want %v: %v", got, tt.want, err)
+			}
+
+			resp, err := svc.GetUser(context.Background(), &pb.GetUserRequest{Id: &pb.UUID{Value: userId}})
+			if err != nil {
+				t.Fatalf("GetUser: %v", err)
+			}
+			if renamed := resp.User.Name == "Jane Wanjiku"; renamed != (tt.want == codes.OK) {
+				t.Errorf("name = %q after a %v", resp.User.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	svc, userId := newUsers(t)
+	ctx := context.Background()
+	id := &pb.UUID{Value: userId}
+
+	if _, err := svc.AddAddress(ctx, &pb.AddAddressRequest{Address: &pb.Address{UserId: id, Town: "Nairobi"}}); err != nil {
+		t.Fatalf("AddAddress: %v", err)
+	}
+	repo := svc.repo.(*MemoryRepository)
+	repo.AddComment(userId, uuid.NewString(), "Call me on 0700000000")
+	if _, err := svc.DeleteUser(ctx, &pb.DeleteUserRequest{Id: id}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	// the user is erased, not removed.
+	resp, err := svc.GetUser(ctx, &pb.GetUserRequest{Id: id})
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if resp.User.Email == "jane@example.com" || resp.User.Phone != "" {
+		t.Errorf("user kept their details: %v", resp.User)
+	}
+	addresses, err := svc.ListAddresses(ctx, &pb.ListAddressesRequest{UserId: id})
+	if err != nil || len(addresses.Addresses) != 0 {
+		t.Errorf("got %d addresses, %v", len(addresses.GetAddresses()), err)
+	}
+	_, err = svc.VerifyCredentials(ctx, &pb.VerifyCredentialsRequest{Email: "jane@example.com", Password: password})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("erased user logged in: %v", err)
+	}
+	// comments stay in their threads without what the user wrote.
+	comments, err := repo.ExportComments(ctx, userId)
+	if err != nil || len(comments) != 1 || comments[0].Body != erasedComment {
+		t.Errorf("got comments %+v, %v", comments, err)
+	}
+
+	if _, err := svc.DeleteUser(ctx, &pb.DeleteUserRequest{Id: id}); status.Code(err) != codes.NotFound {
+		t.Errorf("erasing twice got %v, want %v", err, codes.NotFound)
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	tests := []struct {
+		name string
+		// defaults says which of three addresses are added as the default.
+		defaults [3]bool
+		// update makes the address at this index the default, -1 for none.
+		update      int
+		wantDefault int
+	}{
+		{name: "first address is the default", update: -1, wantDefault: 0},
+		{name: "a new default replaces the old one", defaults: [3]bool{false, false, true}, update: -1, wantDefault: 2},
+		{name: "updating an address to the default", update: 1, wantDefault: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, userId := newUsers(t)
+			ctx := context.Background()
+			user := &pb.UUID{Value: userId}
+
+			var ids []string
+			for i, isDefault := range tt.defaults {
+				resp, err := svc.AddAddress(ctx, &pb.AddAddressRequest{Address: &pb.Address{
+					UserId:    user,
+					Label:     []string{"home", "work", "parents"}[i],
+					Town:      "Nairobi",
+					IsDefault: isDefault,
+				}})
+				if err != nil {
+					t.Fatalf("AddAddress: %v", err)
+				}
+				ids = append(ids, resp.Id.Value)
+			}
+
+			if tt.update >= 0 {
+				_, err := svc.UpdateAddress(ctx, &pb.UpdateAddressRequest{Address: &pb.Address{
+					Id:        &pb.UUID{Value: ids[tt.update]},
+					UserId:    user,
+					Label:     "office",
+					Town:      "Nairobi",
+					IsDefault: true,
+				}})
+				if err != nil {
+					t.Fatalf("UpdateAddress: %v", err)
+				}
+			}
+
+			resp, err := svc.ListAddresses(ctx, &pb.ListAddressesRequest{UserId: user})
+			if err != nil {
+				t.Fatalf("ListAddresses: %v", err)
+			}
+			var defaults []string
+			for _, address := range resp.Addresses {
+				if address.IsDefault {
+					defaults = append(defaults, address.Id.Value)
+				}
+			}
+			if len(defaults) != 1 || defaults[0] != ids[tt.wantDefault] {
+				t.Errorf("defaults = %v, want [%s]", defaults, ids[tt.wantDefault])
+			}
+			if resp.Addresses[0].Id.Value != ids[tt.wantDefault] {
+				t.Errorf("default is not listed first")
+			}
+		})
+	}
+
+	// a failed update keeps the old default.
+	svc, userId := newUsers(t)
+	ctx := context.Background()
+	user := &pb.UUID{Value: userId}
+	added, err := svc.AddAddress(ctx, &pb.AddAddressRequest{Address: &pb.Address{UserId: user, Town: "Nairobi"}})
+	if err != nil {
+		t.Fatalf("AddAddress: %v", err)
+	}
+	_, err = svc.UpdateAddress(ctx, &pb.UpdateAddressRequest{Address: &pb.Address{
+		Id:        &pb.UUID{Value: uuid.NewString()},
+		UserId:    user,
+		IsDefault: true,
+	}})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v, want %v", err, codes.NotFound)
+	}
+	address, err := svc.GetAddress(ctx, &pb.GetAddressRequest{Id: added.Id, UserId: user})
+	if err != nil {
+		t.Fatalf("GetAddress: %v", err)
+	}
+	if !address.Address.IsDefault {
+		t.Errorf("failed update cleared the default")
+	}
+}
+
+func TestBuildExportArchive(t *testing.T) {
+	svc, userId := newUsers(t)
+	ctx := context.Background()
+	postId := uuid.NewString()
+	svc.repo.(*MemoryRepository).AddComment(userId, postId, "Great read")
+
+	archive, err := svc.buildExportArchive(ctx, userId)
+	if err != nil {
+		t.Fatalf("buildExportArchive: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"profile.json", "addresses.json", "orders.json", "reviews.json", "comments.json"} {
+		if files[name] == nil {
+			t.Errorf("archive has no %s", name)
+		}
+	}
+	if files["comments.json"] == nil {
+		return
+	}
+
+	r, err := files["comments.json"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var comments []ExportComment
+	if err := json.NewDecoder(r).Decode(&comments); err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || comments[0].PostId != postId || comments[0].Body != "Great read" {
+		t.Errorf("got comments %+v", comments)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		limit     int32
+		erase     bool
+		wantUsers int
+		wantLimit int32
+	}{
+		{name: "finds the user", query: "jane", wantUsers: 1, wantLimit: 20},
+		{name: "leaves erased users out", query: "", erase: true, wantUsers: 0, wantLimit: 20},
+		{name: "caps the page size", limit: 1000, wantUsers: 1, wantLimit: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, userId := newUsers(t)
+			ctx := context.Background()
+			if tt.erase {
+				if _, err := svc.DeleteUser(ctx, &pb.DeleteUserRequest{Id: &pb.UUID{Value: userId}}); err != nil {
+					t.Fatalf("DeleteUser: %v", err)
+				}
+			}
+
+			resp, err := svc.SearchUsers(ctx, &pb.SearchUsersRequest{Query: tt.query, Limit: tt.limit})
+			if err != nil {
+				t.Fatalf("SearchUsers: %v", err)
+			}
+			if len(resp.Users) != tt.wantUsers || resp.Total != int32(tt.wantUsers) || resp.Limit != tt.wantLimit {
+				t.Errorf("got %d of %d users, limit %d, want %d, limit %d",
+					len(resp.Users), resp.Total, resp.Limit, tt.wantUsers, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+//go:build integration
+
+package testenv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/kelcheone/chemistke/internal/database"
+)
+
+const (
+	pgUser     = "chemistke"
+	pgPassword = "chemistke"
+	pgDatabase = "chemistke"
+)
+
+// StartPostgres starts a throwaway Postgres with its data in a temp dir,
+// applies every migration and returns a connection to it. The server is
+// stopped when the test finishes.
+//
+// The Postgres binaries are downloaded on first use and cached under
+// ~/.embedded-postgres-go. Postgres refuses to run as root.
+func StartPostgres(tb testing.TB) database.DB {
+	tb.Helper()
+
+	port, err := freePort()
+	if err != nil {
+		tb.Fatalf("could not find a free port: %v", err)
+	}
+
+	dir := tb.TempDir()
+	pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V16).
+		Port(uint32(port)).
+		Username(pgUser).
+		Password(pgPassword).
+		Database(pgDatabase).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		Logger(io.Discard))
+	if err := pg.Start(); err != nil {
+		tb.Fatalf("could not start postgres: %v", err)
+	}
+	tb.Cleanup(func() {
+		if err := pg.Stop(); err != nil {
+			tb.Errorf("could not stop postgres: %v", err)
+		}
+	})
+
+	dsn := fmt.Sprintf(
+		"host=localhost port=%d user=%s password=%s dbname=%s sslmode=disable",
+		port,
+		pgUser,
+		pgPassword,
+		pgDatabase,
+	)
+	db, err := database.NewDatabase("postgres", dsn)
+	if err != nil {
+		tb.Fatalf("could not connect to postgres: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	migrations, err := database.Migrations(db)
+	if err != nil {
+		tb.Fatalf("could not load the migrations: %v", err)
+	}
+	if _, err := migrations.Up(context.Background()); err != nil {
+		tb.Fatalf("could not migrate the database: %v", err)
+	}
+
+	return db
+}
+
+func freePort() (int, error) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port, nil
+}
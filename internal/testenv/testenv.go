@@ -0,0 +1,195 @@
+//go:build integration
+
+// Package testenv runs the whole backend inside a test: a throwaway Postgres
+// with every migration applied, the four gRPC services on in-memory bufconn
+// listeners and the gateway's echo routes behind an httptest server.
+//
+//	func TestCheckout(t *testing.T) {
+//		env := testenv.New(t)
+//		resp := env.Do(t, http.MethodPost, "/api/v1/users", user, "")
+//		...
+//	}
+//
+// The package is only built with the integration tag, so it and its
+// embedded Postgres stay out of the services and of a plain go test:
+//
+//	go test -tags integration ./...
+package testenv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kelcheone/chemistke/cmd/api-gateway/routes"
+	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/logging"
+	cmsservice "github.com/kelcheone/chemistke/internal/services/cms"
+	orderservice "github.com/kelcheone/chemistke/internal/services/orders"
+	productservice "github.com/kelcheone/chemistke/internal/services/products"
+	userservice "github.com/kelcheone/chemistke/internal/services/users"
+	cms_proto "github.com/kelcheone/chemistke/pkg/grpc/cms"
+	order_proto "github.com/kelcheone/chemistke/pkg/grpc/order"
+	product_proto "github.com/kelcheone/chemistke/pkg/grpc/product"
+	user_proto "github.com/kelcheone/chemistke/pkg/grpc/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	bufSize = 1024 * 1024
+	// jwtSecret signs the tokens the gateway hands out during a test.
+	jwtSecret = "testenv"
+)
+
+// Env is one running copy of the backend.
+type Env struct {
+	DB       database.DB
+	Users    user_proto.UserServiceClient
+	Products product_proto.ProductServiceClient
+	Orders   order_proto.OrderServiceClient
+	Cms      cms_proto.CmsServiceClient
+	// Gateway serves the same routes as cmd/api-gateway.
+	Gateway *httptest.Server
+}
+
+// New starts Postgres, the services and the gateway. Everything is torn
+// down when the test finishes.
+func New(tb testing.TB) *Env {
+	tb.Helper()
+
+	db := StartPostgres(tb)
+	env := &Env{DB: db}
+
+	userConn := serve(tb, func(s *grpc.Server) {
+		user_proto.RegisterUserServiceServer(s, userservice.NewService(userservice.NewPostgresRepository(db), config.Default().Password))
+	})
+	env.Users = user_proto.NewUserServiceClient(userConn)
+
+	productConn := serve(tb, func(s *grpc.Server) {
+		product_proto.RegisterProductServiceServer(
+			s,
+			productservice.NewProductService(productservice.NewPostgresRepository(db)),
+		)
+	})
+	env.Products = product_proto.NewProductServiceClient(productConn)
+
+	orderConn := serve(tb, func(s *grpc.Server) {
+		order_proto.RegisterOrderServiceServer(s, orderservice.NewOrderService(orderservice.NewPostgresRepository(db)))
+	})
+	env.Orders = order_proto.NewOrderServiceClient(orderConn)
+
+	cmsConn := serve(tb, func(s *grpc.Server) {
+		cms := cmsservice.NewCmsService(cmsservice.NewPostgresRepository(db))
+		cms.Products = env.Products
+		cms_proto.RegisterCmsServiceServer(s, cms)
+	})
+	env.Cms = cms_proto.NewCmsServiceClient(cmsConn)
+
+	utils.SetSecretKey(jwtSecret)
+	seo := &routes.SeoServer{CmsClient: env.Cms, ProductClient: env.Products}
+	e := routes.NewRouter(routes.Servers{
+		Users:    &routes.UserServer{UserClient: env.Users},
+		Products: &routes.ProductServer{ProductClient: env.Products},
+		Orders:   &routes.OrderServer{OrderClient: env.Orders, UserClient: env.Users},
+		Cms:      &routes.CmsServer{CmsClient: env.Cms},
+		Seo:      seo,
+	}, config.Default().Gateway)
+	env.Gateway = httptest.NewServer(e)
+	tb.Cleanup(env.Gateway.Close)
+	seo.SiteURL = env.Gateway.URL
+
+	return env
+}
+
+// serve starts a gRPC server on a bufconn listener and returns a client
+// connection to it.
+func serve(tb testing.TB, register func(*grpc.Server)) *grpc.ClientConn {
+	tb.Helper()
+
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer(grpc.UnaryInterceptor(logging.UnaryServerInterceptor()))
+	register(s)
+	go s.Serve(lis)
+	tb.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logging.UnaryClientInterceptor()),
+	)
+	if err != nil {
+		tb.Fatalf("could not connect to the bufconn server: %v", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// Do sends a request to the gateway. body is encoded as JSON unless it is
+// nil, and token is sent as a bearer token unless it is empty.
+func (env *Env) Do(tb testing.TB, method, path string, body any, token string) *http.Response {
+	tb.Helper()
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			tb.Fatalf("could not encode the request body: %v", err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, env.Gateway.URL+path, r)
+	if err != nil {
+		tb.Fatalf("could not build the request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := env.Gateway.Client().Do(req)
+	if err != nil {
+		tb.Fatalf("%s %s: %v", method, path, err)
+	}
+	tb.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+// Decode checks the response status and decodes its JSON body into v.
+func Decode(tb testing.TB, resp *http.Response, wantStatus int, v any) {
+	tb.Helper()
+
+	if resp.StatusCode != wantStatus {
+		b, _ := io.ReadAll(resp.Body)
+		tb.Fatalf(
+			"%s %s: got status %d, want %d: %s",
+			resp.Request.Method,
+			resp.Request.URL.Path,
+			resp.StatusCode,
+			wantStatus,
+			b,
+		)
+	}
+	if v == nil {
+		return
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		tb.Fatalf("could not decode the response: %v", err)
+	}
+}
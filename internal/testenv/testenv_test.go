@@ -0,0 +1,142 @@
+//go:build integration
+
+package testenv_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/kelcheone/chemistke/internal/testenv"
+	user_proto "github.com/kelcheone/chemistke/pkg/grpc/user"
+)
+
+type created struct {
+	Id struct {
+		Value string `json:"value"`
+	} `json:"id"`
+}
+
+type token struct {
+	Token string `json:"token"`
+}
+
+// register signs a user up through the gateway, logs them in and returns
+// their id and token.
+func register(t *testing.T, env *testenv.Env, email string) (string, string) {
+	t.Helper()
+
+	var user created
+	testenv.Decode(t, env.Do(t, http.MethodPost, "/api/v1/users", map[string]string{
+		"name":     "Jane Doe",
+		"email":    email,
+		"phone":    "+254700000000",
+		"password": "secret123",
+	}, ""), http.StatusCreated, &user)
+
+	return user.Id.Value, login(t, env, email)
+}
+
+func login(t *testing.T, env *testenv.Env, email string) string {
+	t.Helper()
+
+	var login token
+	testenv.Decode(t, env.Do(t, http.MethodPost, "/api/v1/auth/login", map[string]string{
+		"email":    email,
+		"password": "secret123",
+	}, ""), http.StatusAccepted, &login)
+	return login.Token
+}
+
+// TestCheckout registers an admin and a customer, has the admin add a
+// product and the customer order it.
+func TestCheckout(t *testing.T) {
+	env := testenv.New(t)
+	ctx := context.Background()
+
+	// roles are only granted through the admin role endpoint, which needs
+	// an admin, so the first one is made directly.
+	adminId, _ := register(t, env, "admin@example.com")
+	_, err := env.Users.UpdateUserRole(ctx, &user_proto.UpdateUserRoleRequest{
+		Id:   &user_proto.UUID{Value: adminId},
+		Role: user_proto.UserRoles_ADMIN,
+	})
+	if err != nil {
+		t.Fatalf("UpdateUserRole: %v", err)
+	}
+	adminToken := login(t, env, "admin@example.com")
+
+	var categoryId, subCategoryId, brandId string
+	err = env.DB.QueryRowContext(ctx, `WITH
+	  c AS (INSERT INTO product_category (name, slug) VALUES ('Pain relief', 'pain-relief') RETURNING id),
+	  s AS (INSERT INTO product_sub_category (name, category_id, slug) SELECT 'Tablets', id, 'tablets' FROM c RETURNING id),
+	  b AS (INSERT INTO product_brand (name) VALUES ('Panadol') RETURNING id)
+	SELECT c.id, s.id, b.id FROM c, s, b`).Scan(&categoryId, &subCategoryId, &brandId)
+	if err != nil {
+		t.Fatalf("could not add the catalogue: %v", err)
+	}
+
+	var product created
+	testenv.Decode(t, env.Do(t, http.MethodPost, "/api/v1/products", map[string]any{
+		"name":            "Panadol Extra",
+		"description":     "500mg tablets",
+		"category_id":     categoryId,
+		"sub_category_id": subCategoryId,
+		"brand_id":        brandId,
+		"price":           250,
+		"quantity":        5,
+	}, adminToken), http.StatusOK, &product)
+
+	_, customerToken := register(t, env, "jane@example.com")
+	testenv.Decode(t, env.Do(t, http.MethodPost, "/api/v1/products", map[string]any{
+		"name":            "Panadol Night",
+		"description":     "500mg tablets",
+		"category_id":     categoryId,
+		"sub_category_id": subCategoryId,
+		"brand_id":        brandId,
+	}, customerToken), http.StatusUnauthorized, nil)
+
+	var address created
+	testenv.Decode(t, env.Do(t, http.MethodPost, "/api/v1/users/me/addresses", map[string]any{
+		"label":  "Home",
+		"county": "Nairobi",
+		"town":   "Westlands",
+		"phone":  "+254700000000",
+	}, customerToken), http.StatusCreated, &address)
+
+	order := func(quantity int32) map[string]any {
+		return map[string]any{
+			"product_id": product.Id.Value,
+			"quantity":   quantity,
+			"total":      float32(quantity) * 250,
+			"address_id": address.Id.Value,
+		}
+	}
+
+	var placed struct {
+		Order struct {
+			Id struct {
+				Value string `json:"value"`
+			} `json:"id"`
+			Quantity int32  `json:"quantity"`
+			Status   string `json:"status"`
+		} `json:"order"`
+	}
+	testenv.Decode(t, env.Do(t, http.MethodPost, "/api/v1/orders", order(2), customerToken), http.StatusCreated, &placed)
+	if placed.Order.Id.Value == "" || placed.Order.Quantity != 2 || placed.Order.Status != "pending" {
+		t.Errorf("got order %+v", placed.Order)
+	}
+
+	// only 3 units are left.
+	testenv.Decode(t, env.Do(t, http.MethodPost, "/api/v1/orders", order(4), customerToken), http.StatusBadRequest, nil)
+	testenv.Decode(t, env.Do(t, http.MethodPost, "/api/v1/orders", order(1), ""), http.StatusUnauthorized, nil)
+
+	var quantity int32
+	err = env.DB.QueryRowContext(ctx, `SELECT quantity FROM products WHERE id=$1`, product.Id.Value).Scan(&quantity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quantity != 3 {
+		t.Errorf("stock = %d, want 3", quantity)
+	}
+}
@@ -0,0 +1,126 @@
+// Package tracing sets up OpenTelemetry tracing for the gateway and the gRPC
+// services, and the hooks that carry a trace across HTTP, gRPC and SQL.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kelcheone/chemistke/internal/config"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"google.golang.org/grpc"
+)
+
+// Init installs the global tracer provider for service. cfg.Exporter picks
+// the exporter:
+//
+//   - otlp sends spans to OTEL_EXPORTER_OTLP_ENDPOINT over gRPC
+//   - stdout prints them, for local runs
+//   - file appends them as JSON to cfg.File
+//   - none, or empty, turns tracing off
+//
+// The returned func flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context, service string, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, closeExporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.New(
+		ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(service)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not describe service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeErr := closeExporter(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch kind := strings.ToLower(cfg.Exporter); kind {
+	case "", "none":
+		return nil, noClose, nil
+	case "otlp":
+		exporter, err := otlptracegrpc.New(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not create otlp exporter: %w", err)
+		}
+		return exporter, noClose, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not create stdout exporter: %w", err)
+		}
+		return exporter, noClose, nil
+	case "file":
+		if cfg.File == "" {
+			return nil, nil, fmt.Errorf("a trace file is required for the file exporter")
+		}
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("could not create file exporter: %w", err)
+		}
+		return exporter, f.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q", kind)
+	}
+}
+
+// ServerOption traces incoming gRPC calls, continuing the caller's trace.
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+// DialOption traces outgoing gRPC calls and passes the trace along.
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// EchoMiddleware starts a span for every request except scrapes and health
+// checks.
+func EchoMiddleware(service string) echo.MiddlewareFunc {
+	return otelecho.Middleware(service, otelecho.WithSkipper(func(c echo.Context) bool {
+		return c.Path() == "/metrics" || c.Path() == "/health"
+	}))
+}
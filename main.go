@@ -1,19 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"os"
 
-	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/kelcheone/chemistke/cmd/utils"
+	"github.com/kelcheone/chemistke/internal/config"
 	"github.com/kelcheone/chemistke/internal/database"
+	"github.com/kelcheone/chemistke/internal/files"
+	"github.com/kelcheone/chemistke/internal/logging"
+	"github.com/kelcheone/chemistke/internal/metrics"
 	cmsservice "github.com/kelcheone/chemistke/internal/services/cms"
 	orderservice "github.com/kelcheone/chemistke/internal/services/orders"
 	productservice "github.com/kelcheone/chemistke/internal/services/products"
 	userservice "github.com/kelcheone/chemistke/internal/services/users"
+	"github.com/kelcheone/chemistke/internal/tracing"
 	cms_proto "github.com/kelcheone/chemistke/pkg/grpc/cms"
 	order_proto "github.com/kelcheone/chemistke/pkg/grpc/order"
 	product_proto "github.com/kelcheone/chemistke/pkg/grpc/product"
@@ -21,49 +28,94 @@ import (
 )
 
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Could not load env variables: %v\n", err)
+	logging.Init("chemistke")
+	if err := run(); err != nil {
+		logging.Fatal("chemistke stopped", "error", err)
 	}
-	connStr := fmt.Sprintf(
-
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+}
 
-		os.Getenv("DB_HOST"),
+// run serves until something fails. The binaries under cmd follow the same
+// shape: errors come back to main instead of exiting, so deferred closes
+// and the trace flush still run.
+func run() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	logging.SetLevel(cfg.Log.Level)
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		fmt.Print(cfg)
+		return nil
+	}
+	files.Configure(cfg.AWS)
 
-		os.Getenv("DB_PORT"),
+	db, err := utils.GetDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("could not connect to the database: %w", err)
+	}
 
-		os.Getenv("DB_USER"),
+	defer db.Close()
 
-		os.Getenv("DB_PASSWORD"),
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.RunMigrate(context.Background(), db, os.Args[2:], os.Stdout); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		return nil
+	}
+	// refuse to serve against a schema this build was not written for.
+	if err := database.CheckSchema(context.Background(), db); err != nil {
+		return fmt.Errorf("database schema is not current: %w", err)
+	}
 
-		os.Getenv("DB_NAME"),
-	)
-	db, err := database.NewDatabase("postgres", connStr)
+	shutdownTracing, err := tracing.Init(context.Background(), "chemistke", cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Could not connect to the database: %v\n", err)
+		return fmt.Errorf("could not set up tracing: %w", err)
 	}
+	defer shutdownTracing(context.Background())
 
-	defer db.Close()
+	newUservice := userservice.NewService(userservice.NewPostgresRepository(db), cfg.Password)
+	newProductService := productservice.NewProductService(productservice.NewPostgresRepository(db))
+	newOrderService := orderservice.NewOrderService(orderservice.NewPostgresRepository(db))
+	newCmsService := cmsservice.NewCmsService(cmsservice.NewPostgresRepository(db))
+	if host := cfg.Services.Product.Host; host != "" {
+		productConn, err := grpc.NewClient(
+			host,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithUnaryInterceptor(logging.UnaryClientInterceptor()),
+			tracing.DialOption(),
+		)
+		if err != nil {
+			return fmt.Errorf("could not connect to the product service: %w", err)
+		}
+		defer productConn.Close()
+		newCmsService.Products = product_proto.NewProductServiceClient(productConn)
+	}
+	// flips scheduled posts live once their publish time passes.
+	go newCmsService.RunScheduler(context.Background(), cfg.Cms.SchedulerInterval)
 
-	newUservice := userservice.NewService(db)
-	newProductService := productservice.NewProductService(db)
-	newOrderService := orderservice.NewOrderService(db)
-	newCmsService := cmsservice.NewCmsService(db)
+	metrics.RegisterDB(db, "chemistke")
+	metrics.Serve(cfg.Services.Monolith.MetricsAddr)
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			logging.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor(),
+		),
+		tracing.ServerOption(),
+	)
 
 	user_proto.RegisterUserServiceServer(grpcServer, newUservice)
 	product_proto.RegisterProductServiceServer(grpcServer, newProductService)
 	order_proto.RegisterOrderServiceServer(grpcServer, newOrderService)
 	cms_proto.RegisterCmsServiceServer(grpcServer, newCmsService)
-	lis, err := net.Listen("tcp", ":8090")
+	lis, err := net.Listen("tcp", cfg.Services.Monolith.Addr)
 	if err != nil {
-		log.Fatalf("Could not start the listener: %v\n", err)
+		return fmt.Errorf("could not start the listener: %w", err)
 	}
 
-	fmt.Println("Serving on port 8090------------------")
+	slog.Info("serving", "addr", cfg.Services.Monolith.Addr)
 	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("Could not serve: %v\n", err)
+		return fmt.Errorf("could not serve: %w", err)
 	}
+	return nil
 }
@@ -73,16 +73,14 @@ func Init() error {
 
 	// create post
 	newPost := &cms_proto.Post{
-		PublishedDate: gofakeit.Date().Format("2006-01-02 15:04"),
-		UpdatedDate:   gofakeit.Date().Format("2006-01-02 15:04"),
-		CoverImage:    gofakeit.URL(),
-		Title:         gofakeit.BookTitle(),
-		Description:   gofakeit.LoremIpsumSentence(60),
-		Slug:          gofakeit.URL(),
-		Content:       gofakeit.LoremIpsumParagraph(6, 6, 100, " "),
-		Status:        "draft",
-		AuthorId:      author.AuthorId,
-		CategoryId:    createCategoryRes.CategoryId,
+		CoverImage:  gofakeit.URL(),
+		Title:       gofakeit.BookTitle(),
+		Description: gofakeit.LoremIpsumSentence(60),
+		Slug:        gofakeit.URL(),
+		Content:     gofakeit.LoremIpsumParagraph(6, 6, 100, " "),
+		Status:      cms_proto.PostStatus_DRAFT,
+		AuthorId:    author.AuthorId,
+		CategoryId:  createCategoryRes.CategoryId,
 	}
 
 	postId, err := c.CreatePost(ctx, &cms_proto.CreatePostRequest{Post: newPost})
@@ -96,7 +94,6 @@ func Init() error {
 	newPost.PostId = postId.PostId
 	newPost.Slug = gofakeit.URL()
 	newPost.Content = gofakeit.LoremIpsumParagraph(6, 6, 300, "    ")
-	newPost.Status = "pupblished"
 
 	updatePostRes, err := c.UpdatePost(ctx, &cms_proto.UpdatePostRequest{PostId: newPost.PostId, Post: newPost})
 	if err != nil {
@@ -106,7 +103,7 @@ func Init() error {
 	fmt.Printf("Updated user: %v\n", updatePostRes.PostId)
 
 	// GET post
-	getPostRes, err := c.GetPost(ctx, &cms_proto.GetPostRequest{PostId: newPost.PostId})
+	getPostRes, err := c.GetPost(ctx, &cms_proto.GetPostRequest{PostId: newPost.PostId, IncludeUnpublished: true})
 	if err != nil {
 		return err
 	}
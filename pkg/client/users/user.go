@@ -154,7 +154,7 @@ func CreateUser(
 			Email: gofakeit.Email(),
 			Phone: gofakeit.Phone(),
 			// Password: gofakeit.Password(true, true, true, true, false, 12),
-			Password: "1234567",
+			Password: "password123",
 			Role:     user_proto.UserRoles_ADMIN,
 		},
 	}
@@ -2,9 +2,12 @@
 package status
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/kelcheone/chemistke/pkg/codes"
+	grpccodes "google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
 // Status represents an error with additional context.
@@ -12,6 +15,7 @@ type Status struct {
 	code    codes.Code
 	message string
 	details []interface{}
+	cause   error
 }
 
 // New creates a new Status.
@@ -42,17 +46,30 @@ func (s *Status) Message() string {
 	return s.message
 }
 
+// GRPCStatus converts the status into a gRPC status, so errors returned from
+// a gRPC handler reach the client with their code rather than as Unknown.
+// Codes match gRPC's one for one, details are not carried over.
+func (s *Status) GRPCStatus() *grpcstatus.Status {
+	return grpcstatus.New(grpccodes.Code(s.code), s.message)
+}
+
 // WithDetails returns a new Status with the provided details appended.
 func (s *Status) WithDetails(details ...interface{}) *Status {
 	newStatus := &Status{
 		code:    s.code,
 		message: s.message,
 		details: append([]interface{}{}, s.details...),
+		cause:   s.cause,
 	}
 	newStatus.details = append(newStatus.details, details...)
 	return newStatus
 }
 
+// Unwrap returns the error the status was created from, if any.
+func (s *Status) Unwrap() error {
+	return s.cause
+}
+
 // Details returns the status details.
 func (s *Status) Details() []interface{} {
 	return s.details
@@ -63,15 +80,22 @@ func FromError(err error) *Status {
 	if err == nil {
 		return nil
 	}
-	if se, ok := err.(*Status); ok {
+	var se *Status
+	if errors.As(err, &se) {
 		return se
 	}
 	return New(codes.Unknown, err.Error())
 }
 
-// Errorf creates a Status from the given code and format string.
+// Errorf creates a Status from the given code and format string. An error
+// formatted with %w stays reachable through errors.Is and errors.As.
 func Errorf(c codes.Code, format string, a ...interface{}) error {
-	return Newf(c, format, a...)
+	err := fmt.Errorf(format, a...)
+	return &Status{
+		code:    c,
+		message: err.Error(),
+		cause:   errors.Unwrap(err),
+	}
 }
 
 // Convert is a convenience function to convert errors to Status objects.
@@ -79,12 +103,22 @@ func Convert(err error) *Status {
 	if err == nil {
 		return nil
 	}
-	if se, ok := err.(*Status); ok {
+	var se *Status
+	if errors.As(err, &se) {
 		return se
 	}
 	return New(codes.Unknown, err.Error())
 }
 
+// Code returns the code of err: OK for nil and Unknown for an error that is
+// not a Status.
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	return Convert(err).Code()
+}
+
 // OK returns a Status with OK code and no message.
 func OK() *Status {
 	return New(codes.OK, "")